@@ -1,80 +1,470 @@
 package main
 
 import (
-	"log"
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/99designs/gqlgen/graphql/handler"
-	"github.com/99designs/gqlgen/graphql/playground"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
 	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/gorilla/websocket"
+	"github.com/vektah/gqlparser/v2/ast"
 
 	"miro-lite-standalone/backend/internal/board"
+	"miro-lite-standalone/backend/internal/boardclient"
+	"miro-lite-standalone/backend/internal/config"
 	"miro-lite-standalone/backend/internal/graph"
+	"miro-lite-standalone/backend/internal/health"
+	"miro-lite-standalone/backend/internal/logging"
+	"miro-lite-standalone/backend/internal/metrics"
+	"miro-lite-standalone/backend/internal/middleware"
+	"miro-lite-standalone/backend/internal/mirror"
+	"miro-lite-standalone/backend/internal/pubsub"
+	"miro-lite-standalone/backend/internal/ratelimit"
+	"miro-lite-standalone/backend/internal/rest"
+	"miro-lite-standalone/backend/internal/tlsutil"
+	"miro-lite-standalone/backend/internal/tracing"
 )
 
+// version est fixée au build : go build -ldflags "-X main.version=1.2.3".
+var version = "dev"
+
+// readinessCacheTTL : durée pendant laquelle /readyz réutilise le résultat
+// des sondes qui écrivent sur le disque ou passent par le réseau.
+const readinessCacheTTL = 5 * time.Second
+
 func main() {
-	svc := board.NewService("data/boards.json")
+	cfg, err := config.Load(os.Args[1:], os.Getenv)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "config: %v\n", err)
+		os.Exit(2)
+	}
+	if cfg.PrintConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	level, _ := logging.ParseLevel(cfg.Log.Level) // validé par config.Load
+	logger, err := logging.New(os.Stderr, level, cfg.Log.Format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	slog.SetDefault(logger)
+
+	// Avant le service : le chargement du store est tracé.
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		SampleRatio: cfg.Tracing.SampleRatio,
+		ServiceName: cfg.Tracing.ServiceName,
+		Version:     version,
+	})
+	if err != nil {
+		logger.Error("tracing: setup failed", "err", err)
+		os.Exit(1)
+	}
+	if cfg.Tracing.Exporter != tracing.ExporterNone {
+		logger.Info("tracing: enabled", "exporter", cfg.Tracing.Exporter, "endpoint", cfg.Tracing.Endpoint, "sampleRatio", cfg.Tracing.SampleRatio)
+	}
+
+	var m *metrics.Metrics
+	if cfg.Features.Metrics {
+		m = metrics.New()
+	}
+	// Avant le service : avec Redis, les écritures passent par l'état
+	// partagé entre instances.
+	broker, err := newBroker(cfg.PubSub, m, logger)
+	if err != nil {
+		logger.Error("pubsub: cannot start", "err", err)
+		os.Exit(1)
+	}
+	redisBroker, _ := broker.(*pubsub.Redis)
+	opts := []board.Option{
+		board.WithMissingBoardPolicy(cfg.Boards.MissingPolicy),
+		board.WithCorruptStorePolicy(cfg.Storage.OnCorrupt),
+		board.WithMaxWidgets(cfg.Limits.MaxWidgetsPerBoard),
+		board.WithSaveObserver(m.ObserveSave),
+		board.WithLogger(logger),
+	}
+	if redisBroker != nil {
+		opts = append(opts, board.WithSharedState(redisBroker))
+	}
+	var mir *mirror.Mirror
+	if cfg.Mirror.Enabled() {
+		mir = mirror.New(cfg.Mirror.Upstream, cfg.Mirror.Boards,
+			mirror.WithMode(cfg.Mirror.Mode),
+			mirror.WithCacheTTL(cfg.Mirror.CacheTTL),
+			mirror.WithToken(cfg.Mirror.Token),
+			mirror.WithLogger(logger),
+		)
+		opts = append(opts, board.WithUpstream(mir))
+	}
+	svc, err := board.NewService(cfg.StorePath(), opts...)
+	if err != nil {
+		logger.Error("store: cannot start", "err", err)
+		os.Exit(1)
+	}
+	// ctx est annulé au premier SIGINT/SIGTERM ; le suivant arrête le
+	// processus sans attendre l'arrêt gracieux.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	context.AfterFunc(ctx, stop)
+	if mir != nil {
+		logger.Info("mirror: enabled", "upstream", cfg.Mirror.Upstream, "boards", cfg.Mirror.Boards, "mode", cfg.Mirror.Mode)
+		// Les subscriptions de l'upstream sont fermées dès le début de l'arrêt.
+		go func() { _ = mir.Run(ctx, svc) }()
+	}
+	m.RegisterBoardCount(svc.Count)
+	svc.OnBoardDeleted(m.BoardDeleted)
+
+	checker := health.NewChecker(health.ReadBuildInfo(version))
+	checker.AddLiveness("board-service", svc.Ping)
+	checker.AddReadiness("store", svc.CheckStore)
+	checker.AddReadiness("storage", health.Cached(readinessCacheTTL, svc.CheckStorage))
+	if mir != nil {
+		checker.AddReadiness("mirror-upstream", health.Cached(readinessCacheTTL, mir.CheckUpstream))
+	}
+	if redisBroker != nil {
+		checker.AddReadiness("pubsub", health.Cached(readinessCacheTTL, redisBroker.Ping))
+	}
+	// Les écritures et suppressions des autres instances remplacent les
+	// copies locales ; leurs opérations de texte rejoignent les répliques.
+	broker.OnRemote(func(b *board.Model) {
+		if _, err := svc.ApplyRemote(context.Background(), b); err != nil {
+			logger.Warn("pubsub: remote update not applied", "board", b.ID, "version", b.Version, "err", err)
+		}
+	})
+	broker.OnRemoteText(func(change *board.TextChange) {
+		if err := svc.ApplyRemoteText(context.Background(), change); err != nil {
+			logger.Warn("pubsub: remote text update not applied", "board", change.BoardID, "widget", change.WidgetID, "field", change.Field, "err", err)
+		}
+	})
+	broker.OnRemoteDeleted(func(id string) {
+		applied, err := svc.ApplyRemoteDelete(context.Background(), id)
+		if err != nil {
+			logger.Warn("pubsub: remote deletion not applied", "board", id, "err", err)
+		}
+		if applied {
+			m.BoardDeleted(id)
+		}
+	})
 
 	// GraphQL
-	resolver := &graph.Resolver{BoardService: svc}
-	gqlSrv := handler.New(graph.NewExecutableSchema(graph.Config{Resolvers: resolver}))
+	resolver := graph.NewResolver(svc, broker)
+	resolver.Logger = logger
+	resolver.MaxSubscriptionsPerConn = cfg.Limits.MaxSubscriptionsPerConnection
+	handler, err := newHandler(cfg, svc, resolver, checker, m, logger)
+	if err != nil {
+		logger.Error("graphql", "err", err)
+		os.Exit(1)
+	}
+
+	if cfg.Storage.Backend == config.StorageMemory {
+		logger.Warn("storage: in-memory, nothing is persisted")
+	} else {
+		logger.Info("storage: file", "path", cfg.StorePath())
+	}
+	srv := &http.Server{
+		Addr:     cfg.Addr,
+		Handler:  handler,
+		ErrorLog: slog.NewLogLogger(logger.Handler(), slog.LevelWarn),
+	}
+	listen := srv.ListenAndServe
+	scheme := "http"
+	switch {
+	case cfg.TLS.Enabled():
+		tlsConfig, err := newTLSConfig(cfg.TLS, logger)
+		if err != nil {
+			logger.Error("tls: setup failed", "err", err)
+			os.Exit(1)
+		}
+		srv.TLSConfig = tlsConfig
+		// Certificats fournis par TLSConfig.GetCertificate / Certificates.
+		listen = func() error { return srv.ListenAndServeTLS("", "") }
+		scheme = "https"
+	case cfg.HTTP2.H2C:
+		var protocols http.Protocols
+		protocols.SetHTTP1(true)
+		protocols.SetUnencryptedHTTP2(true)
+		srv.Protocols = &protocols
+		logger.Info("h2c: cleartext HTTP/2 enabled")
+	}
+
+	logger.Info("backend listening", "addr", cfg.Addr, "scheme", scheme)
+	if cfg.Features.Playground {
+		logger.Info("GraphiQL playground", "url", fmt.Sprintf("%s://%s/playground", scheme, displayHost(cfg.Addr)))
+	}
+	if err := serve(ctx, srv, listen, checker, resolver, svc, shutdownTracing, cfg.ShutdownTimeout, logger); err != nil {
+		logger.Error("server stopped", "err", err)
+		os.Exit(1)
+	}
+}
+
+// newHandler assemble les routes (REST, GraphQL, sondes, métriques) et les
+// middlewares autour de svc et de resolver.
+func newHandler(cfg config.Config, svc *board.Service, resolver *graph.Resolver, checker *health.Checker, m *metrics.Metrics, logger *slog.Logger) (http.Handler, error) {
+	allowedOrigins := newOriginSet(cfg.CORS.AllowedOrigins)
+	limiter := ratelimit.New(cfg.Limits.RequestsPerSecond, cfg.Limits.Burst)
+	gqlSrv := handler.New(graph.NewExecutableSchema(graph.NewConfig(resolver)))
+	gqlSrv.SetErrorPresenter(graph.NewErrorPresenter(logger))
+	persisted, err := useQueryLimits(gqlSrv, cfg.GraphQL, logger)
+	if err != nil {
+		return nil, err
+	}
+	if m != nil {
+		// Les autres noms d'opérations sont comptés sous "other".
+		operations := boardclient.OperationNames()
+		if persisted != nil {
+			operations = append(operations, persisted.OperationNames()...)
+		}
+		gqlSrv.Use(m.GraphQLExtension(operations))
+	}
+	gqlSrv.Use(tracing.GraphQLExtension())
+	gqlSrv.Use(graph.WebsocketRateLimit(limiter))
 	gqlSrv.AddTransport(transport.Options{})
 	gqlSrv.AddTransport(transport.GET{})
 	gqlSrv.AddTransport(transport.POST{})
 	gqlSrv.AddTransport(transport.MultipartForm{})
 	gqlSrv.AddTransport(transport.Websocket{
-		KeepAlivePingInterval: 15 * time.Second,
+		KeepAlivePingInterval: cfg.Websocket.PingInterval,
+		InitFunc:              resolver.WebsocketInit,
 		Upgrader: websocket.Upgrader{
+			// permessage-deflate, négocié seulement si le client le propose.
+			EnableCompression: cfg.Features.Compression,
 			CheckOrigin: func(r *http.Request) bool {
 				origin := r.Header.Get("Origin")
 				if origin == "" {
 					return true
 				}
-				allowedOrigins := parseAllowedOrigins(os.Getenv("ALLOWED_ORIGINS"))
-				return allowedOrigins[origin]
+				return allowedOrigins.allows(origin)
 			},
 		},
 	})
 
 	mux := http.NewServeMux()
 
-	// REST (inchangé)
+	// REST
 	mux.HandleFunc("/health", func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("ok"))
 	})
-	mux.HandleFunc("/api/boards/", svc.HandleBoard)
+	mux.Handle("/livez", checker.LiveHandler())
+	mux.Handle("/readyz", checker.ReadyHandler())
+	if cfg.Features.REST {
+		mux.Handle("/api/", rest.NewHandler(svc, m, logger))
+	}
+	if m != nil {
+		mux.Handle("/metrics", m.Handler())
+	}
 
 	// GraphQL
-	mux.Handle("/graphql", gqlSrv)
-	mux.Handle("/playground", playground.Handler("GraphQL Playground", "/graphql"))
+	mux.Handle("/graphql", tracing.Route("/graphql", gqlSrv))
+	if cfg.Features.Playground {
+		mux.Handle("/playground", playground.Handler("GraphQL Playground", "/graphql"))
+	}
 
-	handler := withCORS(mux)
-	log.Println("backend listening on :8091")
-	log.Println("GraphiQL playground → http://localhost:8091/playground")
-	if err := http.ListenAndServe(":8091", handler); err != nil {
-		log.Fatal(err)
+	var handler http.Handler = mux
+	if cfg.Features.Compression {
+		handler = middleware.Compress(cfg.Limits.CompressMinSize)(handler)
+	}
+	handler = middleware.MaxBody(cfg.Limits.MaxBodyBytes, cfg.Limits.MaxUploadBytes)(handler)
+	handler = middleware.RateLimit(limiter, cfg.Limits.TrustedProxies, m.RateLimited, "/health", "/livez", "/readyz", "/metrics")(handler)
+	handler = middleware.Identity(cfg.Features.UserIDHeader, "/health", "/livez", "/readyz", "/metrics")(handler)
+	handler = withCORS(handler, allowedOrigins, strings.Join(cfg.CORS.AllowedHeaders, ","))
+	if cfg.Log.AccessLog {
+		handler = middleware.AccessLog(logger, slog.LevelInfo)(handler)
 	}
+	handler = tracing.Middleware("/health", "/livez", "/readyz", "/metrics")(handler)
+	handler = middleware.RequestID(handler)
+	return handler, nil
 }
 
-func withCORS(next http.Handler) http.Handler {
-	allowedOrigins := parseAllowedOrigins(os.Getenv("ALLOWED_ORIGINS"))
-	allowedHeaders := parseAllowedHeaders(os.Getenv("ALLOWED_HEADERS"))
+func newTLSConfig(cfg config.TLSConfig, logger *slog.Logger) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.SelfSigned {
+		cert, err := tlsutil.SelfSigned()
+		if err != nil {
+			return nil, fmt.Errorf("self-signed certificate: %w", err)
+		}
+		logger.Warn("tls: self-signed dev certificate", "sha256", tlsutil.Fingerprint(cert))
+		tlsConfig.Certificates = []tls.Certificate{*cert}
+		return tlsConfig, nil
+	}
+	reloader, err := tlsutil.NewReloader(cfg.CertFile, cfg.KeyFile, func(err error) {
+		if err != nil {
+			logger.Error("tls: reload failed, keeping previous certificate", "cert", cfg.CertFile, "err", err)
+			return
+		}
+		logger.Info("tls: certificate reloaded", "cert", cfg.CertFile)
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("tls: serving certificate, reloaded on change", "cert", cfg.CertFile)
+	tlsConfig.GetCertificate = reloader.GetCertificate
+	return tlsConfig, nil
+}
+
+// originSet compare les origines sans tenir compte de la casse ni du schéma
+// websocket : wss://host équivaut à https://host, ws://host à http://host.
+// Une page servie en https ouvre son websocket en wss avec Origin https://.
+type originSet map[string]bool
+
+func newOriginSet(origins []string) originSet {
+	set := make(originSet, len(origins))
+	for _, origin := range origins {
+		set[normalizeOrigin(origin)] = true
+	}
+	return set
+}
+
+func (s originSet) allows(origin string) bool {
+	return s[normalizeOrigin(origin)]
+}
+
+func normalizeOrigin(origin string) string {
+	origin = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(origin)), "/")
+	if rest, ok := strings.CutPrefix(origin, "wss://"); ok {
+		return "https://" + rest
+	}
+	if rest, ok := strings.CutPrefix(origin, "ws://"); ok {
+		return "http://" + rest
+	}
+	return origin
+}
+
+func newBroker(cfg config.PubSubConfig, m *metrics.Metrics, logger *slog.Logger) (pubsub.Broker, error) {
+	opts := []pubsub.Option{
+		pubsub.WithObserver(m), pubsub.WithLogger(logger), pubsub.WithChannel(cfg.Channel),
+		pubsub.WithReplay(cfg.ReplayBuffer), pubsub.WithOverflow(cfg.Overflow),
+	}
+	if cfg.Backend != config.PubSubRedis {
+		return pubsub.NewMemory(opts...), nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	broker, err := pubsub.NewRedis(ctx, cfg.RedisURL, opts...)
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("pubsub: redis", "channel", cfg.Channel)
+	return broker, nil
+}
+
+// useQueryLimits installe le cache de requêtes, les persisted queries et les
+// limites de complexité et de profondeur. Renvoie le manifeste chargé, nil
+// sans persisted queries.
+func useQueryLimits(srv *handler.Server, cfg config.GraphQLConfig, logger *slog.Logger) (*graph.PersistedQueries, error) {
+	if cfg.QueryCacheSize > 0 {
+		srv.SetQueryCache(lru.New[*ast.QueryDocument](cfg.QueryCacheSize))
+	}
+	var pq *graph.PersistedQueries
+	if cfg.PersistedQueries != "" {
+		var err error
+		pq, err = graph.LoadPersistedQueries(cfg.PersistedQueries)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("persisted queries loaded", "path", cfg.PersistedQueries, "operations", pq.Len(), "allowlist", cfg.Allowlist)
+		srv.Use(graph.PersistedQueryExtension(pq, cfg.Allowlist))
+	}
+	if cfg.APQCacheSize > 0 && !cfg.Allowlist {
+		srv.Use(extension.AutomaticPersistedQuery{Cache: lru.New[string](cfg.APQCacheSize)})
+	}
+	if cfg.MaxComplexity > 0 {
+		srv.Use(extension.FixedComplexityLimit(cfg.MaxComplexity))
+	}
+	srv.Use(graph.DepthLimit(cfg.MaxDepth))
+	return pq, nil
+}
+
+// serve tourne jusqu'à l'annulation de ctx (SIGINT/SIGTERM) puis arrête
+// proprement : plus de nouvelles connexions, fin des requêtes (et donc des
+// mutations) en cours, dernier flush du store, puis fermeture des
+// subscriptions, qui reçoivent ainsi tout ce qui a été écrit jusque-là. Au-delà
+// de timeout les connexions restantes sont coupées.
+func serve(ctx context.Context, srv *http.Server, listen func() error, checker *health.Checker, resolver *graph.Resolver, svc *board.Service, flushTraces func(context.Context) error, timeout time.Duration, logger *slog.Logger) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- listen()
+	}()
 
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	logger.Info("shutting down", "deadline", timeout)
+	checker.Drain()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("http shutdown: %w", err))
+		if err := srv.Close(); err != nil {
+			logger.Error("http close failed", "err", err)
+		}
+	}
+	if err := svc.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("final flush: %w", err))
+	}
+	// Les websockets sont détournées du http.Server : Shutdown ne les attend
+	// pas, c'est le resolver qui les ferme, avec le broker, une fois les
+	// dernières écritures publiées.
+	resolver.Shutdown()
+	// En dernier : le flush du store produit lui aussi un span.
+	if err := flushTraces(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("flush traces: %w", err))
+	}
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	logger.Info("shutdown complete")
+	return nil
+}
+
+// displayHost complète ":8091" en "localhost:8091" pour les logs.
+func displayHost(addr string) string {
+	if strings.HasPrefix(addr, ":") {
+		return "localhost" + addr
+	}
+	return addr
+}
+
+func withCORS(next http.Handler, allowedOrigins originSet, allowedHeaders string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		origin := r.Header.Get("Origin")
-		isAllowedOrigin := origin == "" || allowedOrigins[origin] // ← origin vide = same-server = OK
+		isAllowedOrigin := origin == "" || allowedOrigins.allows(origin) // ← origin vide = same-server = OK
 
 		if isAllowedOrigin && origin != "" {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
+			// Le cookie de session (middleware.Identity) suit les requêtes
+			// faites avec credentials: "include".
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
 			w.Header().Set("Vary", "Origin")
 		}
 		w.Header().Set("Access-Control-Allow-Headers", allowedHeaders)
-		w.Header().Set("Access-Control-Allow-Methods", "GET,PUT,POST,OPTIONS")
+		w.Header().Set("Access-Control-Allow-Methods", "GET,PUT,POST,DELETE,OPTIONS")
+		w.Header().Set("Access-Control-Expose-Headers", "ETag,Location,Retry-After,"+middleware.RequestIDHeader)
 
 		if r.Method == http.MethodOptions {
 			if !isAllowedOrigin {
@@ -102,44 +492,3 @@ func rejectCORS(w http.ResponseWriter, r *http.Request) {
 	}
 	http.Error(w, "origin not allowed", http.StatusForbidden)
 }
-
-func parseAllowedOrigins(raw string) map[string]bool {
-	if strings.TrimSpace(raw) == "" {
-		// ← :8091 ajouté pour que le Playground fonctionne sans config
-		raw = "http://localhost:4200,http://localhost:4201,http://localhost:8091"
-	}
-	origins := make(map[string]bool)
-	for _, value := range strings.Split(raw, ",") {
-		origin := strings.TrimSpace(value)
-		if origin == "" {
-			continue
-		}
-		origins[origin] = true
-	}
-	return origins
-}
-
-func parseAllowedHeaders(raw string) string {
-	if strings.TrimSpace(raw) == "" {
-		return "Content-Type,Authorization,Apollo-Require-Preflight,X-Requested-With,Accept,Origin"
-	}
-
-	headers := make([]string, 0)
-	seen := make(map[string]bool)
-	for _, value := range strings.Split(raw, ",") {
-		header := strings.TrimSpace(value)
-		if header == "" {
-			continue
-		}
-		key := strings.ToLower(header)
-		if seen[key] {
-			continue
-		}
-		seen[key] = true
-		headers = append(headers, header)
-	}
-	if len(headers) == 0 {
-		return "Content-Type,Authorization,Apollo-Require-Preflight,X-Requested-With,Accept,Origin"
-	}
-	return strings.Join(headers, ",")
-}
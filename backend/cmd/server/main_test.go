@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"miro-lite-standalone/backend/internal/board"
+	"miro-lite-standalone/backend/internal/config"
+	"miro-lite-standalone/backend/internal/crdt"
+	"miro-lite-standalone/backend/internal/graph"
+	"miro-lite-standalone/backend/internal/health"
+	"miro-lite-standalone/backend/internal/pubsub"
+)
+
+// Une mutation encore en cours au signal d'arrêt, et le texte enregistré par
+// le dernier flush, arrivent aux abonnés avant la fermeture des
+// subscriptions.
+func TestServeShutdownDeliversLastWrites(t *testing.T) {
+	svc, err := board.NewService("", board.WithTextFlushDelay(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	broker := pubsub.NewMemory()
+	resolver := graph.NewResolver(svc, broker)
+	ctx := context.Background()
+	if _, _, err := svc.AddWidget(ctx, "b1", board.AnyVersion, board.Widget{ID: "w1", Type: "text"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.ApplyTextUpdate(ctx, "b1", "w1", "text", crdt.Update{Inserts: []crdt.Insert{{ID: crdt.ID{Client: "c", Clock: 1}, Text: "hi"}}}); err != nil {
+		t.Fatal(err)
+	}
+	sub, err := broker.Subscribe("b1", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started, release := make(chan struct{}), make(chan struct{})
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		if _, _, err := svc.AddWidget(r.Context(), "b1", board.AnyVersion, board.Widget{ID: "w2", Type: "text"}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serveCtx, stop := context.WithCancel(ctx)
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(serveCtx, srv, func() error { return srv.Serve(ln) }, health.NewChecker(health.BuildInfo{}), resolver, svc,
+			func(context.Context) error { return nil }, 5*time.Second, slog.New(slog.DiscardHandler))
+	}()
+	responded := make(chan error, 1)
+	go func() {
+		resp, err := http.Post("http://"+ln.Addr().String()+"/", "text/plain", nil)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				err = errors.New(resp.Status)
+			}
+		}
+		responded <- err
+	}()
+	<-started
+	stop()
+	// Le listener fermé, l'arrêt attend la requête en cours.
+	for {
+		conn, err := net.Dial("tcp", ln.Addr().String())
+		if err != nil {
+			break
+		}
+		conn.Close()
+		time.Sleep(5 * time.Millisecond)
+	}
+	close(release)
+	if err := <-responded; err != nil {
+		t.Fatalf("in-flight request: %v", err)
+	}
+	if err := <-served; err != nil {
+		t.Fatalf("serve: %v", err)
+	}
+
+	// Le broker est fermé en dernier : Updates se termine après les deux
+	// écritures.
+	var added, flushed bool
+	for b := range sub.Updates() {
+		added = added || len(b.Widgets) == 2
+		flushed = flushed || b.Widgets[0].Config["text"] == "hi"
+	}
+	if !added || !flushed {
+		t.Fatalf("subscriber saw the in-flight write %v, the text flush %v, want both", added, flushed)
+	}
+}
+
+// newTestServer sert le handler complet de main avec la configuration de
+// args, sur un store en mémoire.
+func newTestServer(t *testing.T, args ...string) *httptest.Server {
+	t.Helper()
+	cfg, err := config.Load(append([]string{"-storage", "memory"}, args...), func(string) string { return "" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc, err := board.NewService("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.CreateBoard(context.Background(), "b1", "Plan"); err != nil {
+		t.Fatal(err)
+	}
+	resolver := graph.NewResolver(svc, pubsub.NewMemory())
+	h, err := newHandler(cfg, svc, resolver, health.NewChecker(health.BuildInfo{}), nil, slog.New(slog.DiscardHandler))
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(h)
+	t.Cleanup(func() {
+		srv.Close()
+		resolver.Shutdown()
+	})
+	return srv
+}
+
+// client joue un utilisateur : un navigateur qui garde ses cookies, ou,
+// avec userID, un client sans cookies derrière un proxy d'authentification
+// qui pose X-User-ID.
+type client struct {
+	http   *http.Client
+	userID string
+}
+
+func newClient(t *testing.T, userID string) *client {
+	t.Helper()
+	if userID != "" {
+		return &client{http: &http.Client{}, userID: userID}
+	}
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &client{http: &http.Client{Jar: jar}}
+}
+
+// do envoie la requête et renvoie le statut et les widgets du board de la
+// réponse (REST : le board ou le widget ; GraphQL : data.<champ>).
+func (c *client) do(t *testing.T, srv *httptest.Server, method, path, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.userID != "" {
+		req.Header.Set("X-User-ID", c.userID)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out struct {
+		Widgets []struct{ ID string } `json:"widgets"`
+		Data    map[string]struct {
+			Widgets []struct{ ID string } `json:"widgets"`
+		} `json:"data"`
+		Errors []struct {
+			Extensions struct{ Code string } `json:"extensions"`
+		} `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	if len(out.Errors) > 0 {
+		return resp.StatusCode, "error " + out.Errors[0].Extensions.Code
+	}
+	widgets := out.Widgets
+	for _, d := range out.Data {
+		widgets = d.Widgets
+	}
+	var ids []string
+	for _, w := range widgets {
+		ids = append(ids, w.ID)
+	}
+	return resp.StatusCode, strings.Join(ids, ",")
+}
+
+func (c *client) add(t *testing.T, srv *httptest.Server, id string) {
+	t.Helper()
+	if code, _ := c.do(t, srv, http.MethodPost, "/api/boards/b1/widgets", fmt.Sprintf(`{"id":%q,"type":"text"}`, id)); code != http.StatusCreated {
+		t.Fatalf("add %s: status %d", id, code)
+	}
+}
+
+// L'historique d'annulation fonctionne de bout en bout, par le REST comme
+// par le GraphQL, avec la configuration par défaut comme derrière un proxy
+// d'authentification.
+func TestUndoThroughHTTP(t *testing.T) {
+	undoREST := func(c *client, srv *httptest.Server) (int, string) {
+		return c.do(t, srv, http.MethodPost, "/api/boards/b1/undo", "")
+	}
+	undoGraphQL := func(c *client, srv *httptest.Server) (int, string) {
+		return c.do(t, srv, http.MethodPost, "/graphql", `{"query":"mutation { undo(boardId: \"b1\") { widgets { id } } }"}`)
+	}
+	tests := []struct {
+		name  string
+		args  []string
+		users [2]string
+	}{
+		{"default config", nil, [2]string{"", ""}},
+		{"trusted user header", []string{"-user-id-header", "true"}, [2]string{"alice", "bob"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestServer(t, tt.args...)
+			alice, bob := newClient(t, tt.users[0]), newClient(t, tt.users[1])
+			alice.add(t, srv, "a1")
+			bob.add(t, srv, "b1")
+			alice.add(t, srv, "a2")
+
+			steps := []struct {
+				name     string
+				undo     func(*client, *httptest.Server) (int, string)
+				who      *client
+				wantCode int
+				want     string
+			}{
+				{"bob undoes his write only", undoGraphQL, bob, http.StatusOK, "a1,a2"},
+				{"alice undoes her last write", undoREST, alice, http.StatusOK, "a1"},
+				{"bob has nothing left", undoREST, bob, http.StatusBadRequest, ""},
+				{"alice undoes her first write", undoGraphQL, alice, http.StatusOK, ""},
+			}
+			for _, step := range steps {
+				code, got := step.undo(step.who, srv)
+				if code != step.wantCode || (code == http.StatusOK && got != step.want) {
+					t.Fatalf("%s: status %d, widgets %q, want %d, %q", step.name, code, got, step.wantCode, step.want)
+				}
+			}
+			if code, got := alice.do(t, srv, http.MethodPost, "/api/boards/b1/redo", ""); code != http.StatusOK || got != "a1" {
+				t.Fatalf("redo: status %d, widgets %q", code, got)
+			}
+		})
+	}
+
+	// Un client qui ne garde pas ses cookies n'a pas d'historique.
+	srv := newTestServer(t)
+	anonymous := &client{http: &http.Client{}}
+	anonymous.add(t, srv, "x")
+	if code, got := anonymous.do(t, srv, http.MethodPost, "/graphql", `{"query":"mutation { undo(boardId: \"b1\") { widgets { id } } }"}`); code != http.StatusOK || got != "error VALIDATION_FAILED" {
+		t.Fatalf("undo without cookies: status %d, %q", code, got)
+	}
+}
+
+// Un ETag reçu avec une réponse compressée revalide le cache et sert de
+// précondition à l'écriture suivante.
+func TestCompressedETagThroughHTTP(t *testing.T) {
+	srv := newTestServer(t)
+	c := newClient(t, "")
+	c.add(t, srv, strings.Repeat("w", 2000))
+	send := func(method, path, header, value string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(`{"id":"w2","type":"text"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Accept-Encoding", "gzip")
+		req.Header.Set("Content-Type", "application/json")
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		resp, err := c.http.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp
+	}
+	resp := send(http.MethodGet, "/api/boards/b1", "", "")
+	etag := resp.Header.Get("ETag")
+	if resp.Header.Get("Content-Encoding") != "gzip" || etag != `"b1.2-gzip"` {
+		t.Fatalf("GET: Content-Encoding %q, ETag %s, want gzip, \"b1.2-gzip\"", resp.Header.Get("Content-Encoding"), etag)
+	}
+	if resp := send(http.MethodGet, "/api/boards/b1", "If-None-Match", etag); resp.StatusCode != http.StatusNotModified || resp.Header.Get("ETag") != etag {
+		t.Fatalf("conditional GET: status %d, ETag %s", resp.StatusCode, resp.Header.Get("ETag"))
+	}
+	if resp := send(http.MethodPost, "/api/boards/b1/widgets", "If-Match", etag); resp.StatusCode != http.StatusCreated {
+		t.Fatalf("write with If-Match %s: status %d", etag, resp.StatusCode)
+	}
+	if resp := send(http.MethodPost, "/api/boards/b1/widgets", "If-Match", etag); resp.StatusCode != http.StatusPreconditionFailed {
+		t.Fatalf("stale If-Match: status %d, want %d", resp.StatusCode, http.StatusPreconditionFailed)
+	}
+}
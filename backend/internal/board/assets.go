@@ -0,0 +1,99 @@
+package board
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Asset est une image référencée par le champ config.src d'un widget. Les
+// images importées depuis le frontend sont embarquées en data URL ; les autres
+// sont des liens externes.
+type Asset struct {
+	WidgetID  string `json:"widgetId"`
+	MediaType string `json:"mediaType,omitempty"`
+	Size      int    `json:"size,omitempty"`
+	External  bool   `json:"external"`
+	URL       string `json:"url,omitempty"`
+}
+
+// Assets liste les images du board.
+func (s *Service) Assets(ctx context.Context, boardID string) ([]Asset, error) {
+	b, err := s.ResolveBoard(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+	assets := make([]Asset, 0)
+	for _, w := range b.Widgets {
+		if asset, _, ok := widgetAsset(w); ok {
+			assets = append(assets, asset)
+		}
+	}
+	return assets, nil
+}
+
+// Asset renvoie l'image d'un widget et, si elle est embarquée, son contenu
+// décodé.
+func (s *Service) Asset(ctx context.Context, boardID, widgetID string) (*Asset, []byte, error) {
+	_, w, err := s.GetWidget(ctx, boardID, widgetID)
+	if err != nil {
+		return nil, nil, err
+	}
+	asset, data, ok := widgetAsset(*w)
+	if !ok {
+		return nil, nil, &NotFoundError{BoardID: boardID, WidgetID: widgetID}
+	}
+	return &asset, data, nil
+}
+
+func widgetAsset(w Widget) (Asset, []byte, bool) {
+	src, _ := w.Config["src"].(string)
+	if src == "" {
+		return Asset{}, nil, false
+	}
+	if !strings.HasPrefix(src, "data:") {
+		return Asset{WidgetID: w.ID, External: true, URL: src}, nil, true
+	}
+	mediaType, data, err := DecodeDataURL(src)
+	if err != nil {
+		return Asset{}, nil, false
+	}
+	return Asset{WidgetID: w.ID, MediaType: mediaType, Size: len(data)}, data, true
+}
+
+// DecodeDataURL décode une URL "data:[<mediatype>][;base64],<data>".
+func DecodeDataURL(src string) (string, []byte, error) {
+	rest, ok := strings.CutPrefix(src, "data:")
+	if !ok {
+		return "", nil, fmt.Errorf("not a data URL")
+	}
+	meta, payload, ok := strings.Cut(rest, ",")
+	if !ok {
+		return "", nil, fmt.Errorf("malformed data URL")
+	}
+	isBase64 := strings.HasSuffix(meta, ";base64")
+	mediaType := strings.TrimSuffix(meta, ";base64")
+	if mediaType == "" {
+		mediaType = "text/plain;charset=US-ASCII"
+	}
+	if isBase64 {
+		data, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return "", nil, fmt.Errorf("decode data URL: %w", err)
+		}
+		return mediaType, data, nil
+	}
+	decoded, err := url.PathUnescape(payload)
+	if err != nil {
+		return "", nil, fmt.Errorf("decode data URL: %w", err)
+	}
+	return mediaType, []byte(decoded), nil
+}
+
+// EncodeDataURL est l'inverse de DecodeDataURL, au format produit par
+// FileReader.readAsDataURL côté frontend.
+func EncodeDataURL(mediaType string, data []byte) string {
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
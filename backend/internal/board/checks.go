@@ -0,0 +1,86 @@
+package board
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// probeSize est écrit à chaque vérification du disque : assez pour échouer
+// sur un disque plein, assez peu pour être appelé toutes les quelques
+// secondes.
+const probeSize = 4 << 10
+
+// Ping vérifie que le verrou du service peut être pris avant l'échéance de
+// ctx. Un échec signale un blocage que seul un redémarrage résout. Les appels
+// concurrents, et ceux qui suivent une échéance dépassée, attendent la même
+// sonde : un verrou bloqué n'immobilise qu'une goroutine.
+func (s *Service) Ping(ctx context.Context) error {
+	s.pingMu.Lock()
+	done := s.pinging
+	if done == nil {
+		done = make(chan struct{})
+		s.pinging = done
+		go func() {
+			s.mu.RLock()
+			s.mu.RUnlock()
+			s.pingMu.Lock()
+			s.pinging = nil
+			s.pingMu.Unlock()
+			close(done)
+		}()
+	}
+	s.pingMu.Unlock()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("board service lock not acquired: %w", ctx.Err())
+	}
+}
+
+// CheckStore échoue quand le service ne peut pas accepter d'écritures : store
+// mis en quarantaine au démarrage ou arrêt en cours.
+func (s *Service) CheckStore(context.Context) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.writableLocked()
+}
+
+// CheckStorage vérifie que le répertoire du store existe et accepte une
+// écriture synchronisée. Sans persistance, il n'y a rien à vérifier. La
+// sonde force un fsync : l'enregistrer derrière health.Cached.
+func (s *Service) CheckStorage(ctx context.Context) error {
+	if s.storePath == "" {
+		return nil
+	}
+	// Comme writeStore : le répertoire est créé au besoin.
+	dir := filepath.Dir(s.storePath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("data dir: %w", err)
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("data dir: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("data dir %s is not a directory", dir)
+	}
+	f, err := os.CreateTemp(dir, ".probe-*")
+	if err != nil {
+		return fmt.Errorf("data dir not writable: %w", err)
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(make([]byte, probeSize))
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("data dir write probe: %w", err)
+	}
+	return ctx.Err()
+}
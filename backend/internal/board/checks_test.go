@@ -0,0 +1,82 @@
+package board
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckStorage(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    func(t *testing.T) string
+		wantErr bool
+	}{
+		{"memory", func(*testing.T) string { return "" }, false},
+		{"existing dir", func(t *testing.T) string { return filepath.Join(t.TempDir(), "boards.json") }, false},
+		{"missing dir created", func(t *testing.T) string { return filepath.Join(t.TempDir(), "a", "b", "boards.json") }, false},
+		{"dir is a file", func(t *testing.T) string {
+			file := filepath.Join(t.TempDir(), "data")
+			if err := os.WriteFile(file, nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			return filepath.Join(file, "boards.json")
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Service{storePath: tt.path(t)}
+			err := s.CheckStorage(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckStorage = %v, want error %v", err, tt.wantErr)
+			}
+			if s.storePath == "" || tt.wantErr {
+				return
+			}
+			entries, _ := os.ReadDir(filepath.Dir(s.storePath))
+			if len(entries) != 0 {
+				t.Errorf("probe left %d files behind", len(entries))
+			}
+		})
+	}
+}
+
+func TestPing(t *testing.T) {
+	s, err := NewService("")
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	if err := s.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping = %v", err)
+	}
+	s.mu.Lock()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for range 3 {
+		if err := s.Ping(ctx); err == nil {
+			t.Fatal("Ping with the lock held succeeded")
+		}
+	}
+	// Les sondes échouées partagent une seule attente du verrou.
+	s.pingMu.Lock()
+	probe := s.pinging
+	s.pingMu.Unlock()
+	if probe == nil {
+		t.Fatal("no probe in flight while the lock is held")
+	}
+	if err := s.Ping(ctx); err == nil {
+		t.Fatal("Ping with the lock held succeeded")
+	}
+	s.pingMu.Lock()
+	again := s.pinging
+	s.pingMu.Unlock()
+	if again != probe {
+		t.Fatal("Ping started a second probe")
+	}
+	s.mu.Unlock()
+	<-probe
+	if err := s.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping after unlock = %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package board
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Erreurs sentinelles, à tester avec errors.Is.
+var (
+	ErrNotFound        = errors.New("board not found")
+	ErrVersionConflict = errors.New("version conflict")
+	ErrValidation      = errors.New("validation failed")
+	// ErrClosed est renvoyée par les écritures après Service.Close.
+	ErrClosed = errors.New("board service is shutting down")
+	// ErrReadOnly est renvoyée par les écritures quand le store a été mis en
+	// quarantaine au démarrage.
+	ErrReadOnly = errors.New("board service is read-only: the store was quarantined at startup")
+	// ErrCorruptStore enveloppe les erreurs de décodage du fichier de
+	// persistance (voir CorruptStoreError).
+	ErrCorruptStore = errors.New("corrupt store")
+)
+
+// Codes d'erreur partagés par le REST (corps JSON) et le GraphQL
+// (extensions.code).
+const (
+	CodeNotFound        = "NOT_FOUND"
+	CodeVersionConflict = "VERSION_CONFLICT"
+	CodeValidation      = "VALIDATION_FAILED"
+	CodeUnavailable     = "UNAVAILABLE"
+	CodeInternal        = "INTERNAL"
+)
+
+// ErrorCode renvoie le code stable associé à err.
+func ErrorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return CodeNotFound
+	case errors.Is(err, ErrVersionConflict):
+		return CodeVersionConflict
+	case errors.Is(err, ErrValidation):
+		return CodeValidation
+	case errors.Is(err, ErrClosed), errors.Is(err, ErrReadOnly), errors.Is(err, ErrUpstream), errors.Is(err, ErrSharedState):
+		return CodeUnavailable
+	default:
+		return CodeInternal
+	}
+}
+
+// NotFoundError vise un board, ou un widget de ce board si WidgetID est
+// renseigné.
+type NotFoundError struct {
+	BoardID  string
+	WidgetID string
+}
+
+func (e *NotFoundError) Error() string {
+	if e.WidgetID != "" {
+		return fmt.Sprintf("widget %s not found on board %s", e.WidgetID, e.BoardID)
+	}
+	return fmt.Sprintf("board %s not found", e.BoardID)
+}
+
+func (e *NotFoundError) Unwrap() error { return ErrNotFound }
+
+// VersionConflictError porte la version courante pour que le client puisse
+// recharger puis rejouer sa sauvegarde.
+type VersionConflictError struct {
+	BoardID         string
+	CurrentVersion  int
+	ProvidedVersion int
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("version conflict: expected %d got %d", e.CurrentVersion, e.ProvidedVersion)
+}
+
+func (e *VersionConflictError) Unwrap() error { return ErrVersionConflict }
+
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Field == "" {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+func (e *ValidationError) Unwrap() error { return ErrValidation }
@@ -0,0 +1,44 @@
+package board
+
+import (
+	"context"
+	"time"
+)
+
+// maxRevisionsPerBoard borne l'historique gardé en mémoire pour chaque board.
+const maxRevisionsPerBoard = 100
+
+// Revision décrit une écriture sur un board. L'historique n'est pas persisté :
+// il repart de zéro au redémarrage.
+type Revision struct {
+	Version     int       `json:"version"`
+	Operation   string    `json:"operation"`
+	WidgetCount int       `json:"widgetCount"`
+	At          time.Time `json:"at"`
+}
+
+// History renvoie les révisions connues du board, de la plus ancienne à la
+// plus récente.
+func (s *Service) History(ctx context.Context, id string) ([]Revision, error) {
+	if _, err := s.ResolveBoard(ctx, id); err != nil {
+		return nil, err
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	revisions := make([]Revision, len(s.history[id]))
+	copy(revisions, s.history[id])
+	return revisions, nil
+}
+
+func (s *Service) recordLocked(b Model, op string) {
+	revisions := append(s.history[b.ID], Revision{
+		Version:     b.Version,
+		Operation:   op,
+		WidgetCount: len(b.Widgets),
+		At:          time.Now().UTC(),
+	})
+	if len(revisions) > maxRevisionsPerBoard {
+		revisions = revisions[len(revisions)-maxRevisionsPerBoard:]
+	}
+	s.history[b.ID] = revisions
+}
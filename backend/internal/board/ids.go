@@ -0,0 +1,15 @@
+package board
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+func NewBoardID() string {
+	return fmt.Sprintf("board-%s", uuid.NewString()[:8])
+}
+
+func NewWidgetID() string {
+	return fmt.Sprintf("widget-%s", uuid.NewString()[:8])
+}
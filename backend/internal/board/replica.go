@@ -0,0 +1,121 @@
+package board
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrSharedState enveloppe les échecs de l'état partagé entre instances
+// (serveur Redis injoignable, par exemple). Elle donne le code UNAVAILABLE.
+var ErrSharedState = errors.New("shared board state unavailable")
+
+// SharedState est la dernière version de chaque board, partagée par toutes
+// les instances du serveur (voir pubsub.Redis). Chaque écriture y est
+// enregistrée avant d'être appliquée localement : deux instances qui
+// partent de la même version ne peuvent pas l'écrire toutes les deux, la
+// seconde est refusée et relit le board enregistré par la première.
+type SharedState interface {
+	// Load renvoie le board id, nil s'il n'a jamais été enregistré.
+	Load(ctx context.Context, id string) (*Model, error)
+	// Commit enregistre b si aucune instance n'a déjà enregistré la version
+	// b.Version de ce board ou une suivante. Sinon, b n'est pas enregistré
+	// et Commit renvoie le board le plus récent.
+	Commit(ctx context.Context, b *Model) (latest *Model, err error)
+	// Remove oublie le board id, supprimé.
+	Remove(ctx context.Context, id string) error
+}
+
+// WithSharedState coordonne les écritures de ce service avec celles des
+// autres instances à travers st. Les boards miroirs n'y passent pas : leur
+// source de vérité est l'upstream.
+func WithSharedState(st SharedState) Option {
+	return func(s *Service) { s.shared = st }
+}
+
+// ApplyRemote enregistre un board écrit par une autre instance du serveur
+// (voir le package pubsub) s'il est plus récent que la copie locale. Les
+// abonnés de cette instance l'ont déjà reçu par le broker : il n'est pas
+// republié. Avec WithSharedState, une version n'est écrite que par une
+// instance : la plus récente est toujours la bonne.
+func (s *Service) ApplyRemote(ctx context.Context, b *Model) (applied bool, err error) {
+	ctx, span := startSpan(ctx, "board.ApplyRemote", b.ID)
+	defer func() { endSpan(span, err) }()
+	stored, err := s.storeIfNewer(ctx, b, "remote")
+	return stored != nil, err
+}
+
+// ApplyRemoteDelete supprime la copie locale d'un board supprimé par une
+// autre instance. Les callbacks OnBoardDeleted ne sont pas appelés : la
+// suppression a déjà été publiée. applied est faux si le board n'existait
+// pas ici.
+func (s *Service) ApplyRemoteDelete(ctx context.Context, id string) (applied bool, err error) {
+	ctx, span := startSpan(ctx, "board.ApplyRemoteDelete", id)
+	defer func() { endSpan(span, err) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writableLocked(); err != nil {
+		return false, err
+	}
+	current, ok := s.boards[id]
+	if !ok {
+		return false, nil
+	}
+	delete(s.boards, id)
+	if err := s.saveToDisk(ctx, id, "remote-delete"); err != nil {
+		s.boards[id] = current
+		return false, err
+	}
+	s.forgetLocked(id)
+	return true, nil
+}
+
+// loadShared lit sur l'état partagé un board absent de la copie locale, par
+// exemple écrit par une autre instance avant le démarrage de celle-ci. Il
+// renvoie nil si aucune instance ne le connaît.
+func (s *Service) loadShared(ctx context.Context, id string) (*Model, error) {
+	b, err := s.shared.Load(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrSharedState, err)
+	}
+	if b == nil {
+		return nil, nil
+	}
+	if _, err := s.storeIfNewer(ctx, b, "remote"); err != nil {
+		return nil, err
+	}
+	local, _ := s.GetBoard(id)
+	return local, nil
+}
+
+// commitLocked enregistre next dans l'état partagé avant qu'il ne soit
+// persisté. Si une autre instance a déjà écrit cette version, rollback
+// restaure la copie locale, qui est ensuite remplacée par le board de
+// l'autre instance, et l'écriture échoue en conflit. s.mu est tenu pendant
+// l'aller-retour : les écritures de cette instance restent sérialisées.
+func (s *Service) commitLocked(ctx context.Context, next *Model, rollback func()) error {
+	if s.shared == nil {
+		return nil
+	}
+	latest, err := s.shared.Commit(ctx, next)
+	if err != nil {
+		rollback()
+		return fmt.Errorf("%w: %w", ErrSharedState, err)
+	}
+	if latest == nil {
+		return nil
+	}
+	rollback()
+	if _, err := s.storeIfNewerLocked(ctx, latest, "remote"); err != nil {
+		s.logger.WarnContext(ctx, "board: newer shared copy not stored", "board", latest.ID, "version", latest.Version, "err", err)
+	}
+	return &VersionConflictError{BoardID: next.ID, CurrentVersion: latest.Version, ProvidedVersion: next.Version - 1}
+}
+
+// forgetLocked oublie l'historique, les textes et les écritures annulables
+// d'un board supprimé.
+func (s *Service) forgetLocked(id string) {
+	delete(s.history, id)
+	s.dropTextsLocked(id)
+	s.dropEditsLocked(id)
+}
@@ -0,0 +1,63 @@
+package board
+
+import (
+	"context"
+	"testing"
+)
+
+func TestApplyRemote(t *testing.T) {
+	tests := []struct {
+		name        string
+		version     int
+		wantApplied bool
+		wantVersion int
+	}{
+		{"newer", 5, true, 5},
+		{"same version", 2, false, 2},
+		{"older", 1, false, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+			if _, _, err := s.AddWidget(context.Background(), "b1", AnyVersion, Widget{ID: "w1", Type: "text"}); err != nil {
+				t.Fatalf("AddWidget: %v", err)
+			}
+			published := 0
+			s.OnBoardUpdated(func(*Model) { published++ })
+			applied, err := s.ApplyRemote(context.Background(), &Model{ID: "b1", Title: "remote", Version: tt.version})
+			if err != nil || applied != tt.wantApplied {
+				t.Fatalf("ApplyRemote = %v, %v, want applied %v", applied, err, tt.wantApplied)
+			}
+			if got, _ := s.GetBoard("b1"); got.Version != tt.wantVersion {
+				t.Fatalf("version = %d, want %d", got.Version, tt.wantVersion)
+			}
+			if published != 0 {
+				t.Fatal("remote update republished")
+			}
+		})
+	}
+}
+
+func TestApplyRemoteDelete(t *testing.T) {
+	s := newTestService(t)
+	if _, _, err := s.AddWidget(context.Background(), "b1", AnyVersion, Widget{ID: "w1", Type: "text"}); err != nil {
+		t.Fatalf("AddWidget: %v", err)
+	}
+	deleted := 0
+	s.OnBoardDeleted(func(string) { deleted++ })
+	for i, want := range []bool{true, false} {
+		applied, err := s.ApplyRemoteDelete(context.Background(), "b1")
+		if err != nil || applied != want {
+			t.Fatalf("ApplyRemoteDelete #%d = %v, %v, want applied %v", i+1, applied, err, want)
+		}
+	}
+	if _, ok := s.GetBoard("b1"); ok {
+		t.Fatal("board still present")
+	}
+	if len(s.history["b1"]) != 0 {
+		t.Fatal("history of the deleted board kept")
+	}
+	if deleted != 0 {
+		t.Fatal("remote deletion republished")
+	}
+}
@@ -1,14 +1,16 @@
 package board
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
-	"net/http"
-	"os"
-	"path/filepath"
+	"log/slog"
+	"sort"
 	"strings"
 	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type Widget struct {
@@ -31,25 +33,175 @@ type Model struct {
 
 type SaveRequest struct {
 	Version int      `json:"version"`
+	Title   *string  `json:"title,omitempty"`
 	Widgets []Widget `json:"widgets"`
 }
 
+// MissingBoardPolicy décide du comportement quand un board inconnu est lu ou
+// écrit. Elle s'applique de la même façon au REST et au GraphQL.
+type MissingBoardPolicy string
+
+const (
+	// MissingBoardStrict renvoie ErrNotFound (404 / NOT_FOUND).
+	MissingBoardStrict MissingBoardPolicy = "strict"
+	// MissingBoardAutoCreate crée un board vide, le persiste et le publie.
+	MissingBoardAutoCreate MissingBoardPolicy = "auto-create"
+)
+
+func ParseMissingBoardPolicy(raw string) (MissingBoardPolicy, error) {
+	switch MissingBoardPolicy(strings.ToLower(strings.TrimSpace(raw))) {
+	case "", MissingBoardAutoCreate:
+		return MissingBoardAutoCreate, nil
+	case MissingBoardStrict:
+		return MissingBoardStrict, nil
+	default:
+		return "", fmt.Errorf("unknown missing board policy %q (want %q or %q)", raw, MissingBoardStrict, MissingBoardAutoCreate)
+	}
+}
+
+type Option func(*Service)
+
+func WithMissingBoardPolicy(policy MissingBoardPolicy) Option {
+	return func(s *Service) { s.missingPolicy = policy }
+}
+
+// WithSaveObserver est appelé après chaque écriture du store sur disque avec
+// sa durée, le nombre d'octets écrits et l'erreur éventuelle.
+func WithSaveObserver(fn func(d time.Duration, bytes int, err error)) Option {
+	return func(s *Service) { s.saveObserver = fn }
+}
+
+// WithMaxWidgets borne le nombre de widgets d'un board (0 : pas de limite).
+func WithMaxWidgets(n int) Option {
+	return func(s *Service) { s.maxWidgets = n }
+}
+
+// WithLogger remplace le logger (muet par défaut) du service.
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Service) { s.logger = logger }
+}
+
 type Service struct {
-	mu        sync.RWMutex
-	boards    map[string]Model
-	storePath string
+	mu            sync.RWMutex
+	boards        map[string]Model
+	storePath     string
+	missingPolicy MissingBoardPolicy
+	corruptPolicy CorruptStorePolicy
+	closed        bool
+	readOnly      bool
+	maxWidgets    int
+	saveObserver  func(time.Duration, int, error)
+	logger        *slog.Logger
+	upstream      Upstream
+	upstreamMu    sync.Mutex
+	shared        SharedState
+
+	history map[string][]Revision
+	// texts : répliques des textes collaboratifs et textFlushes : leurs
+	// enregistrements en attente, par board, protégés par mu. textsClosed
+	// refuse les opérations de texte pendant Close.
+	texts          map[textKey]*textReplica
+	textFlushes    map[string]*textFlush
+	textFlushDelay time.Duration
+	textsClosed    bool
+	// edits : écritures annulables de chaque utilisateur, protégées par mu.
+	edits map[editKey]*editLog
+	// pinging : fermé quand la sonde de Ping en cours a pris le verrou ;
+	// nil sans sonde en cours. Protégé par pingMu.
+	pingMu  sync.Mutex
+	pinging chan struct{}
+
+	listenersMu     sync.RWMutex
+	listeners       []func(*Model)
+	textListeners   []func(*TextChange)
+	deleteListeners []func(string)
 }
 
-func NewService(storePath string) *Service {
+// NewService charge le store. Un fichier illisible ou corrompu est une
+// erreur, sauf si WithCorruptStorePolicy en décide autrement.
+func NewService(storePath string, opts ...Option) (*Service, error) {
 	s := &Service{
-		boards:    make(map[string]Model),
-		storePath: storePath,
+		boards:         make(map[string]Model),
+		storePath:      storePath,
+		missingPolicy:  MissingBoardAutoCreate,
+		corruptPolicy:  CorruptStoreFail,
+		history:        make(map[string][]Revision),
+		texts:          make(map[textKey]*textReplica),
+		textFlushes:    make(map[string]*textFlush),
+		textFlushDelay: DefaultTextFlushDelay,
+		edits:          make(map[editKey]*editLog),
+		logger:         slog.New(slog.DiscardHandler),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if err := s.loadFromDisk(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Service) MissingBoardPolicy() MissingBoardPolicy {
+	return s.missingPolicy
+}
+
+// OnBoardUpdated enregistre un callback appelé (hors verrou) après chaque
+// écriture réussie, quel que soit le transport qui l'a déclenchée.
+func (s *Service) OnBoardUpdated(fn func(*Model)) {
+	s.listenersMu.Lock()
+	defer s.listenersMu.Unlock()
+	s.listeners = append(s.listeners, fn)
+}
+
+// OnBoardDeleted enregistre un callback appelé (hors verrou) après chaque
+// suppression de board réussie.
+func (s *Service) OnBoardDeleted(fn func(id string)) {
+	s.listenersMu.Lock()
+	defer s.listenersMu.Unlock()
+	s.deleteListeners = append(s.deleteListeners, fn)
+}
+
+func (s *Service) notifyDeleted(id string) {
+	s.listenersMu.RLock()
+	listeners := s.deleteListeners
+	s.listenersMu.RUnlock()
+	for _, fn := range listeners {
+		fn(id)
 	}
-	s.loadFromDisk()
-	return s
 }
 
-// ─── Méthodes publiques pour les resolvers GraphQL ───────────────────────────
+func (s *Service) notify(boards ...*Model) {
+	s.listenersMu.RLock()
+	listeners := s.listeners
+	s.listenersMu.RUnlock()
+	for _, b := range boards {
+		if b == nil {
+			continue
+		}
+		for _, fn := range listeners {
+			fn(b)
+		}
+	}
+}
+
+// ─── Méthodes publiques (GraphQL et REST) ────────────────────────────────────
+
+// AnyVersion désactive le contrôle de version optimiste d'une écriture
+// demandée par un client (If-Match: *, ou écriture REST de widget sans
+// If-Match). Une version fournie par un client n'est jamais négative (voir
+// Save), et 0 (version absente) échoue en conflit.
+const AnyVersion = -1
+
+// LatestVersion désactive aussi le contrôle de version, pour les appelants
+// internes (AddStickyNote, textes collaboratifs, undo) dont l'opération peut
+// être rejouée sur l'état le plus récent : sur un board miroir, un conflit
+// avec l'upstream est retenté au lieu d'être renvoyé.
+const LatestVersion = -2
+
+// unconditional indique si ifVersion désactive le contrôle de version.
+func unconditional(ifVersion int) bool {
+	return ifVersion == AnyVersion || ifVersion == LatestVersion
+}
 
 func (s *Service) GetBoard(id string) (*Model, bool) {
 	s.mu.RLock()
@@ -61,6 +213,73 @@ func (s *Service) GetBoard(id string) (*Model, bool) {
 	return &b, true
 }
 
+// ResolveBoard lit un board en appliquant la MissingBoardPolicy. Un board
+// miroir est relu sur l'upstream si sa copie locale n'est plus fraîche ; avec
+// WithSharedState, un board absent d'ici est cherché sur l'état partagé
+// avant d'être créé.
+func (s *Service) ResolveBoard(ctx context.Context, id string) (_ *Model, err error) {
+	ctx, span := startSpan(ctx, "board.ResolveBoard", id)
+	defer func() { endSpan(span, err) }()
+	if s.mirrored(id) {
+		return s.resolveMirrored(ctx, id)
+	}
+	if b, ok := s.GetBoard(id); ok {
+		return b, nil
+	}
+	if strings.TrimSpace(id) == "" {
+		return nil, &ValidationError{Field: "boardId", Message: "must not be empty"}
+	}
+	if s.shared != nil {
+		if b, err := s.loadShared(ctx, id); err != nil || b != nil {
+			return b, err
+		}
+	}
+	s.mu.Lock()
+	current, created, err := s.lookupLocked(id)
+	if err == nil && created {
+		rollback := func() { delete(s.boards, id) }
+		if err = s.commitLocked(ctx, &current, rollback); err == nil {
+			if err = s.saveToDisk(ctx, id, "create"); err != nil {
+				rollback()
+			} else {
+				s.recordLocked(current, "create")
+			}
+		}
+	}
+	s.mu.Unlock()
+	var conflict *VersionConflictError
+	if errors.As(err, &conflict) {
+		// Créé entre-temps par une autre instance : sa copie a été reprise.
+		if b, ok := s.GetBoard(id); ok {
+			return b, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		s.notify(&current)
+	}
+	return &current, nil
+}
+
+// lookupLocked renvoie le board id, ou le crée selon la policy. created
+// indique qu'il vient d'être ajouté à s.boards (reste à persister/publier).
+func (s *Service) lookupLocked(id string) (Model, bool, error) {
+	if b, ok := s.boards[id]; ok {
+		return b, false, nil
+	}
+	if s.missingPolicy == MissingBoardStrict {
+		return Model{}, false, &NotFoundError{BoardID: id}
+	}
+	if err := s.writableLocked(); err != nil {
+		return Model{}, false, err
+	}
+	b := Model{ID: id, Version: 1, Widgets: []Widget{}}
+	s.boards[id] = b
+	return b, true, nil
+}
+
 func (s *Service) ListBoards() []*Model {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -69,136 +288,325 @@ func (s *Service) ListBoards() []*Model {
 		bCopy := b
 		result = append(result, &bCopy)
 	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
 	return result
 }
 
-func (s *Service) CreateBoard(id, title string) *Model {
+func (s *Service) CreateBoard(ctx context.Context, id, title string) (_ *Model, err error) {
+	ctx, span := startSpan(ctx, "board.CreateBoard", id)
+	defer func() { endSpan(span, err) }()
+	if s.mirrored(id) {
+		return nil, &ValidationError{Field: "boardId", Message: fmt.Sprintf("board %s is mirrored from upstream", id)}
+	}
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	if err := s.writableLocked(); err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
 	b := Model{ID: id, Title: title, Version: 1, Widgets: []Widget{}}
 	s.boards[id] = b
-	_ = s.saveToDisk()
-	return &b
+	rollback := func() { delete(s.boards, id) }
+	if err := s.commitLocked(ctx, &b, rollback); err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	if err := s.saveToDisk(ctx, id, "create"); err != nil {
+		rollback()
+		s.mu.Unlock()
+		return nil, err
+	}
+	s.recordLocked(b, "create")
+	s.mu.Unlock()
+	s.notify(&b)
+	return &b, nil
 }
 
-func (s *Service) AddWidget(boardID string, widget Widget) (*Widget, error) {
+func (s *Service) DeleteBoard(ctx context.Context, id string, ifVersion int) (err error) {
+	ctx, span := startSpan(ctx, "board.DeleteBoard", id, attribute.Int("board.if_version", ifVersion))
+	defer func() { endSpan(span, err) }()
+	if s.mirrored(id) {
+		return &ValidationError{Field: "boardId", Message: fmt.Sprintf("board %s is mirrored and cannot be deleted here", id)}
+	}
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	b, ok := s.boards[boardID]
+	if err := s.writableLocked(); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	current, ok := s.boards[id]
 	if !ok {
-		return nil, fmt.Errorf("board %s not found", boardID)
+		s.mu.Unlock()
+		return &NotFoundError{BoardID: id}
 	}
-	normalizeWidget(&widget)
-	b.Widgets = append(b.Widgets, widget)
-	s.boards[boardID] = b
-	if err := s.saveToDisk(); err != nil {
+	if !unconditional(ifVersion) && ifVersion != current.Version {
+		s.mu.Unlock()
+		return &VersionConflictError{BoardID: id, CurrentVersion: current.Version, ProvidedVersion: ifVersion}
+	}
+	if s.shared != nil {
+		if err := s.shared.Remove(ctx, id); err != nil {
+			s.mu.Unlock()
+			return fmt.Errorf("%w: %w", ErrSharedState, err)
+		}
+	}
+	delete(s.boards, id)
+	if err := s.saveToDisk(ctx, id, "delete"); err != nil {
+		s.boards[id] = current
+		s.mu.Unlock()
+		return err
+	}
+	s.forgetLocked(id)
+	s.mu.Unlock()
+	s.notifyDeleted(id)
+	return nil
+}
+
+// Save remplace les widgets (et le titre s'il est fourni) d'un board.
+// req.Version doit correspondre à la version courante : une version absente
+// (0) ou dépassée échoue en conflit, une version négative est refusée. Save
+// ne désactive donc jamais le contrôle de version, voir Replace.
+func (s *Service) Save(ctx context.Context, id string, req SaveRequest) (*Model, error) {
+	if req.Version < 0 {
+		return nil, &ValidationError{Field: "version", Message: "must not be negative"}
+	}
+	return s.Replace(ctx, id, req.Version, req)
+}
+
+// Replace est Save avec la version attendue passée à part : ifVersion peut
+// valoir AnyVersion (If-Match: *), req.Version est ignoré.
+func (s *Service) Replace(ctx context.Context, id string, ifVersion int, req SaveRequest) (*Model, error) {
+	if strings.TrimSpace(id) == "" {
+		return nil, &ValidationError{Field: "boardId", Message: "must not be empty"}
+	}
+	if err := validateWidgets(req.Widgets); err != nil {
 		return nil, err
 	}
-	return &widget, nil
+	if err := s.checkWidgetCount(len(req.Widgets)); err != nil {
+		return nil, err
+	}
+	return s.mutate(ctx, id, ifVersion, "save", func(b *Model) error {
+		widgets := make([]Widget, len(req.Widgets))
+		copy(widgets, req.Widgets)
+		for i := range widgets {
+			normalizeWidget(&widgets[i])
+		}
+		b.Widgets = widgets
+		if req.Title != nil {
+			b.Title = *req.Title
+		}
+		return nil
+	})
 }
 
-func (s *Service) Count() int {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return len(s.boards)
+func (s *Service) SaveBoard(ctx context.Context, id string, version int, widgets []Widget) (*Model, error) {
+	return s.Save(ctx, id, SaveRequest{Version: version, Widgets: widgets})
 }
 
-// ─── Handlers REST (inchangés) ────────────────────────────────────────────────
+func (s *Service) GetWidget(ctx context.Context, boardID, widgetID string) (*Model, *Widget, error) {
+	b, err := s.ResolveBoard(ctx, boardID)
+	if err != nil {
+		return nil, nil, err
+	}
+	idx := indexOfWidget(b.Widgets, widgetID)
+	if idx < 0 {
+		return nil, nil, &NotFoundError{BoardID: boardID, WidgetID: widgetID}
+	}
+	w := b.Widgets[idx]
+	return b, &w, nil
+}
 
-func (s *Service) HandleBoard(w http.ResponseWriter, r *http.Request) {
-	id := strings.TrimPrefix(r.URL.Path, "/api/boards/")
-	if id == "" || strings.Contains(id, "/") {
-		http.Error(w, "invalid board id", http.StatusBadRequest)
-		return
+func (s *Service) AddWidget(ctx context.Context, boardID string, ifVersion int, widget Widget) (*Model, *Widget, error) {
+	if err := validateWidget("widget", widget); err != nil {
+		return nil, nil, err
 	}
-	switch r.Method {
-	case http.MethodGet:
-		s.handleGet(w, id)
-	case http.MethodPut:
-		s.handlePut(w, r, id)
-	default:
-		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	normalizeWidget(&widget)
+	b, err := s.mutate(ctx, boardID, ifVersion, "add-widget", func(b *Model) error {
+		if indexOfWidget(b.Widgets, widget.ID) >= 0 {
+			return &ValidationError{Field: "widget.id", Message: fmt.Sprintf("widget %s already exists", widget.ID)}
+		}
+		if err := s.checkWidgetCount(len(b.Widgets) + 1); err != nil {
+			return err
+		}
+		b.Widgets = append(b.Widgets, widget)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
 	}
+	return b, &widget, nil
 }
 
-func (s *Service) handleGet(w http.ResponseWriter, id string) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	board, ok := s.boards[id]
-	if !ok {
-		board = Model{ID: id, Version: 1, Widgets: []Widget{}}
-		s.boards[id] = board
+// UpdateWidget remplace le widget portant le même ID.
+func (s *Service) UpdateWidget(ctx context.Context, boardID string, ifVersion int, widget Widget) (*Model, *Widget, error) {
+	if err := validateWidget("widget", widget); err != nil {
+		return nil, nil, err
 	}
-	for i := range board.Widgets {
-		normalizeWidget(&board.Widgets[i])
+	normalizeWidget(&widget)
+	b, err := s.mutate(ctx, boardID, ifVersion, "update-widget", func(b *Model) error {
+		idx := indexOfWidget(b.Widgets, widget.ID)
+		if idx < 0 {
+			return &NotFoundError{BoardID: boardID, WidgetID: widget.ID}
+		}
+		b.Widgets[idx] = widget
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
 	}
-	w.Header().Set("Content-Type", "application/json")
-	_ = json.NewEncoder(w).Encode(board)
+	return b, &widget, nil
+}
+
+func (s *Service) DeleteWidget(ctx context.Context, boardID, widgetID string, ifVersion int) (*Model, error) {
+	return s.mutate(ctx, boardID, ifVersion, "delete-widget", func(b *Model) error {
+		idx := indexOfWidget(b.Widgets, widgetID)
+		if idx < 0 {
+			return &NotFoundError{BoardID: boardID, WidgetID: widgetID}
+		}
+		b.Widgets = append(b.Widgets[:idx], b.Widgets[idx+1:]...)
+		return nil
+	})
 }
 
-func (s *Service) handlePut(w http.ResponseWriter, r *http.Request, id string) {
-	var req SaveRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "invalid json", http.StatusBadRequest)
-		return
+// Close enregistre les textes collaboratifs en attente, attend la fin des
+// écritures en cours (elles tiennent le verrou), fait un dernier flush sur
+// disque puis refuse les écritures suivantes. Les lectures restent
+// possibles. En lecture seule il n'y a rien à écrire.
+func (s *Service) Close() error {
+	s.mu.Lock()
+	s.textsClosed = true
+	s.mu.Unlock()
+	if err := s.flushAllTexts(context.Background()); err != nil {
+		s.logger.Warn("board: collaborative texts not saved", "err", err)
 	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	current, ok := s.boards[id]
-	if !ok {
-		current = Model{ID: id, Version: 1, Widgets: []Widget{}}
+	if s.closed {
+		return nil
 	}
-	if req.Version != current.Version {
-		http.Error(w, "version conflict", http.StatusConflict)
-		return
+	s.closed = true
+	if s.readOnly {
+		return nil
 	}
-	for i := range req.Widgets {
-		normalizeWidget(&req.Widgets[i])
+	return s.saveToDisk(context.Background(), "", "close")
+}
+
+func (s *Service) Count() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.boards)
+}
+
+// mutate applique fn à une copie du board sous verrou, incrémente la
+// version, persiste puis publie. En cas d'échec l'état mémoire est restauré.
+// Pour un board miroir, l'écriture est faite par l'upstream. Une écriture
+// interne (LatestVersion) devancée par une autre instance est rejouée sur
+// la copie qu'elle a écrite.
+func (s *Service) mutate(ctx context.Context, id string, ifVersion int, op string, fn func(*Model) error) (_ *Model, err error) {
+	ctx, span := startSpan(ctx, "board."+op, id, attribute.Int("board.if_version", ifVersion))
+	defer func() { endSpan(span, err) }()
+	if s.mirrored(id) {
+		return s.mutateMirrored(ctx, id, ifVersion, fn)
 	}
-	next := Model{ID: id, Version: current.Version + 1, Widgets: req.Widgets}
-	s.boards[id] = next
-	if err := s.saveToDisk(); err != nil {
-		http.Error(w, "failed to persist board", http.StatusInternalServerError)
-		return
+	if _, ok := s.GetBoard(id); !ok && s.shared != nil {
+		if _, err := s.loadShared(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	for attempt := 1; ; attempt++ {
+		b, err := s.mutateLocal(ctx, id, ifVersion, op, fn)
+		if ifVersion != LatestVersion || attempt >= conflictAttempts || !errors.Is(err, ErrVersionConflict) {
+			return b, err
+		}
 	}
-	w.WriteHeader(http.StatusNoContent)
 }
 
-func (s *Service) loadFromDisk() {
-	if s.storePath == "" {
-		return
+func (s *Service) mutateLocal(ctx context.Context, id string, ifVersion int, op string, fn func(*Model) error) (*Model, error) {
+	s.mu.Lock()
+	if err := s.writableLocked(); err != nil {
+		s.mu.Unlock()
+		return nil, err
 	}
-	content, err := os.ReadFile(s.storePath)
+	current, created, err := s.lookupLocked(id)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return
+		s.mu.Unlock()
+		return nil, err
+	}
+	rollback := func() {
+		if created {
+			delete(s.boards, id)
+		} else {
+			s.boards[id] = current
 		}
-		return
 	}
-	var persisted map[string]Model
-	if err := json.Unmarshal(content, &persisted); err != nil {
-		return
+	if !unconditional(ifVersion) && ifVersion != current.Version {
+		rollback()
+		s.mu.Unlock()
+		return nil, &VersionConflictError{BoardID: id, CurrentVersion: current.Version, ProvidedVersion: ifVersion}
+	}
+	next := current
+	next.Widgets = append(make([]Widget, 0, len(current.Widgets)+1), current.Widgets...)
+	if err := fn(&next); err != nil {
+		rollback()
+		s.mu.Unlock()
+		return nil, err
+	}
+	next.Version = current.Version + 1
+	s.boards[id] = next
+	if err := s.commitLocked(ctx, &next, rollback); err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	if err := s.saveToDisk(ctx, id, op); err != nil {
+		rollback()
+		s.mu.Unlock()
+		return nil, err
 	}
-	s.boards = persisted
+	s.recordLocked(next, op)
+	s.trackEditLocked(ctx, op, current, next)
+	resets := s.resetTextsLocked(op, current, next)
+	s.mu.Unlock()
+	s.notify(&next)
+	s.notifyText(resets...)
+	return &next, nil
 }
 
-func (s *Service) saveToDisk() error {
-	if s.storePath == "" {
-		return nil
+func (s *Service) checkWidgetCount(n int) error {
+	if s.maxWidgets > 0 && n > s.maxWidgets {
+		return &ValidationError{Field: "widgets", Message: fmt.Sprintf("a board holds at most %d widgets", s.maxWidgets)}
 	}
-	dir := filepath.Dir(s.storePath)
-	if err := os.MkdirAll(dir, 0o755); err != nil {
-		return err
+	return nil
+}
+
+func indexOfWidget(widgets []Widget, id string) int {
+	for i := range widgets {
+		if widgets[i].ID == id {
+			return i
+		}
 	}
-	data, err := json.MarshalIndent(s.boards, "", "  ")
-	if err != nil {
-		return err
+	return -1
+}
+
+func validateWidgets(widgets []Widget) error {
+	seen := make(map[string]bool, len(widgets))
+	for i := range widgets {
+		field := fmt.Sprintf("widgets[%d]", i)
+		if err := validateWidget(field, widgets[i]); err != nil {
+			return err
+		}
+		if seen[widgets[i].ID] {
+			return &ValidationError{Field: field + ".id", Message: fmt.Sprintf("duplicate widget id %s", widgets[i].ID)}
+		}
+		seen[widgets[i].ID] = true
 	}
-	tempPath := s.storePath + ".tmp"
-	if err := os.WriteFile(tempPath, data, 0o644); err != nil {
-		return err
+	return nil
+}
+
+func validateWidget(field string, widget Widget) error {
+	if strings.TrimSpace(widget.ID) == "" {
+		return &ValidationError{Field: field + ".id", Message: "must not be empty"}
+	}
+	if strings.TrimSpace(widget.Type) == "" {
+		return &ValidationError{Field: field + ".type", Message: "must not be empty"}
 	}
-	return os.Rename(tempPath, s.storePath)
+	return nil
 }
 
 func normalizeWidget(widget *Widget) {
@@ -215,24 +623,3 @@ func normalizeWidget(widget *Widget) {
 		widget.Type = "text"
 	}
 }
-
-func (s *Service) SaveBoard(id string, version int, widgets []Widget) (*Model, error) {
-    s.mu.Lock()
-    defer s.mu.Unlock()
-    current, ok := s.boards[id]
-    if !ok {
-        current = Model{ID: id, Version: 1, Widgets: []Widget{}}
-    }
-    if version != current.Version {
-        return nil, fmt.Errorf("version conflict: expected %d got %d", current.Version, version)
-    }
-    for i := range widgets {
-        normalizeWidget(&widgets[i])
-    }
-    next := Model{ID: id, Title: current.Title, Version: current.Version + 1, Widgets: widgets}
-    s.boards[id] = next
-    if err := s.saveToDisk(); err != nil {
-        return nil, err
-    }
-    return &next, nil
-}
@@ -0,0 +1,294 @@
+package board
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestService(t *testing.T, opts ...Option) *Service {
+	t.Helper()
+	s, err := NewService("", opts...)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	return s
+}
+
+func TestSaveVersionCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		save    func(s *Service) (*Model, error)
+		wantErr string
+	}{
+		{"current version", func(s *Service) (*Model, error) {
+			return s.Save(context.Background(), "b1", SaveRequest{Version: 2})
+		}, ""},
+		{"missing version", func(s *Service) (*Model, error) {
+			return s.Save(context.Background(), "b1", SaveRequest{})
+		}, CodeVersionConflict},
+		{"stale version", func(s *Service) (*Model, error) {
+			return s.Save(context.Background(), "b1", SaveRequest{Version: 1})
+		}, CodeVersionConflict},
+		{"negative version", func(s *Service) (*Model, error) {
+			return s.Save(context.Background(), "b1", SaveRequest{Version: AnyVersion})
+		}, CodeValidation},
+		{"replace any version", func(s *Service) (*Model, error) {
+			return s.Replace(context.Background(), "b1", AnyVersion, SaveRequest{Version: 1})
+		}, ""},
+		{"replace stale version", func(s *Service) (*Model, error) {
+			return s.Replace(context.Background(), "b1", 1, SaveRequest{Version: 2})
+		}, CodeVersionConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+			if _, _, err := s.AddWidget(context.Background(), "b1", AnyVersion, Widget{ID: "w1", Type: "text"}); err != nil {
+				t.Fatalf("AddWidget: %v", err)
+			}
+			b, err := tt.save(s)
+			if tt.wantErr != "" {
+				if err == nil || ErrorCode(err) != tt.wantErr {
+					t.Fatalf("err = %v, want %s", err, tt.wantErr)
+				}
+				if got, _ := s.GetBoard("b1"); got.Version != 2 || len(got.Widgets) != 1 {
+					t.Fatalf("board changed after a refused save: %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("err = %v", err)
+			}
+			if b.Version != 3 || len(b.Widgets) != 0 {
+				t.Fatalf("saved board = %+v, want version 3 without widgets", b)
+			}
+		})
+	}
+}
+
+func TestMaxWidgets(t *testing.T) {
+	widgets := func(n int) []Widget {
+		out := make([]Widget, n)
+		for i := range out {
+			out[i] = Widget{ID: fmt.Sprintf("w%d", i), Type: "text"}
+		}
+		return out
+	}
+	tests := []struct {
+		name    string
+		seed    int
+		op      func(s *Service) error
+		wantErr bool
+	}{
+		{"save at the limit", 1, func(s *Service) error {
+			_, err := s.Save(context.Background(), "b1", SaveRequest{Version: 2, Widgets: widgets(3)})
+			return err
+		}, false},
+		{"save over the limit", 1, func(s *Service) error {
+			_, err := s.Save(context.Background(), "b1", SaveRequest{Version: 2, Widgets: widgets(4)})
+			return err
+		}, true},
+		{"replace any version over the limit", 1, func(s *Service) error {
+			_, err := s.Replace(context.Background(), "b1", AnyVersion, SaveRequest{Widgets: widgets(4)})
+			return err
+		}, true},
+		{"add up to the limit", 2, func(s *Service) error {
+			_, _, err := s.AddWidget(context.Background(), "b1", AnyVersion, Widget{ID: "new", Type: "text"})
+			return err
+		}, false},
+		{"add over the limit", 3, func(s *Service) error {
+			_, _, err := s.AddWidget(context.Background(), "b1", AnyVersion, Widget{ID: "new", Type: "text"})
+			return err
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, WithMaxWidgets(3))
+			if _, err := s.CreateBoard(context.Background(), "b1", "t"); err != nil {
+				t.Fatalf("CreateBoard: %v", err)
+			}
+			before, err := s.Replace(context.Background(), "b1", AnyVersion, SaveRequest{Widgets: widgets(tt.seed)})
+			if err != nil {
+				t.Fatalf("Replace: %v", err)
+			}
+			err = tt.op(s)
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("err = %v", err)
+				}
+				return
+			}
+			var validation *ValidationError
+			if !errors.As(err, &validation) || validation.Field != "widgets" {
+				t.Fatalf("err = %v, want a validation error on widgets", err)
+			}
+			if got, _ := s.GetBoard("b1"); got.Version != before.Version || len(got.Widgets) != tt.seed {
+				t.Fatalf("board after a refused write = %+v, want version %d with %d widgets", got, before.Version, tt.seed)
+			}
+		})
+	}
+}
+
+func TestDeleteBoardVersionCheck(t *testing.T) {
+	tests := []struct {
+		name      string
+		ifVersion int
+		wantErr   bool
+	}{
+		{"any version", AnyVersion, false},
+		{"current version", 1, false},
+		{"zero", 0, true},
+		{"stale version", 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+			if _, err := s.CreateBoard(context.Background(), "b1", "t"); err != nil {
+				t.Fatalf("CreateBoard: %v", err)
+			}
+			err := s.DeleteBoard(context.Background(), "b1", tt.ifVersion)
+			var conflict *VersionConflictError
+			if tt.wantErr != errors.As(err, &conflict) {
+				t.Fatalf("err = %v, want conflict %v", err, tt.wantErr)
+			}
+			if _, ok := s.GetBoard("b1"); ok != tt.wantErr {
+				t.Fatalf("board still present = %v, want %v", ok, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMissingBoardPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  MissingBoardPolicy
+		op      func(s *Service) error
+		wantErr string
+		created bool
+	}{
+		{"strict read", MissingBoardStrict, func(s *Service) error {
+			_, err := s.ResolveBoard(context.Background(), "b1")
+			return err
+		}, CodeNotFound, false},
+		{"strict write", MissingBoardStrict, func(s *Service) error {
+			_, _, err := s.AddWidget(context.Background(), "b1", AnyVersion, Widget{ID: "w1", Type: "text"})
+			return err
+		}, CodeNotFound, false},
+		{"strict save", MissingBoardStrict, func(s *Service) error {
+			_, err := s.Save(context.Background(), "b1", SaveRequest{Version: 1})
+			return err
+		}, CodeNotFound, false},
+		{"auto-create read", MissingBoardAutoCreate, func(s *Service) error {
+			_, err := s.ResolveBoard(context.Background(), "b1")
+			return err
+		}, "", true},
+		{"auto-create write", MissingBoardAutoCreate, func(s *Service) error {
+			_, _, err := s.AddWidget(context.Background(), "b1", AnyVersion, Widget{ID: "w1", Type: "text"})
+			return err
+		}, "", true},
+		{"auto-create failed save", MissingBoardAutoCreate, func(s *Service) error {
+			_, err := s.Save(context.Background(), "b1", SaveRequest{Version: 2})
+			return err
+		}, CodeVersionConflict, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t, WithMissingBoardPolicy(tt.policy))
+			var published []int
+			s.OnBoardUpdated(func(b *Model) { published = append(published, b.Version) })
+			err := tt.op(s)
+			if got := ErrorCode(err); err != nil && got != tt.wantErr || err == nil && tt.wantErr != "" {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+			if _, ok := s.GetBoard("b1"); ok != tt.created {
+				t.Fatalf("board exists = %v, want %v", ok, tt.created)
+			}
+			if tt.created != (len(published) > 0) {
+				t.Fatalf("published versions = %v", published)
+			}
+		})
+	}
+}
+
+func TestParseMissingBoardPolicy(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    MissingBoardPolicy
+		wantErr bool
+	}{
+		{"", MissingBoardAutoCreate, false},
+		{"auto-create", MissingBoardAutoCreate, false},
+		{" Strict ", MissingBoardStrict, false},
+		{"lazy", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMissingBoardPolicy(tt.raw)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseMissingBoardPolicy(%q) = %q, %v", tt.raw, got, err)
+		}
+	}
+}
+
+func TestClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "boards.json")
+	s, err := NewService(path)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	if _, err := s.CreateBoard(context.Background(), "b1", "t"); err != nil {
+		t.Fatalf("CreateBoard: %v", err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatalf("store not written: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("Close did not flush the store: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("second Close: %v", err)
+	}
+
+	writes := []struct {
+		name string
+		fn   func() error
+	}{
+		{"create", func() error { _, err := s.CreateBoard(context.Background(), "b2", "t"); return err }},
+		{"save", func() error { _, err := s.Save(context.Background(), "b1", SaveRequest{Version: 1}); return err }},
+		{"add widget", func() error {
+			_, _, err := s.AddWidget(context.Background(), "b1", AnyVersion, Widget{ID: "w1", Type: "text"})
+			return err
+		}},
+		{"delete", func() error { return s.DeleteBoard(context.Background(), "b1", AnyVersion) }},
+	}
+	for _, tt := range writes {
+		if err := tt.fn(); !errors.Is(err, ErrClosed) || ErrorCode(err) != CodeUnavailable {
+			t.Errorf("%s after Close: err = %v, want ErrClosed", tt.name, err)
+		}
+	}
+	if b, ok := s.GetBoard("b1"); !ok || b.Version != 1 {
+		t.Fatalf("read after Close = %+v, %v", b, ok)
+	}
+}
+
+func TestOnBoardDeleted(t *testing.T) {
+	s := newTestService(t)
+	var deleted []string
+	s.OnBoardDeleted(func(id string) { deleted = append(deleted, id) })
+	if _, err := s.CreateBoard(context.Background(), "b1", "t"); err != nil {
+		t.Fatalf("CreateBoard: %v", err)
+	}
+	_ = s.DeleteBoard(context.Background(), "b1", 2)
+	_ = s.DeleteBoard(context.Background(), "b2", AnyVersion)
+	if err := s.DeleteBoard(context.Background(), "b1", 1); err != nil {
+		t.Fatalf("DeleteBoard: %v", err)
+	}
+	if len(deleted) != 1 || deleted[0] != "b1" {
+		t.Fatalf("deleted = %v, want [b1]", deleted)
+	}
+}
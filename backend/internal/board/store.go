@@ -0,0 +1,306 @@
+package board
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// CorruptStorePolicy décide du démarrage quand le fichier de persistance
+// existe mais ne se décode pas.
+type CorruptStorePolicy string
+
+const (
+	// CorruptStoreFail refuse de démarrer (NewService renvoie l'erreur).
+	CorruptStoreFail CorruptStorePolicy = "fail"
+	// CorruptStoreReadOnly met le fichier en quarantaine et démarre sans
+	// boards, en refusant toute écriture jusqu'au prochain redémarrage.
+	CorruptStoreReadOnly CorruptStorePolicy = "read-only"
+	// CorruptStoreRecover récupère les boards lisibles, met le fichier
+	// d'origine en quarantaine et réécrit un store propre.
+	CorruptStoreRecover CorruptStorePolicy = "recover"
+)
+
+func ParseCorruptStorePolicy(raw string) (CorruptStorePolicy, error) {
+	switch p := CorruptStorePolicy(strings.ToLower(strings.TrimSpace(raw))); p {
+	case "", CorruptStoreFail:
+		return CorruptStoreFail, nil
+	case CorruptStoreReadOnly, CorruptStoreRecover:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown corrupt store policy %q (want %q, %q or %q)", raw, CorruptStoreFail, CorruptStoreReadOnly, CorruptStoreRecover)
+	}
+}
+
+func WithCorruptStorePolicy(policy CorruptStorePolicy) Option {
+	return func(s *Service) { s.corruptPolicy = policy }
+}
+
+// CorruptStoreError décrit un fichier de persistance illisible. Offset est la
+// position approximative de l'erreur dans le fichier, -1 si inconnue.
+type CorruptStoreError struct {
+	Path   string
+	Offset int64
+	Err    error
+}
+
+func (e *CorruptStoreError) Error() string {
+	if e.Offset >= 0 {
+		return fmt.Sprintf("store %s is corrupt at byte %d: %v", e.Path, e.Offset, e.Err)
+	}
+	return fmt.Sprintf("store %s is corrupt: %v", e.Path, e.Err)
+}
+
+func (e *CorruptStoreError) Unwrap() []error { return []error{ErrCorruptStore, e.Err} }
+
+// ReadOnly indique que le service a démarré sur un store en quarantaine et
+// refuse les écritures.
+func (s *Service) ReadOnly() bool {
+	return s.readOnly
+}
+
+// writableLocked renvoie l'erreur à opposer à une écriture, nil si elle est
+// permise.
+func (s *Service) writableLocked() error {
+	switch {
+	case s.closed:
+		return ErrClosed
+	case s.readOnly:
+		return ErrReadOnly
+	}
+	return nil
+}
+
+func (s *Service) loadFromDisk() (err error) {
+	if s.storePath == "" {
+		return nil
+	}
+	_, span := startSpan(context.Background(), "store.load", "")
+	defer func() { endSpan(span, err) }()
+	content, err := os.ReadFile(s.storePath)
+	if errors.Is(err, os.ErrNotExist) {
+		s.logger.Info("store: no file yet, starting empty", "path", s.storePath)
+		return nil
+	}
+	if err != nil {
+		// Illisible n'est pas corrompu : rien à mettre en quarantaine, et
+		// écrire par-dessus serait aussi risqué que de démarrer à vide.
+		return fmt.Errorf("read store: %w", err)
+	}
+	persisted, err := decodeStore(s.storePath, content)
+	if err == nil {
+		s.boards = persisted
+		s.logger.Info("store: loaded", "path", s.storePath, "boards", len(persisted))
+		return nil
+	}
+
+	switch s.corruptPolicy {
+	case CorruptStoreReadOnly:
+		quarantine, qErr := s.quarantine()
+		if qErr != nil {
+			return errors.Join(err, qErr)
+		}
+		s.readOnly = true
+		s.logger.Error("store: corrupt, moved to quarantine; starting empty and read-only",
+			"path", s.storePath, "quarantine", quarantine, "err", err)
+		return nil
+	case CorruptStoreRecover:
+		return s.recover(content, err)
+	default:
+		return fmt.Errorf("%w (restart with --recover to salvage readable boards, or --on-corrupt-store=read-only)", err)
+	}
+}
+
+// decodeStore décode le fichier complet ; toute anomalie est une
+// CorruptStoreError.
+func decodeStore(path string, content []byte) (map[string]Model, error) {
+	if len(bytes.TrimSpace(content)) == 0 {
+		// L'écriture passe par un renommage atomique : un fichier vide n'est
+		// jamais un état normal.
+		return nil, &CorruptStoreError{Path: path, Offset: 0, Err: errors.New("file is empty")}
+	}
+	var persisted map[string]Model
+	if err := json.Unmarshal(content, &persisted); err != nil {
+		return nil, &CorruptStoreError{Path: path, Offset: jsonErrorOffset(err), Err: err}
+	}
+	if persisted == nil {
+		return nil, &CorruptStoreError{Path: path, Offset: -1, Err: errors.New("file does not hold a board map")}
+	}
+	for id, b := range persisted {
+		if err := checkStoredBoard(id, &b); err != nil {
+			return nil, &CorruptStoreError{Path: path, Offset: -1, Err: err}
+		}
+		persisted[id] = b
+	}
+	return persisted, nil
+}
+
+// checkStoredBoard normalise un board relu et rejette ce qui ne peut pas venir
+// de writeStore.
+func checkStoredBoard(id string, b *Model) error {
+	if strings.TrimSpace(id) == "" {
+		return errors.New("board with an empty id")
+	}
+	if b.ID == "" {
+		b.ID = id
+	}
+	if b.ID != id {
+		return fmt.Errorf("board %s stored under key %s", b.ID, id)
+	}
+	if b.Version < 1 {
+		return fmt.Errorf("board %s: invalid version %d", id, b.Version)
+	}
+	if b.Widgets == nil {
+		b.Widgets = []Widget{}
+	}
+	for i := range b.Widgets {
+		normalizeWidget(&b.Widgets[i])
+	}
+	return nil
+}
+
+func jsonErrorOffset(err error) int64 {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		return syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		return typeErr.Offset
+	}
+	return -1
+}
+
+// quarantine renomme le fichier corrompu à côté de l'original et renvoie son
+// nouveau chemin. Une quarantaine existante n'est jamais écrasée.
+func (s *Service) quarantine() (string, error) {
+	base := fmt.Sprintf("%s.corrupt-%s", s.storePath, time.Now().UTC().Format("20060102T150405Z"))
+	target := base
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(target); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		target = fmt.Sprintf("%s.%d", base, i)
+	}
+	if err := os.Rename(s.storePath, target); err != nil {
+		return "", fmt.Errorf("quarantine store: %w", err)
+	}
+	return target, nil
+}
+
+// recover garde les boards qui se décodent un par un, s'arrête à la première
+// erreur de syntaxe (la suite du fichier n'est plus analysable), met
+// l'original en quarantaine puis réécrit un store propre.
+func (s *Service) recover(content []byte, cause error) error {
+	boards, skipped, stopErr := salvageStore(content)
+	for id, err := range skipped {
+		s.logger.Warn("store: recover skipped board", "board", id, "err", err)
+	}
+	if stopErr != nil {
+		s.logger.Warn("store: recover stopped, the rest of the file is lost", "err", stopErr)
+	}
+	quarantine, err := s.quarantine()
+	if err != nil {
+		return errors.Join(cause, err)
+	}
+	s.boards = boards
+	if err := s.saveToDisk(context.Background(), "", "recover"); err != nil {
+		return fmt.Errorf("write recovered store (original kept at %s): %w", quarantine, err)
+	}
+	ids := make([]string, 0, len(boards))
+	for id := range boards {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	s.logger.Warn("store: recovered from corrupt file",
+		"path", s.storePath, "quarantine", quarantine, "cause", cause,
+		"recovered", len(boards), "skipped", len(skipped), "boards", ids)
+	return nil
+}
+
+// salvageStore lit l'objet racine entrée par entrée. skipped contient les
+// boards présents mais invalides ; stopErr l'erreur qui a interrompu la
+// lecture, nil si le fichier a été parcouru en entier.
+func salvageStore(content []byte) (boards map[string]Model, skipped map[string]error, stopErr error) {
+	boards = make(map[string]Model)
+	skipped = make(map[string]error)
+	dec := json.NewDecoder(bytes.NewReader(content))
+	tok, err := dec.Token()
+	if err != nil {
+		return boards, skipped, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return boards, skipped, errors.New("top-level value is not an object")
+	}
+	for dec.More() {
+		offset := dec.InputOffset()
+		tok, err := dec.Token()
+		if err != nil {
+			return boards, skipped, fmt.Errorf("at byte %d: %w", offset, err)
+		}
+		id, _ := tok.(string)
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return boards, skipped, fmt.Errorf("board %s at byte %d: %w", id, offset, err)
+		}
+		var b Model
+		if err := json.Unmarshal(raw, &b); err != nil {
+			skipped[id] = err
+			continue
+		}
+		if err := checkStoredBoard(id, &b); err != nil {
+			skipped[id] = err
+			continue
+		}
+		boards[id] = b
+	}
+	return boards, skipped, nil
+}
+
+// saveToDisk écrit tout le store. boardID et op ne servent qu'aux logs et à
+// la trace.
+func (s *Service) saveToDisk(ctx context.Context, boardID, op string) (err error) {
+	if s.storePath == "" {
+		return nil
+	}
+	ctx, span := startSpan(ctx, "store.save", boardID, attribute.String("store.op", op))
+	defer func() { endSpan(span, err) }()
+	start := time.Now()
+	written, err := s.writeStore()
+	elapsed := time.Since(start)
+	span.SetAttributes(attribute.Int("store.bytes", written))
+	if s.saveObserver != nil {
+		s.saveObserver(elapsed, written, err)
+	}
+	if err != nil {
+		s.logger.ErrorContext(ctx, "store: save failed", "board", boardID, "op", op, "path", s.storePath, "err", err)
+		return err
+	}
+	s.logger.DebugContext(ctx, "store: saved", "board", boardID, "op", op, "bytes", written, "duration", elapsed)
+	return nil
+}
+
+func (s *Service) writeStore() (int, error) {
+	dir := filepath.Dir(s.storePath)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, err
+	}
+	data, err := json.MarshalIndent(s.boards, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	tempPath := s.storePath + ".tmp"
+	if err := os.WriteFile(tempPath, data, 0o644); err != nil {
+		return 0, err
+	}
+	return len(data), os.Rename(tempPath, s.storePath)
+}
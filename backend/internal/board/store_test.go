@@ -0,0 +1,97 @@
+package board
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCorruptStore(t *testing.T) {
+	const valid = `{"b1": {"id": "b1", "version": 2, "widgets": [{"id": "w1", "type": "note"}]}}`
+	tests := []struct {
+		name           string
+		content        string
+		policy         CorruptStorePolicy
+		wantErr        bool
+		wantIDs        string
+		readOnly       bool
+		wantQuarantine bool
+	}{
+		{name: "valid", content: valid, policy: CorruptStoreFail, wantIDs: "b1"},
+		{name: "empty fails", content: "  \n", policy: CorruptStoreFail, wantErr: true},
+		{name: "truncated fails", content: valid[:40], policy: CorruptStoreFail, wantErr: true},
+		{name: "not a map fails", content: `null`, policy: CorruptStoreFail, wantErr: true},
+		{name: "bad version fails", content: `{"b1": {"version": 0}}`, policy: CorruptStoreFail, wantErr: true},
+		{name: "key mismatch fails", content: `{"b1": {"id": "b2", "version": 1}}`, policy: CorruptStoreFail, wantErr: true},
+		{name: "read-only", content: valid[:40], policy: CorruptStoreReadOnly, readOnly: true, wantQuarantine: true},
+		{name: "recover keeps valid boards", content: `{"b1": {"version": 1}, "b2": {"version": 0}, "b3": {"version": "x"}, "b4": {"version": 3}}`,
+			policy: CorruptStoreRecover, wantIDs: "b1 b4", wantQuarantine: true},
+		{name: "recover stops at a syntax error", content: `{"b1": {"version": 1}, "b2": {"vers`, policy: CorruptStoreRecover, wantIDs: "b1", wantQuarantine: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "boards.json")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			s, err := NewService(path, WithCorruptStorePolicy(tt.policy))
+			if tt.wantErr {
+				var corrupt *CorruptStoreError
+				if !errors.As(err, &corrupt) || !errors.Is(err, ErrCorruptStore) {
+					t.Fatalf("err = %v, want a CorruptStoreError", err)
+				}
+				if content, _ := os.ReadFile(path); string(content) != tt.content {
+					t.Fatal("store modified although startup failed")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewService: %v", err)
+			}
+			var ids []string
+			for _, b := range s.ListBoards() {
+				ids = append(ids, b.ID)
+			}
+			if got := strings.Join(ids, " "); got != tt.wantIDs {
+				t.Fatalf("boards = %q, want %q", got, tt.wantIDs)
+			}
+			if s.ReadOnly() != tt.readOnly {
+				t.Fatalf("ReadOnly = %v", s.ReadOnly())
+			}
+			quarantined, _ := filepath.Glob(path + ".corrupt-*")
+			if (len(quarantined) == 1) != tt.wantQuarantine {
+				t.Fatalf("quarantine files = %v", quarantined)
+			}
+			if tt.readOnly {
+				if _, err := s.CreateBoard(context.Background(), "b9", "t"); !errors.Is(err, ErrReadOnly) {
+					t.Fatalf("write on a read-only store: err = %v", err)
+				}
+				return
+			}
+			reloaded, err := NewService(path)
+			if err != nil || reloaded.Count() != len(ids) {
+				t.Fatalf("store after startup does not reload: %v", err)
+			}
+		})
+	}
+}
+
+func TestUnreadableStore(t *testing.T) {
+	dir := t.TempDir()
+	// Un répertoire à la place du fichier : illisible mais pas corrompu.
+	path := filepath.Join(dir, "boards.json")
+	if err := os.Mkdir(path, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	_, err := NewService(path, WithCorruptStorePolicy(CorruptStoreRecover))
+	if err == nil || errors.Is(err, ErrCorruptStore) {
+		t.Fatalf("err = %v, want a read error", err)
+	}
+	if quarantined, _ := filepath.Glob(path + ".corrupt-*"); len(quarantined) != 0 {
+		t.Fatalf("unreadable store quarantined: %v", quarantined)
+	}
+}
@@ -0,0 +1,436 @@
+package board
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"miro-lite-standalone/backend/internal/crdt"
+	"miro-lite-standalone/backend/internal/identity"
+)
+
+// DefaultTextFlushDelay : délai entre une opération de texte et
+// l'enregistrement du texte dans le board.
+const DefaultTextFlushDelay = time.Second
+
+// textCompactTombstones : au-delà de ce nombre de caractères supprimés, et
+// s'ils sont plus nombreux que les caractères visibles, la réplique est
+// recréée à partir de son texte (voir compactTextLocked).
+const textCompactTombstones = 1024
+
+// TextChange décrit le texte collaboratif d'un champ de widget : les
+// opérations appliquées par ApplyTextUpdate, ou l'état complet pour
+// TextState.
+type TextChange struct {
+	BoardID  string `json:"boardId"`
+	WidgetID string `json:"widgetId"`
+	Field    string `json:"field"`
+	// Version est celle du board quand l'opération a été appliquée. Le
+	// texte est enregistré dans une version suivante, au plus tard
+	// WithTextFlushDelay plus tard.
+	Version int         `json:"version"`
+	Update  crdt.Update `json:"update"`
+	Text    string      `json:"text"`
+	// State est l'état complet de la réplique après l'opération : les autres
+	// instances le fusionnent dans la leur (voir ApplyRemoteText).
+	State crdt.Update `json:"state"`
+	// Reset signale une réplique recréée : le champ a été remplacé par une
+	// autre écriture, ou ses suppressions ont été oubliées. Update est alors
+	// l'état complet, et les clients doivent relire TextState.
+	Reset bool `json:"reset,omitempty"`
+}
+
+type textKey struct{ board, widget, field string }
+
+// textReplica est la réplique d'un champ. dirty : son texte n'est pas
+// encore enregistré dans le board.
+type textReplica struct {
+	doc   *crdt.Doc
+	dirty bool
+}
+
+// textFlush attend l'enregistrement des textes d'un board. actors : les
+// utilisateurs qui les ont modifiés depuis le précédent.
+type textFlush struct {
+	timer  *time.Timer
+	actors map[string]bool
+}
+
+// errTextUnchanged interrompt mutate quand aucun texte n'a changé.
+var errTextUnchanged = errors.New("text unchanged")
+
+// WithTextFlushDelay règle le délai d'enregistrement des textes
+// collaboratifs (DefaultTextFlushDelay par défaut). Une frappe ne met à jour
+// que la réplique et les abonnés de textUpdated ; le board n'est écrit,
+// persisté et publié qu'une fois par délai, avec tous les textes modifiés.
+func WithTextFlushDelay(d time.Duration) Option {
+	return func(s *Service) { s.textFlushDelay = d }
+}
+
+// OnTextUpdated enregistre fn, appelée après chaque ApplyTextUpdate qui a
+// changé un texte et pour chaque réplique recréée.
+func (s *Service) OnTextUpdated(fn func(*TextChange)) {
+	s.listenersMu.Lock()
+	defer s.listenersMu.Unlock()
+	s.textListeners = append(s.textListeners, fn)
+}
+
+func (s *Service) notifyText(changes ...*TextChange) {
+	s.listenersMu.RLock()
+	listeners := s.textListeners
+	s.listenersMu.RUnlock()
+	for _, change := range changes {
+		for _, fn := range listeners {
+			fn(change)
+		}
+	}
+}
+
+// ApplyTextUpdate fusionne update dans le texte du champ field d'un widget
+// text ou textarea. Le texte obtenu est enregistré dans Widget.Config après
+// WithTextFlushDelay. Les répliques (crdt.Doc) ne vivent qu'en mémoire :
+// après un redémarrage, ou si le champ a été remplacé entre-temps par une
+// autre écriture, elles repartent du texte enregistré et les clients
+// doivent relire TextState.
+func (s *Service) ApplyTextUpdate(ctx context.Context, boardID, widgetID, field string, update crdt.Update) (_ *TextChange, err error) {
+	ctx, span := startSpan(ctx, "board.update-text", boardID)
+	defer func() { endSpan(span, err) }()
+	if err := s.checkTextBoard(boardID); err != nil {
+		return nil, err
+	}
+	if _, err := s.ResolveBoard(ctx, boardID); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	if err := s.textWritableLocked(); err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	b, ok := s.boards[boardID]
+	if !ok {
+		s.mu.Unlock()
+		return nil, &NotFoundError{BoardID: boardID}
+	}
+	key := textKey{boardID, widgetID, field}
+	replica, err := s.textReplicaLocked(&b, key)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	applied, err := replica.doc.Apply(update)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, &ValidationError{Field: "update", Message: err.Error()}
+	}
+	change := &TextChange{BoardID: boardID, WidgetID: widgetID, Field: field, Version: b.Version, Update: applied, Text: replica.doc.Text()}
+	if applied.Empty() {
+		s.mu.Unlock()
+		return change, nil
+	}
+	change.State = replica.doc.State()
+	replica.dirty = true
+	s.scheduleTextFlushLocked(boardID, identity.ActorFrom(ctx))
+	reset := s.compactTextLocked(key, replica, b.Version)
+	s.mu.Unlock()
+	s.notifyText(change)
+	if reset != nil {
+		s.notifyText(reset)
+	}
+	return change, nil
+}
+
+// TextState renvoie l'état complet du texte du champ field, à appliquer par
+// un client avant ses propres opérations.
+func (s *Service) TextState(ctx context.Context, boardID, widgetID, field string) (*TextChange, error) {
+	if err := s.checkTextBoard(boardID); err != nil {
+		return nil, err
+	}
+	if _, err := s.ResolveBoard(ctx, boardID); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.boards[boardID]
+	if !ok {
+		return nil, &NotFoundError{BoardID: boardID}
+	}
+	replica, err := s.textReplicaLocked(&b, textKey{boardID, widgetID, field})
+	if err != nil {
+		return nil, err
+	}
+	return &TextChange{BoardID: boardID, WidgetID: widgetID, Field: field, Version: b.Version, Update: replica.doc.State(), Text: replica.doc.Text()}, nil
+}
+
+// ApplyRemoteText fusionne dans la réplique locale un changement publié par
+// une autre instance (voir le package pubsub), pour que les clients de
+// celle-ci partent du même état. Le texte est enregistré par l'instance
+// d'origine ; les abonnés locaux le reçoivent par le broker.
+func (s *Service) ApplyRemoteText(ctx context.Context, change *TextChange) (err error) {
+	_, span := startSpan(ctx, "board.ApplyRemoteText", change.BoardID)
+	defer func() { endSpan(span, err) }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writableLocked(); err != nil {
+		return err
+	}
+	key := textKey{change.BoardID, change.WidgetID, change.Field}
+	if replica, ok := s.texts[key]; ok && !change.Reset {
+		if _, err := replica.doc.Apply(change.State); err == nil {
+			return nil
+		}
+	}
+	doc := crdt.NewDoc("", "")
+	if _, err := doc.Apply(change.State); err != nil {
+		delete(s.texts, key)
+		return &ValidationError{Field: "state", Message: err.Error()}
+	}
+	replica := &textReplica{doc: doc}
+	if old, ok := s.texts[key]; ok && !change.Reset {
+		replica.dirty = old.dirty
+	}
+	s.texts[key] = replica
+	return nil
+}
+
+// checkTextBoard refuse les boards miroirs : leurs textes sont fusionnés
+// par l'upstream, qui seul en a les répliques.
+func (s *Service) checkTextBoard(boardID string) error {
+	if s.mirrored(boardID) {
+		return &ValidationError{Field: "boardId", Message: fmt.Sprintf("board %s is mirrored: edit its collaborative text on the upstream", boardID)}
+	}
+	return nil
+}
+
+// textWritableLocked refuse aussi les opérations de texte pendant Close :
+// elles ne seraient plus enregistrées.
+func (s *Service) textWritableLocked() error {
+	if s.textsClosed {
+		return ErrClosed
+	}
+	return s.writableLocked()
+}
+
+// textReplicaLocked renvoie la réplique du champ, créée au besoin à partir
+// du texte enregistré dans b. Le client de départ dépend de la version du
+// board : deux instances qui créent la réplique du même texte obtiennent la
+// même, et les opérations des clients d'une réplique précédente sont
+// refusées (origine inconnue) au lieu d'être mal placées.
+func (s *Service) textReplicaLocked(b *Model, key textKey) (*textReplica, error) {
+	_, current, err := textField(b, key.widget, key.field)
+	if err != nil {
+		return nil, err
+	}
+	if replica, ok := s.texts[key]; ok {
+		return replica, nil
+	}
+	replica := &textReplica{doc: crdt.NewDoc(fmt.Sprintf("seed-%d", b.Version), current)}
+	s.texts[key] = replica
+	return replica, nil
+}
+
+// compactTextLocked recrée la réplique quand ses caractères supprimés
+// dominent : ils ne peuvent pas être oubliés tant que des clients peuvent
+// encore s'en servir comme origine. Le changement renvoyé (Reset) fait
+// relire TextState aux clients et remplace la réplique des autres
+// instances.
+func (s *Service) compactTextLocked(key textKey, replica *textReplica, version int) *TextChange {
+	doc := replica.doc
+	if doc.Tombstones() < textCompactTombstones || doc.Tombstones() < doc.Len() {
+		return nil
+	}
+	replica.doc = crdt.NewDoc(fmt.Sprintf("seed-%d-%d", version, doc.Clock()), doc.Text())
+	return resetChange(key, replica, version)
+}
+
+// resetTextsLocked recrée les répliques des champs qu'une écriture autre
+// qu'un enregistrement de texte a changés (sauvegarde du board, undo...) :
+// leurs opérations en attente sont perdues, le texte écrit l'emporte. Les
+// répliques des widgets supprimés sont oubliées.
+func (s *Service) resetTextsLocked(op string, before, after Model) []*TextChange {
+	if op == "update-text" {
+		return nil
+	}
+	var resets []*TextChange
+	for key := range s.texts {
+		if key.board != after.ID {
+			continue
+		}
+		_, text, err := textField(&after, key.widget, key.field)
+		if err != nil {
+			delete(s.texts, key)
+			continue
+		}
+		if _, previous, err := textField(&before, key.widget, key.field); err == nil && previous == text {
+			continue
+		}
+		replica := &textReplica{doc: crdt.NewDoc(fmt.Sprintf("seed-%d", after.Version), text)}
+		s.texts[key] = replica
+		resets = append(resets, resetChange(key, replica, after.Version))
+	}
+	return resets
+}
+
+func resetChange(key textKey, replica *textReplica, version int) *TextChange {
+	state := replica.doc.State()
+	return &TextChange{BoardID: key.board, WidgetID: key.widget, Field: key.field, Version: version, Update: state, Text: replica.doc.Text(), State: state, Reset: true}
+}
+
+// scheduleTextFlushLocked programme l'enregistrement des textes de boardID
+// s'il ne l'est pas déjà.
+func (s *Service) scheduleTextFlushLocked(boardID, actor string) {
+	flush := s.textFlushes[boardID]
+	if flush == nil {
+		flush = &textFlush{actors: make(map[string]bool)}
+		flush.timer = time.AfterFunc(s.textFlushDelay, func() {
+			if err := s.flushTexts(context.Background(), boardID); err != nil {
+				s.logger.Warn("board: collaborative texts not saved", "board", boardID, "err", err)
+			}
+		})
+		s.textFlushes[boardID] = flush
+	}
+	if actor != "" {
+		flush.actors[actor] = true
+	}
+}
+
+// flushTexts enregistre dans le board les textes modifiés de ses répliques,
+// en une écriture, s'il y en a. Elle est attribuée à l'utilisateur qui les a
+// modifiés s'il est seul (voir Undo). En cas d'échec, un nouvel essai est
+// programmé.
+func (s *Service) flushTexts(ctx context.Context, boardID string) error {
+	s.mu.Lock()
+	flush := s.textFlushes[boardID]
+	delete(s.textFlushes, boardID)
+	s.mu.Unlock()
+	if flush == nil {
+		return nil
+	}
+	flush.timer.Stop()
+	actor := ""
+	if len(flush.actors) == 1 {
+		for a := range flush.actors {
+			actor = a
+		}
+	}
+	ctx = identity.WithActor(ctx, actor)
+	written := make(map[*textReplica]string)
+	_, err := s.mutate(ctx, boardID, LatestVersion, "update-text", func(b *Model) error {
+		clear(written)
+		if !s.writeTextsLocked(b, written) {
+			return errTextUnchanged
+		}
+		return nil
+	})
+	if errors.Is(err, errTextUnchanged) {
+		err = nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err == nil {
+		for replica, text := range written {
+			if replica.doc.Text() == text {
+				replica.dirty = false
+			}
+		}
+		return nil
+	}
+	if !errors.Is(err, ErrNotFound) && !errors.Is(err, ErrClosed) && !s.textsClosed {
+		s.scheduleTextFlushLocked(boardID, "")
+		maps.Copy(s.textFlushes[boardID].actors, flush.actors)
+	}
+	return err
+}
+
+// writeTextsLocked écrit dans b le texte des répliques modifiées de ses
+// widgets, note dans written le texte écrit de chacune et indique si b a
+// changé.
+func (s *Service) writeTextsLocked(b *Model, written map[*textReplica]string) bool {
+	changed := false
+	for key, replica := range s.texts {
+		if key.board != b.ID || !replica.dirty {
+			continue
+		}
+		idx, current, err := textField(b, key.widget, key.field)
+		if err != nil {
+			delete(s.texts, key)
+			continue
+		}
+		text := replica.doc.Text()
+		written[replica] = text
+		if text == current {
+			continue
+		}
+		widget := b.Widgets[idx]
+		widget.Config = maps.Clone(widget.Config)
+		if widget.Config == nil {
+			widget.Config = make(map[string]interface{})
+		}
+		widget.Config[key.field] = text
+		b.Widgets[idx] = widget
+		changed = true
+	}
+	return changed
+}
+
+// flushAllTexts enregistre les textes en attente de tous les boards.
+func (s *Service) flushAllTexts(ctx context.Context) error {
+	s.mu.Lock()
+	boards := slices.Sorted(maps.Keys(s.textFlushes))
+	s.mu.Unlock()
+	var errs []error
+	for _, id := range boards {
+		if err := s.flushTexts(ctx, id); err != nil {
+			errs = append(errs, fmt.Errorf("board %s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// dropTextsLocked oublie les répliques d'un board supprimé et leur
+// enregistrement en attente.
+func (s *Service) dropTextsLocked(boardID string) {
+	for key := range s.texts {
+		if key.board == boardID {
+			delete(s.texts, key)
+		}
+	}
+	if flush := s.textFlushes[boardID]; flush != nil {
+		flush.timer.Stop()
+		delete(s.textFlushes, boardID)
+	}
+}
+
+// textFields : champs de config à texte collaboratif, par type de widget.
+var textFields = map[string][]string{
+	"text":     {"text"},
+	"textarea": {"text"},
+}
+
+// textField renvoie l'index du widget et le texte du champ, qui doit être un
+// champ de textFields pour le type du widget, et une chaîne (ou absent).
+func textField(b *Model, widgetID, field string) (int, string, error) {
+	idx := indexOfWidget(b.Widgets, widgetID)
+	if idx < 0 {
+		return -1, "", &NotFoundError{BoardID: b.ID, WidgetID: widgetID}
+	}
+	w := b.Widgets[idx]
+	fields, ok := textFields[w.Type]
+	if !ok {
+		return -1, "", &ValidationError{Field: "widgetId", Message: fmt.Sprintf("widget %s is a %s widget: only text and textarea widgets have collaborative text", widgetID, w.Type)}
+	}
+	if !slices.Contains(fields, field) {
+		return -1, "", &ValidationError{Field: "field", Message: fmt.Sprintf("%s widgets have no collaborative text field %q (want one of %v)", w.Type, field, fields)}
+	}
+	value, ok := w.Config[field]
+	if !ok || value == nil {
+		return idx, "", nil
+	}
+	text, ok := value.(string)
+	if !ok {
+		return -1, "", &ValidationError{Field: "field", Message: fmt.Sprintf("config.%s of widget %s is not a string", field, widgetID)}
+	}
+	return idx, text, nil
+}
@@ -0,0 +1,363 @@
+package board
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"miro-lite-standalone/backend/internal/crdt"
+	"miro-lite-standalone/backend/internal/identity"
+)
+
+// newTextService crée un service avec le board b1 et son widget text w1.
+// Le délai d'enregistrement est long : les tests appellent flushTexts.
+func newTextService(t *testing.T, text string) *Service {
+	t.Helper()
+	s := newTestService(t, WithTextFlushDelay(time.Hour))
+	t.Cleanup(func() { _ = s.Close() })
+	if _, _, err := s.AddWidget(context.Background(), "b1", AnyVersion, Widget{ID: "w1", Type: "text", Config: map[string]interface{}{"text": text}}); err != nil {
+		t.Fatalf("AddWidget: %v", err)
+	}
+	return s
+}
+
+// textClient est la réplique d'un client, partie de TextState.
+func textClient(t *testing.T, s *Service) *crdt.Doc {
+	t.Helper()
+	state, err := s.TextState(context.Background(), "b1", "w1", "text")
+	if err != nil {
+		t.Fatalf("TextState: %v", err)
+	}
+	doc := crdt.NewDoc("", "")
+	if _, err := doc.Apply(state.Update); err != nil {
+		t.Fatalf("Apply(state): %v", err)
+	}
+	return doc
+}
+
+func savedText(t *testing.T, s *Service) (string, int) {
+	t.Helper()
+	b, ok := s.GetBoard("b1")
+	if !ok {
+		t.Fatal("board b1 missing")
+	}
+	text, _ := b.Widgets[0].Config["text"].(string)
+	return text, b.Version
+}
+
+func TestTextFlush(t *testing.T) {
+	s := newTextService(t, "ac")
+	published := 0
+	s.OnBoardUpdated(func(*Model) { published++ })
+	var changes []*TextChange
+	s.OnTextUpdated(func(c *TextChange) { changes = append(changes, c) })
+	_, base := savedText(t, s)
+	client := textClient(t, s)
+	for i, r := range "bcd" {
+		u, err := client.InsertAt("alice", 1+i, string(r))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.ApplyTextUpdate(context.Background(), "b1", "w1", "text", u); err != nil {
+			t.Fatalf("ApplyTextUpdate: %v", err)
+		}
+	}
+	if len(changes) != 3 || changes[2].Text != "abcdc" {
+		t.Fatalf("text changes = %d, last %+v", len(changes), changes[len(changes)-1])
+	}
+	// Les frappes ne touchent pas au board...
+	if text, version := savedText(t, s); text != "ac" || version != base || published != 0 {
+		t.Fatalf("before flush: %q v%d, %d published", text, version, published)
+	}
+	// ...qui est écrit une fois, avec le texte final.
+	if err := s.flushTexts(context.Background(), "b1"); err != nil {
+		t.Fatalf("flushTexts: %v", err)
+	}
+	if text, version := savedText(t, s); text != "abcdc" || version != base+1 || published != 1 {
+		t.Fatalf("after flush: %q v%d, %d published", text, version, published)
+	}
+	// Rien en attente : pas de nouvelle écriture.
+	if err := s.flushTexts(context.Background(), "b1"); err != nil || published != 1 {
+		t.Fatalf("second flush: %v, %d published", err, published)
+	}
+	// La réplique survit à l'écriture : le client continue.
+	u, err := client.DeleteAt(0, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change, err := s.ApplyTextUpdate(context.Background(), "b1", "w1", "text", u); err != nil || change.Text != "bcdc" {
+		t.Fatalf("after flush: %+v, %v", change, err)
+	}
+}
+
+func TestTextFlushDelay(t *testing.T) {
+	s := newTestService(t, WithTextFlushDelay(10*time.Millisecond))
+	t.Cleanup(func() { _ = s.Close() })
+	if _, _, err := s.AddWidget(context.Background(), "b1", AnyVersion, Widget{ID: "w1", Type: "text"}); err != nil {
+		t.Fatal(err)
+	}
+	_, base := savedText(t, s)
+	client := textClient(t, s)
+	for i := 0; i < 5; i++ {
+		u, err := client.InsertAt("alice", i, "x")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.ApplyTextUpdate(identity.WithActor(context.Background(), "alice"), "b1", "w1", "text", u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if text, version := savedText(t, s); text == "xxxxx" {
+			if version != base+1 {
+				t.Fatalf("version = %d, want one write", version)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("text never saved")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	// L'écriture revient au seul utilisateur qui a tapé.
+	if _, err := s.Undo(identity.WithActor(context.Background(), "alice"), "b1"); err != nil {
+		t.Fatalf("Undo: %v", err)
+	}
+	if text, _ := savedText(t, s); text != "" {
+		t.Fatalf("after undo: %q", text)
+	}
+}
+
+func TestTextFieldValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		widgetID string
+		field    string
+		wantErr  string
+	}{
+		{"text field", "w1", "text", ""},
+		{"empty field", "w1", "", "field"},
+		{"other config field", "w1", "color", "field"},
+		{"non-text widget", "w2", "text", "widgetId"},
+		{"missing widget", "nope", "text", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTextService(t, "old")
+			if _, _, err := s.AddWidget(context.Background(), "b1", AnyVersion, Widget{ID: "w2", Type: "counter"}); err != nil {
+				t.Fatalf("AddWidget: %v", err)
+			}
+			_, err := s.ApplyTextUpdate(context.Background(), "b1", tt.widgetID, tt.field, crdt.Update{})
+			var validation *ValidationError
+			var notFound *NotFoundError
+			switch {
+			case tt.wantErr != "":
+				if !errors.As(err, &validation) || validation.Field != tt.wantErr {
+					t.Fatalf("ApplyTextUpdate err = %v, want a validation error on %s", err, tt.wantErr)
+				}
+				if b, _ := s.GetBoard("b1"); b.Widgets[0].Config["color"] != nil {
+					t.Fatalf("config = %v, want it unchanged", b.Widgets[0].Config)
+				}
+			case tt.widgetID == "nope":
+				if !errors.As(err, &notFound) {
+					t.Fatalf("ApplyTextUpdate err = %v, want not found", err)
+				}
+			case err != nil:
+				t.Fatalf("ApplyTextUpdate: %v", err)
+			}
+		})
+	}
+}
+
+func TestTextReset(t *testing.T) {
+	tests := []struct {
+		name      string
+		write     func(s *Service) error
+		wantReset bool
+		// wantSaved : texte enregistré après une nouvelle frappe du client
+		// (">" après le premier caractère) ; wantErr : erreur de cette frappe.
+		wantSaved string
+		wantErr   string
+	}{
+		{"field replaced", func(s *Service) error {
+			_, _, err := s.UpdateWidget(context.Background(), "b1", AnyVersion, Widget{ID: "w1", Type: "text", Config: map[string]interface{}{"text": "new"}})
+			return err
+		}, true, "new", "unknown origin"},
+		{"other field changed", func(s *Service) error {
+			_, _, err := s.UpdateWidget(context.Background(), "b1", AnyVersion, Widget{ID: "w1", Type: "text", X: 5, Config: map[string]interface{}{"text": "old"}})
+			return err
+		}, false, "o>ld!", ""},
+		{"widget deleted", func(s *Service) error {
+			_, err := s.DeleteWidget(context.Background(), "b1", "w1", AnyVersion)
+			return err
+		}, false, "", "not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTextService(t, "old")
+			var resets []*TextChange
+			s.OnTextUpdated(func(c *TextChange) {
+				if c.Reset {
+					resets = append(resets, c)
+				}
+			})
+			client := textClient(t, s)
+			// Une frappe en attente d'enregistrement, puis l'écriture.
+			for _, step := range []func() error{
+				func() error {
+					u, err := client.InsertAt("alice", 3, "!")
+					if err != nil {
+						return err
+					}
+					_, err = s.ApplyTextUpdate(context.Background(), "b1", "w1", "text", u)
+					return err
+				},
+				func() error { return tt.write(s) },
+			} {
+				if err := step(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := len(resets) == 1; got != tt.wantReset {
+				t.Fatalf("resets = %+v, want reset %v", resets, tt.wantReset)
+			}
+			u, err := client.InsertAt("alice", 1, ">")
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Une réplique périmée est refusée, pas mal placée.
+			_, err = s.ApplyTextUpdate(context.Background(), "b1", "w1", "text", u)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ApplyTextUpdate err = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("ApplyTextUpdate: %v", err)
+			}
+			if tt.wantSaved == "" {
+				return
+			}
+			if err := s.flushTexts(context.Background(), "b1"); err != nil {
+				t.Fatal(err)
+			}
+			if text, _ := savedText(t, s); text != tt.wantSaved {
+				t.Fatalf("saved text = %q, want %q", text, tt.wantSaved)
+			}
+		})
+	}
+}
+
+func TestApplyRemoteText(t *testing.T) {
+	a, b := newTextService(t, "ac"), newTextService(t, "ac")
+	// Les deux instances se relaient leurs changements, comme pubsub.Redis.
+	a.OnTextUpdated(func(c *TextChange) {
+		if err := b.ApplyRemoteText(context.Background(), c); err != nil {
+			t.Errorf("b.ApplyRemoteText: %v", err)
+		}
+	})
+	b.OnTextUpdated(func(c *TextChange) {
+		if err := a.ApplyRemoteText(context.Background(), c); err != nil {
+			t.Errorf("a.ApplyRemoteText: %v", err)
+		}
+	})
+	alice, bob := textClient(t, a), textClient(t, b)
+	ua, err := alice.InsertAt("alice", 1, "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ub, err := bob.InsertAt("bob", 1, "x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.ApplyTextUpdate(context.Background(), "b1", "w1", "text", ua); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.ApplyTextUpdate(context.Background(), "b1", "w1", "text", ub); err != nil {
+		t.Fatal(err)
+	}
+	// Chaque client peut envoyer ses opérations à l'une ou l'autre instance.
+	if _, err := b.ApplyTextUpdate(context.Background(), "b1", "w1", "text", ua); err != nil {
+		t.Fatalf("alice's update on b: %v", err)
+	}
+	sa, _ := a.TextState(context.Background(), "b1", "w1", "text")
+	sb, _ := b.TextState(context.Background(), "b1", "w1", "text")
+	if sa.Text != sb.Text || len([]rune(sa.Text)) != 4 {
+		t.Fatalf("a = %q, b = %q", sa.Text, sb.Text)
+	}
+	// Une réplique recréée sur a remplace celle de b.
+	if _, _, err := a.UpdateWidget(context.Background(), "b1", AnyVersion, Widget{ID: "w1", Type: "text", Config: map[string]interface{}{"text": "reset"}}); err != nil {
+		t.Fatal(err)
+	}
+	if sb, _ = b.TextState(context.Background(), "b1", "w1", "text"); sb.Text != "reset" {
+		t.Fatalf("b after reset = %q", sb.Text)
+	}
+	carol := textClient(t, b)
+	uc, err := carol.InsertAt("carol", 5, "!")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.ApplyTextUpdate(context.Background(), "b1", "w1", "text", uc); err != nil {
+		t.Fatalf("update from b's replica on a: %v", err)
+	}
+}
+
+func TestTextCompaction(t *testing.T) {
+	s := newTextService(t, "")
+	var resets []*TextChange
+	s.OnTextUpdated(func(c *TextChange) {
+		if c.Reset {
+			resets = append(resets, c)
+		}
+	})
+	client := textClient(t, s)
+	long := strings.Repeat("x", textCompactTombstones)
+	for _, edit := range []func() (crdt.Update, error){
+		func() (crdt.Update, error) { return client.InsertAt("alice", 0, long+"kept") },
+		func() (crdt.Update, error) { return client.DeleteAt(0, textCompactTombstones) },
+	} {
+		u, err := edit()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.ApplyTextUpdate(context.Background(), "b1", "w1", "text", u); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(resets) != 1 || resets[0].Text != "kept" {
+		t.Fatalf("resets = %d", len(resets))
+	}
+	if doc := s.texts[textKey{"b1", "w1", "text"}].doc; doc.Tombstones() != 0 || doc.Text() != "kept" {
+		t.Fatalf("replica %q keeps %d tombstones", doc.Text(), doc.Tombstones())
+	}
+	// Le texte en attente est toujours enregistré.
+	if err := s.flushTexts(context.Background(), "b1"); err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := savedText(t, s); text != "kept" {
+		t.Fatalf("saved text = %q", text)
+	}
+}
+
+func TestCloseFlushesTexts(t *testing.T) {
+	s := newTextService(t, "")
+	client := textClient(t, s)
+	u, err := client.InsertAt("alice", 0, "bye")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ApplyTextUpdate(context.Background(), "b1", "w1", "text", u); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if text, _ := savedText(t, s); text != "bye" {
+		t.Fatalf("saved text = %q", text)
+	}
+	if _, err := s.ApplyTextUpdate(context.Background(), "b1", "w1", "text", u); !errors.Is(err, ErrClosed) {
+		t.Fatalf("after Close: err = %v", err)
+	}
+}
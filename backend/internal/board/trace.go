@@ -0,0 +1,34 @@
+package board
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "miro-lite-standalone/backend/internal/board"
+
+func startSpan(ctx context.Context, name, boardID string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if boardID != "" {
+		attrs = append(attrs, attribute.String("board.id", boardID))
+	}
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endSpan termine span. Les erreurs du domaine (not found, conflit,
+// validation) sont notées sans marquer le span en échec : seules les pannes
+// du service le sont.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetAttributes(attribute.String("error.code", ErrorCode(err)))
+		switch ErrorCode(err) {
+		case CodeInternal, CodeUnavailable:
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+	span.End()
+}
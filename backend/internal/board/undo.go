@@ -0,0 +1,250 @@
+package board
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"miro-lite-standalone/backend/internal/identity"
+)
+
+// maxEditsPerUser borne les écritures annulables gardées pour un
+// utilisateur sur un board.
+const maxEditsPerUser = 100
+
+// edit est une écriture d'un utilisateur, réduite aux widgets (et au titre)
+// qu'elle a changés : l'annuler ne touche pas au reste du board.
+type edit struct {
+	op      string
+	title   *titleEdit
+	widgets []widgetEdit
+}
+
+type titleEdit struct{ before, after string }
+
+// widgetEdit : before nil pour un ajout, after nil pour une suppression.
+// index est la position du widget, pour le remettre à sa place.
+type widgetEdit struct {
+	id            string
+	before, after *Widget
+	index         int
+}
+
+type editKey struct{ board, actor string }
+
+// editLog : done est la pile d'Undo, undone celle de Redo.
+type editLog struct {
+	done, undone []*edit
+}
+
+// replayPlan passe de Undo/Redo à trackEditLocked (par le contexte de
+// mutate) l'écriture rejouée et celles qui ont été écartées.
+type replayPlan struct {
+	undo      bool
+	applied   *edit
+	discarded int
+}
+
+type replayPlanKey struct{}
+
+// Undo annule la dernière écriture de l'utilisateur de ctx (identity.WithActor) sur
+// le board, par une nouvelle écriture qui remet les widgets qu'elle avait
+// changés dans leur état précédent. Un widget modifié depuis par quelqu'un
+// d'autre garde sa version : seule la part encore intacte est annulée, et
+// une écriture entièrement recouverte est écartée au profit de la
+// précédente. L'historique vit en mémoire, par instance.
+func (s *Service) Undo(ctx context.Context, boardID string) (*Model, error) {
+	return s.replay(ctx, boardID, true)
+}
+
+// Redo rejoue la dernière écriture annulée par Undo, tant que l'utilisateur
+// n'a rien écrit d'autre sur le board.
+func (s *Service) Redo(ctx context.Context, boardID string) (*Model, error) {
+	return s.replay(ctx, boardID, false)
+}
+
+func (s *Service) replay(ctx context.Context, boardID string, undo bool) (*Model, error) {
+	op := "redo"
+	if undo {
+		op = "undo"
+	}
+	actor := identity.ActorFrom(ctx)
+	if actor == "" {
+		return nil, &ValidationError{Field: "user", Message: fmt.Sprintf("%s needs an identified caller: the session cookie, or an authenticated user", op)}
+	}
+	if s.mirrored(boardID) {
+		return nil, &ValidationError{Field: "boardId", Message: fmt.Sprintf("board %s is mirrored: %s is not available", boardID, op)}
+	}
+	// Les textes en attente deviennent d'abord une écriture annulable.
+	if err := s.flushTexts(ctx, boardID); err != nil {
+		return nil, err
+	}
+	plan := &replayPlan{undo: undo}
+	ctx = context.WithValue(ctx, replayPlanKey{}, plan)
+	return s.mutate(ctx, boardID, LatestVersion, op, func(b *Model) error {
+		log := s.edits[editKey{boardID, actor}]
+		var stack []*edit
+		if log != nil {
+			stack = log.done
+			if !undo {
+				stack = log.undone
+			}
+		}
+		plan.applied, plan.discarded = nil, 0
+		for i := len(stack) - 1; i >= 0; i-- {
+			if stack[i].apply(b, undo) {
+				plan.applied = stack[i]
+				return nil
+			}
+			plan.discarded++
+		}
+		return &ValidationError{Field: "boardId", Message: fmt.Sprintf("nothing to %s on board %s", op, boardID)}
+	})
+}
+
+// trackEditLocked tient à jour l'historique de l'utilisateur après une
+// écriture réussie de mutate.
+func (s *Service) trackEditLocked(ctx context.Context, op string, before, after Model) {
+	actor := identity.ActorFrom(ctx)
+	if actor == "" {
+		return
+	}
+	key := editKey{before.ID, actor}
+	log := s.edits[key]
+	if plan, ok := ctx.Value(replayPlanKey{}).(*replayPlan); ok {
+		if log == nil || plan.applied == nil {
+			return
+		}
+		from, to := &log.done, &log.undone
+		if !plan.undo {
+			from, to = to, from
+		}
+		*from = (*from)[:len(*from)-1-plan.discarded]
+		*to = append(*to, plan.applied)
+		return
+	}
+	e := diffModels(op, before, after)
+	if e == nil {
+		return
+	}
+	if log == nil {
+		log = &editLog{}
+		s.edits[key] = log
+	}
+	log.undone = nil
+	// La frappe dans un texte arrive par petites touches : elles forment une
+	// seule écriture tant qu'elles portent sur le même champ.
+	if n := len(log.done); n > 0 && op == "update-text" && log.done[n-1].merge(e) {
+		return
+	}
+	log.done = append(log.done, e)
+	if len(log.done) > maxEditsPerUser {
+		log.done = log.done[len(log.done)-maxEditsPerUser:]
+	}
+}
+
+// dropEditsLocked oublie les historiques d'un board supprimé.
+func (s *Service) dropEditsLocked(boardID string) {
+	for key := range s.edits {
+		if key.board == boardID {
+			delete(s.edits, key)
+		}
+	}
+}
+
+func diffModels(op string, before, after Model) *edit {
+	e := &edit{op: op}
+	if before.Title != after.Title {
+		e.title = &titleEdit{before: before.Title, after: after.Title}
+	}
+	for i := range before.Widgets {
+		bw := before.Widgets[i]
+		j := indexOfWidget(after.Widgets, bw.ID)
+		switch {
+		case j < 0:
+			e.widgets = append(e.widgets, widgetEdit{id: bw.ID, before: &bw, index: i})
+		case !reflect.DeepEqual(bw, after.Widgets[j]):
+			aw := after.Widgets[j]
+			e.widgets = append(e.widgets, widgetEdit{id: bw.ID, before: &bw, after: &aw, index: j})
+		}
+	}
+	for j := range after.Widgets {
+		aw := after.Widgets[j]
+		if indexOfWidget(before.Widgets, aw.ID) < 0 {
+			e.widgets = append(e.widgets, widgetEdit{id: aw.ID, after: &aw, index: j})
+		}
+	}
+	if e.title == nil && len(e.widgets) == 0 {
+		return nil
+	}
+	return e
+}
+
+// merge absorbe next, une écriture du même widget qui part de l'état laissé
+// par e.
+func (e *edit) merge(next *edit) bool {
+	if e.op != next.op || e.title != nil || next.title != nil || len(e.widgets) != 1 || len(next.widgets) != 1 {
+		return false
+	}
+	last, w := &e.widgets[0], next.widgets[0]
+	if last.id != w.id || last.after == nil || w.before == nil || !reflect.DeepEqual(*last.after, *w.before) {
+		return false
+	}
+	last.after = w.after
+	return true
+}
+
+// apply annule (undo) ou rejoue e sur b, pour chaque widget encore dans
+// l'état où e l'a laissé (ou trouvé, pour redo). Il renvoie faux si rien
+// n'a pu l'être.
+func (e *edit) apply(b *Model, undo bool) bool {
+	changed := false
+	if t := e.title; t != nil {
+		from, to := t.after, t.before
+		if !undo {
+			from, to = to, from
+		}
+		if b.Title == from {
+			b.Title = to
+			changed = true
+		}
+	}
+	for k := range e.widgets {
+		w := e.widgets[k]
+		if undo {
+			// Dans l'ordre inverse, pour que les positions restaurées
+			// correspondent.
+			w = e.widgets[len(e.widgets)-1-k]
+		}
+		from, to := w.after, w.before
+		if !undo {
+			from, to = to, from
+		}
+		idx := indexOfWidget(b.Widgets, w.id)
+		var current *Widget
+		if idx >= 0 {
+			current = &b.Widgets[idx]
+		}
+		if !sameWidget(current, from) {
+			continue
+		}
+		switch {
+		case to == nil:
+			b.Widgets = append(b.Widgets[:idx], b.Widgets[idx+1:]...)
+		case current == nil:
+			at := min(w.index, len(b.Widgets))
+			b.Widgets = append(b.Widgets[:at], append([]Widget{*to}, b.Widgets[at:]...)...)
+		default:
+			b.Widgets[idx] = *to
+		}
+		changed = true
+	}
+	return changed
+}
+
+func sameWidget(a, b *Widget) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return reflect.DeepEqual(*a, *b)
+}
@@ -0,0 +1,71 @@
+package board
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"miro-lite-standalone/backend/internal/identity"
+)
+
+func TestUndo(t *testing.T) {
+	as := func(actor string) context.Context { return identity.WithActor(context.Background(), actor) }
+	add := func(actor, id string) func(s *Service) error {
+		return func(s *Service) error {
+			_, _, err := s.AddWidget(as(actor), "b1", AnyVersion, Widget{ID: id, Type: "text"})
+			return err
+		}
+	}
+	move := func(actor, id string, x float64) func(s *Service) error {
+		return func(s *Service) error {
+			_, _, err := s.UpdateWidget(as(actor), "b1", AnyVersion, Widget{ID: id, Type: "text", X: x})
+			return err
+		}
+	}
+	undo := func(actor string) func(s *Service) error {
+		return func(s *Service) error { _, err := s.Undo(as(actor), "b1"); return err }
+	}
+	redo := func(actor string) func(s *Service) error {
+		return func(s *Service) error { _, err := s.Redo(as(actor), "b1"); return err }
+	}
+	tests := []struct {
+		name  string
+		steps []func(s *Service) error
+		// last est l'opération testée ; want : les widgets ensuite (ID@x).
+		last    func(s *Service) error
+		want    string
+		wantErr string
+	}{
+		{"own write", []func(*Service) error{add("alice", "w1")}, undo("alice"), "", ""},
+		{"other user's write kept", []func(*Service) error{add("alice", "w1"), add("bob", "w2")}, undo("alice"), "w2@0", ""},
+		{"overwritten write skipped", []func(*Service) error{add("alice", "w1"), move("alice", "w1", 1), add("alice", "w2"), move("bob", "w2", 2)}, undo("alice"), "w1@0 w2@2", ""},
+		{"fully overwritten", []func(*Service) error{add("alice", "w1"), move("alice", "w1", 1), move("bob", "w1", 2)}, undo("alice"), "w1@2", "nothing to undo"},
+		{"redo", []func(*Service) error{add("alice", "w1"), move("alice", "w1", 1), undo("alice")}, redo("alice"), "w1@1", ""},
+		{"new write clears redo", []func(*Service) error{add("alice", "w1"), undo("alice"), add("alice", "w2")}, redo("alice"), "w2@0", "nothing to redo"},
+		{"someone else's history", []func(*Service) error{add("alice", "w1")}, undo("bob"), "w1@0", "nothing to undo"},
+		{"anonymous", []func(*Service) error{add("alice", "w1")}, undo(""), "w1@0", "needs an identified caller"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+			for i, step := range tt.steps {
+				if err := step(s); err != nil {
+					t.Fatalf("step %d: %v", i, err)
+				}
+			}
+			err := tt.last(s)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+			b, _ := s.GetBoard("b1")
+			var got []string
+			for _, w := range b.Widgets {
+				got = append(got, fmt.Sprintf("%s@%g", w.ID, w.X))
+			}
+			if strings.Join(got, " ") != tt.want {
+				t.Fatalf("widgets = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package board
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrUpstream enveloppe les échecs de l'upstream d'un board miroir (réseau,
+// serveur indisponible). Elle donne le code UNAVAILABLE.
+var ErrUpstream = errors.New("upstream unavailable")
+
+// conflictAttempts : nombre d'essais d'une écriture interne (LatestVersion)
+// quand la copie locale s'avère en retard sur l'upstream ou sur une autre
+// instance (voir SharedState). Les écritures des clients, AnyVersion
+// comprise, ne sont tentées qu'une fois : le conflit leur est renvoyé.
+const conflictAttempts = 5
+
+// Upstream est la source de vérité des boards miroirs (voir le package
+// mirror). Pour ces boards, le service n'est qu'un cache : les lectures
+// passent par Fetch quand la copie locale manque ou n'est plus fraîche, et
+// chaque écriture est envoyée à Save avant d'être appliquée localement.
+type Upstream interface {
+	// Mirrors indique si le board id vient de l'upstream.
+	Mirrors(id string) bool
+	// Fresh indique si la copie locale de id peut être servie sans relecture.
+	Fresh(id string) bool
+	// Fetch lit le board sur l'upstream.
+	Fetch(ctx context.Context, id string) (*Model, error)
+	// Save remplace le board sur l'upstream par next si sa version y est
+	// toujours version, et renvoie le board enregistré (avec la version de
+	// l'upstream). Un conflit est une *VersionConflictError.
+	Save(ctx context.Context, id string, version int, next *Model) (*Model, error)
+}
+
+// WithUpstream fait des boards désignés par u.Mirrors des miroirs de u.
+func WithUpstream(u Upstream) Option {
+	return func(s *Service) { s.upstream = u }
+}
+
+func (s *Service) mirrored(id string) bool {
+	return s.upstream != nil && s.upstream.Mirrors(id)
+}
+
+// resolveMirrored sert la copie locale si elle est fraîche, la relit sinon.
+// Si l'upstream ne répond pas, une copie périmée vaut mieux que rien.
+func (s *Service) resolveMirrored(ctx context.Context, id string) (*Model, error) {
+	local, ok := s.GetBoard(id)
+	if ok && s.upstream.Fresh(id) {
+		return local, nil
+	}
+	b, err := s.refreshMirror(ctx, id)
+	if err == nil {
+		return b, nil
+	}
+	if ok && errors.Is(err, ErrUpstream) {
+		s.logger.WarnContext(ctx, "board: upstream unreachable, serving stale mirror", "board", id, "version", local.Version, "err", err)
+		return local, nil
+	}
+	return nil, err
+}
+
+// refreshMirror relit id sur l'upstream et met la copie locale à jour.
+func (s *Service) refreshMirror(ctx context.Context, id string) (*Model, error) {
+	b, err := s.upstream.Fetch(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.ApplyUpstream(ctx, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// mutateMirrored applique fn à la copie locale et envoie le résultat à
+// l'upstream avec la version de départ. s.mu n'est pas tenu pendant l'appel
+// réseau ; upstreamMu sérialise les écritures miroirs de ce serveur, qui
+// sinon se mettraient mutuellement en conflit.
+func (s *Service) mutateMirrored(ctx context.Context, id string, ifVersion int, fn func(*Model) error) (*Model, error) {
+	s.upstreamMu.Lock()
+	defer s.upstreamMu.Unlock()
+	s.mu.RLock()
+	err := s.writableLocked()
+	s.mu.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+	for attempt := 1; ; attempt++ {
+		current, err := s.resolveMirrored(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if !unconditional(ifVersion) && ifVersion != current.Version {
+			return nil, &VersionConflictError{BoardID: id, CurrentVersion: current.Version, ProvidedVersion: ifVersion}
+		}
+		next := *current
+		next.Widgets = append(make([]Widget, 0, len(current.Widgets)+1), current.Widgets...)
+		if err := fn(&next); err != nil {
+			return nil, err
+		}
+		if next.Title != current.Title {
+			return nil, &ValidationError{Field: "title", Message: "cannot be changed on a mirrored board"}
+		}
+		saved, err := s.upstream.Save(ctx, id, current.Version, &next)
+		if err == nil {
+			if _, err := s.ApplyUpstream(ctx, saved); err != nil {
+				return nil, err
+			}
+			return saved, nil
+		}
+		if !errors.Is(err, ErrVersionConflict) {
+			return nil, err
+		}
+		// La copie locale était en retard : la relire pour que le prochain
+		// essai (ou le client, qui va recharger) parte de la bonne version.
+		if _, refreshErr := s.refreshMirror(ctx, id); refreshErr != nil {
+			s.logger.WarnContext(ctx, "board: mirror refresh after conflict failed", "board", id, "err", refreshErr)
+		}
+		if ifVersion != LatestVersion || attempt >= conflictAttempts {
+			return nil, err
+		}
+	}
+}
+
+// ApplyUpstream remplace la copie locale d'un board miroir par b si b est
+// plus récent, le persiste et le publie aux abonnés. applied est faux si la
+// copie locale était déjà à jour.
+func (s *Service) ApplyUpstream(ctx context.Context, b *Model) (applied bool, err error) {
+	ctx, span := startSpan(ctx, "board.ApplyUpstream", b.ID)
+	defer func() { endSpan(span, err) }()
+	if !s.mirrored(b.ID) {
+		return false, &ValidationError{Field: "boardId", Message: fmt.Sprintf("board %s is not mirrored", b.ID)}
+	}
+	stored, err := s.storeIfNewer(ctx, b, "mirror")
+	if stored != nil {
+		s.notify(stored)
+	}
+	return stored != nil, err
+}
+
+// storeIfNewer enregistre b à la place de la copie locale si sa version est
+// supérieure, et le persiste. Il renvoie la copie enregistrée, nil si la
+// copie locale était à jour ; la publication reste à l'appelant.
+func (s *Service) storeIfNewer(ctx context.Context, b *Model, op string) (*Model, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writableLocked(); err != nil {
+		return nil, err
+	}
+	return s.storeIfNewerLocked(ctx, b, op)
+}
+
+func (s *Service) storeIfNewerLocked(ctx context.Context, b *Model, op string) (*Model, error) {
+	previous, existed := s.boards[b.ID]
+	if existed && previous.Version >= b.Version {
+		return nil, nil
+	}
+	next := *b
+	next.Widgets = append([]Widget{}, b.Widgets...)
+	for i := range next.Widgets {
+		normalizeWidget(&next.Widgets[i])
+	}
+	s.boards[b.ID] = next
+	if err := s.saveToDisk(ctx, b.ID, op); err != nil {
+		if existed {
+			s.boards[b.ID] = previous
+		} else {
+			delete(s.boards, b.ID)
+		}
+		return nil, err
+	}
+	s.recordLocked(next, op)
+	return &next, nil
+}
@@ -0,0 +1,78 @@
+package board
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// fakeUpstream est un upstream en mémoire. Chacun des conflicts premiers
+// Save échoue en conflit, comme si un autre serveur venait d'écrire.
+type fakeUpstream struct {
+	mu        sync.Mutex
+	board     Model
+	conflicts int
+	saves     int
+}
+
+func (u *fakeUpstream) Mirrors(id string) bool { return id == u.board.ID }
+func (u *fakeUpstream) Fresh(string) bool      { return true }
+
+func (u *fakeUpstream) Fetch(_ context.Context, id string) (*Model, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	b := u.board
+	return &b, nil
+}
+
+func (u *fakeUpstream) Save(_ context.Context, id string, version int, next *Model) (*Model, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.saves++
+	if u.conflicts > 0 {
+		u.conflicts--
+		u.board.Version++
+	}
+	if version != u.board.Version {
+		return nil, &VersionConflictError{BoardID: id, CurrentVersion: u.board.Version, ProvidedVersion: version}
+	}
+	u.board = *next
+	u.board.Version = version + 1
+	b := u.board
+	return &b, nil
+}
+
+func TestMirroredConflictRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		ifVersion int
+		conflicts int
+		wantSaves int
+		wantErr   bool
+	}{
+		{"latest version retried", LatestVersion, 2, 3, false},
+		{"latest version gives up", LatestVersion, conflictAttempts, conflictAttempts, true},
+		{"any version not retried", AnyVersion, 1, 1, true},
+		{"explicit version not retried", 1, 1, 1, true},
+		{"no conflict", AnyVersion, 0, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := &fakeUpstream{board: Model{ID: "m1", Version: 1}, conflicts: tt.conflicts}
+			s := newTestService(t, WithUpstream(u))
+			_, _, err := s.AddWidget(context.Background(), "m1", tt.ifVersion, Widget{ID: "w1", Type: "text"})
+			if tt.wantErr != errors.Is(err, ErrVersionConflict) || (!tt.wantErr && err != nil) {
+				t.Fatalf("AddWidget err = %v, want conflict %v", err, tt.wantErr)
+			}
+			if u.saves != tt.wantSaves {
+				t.Fatalf("upstream saves = %d, want %d", u.saves, tt.wantSaves)
+			}
+			// Après un conflit, la copie locale a rattrapé l'upstream.
+			local, _ := s.GetBoard("m1")
+			if local.Version != u.board.Version {
+				t.Fatalf("local version = %d, upstream version = %d", local.Version, u.board.Version)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+// Package boardclienttest sert à tester le code qui utilise boardclient sans
+// serveur : les opérations du SDK sont exécutées en mémoire par le vrai
+// schéma GraphQL, sur un board.Service sans persistance. Les erreurs
+// (conflits de version, validation, NOT_FOUND) sont donc celles du serveur.
+package boardclienttest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/executor"
+
+	"miro-lite-standalone/backend/internal/board"
+	"miro-lite-standalone/backend/internal/boardclient"
+	"miro-lite-standalone/backend/internal/graph"
+	"miro-lite-standalone/backend/internal/remote"
+)
+
+// Fake est un boardclient.Client branché sur un Remote en mémoire.
+type Fake struct {
+	*boardclient.Client
+	*Remote
+}
+
+// New crée un faux serveur vide ; opts règle le service (par exemple
+// board.WithMissingBoardPolicy(board.MissingBoardStrict)).
+func New(opts ...board.Option) *Fake {
+	r := NewRemote(opts...)
+	return &Fake{Client: boardclient.New(r), Remote: r}
+}
+
+// Remote implémente remote.RemoteGraphQL. Service est exposé pour préparer
+// des données ou simuler l'écriture concurrente d'un autre client.
+type Remote struct {
+	Service *board.Service
+
+	exec *executor.Executor
+
+	mu         sync.Mutex
+	operations []string
+	failures   []error
+}
+
+var _ remote.RemoteGraphQL = (*Remote)(nil)
+
+func NewRemote(opts ...board.Option) *Remote {
+	svc, err := board.NewService("", opts...)
+	if err != nil {
+		// Sans fichier, rien n'est chargé : seule une option invalide
+		// peut échouer.
+		panic(fmt.Sprintf("boardclienttest: %v", err))
+	}
+	exec := executor.New(graph.NewExecutableSchema(graph.NewConfig(graph.NewResolver(svc, nil))))
+	exec.SetErrorPresenter(graph.ErrorPresenter)
+	return &Remote{Service: svc, exec: exec}
+}
+
+// FailNext fait échouer les prochaines opérations avec errs, une par
+// opération, sans les exécuter (panne réseau, serveur indisponible).
+func (r *Remote) FailNext(errs ...error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.failures = append(r.failures, errs...)
+}
+
+// Operations renvoie le nom des opérations reçues, dans l'ordre, y compris
+// celles qui ont échoué.
+func (r *Remote) Operations() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.operations...)
+}
+
+func (r *Remote) Do(ctx context.Context, query string, variables map[string]interface{}, resp interface{}) error {
+	// Aller-retour JSON : les variables arrivent comme sur le réseau, nombres
+	// compris (json.Number, comme le transport HTTP de gqlgen).
+	raw, err := json.Marshal(variables)
+	if err != nil {
+		return fmt.Errorf("boardclienttest: encode variables: %w", err)
+	}
+	params := &graphql.RawParams{Query: query}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&params.Variables); err != nil {
+		return fmt.Errorf("boardclienttest: decode variables: %w", err)
+	}
+
+	ctx = graphql.StartOperationTrace(ctx)
+	opCtx, errs := r.exec.CreateOperationContext(ctx, params)
+	name := "unknown"
+	if opCtx.Operation != nil {
+		name = opCtx.Operation.Name
+	}
+	if err := r.record(name); err != nil {
+		return err
+	}
+	var response *graphql.Response
+	if errs != nil {
+		response = r.exec.DispatchError(graphql.WithOperationContext(ctx, opCtx), errs)
+	} else {
+		handler, ctx := r.exec.DispatchOperation(ctx, opCtx)
+		response = handler(ctx)
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		return fmt.Errorf("boardclienttest: encode response: %w", err)
+	}
+	var out struct {
+		Data   json.RawMessage      `json:"data"`
+		Errors remote.GraphQLErrors `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return fmt.Errorf("boardclienttest: decode response: %w", err)
+	}
+	if len(out.Data) > 0 && string(out.Data) != "null" && resp != nil {
+		if err := json.Unmarshal(out.Data, resp); err != nil {
+			return fmt.Errorf("boardclienttest: decode data: %w", err)
+		}
+	}
+	if len(out.Errors) > 0 {
+		return out.Errors
+	}
+	return nil
+}
+
+func (r *Remote) record(operation string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.operations = append(r.operations, operation)
+	if len(r.failures) == 0 {
+		return nil
+	}
+	err := r.failures[0]
+	r.failures = r.failures[1:]
+	return err
+}
@@ -0,0 +1,215 @@
+// Package boardclient est un client typé de l'API GraphQL des boards,
+// construit sur remote.RemoteGraphQL. Les erreurs du serveur sont traduites
+// en erreurs du package board (*board.VersionConflictError,
+// *board.NotFoundError, *board.ValidationError) : errors.Is(err,
+// board.ErrVersionConflict) fonctionne comme côté serveur.
+package boardclient
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"miro-lite-standalone/backend/internal/board"
+	"miro-lite-standalone/backend/internal/remote"
+)
+
+// API est implémentée par Client ; les services qui scriptent des boards en
+// dépendent pour pouvoir être testés avec boardclienttest.
+type API interface {
+	GetBoard(ctx context.Context, id string) (*Board, error)
+	ListBoards(ctx context.Context) ([]*Board, error)
+	CreateBoard(ctx context.Context, title string) (*Board, error)
+	SaveBoard(ctx context.Context, boardID string, version int, widgets []*WidgetInput) (*Board, error)
+	AddStickyNote(ctx context.Context, boardID string, note AddStickyNoteInput) (*StickyNote, error)
+}
+
+type Client struct {
+	remote remote.RemoteGraphQL
+}
+
+var _ API = (*Client)(nil)
+
+func New(r remote.RemoteGraphQL) *Client {
+	return &Client{remote: r}
+}
+
+const boardFields = `
+	id
+	title
+	version
+	widgets {
+		id
+		type
+		x
+		y
+		width
+		height
+		configJson
+	}`
+
+const (
+	getBoardQuery = `query GetBoard($id: ID!) {
+  board(id: $id) {` + boardFields + `
+  }
+}`
+	// Sans les widgets : lister tous les boards avec leur contenu dépasse la
+	// limite de complexité du serveur.
+	listBoardsQuery = `query ListBoards {
+  boards {
+    id
+    title
+    version
+  }
+}`
+	createBoardMutation = `mutation CreateBoard($title: String!) {
+  createBoard(title: $title) {` + boardFields + `
+  }
+}`
+	saveBoardMutation = `mutation SaveBoard($boardId: ID!, $version: Int!, $widgets: [WidgetInput!]!) {
+  saveBoard(boardId: $boardId, version: $version, widgets: $widgets) {` + boardFields + `
+  }
+}`
+	// BoardUpdatedSubscription suit un board avec remote.SubscriptionClient
+	// (variables boardId et, facultative, sinceVersion : la version déjà
+	// connue, après laquelle l'upstream rejoue les écritures manquées) ;
+	// chaque événement se décode dans BoardUpdatedEvent.
+	BoardUpdatedSubscription = `subscription BoardUpdated($boardId: ID!, $sinceVersion: Int) {
+  boardUpdated(boardId: $boardId, sinceVersion: $sinceVersion) {` + boardFields + `
+  }
+}`
+	addStickyNoteMutation = `mutation AddStickyNote($boardId: ID!, $item: AddStickyNoteInput!) {
+  addStickyNote(boardId: $boardId, item: $item) {
+    id
+    x
+    y
+    width
+    height
+    rotation
+    zIndex
+    text
+    color
+  }
+}`
+)
+
+// OperationNames renvoie le nom des opérations envoyées par le client (le
+// frontend utilise les mêmes), que le serveur reprend dans ses métriques.
+func OperationNames() []string {
+	var names []string
+	for _, query := range []string{getBoardQuery, listBoardsQuery, createBoardMutation, saveBoardMutation, BoardUpdatedSubscription, addStickyNoteMutation} {
+		// "query GetBoard(...) {" : le nom suit le type d'opération.
+		fields := strings.FieldsFunc(query, func(r rune) bool { return r == ' ' || r == '(' || r == '{' })
+		names = append(names, fields[1])
+	}
+	return names
+}
+
+// BoardUpdatedEvent est la donnée d'un événement de
+// BoardUpdatedSubscription : l'état complet du board après l'écriture.
+type BoardUpdatedEvent struct {
+	BoardUpdated *Board `json:"boardUpdated"`
+}
+
+// GetBoard renvoie une erreur *board.NotFoundError si le board n'existe pas
+// (serveur en politique strict ; sinon il est créé à la volée).
+func (c *Client) GetBoard(ctx context.Context, id string) (*Board, error) {
+	var out struct {
+		Board *Board `json:"board"`
+	}
+	if err := c.do(ctx, getBoardQuery, map[string]interface{}{"id": id}, &out); err != nil {
+		return nil, err
+	}
+	if out.Board == nil {
+		return nil, &board.NotFoundError{BoardID: id}
+	}
+	return out.Board, nil
+}
+
+// ListBoards renvoie les boards sans leurs widgets (Widgets est nil) :
+// GetBoard charge le contenu d'un board.
+func (c *Client) ListBoards(ctx context.Context) ([]*Board, error) {
+	var out struct {
+		Boards []*Board `json:"boards"`
+	}
+	if err := c.do(ctx, listBoardsQuery, nil, &out); err != nil {
+		return nil, err
+	}
+	return out.Boards, nil
+}
+
+func (c *Client) CreateBoard(ctx context.Context, title string) (*Board, error) {
+	var out struct {
+		CreateBoard *Board `json:"createBoard"`
+	}
+	if err := c.do(ctx, createBoardMutation, map[string]interface{}{"title": title}, &out); err != nil {
+		return nil, err
+	}
+	return out.CreateBoard, nil
+}
+
+// SaveBoard remplace les widgets si version est la version courante, et
+// renvoie sinon une *board.VersionConflictError (voir UpdateBoard).
+func (c *Client) SaveBoard(ctx context.Context, boardID string, version int, widgets []*WidgetInput) (*Board, error) {
+	if widgets == nil {
+		widgets = []*WidgetInput{}
+	}
+	var out struct {
+		SaveBoard *Board `json:"saveBoard"`
+	}
+	vars := map[string]interface{}{"boardId": boardID, "version": version, "widgets": widgets}
+	if err := c.do(ctx, saveBoardMutation, vars, &out); err != nil {
+		return nil, err
+	}
+	return out.SaveBoard, nil
+}
+
+func (c *Client) AddStickyNote(ctx context.Context, boardID string, note AddStickyNoteInput) (*StickyNote, error) {
+	var out struct {
+		AddStickyNote *StickyNote `json:"addStickyNote"`
+	}
+	vars := map[string]interface{}{"boardId": boardID, "item": note}
+	if err := c.do(ctx, addStickyNoteMutation, vars, &out); err != nil {
+		return nil, err
+	}
+	return out.AddStickyNote, nil
+}
+
+func (c *Client) do(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	return translateError(c.remote.Do(ctx, query, variables, out))
+}
+
+// translateError remplace une erreur GraphQL portant un code du domaine par
+// l'erreur board correspondante ; les autres sont renvoyées telles quelles.
+func translateError(err error) error {
+	var gqlErr *remote.GraphQLError
+	if !errors.As(err, &gqlErr) {
+		return err
+	}
+	ext := gqlErr.Extensions
+	switch gqlErr.Code() {
+	case board.CodeVersionConflict:
+		return &board.VersionConflictError{
+			BoardID:         stringExt(ext, "boardId"),
+			CurrentVersion:  intExt(ext, "currentVersion"),
+			ProvidedVersion: intExt(ext, "providedVersion"),
+		}
+	case board.CodeNotFound:
+		return &board.NotFoundError{BoardID: stringExt(ext, "boardId"), WidgetID: stringExt(ext, "widgetId")}
+	case board.CodeValidation:
+		field := stringExt(ext, "field")
+		return &board.ValidationError{Field: field, Message: strings.TrimPrefix(gqlErr.Message, field+": ")}
+	}
+	return err
+}
+
+func stringExt(ext map[string]interface{}, key string) string {
+	s, _ := ext[key].(string)
+	return s
+}
+
+// intExt : les nombres JSON sont décodés en float64.
+func intExt(ext map[string]interface{}, key string) int {
+	f, _ := ext[key].(float64)
+	return int(f)
+}
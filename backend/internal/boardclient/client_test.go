@@ -0,0 +1,13 @@
+package boardclient
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOperationNames(t *testing.T) {
+	want := "GetBoard ListBoards CreateBoard SaveBoard BoardUpdated AddStickyNote"
+	if got := strings.Join(OperationNames(), " "); got != want {
+		t.Fatalf("OperationNames() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,56 @@
+package boardclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"miro-lite-standalone/backend/internal/board"
+)
+
+// DefaultConflictAttempts : nombre de tentatives de RetryOnConflict et
+// UpdateBoard.
+const DefaultConflictAttempts = 5
+
+// RetryOnConflict appelle fn jusqu'à ce qu'elle ne renvoie plus de conflit
+// de version, au plus attempts fois (DefaultConflictAttempts si attempts <=
+// 0). fn doit relire l'état courant à chaque appel. Après la dernière
+// tentative, le conflit est renvoyé.
+func RetryOnConflict(ctx context.Context, attempts int, fn func(ctx context.Context) error) error {
+	if attempts <= 0 {
+		attempts = DefaultConflictAttempts
+	}
+	var err error
+	for range attempts {
+		if err = fn(ctx); !errors.Is(err, board.ErrVersionConflict) {
+			return err
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+	}
+	return fmt.Errorf("boardclient: still conflicting after %d attempts: %w", attempts, err)
+}
+
+// UpdateBoard lit le board, laisse update modifier ses widgets puis
+// sauvegarde avec la version lue. Si un autre client a écrit entre-temps,
+// le board est relu et update rejouée. update ne doit donc dépendre que du
+// board reçu.
+func UpdateBoard(ctx context.Context, api API, boardID string, update func(b *Board) error) (*Board, error) {
+	var saved *Board
+	err := RetryOnConflict(ctx, DefaultConflictAttempts, func(ctx context.Context) error {
+		b, err := api.GetBoard(ctx, boardID)
+		if err != nil {
+			return err
+		}
+		if err := update(b); err != nil {
+			return err
+		}
+		saved, err = api.SaveBoard(ctx, boardID, b.Version, WidgetInputs(b.Widgets))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return saved, nil
+}
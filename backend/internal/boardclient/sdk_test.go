@@ -0,0 +1,221 @@
+package boardclient_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"miro-lite-standalone/backend/internal/board"
+	"miro-lite-standalone/backend/internal/boardclient"
+	"miro-lite-standalone/backend/internal/boardclient/boardclienttest"
+)
+
+func TestClientRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	fake := boardclienttest.New()
+	created, err := fake.CreateBoard(ctx, "Plan")
+	if err != nil {
+		t.Fatalf("CreateBoard: %v", err)
+	}
+	note, err := boardclient.NewWidgetInput("w1", "sticky", 10, 20, 100, 80, map[string]interface{}{"text": "hello"})
+	if err != nil {
+		t.Fatalf("NewWidgetInput: %v", err)
+	}
+	saved, err := fake.SaveBoard(ctx, created.ID, created.Version, []*boardclient.WidgetInput{note})
+	if err != nil {
+		t.Fatalf("SaveBoard: %v", err)
+	}
+	if saved.Version != created.Version+1 || len(saved.Widgets) != 1 {
+		t.Fatalf("saved = %+v", saved)
+	}
+	got, err := fake.GetBoard(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetBoard: %v", err)
+	}
+	if w := got.Widgets[0]; w.ID != "w1" || w.X != 10 || w.ConfigJSON != `{"text":"hello"}` {
+		t.Errorf("widget = %+v", w)
+	}
+	list, err := fake.ListBoards(ctx)
+	if err != nil {
+		t.Fatalf("ListBoards: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != created.ID || list[0].Widgets != nil {
+		t.Errorf("ListBoards = %+v, want one board without widgets", list)
+	}
+	want := []string{"CreateBoard", "SaveBoard", "GetBoard", "ListBoards"}
+	if ops := fake.Operations(); !slices.Equal(ops, want) {
+		t.Errorf("operations = %v, want %v", ops, want)
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		call  func(api boardclient.API) error
+		check func(t *testing.T, err error)
+	}{
+		{"missing board", func(api boardclient.API) error {
+			_, err := api.GetBoard(ctx, "nope")
+			return err
+		}, func(t *testing.T, err error) {
+			var nf *board.NotFoundError
+			if !errors.As(err, &nf) || nf.BoardID != "nope" {
+				t.Errorf("err = %v, want NotFoundError for nope", err)
+			}
+		}},
+		{"stale version", func(api boardclient.API) error {
+			_, err := api.SaveBoard(ctx, "b1", 7, nil)
+			return err
+		}, func(t *testing.T, err error) {
+			var conflict *board.VersionConflictError
+			if !errors.As(err, &conflict) || conflict.BoardID != "b1" || conflict.CurrentVersion != 1 || conflict.ProvidedVersion != 7 {
+				t.Errorf("err = %v, want a conflict 7 vs 1 on b1", err)
+			}
+		}},
+		{"invalid widget", func(api boardclient.API) error {
+			_, err := api.SaveBoard(ctx, "b1", 1, []*boardclient.WidgetInput{{ID: "", Type: "sticky", Width: 1, Height: 1, ConfigJSON: "{}"}})
+			return err
+		}, func(t *testing.T, err error) {
+			var invalid *board.ValidationError
+			if !errors.As(err, &invalid) || invalid.Field != "widgets[0].id" {
+				t.Errorf("err = %v, want a validation error on widgets[0].id", err)
+			}
+		}},
+		{"note on a missing board", func(api boardclient.API) error {
+			_, err := api.AddStickyNote(ctx, "nope", boardclient.AddStickyNoteInput{Text: "hi"})
+			return err
+		}, func(t *testing.T, err error) {
+			if !errors.Is(err, board.ErrNotFound) {
+				t.Errorf("err = %v, want ErrNotFound", err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := boardclienttest.New(board.WithMissingBoardPolicy(board.MissingBoardStrict))
+			if _, err := fake.Service.CreateBoard(ctx, "b1", "t"); err != nil {
+				t.Fatalf("CreateBoard: %v", err)
+			}
+			tt.check(t, tt.call(fake))
+		})
+	}
+}
+
+func TestFailNext(t *testing.T) {
+	fake := boardclienttest.New()
+	down := errors.New("connection refused")
+	fake.FailNext(down)
+	if _, err := fake.ListBoards(context.Background()); !errors.Is(err, down) {
+		t.Fatalf("err = %v, want %v", err, down)
+	}
+	if _, err := fake.ListBoards(context.Background()); err != nil {
+		t.Fatalf("second call: %v", err)
+	}
+}
+
+func TestUpdateBoard(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name          string
+		concurrent    int
+		wantErr       error
+		wantSaves     int
+		wantWidgetIDs []string
+	}{
+		{"no contention", 0, nil, 1, []string{"mine"}},
+		{"one concurrent write", 1, nil, 2, []string{"theirs-1", "mine"}},
+		{"always losing", boardclient.DefaultConflictAttempts, board.ErrVersionConflict, boardclient.DefaultConflictAttempts, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := boardclienttest.New()
+			if _, err := fake.Service.CreateBoard(ctx, "b1", "t"); err != nil {
+				t.Fatal(err)
+			}
+			calls := 0
+			saved, err := boardclient.UpdateBoard(ctx, fake, "b1", func(b *boardclient.Board) error {
+				calls++
+				// Un autre client écrit entre la lecture et la sauvegarde.
+				if calls <= tt.concurrent {
+					current, _ := fake.Service.GetBoard("b1")
+					widgets := append(current.Widgets, board.Widget{ID: "theirs-" + string(rune('0'+calls)), Type: "sticky", Width: 1, Height: 1})
+					if _, err := fake.Service.SaveBoard(ctx, "b1", current.Version, widgets); err != nil {
+						t.Fatal(err)
+					}
+				}
+				b.Widgets = append(b.Widgets, &boardclient.WidgetPayload{ID: "mine", Type: "sticky", Width: 1, Height: 1, ConfigJSON: "{}"})
+				return nil
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			saves := 0
+			for _, op := range fake.Operations() {
+				if op == "SaveBoard" {
+					saves++
+				}
+			}
+			if saves != tt.wantSaves {
+				t.Errorf("SaveBoard calls = %d, want %d", saves, tt.wantSaves)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			var ids []string
+			for _, w := range saved.Widgets {
+				ids = append(ids, w.ID)
+			}
+			if !slices.Equal(ids, tt.wantWidgetIDs) {
+				t.Errorf("widgets = %v, want %v", ids, tt.wantWidgetIDs)
+			}
+		})
+	}
+}
+
+func TestRetryOnConflict(t *testing.T) {
+	conflict := &board.VersionConflictError{BoardID: "b1", CurrentVersion: 2, ProvidedVersion: 1}
+	other := errors.New("boom")
+	tests := []struct {
+		name      string
+		attempts  int
+		results   []error
+		wantCalls int
+		wantErr   error
+	}{
+		{"success", 3, []error{nil}, 1, nil},
+		{"conflict then success", 3, []error{conflict, nil}, 2, nil},
+		{"other error stops", 3, []error{other, nil}, 1, other},
+		{"attempts exhausted", 2, []error{conflict, conflict, nil}, 2, board.ErrVersionConflict},
+		{"default attempts", 0, slices.Repeat([]error{conflict}, 10), boardclient.DefaultConflictAttempts, board.ErrVersionConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := boardclient.RetryOnConflict(context.Background(), tt.attempts, func(context.Context) error {
+				calls++
+				return tt.results[calls-1]
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetryOnConflictCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := boardclient.RetryOnConflict(ctx, 5, func(context.Context) error {
+		calls++
+		cancel()
+		return &board.VersionConflictError{BoardID: "b1"}
+	})
+	if calls != 1 || !errors.Is(err, board.ErrVersionConflict) {
+		t.Fatalf("calls = %d, err = %v; want one call returning the conflict", calls, err)
+	}
+}
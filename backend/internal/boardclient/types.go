@@ -0,0 +1,48 @@
+package boardclient
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"miro-lite-standalone/backend/internal/graph/model"
+)
+
+// Les types de requête et de réponse sont ceux que gqlgen génère à partir
+// de schema.graphqls (go run github.com/99designs/gqlgen generate) : le SDK
+// suit le schéma sans copie à maintenir.
+type (
+	Board              = model.Board
+	WidgetPayload      = model.WidgetPayload
+	WidgetInput        = model.WidgetInput
+	StickyNote         = model.StickyNote
+	AddStickyNoteInput = model.AddStickyNoteInput
+)
+
+// WidgetInputs convertit les widgets lus en entrées de SaveBoard.
+func WidgetInputs(widgets []*WidgetPayload) []*WidgetInput {
+	inputs := make([]*WidgetInput, len(widgets))
+	for i, w := range widgets {
+		inputs[i] = &WidgetInput{
+			ID:         w.ID,
+			Type:       w.Type,
+			X:          w.X,
+			Y:          w.Y,
+			Width:      w.Width,
+			Height:     w.Height,
+			ConfigJSON: w.ConfigJSON,
+		}
+	}
+	return inputs
+}
+
+// NewWidgetInput sérialise config dans ConfigJSON.
+func NewWidgetInput(id, widgetType string, x, y, width, height float64, config map[string]interface{}) (*WidgetInput, error) {
+	if config == nil {
+		config = map[string]interface{}{}
+	}
+	raw, err := json.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("boardclient: encode widget %s config: %w", id, err)
+	}
+	return &WidgetInput{ID: id, Type: widgetType, X: x, Y: y, Width: width, Height: height, ConfigJSON: string(raw)}, nil
+}
@@ -0,0 +1,714 @@
+// Package config charge la configuration du serveur. Ordre de priorité :
+// valeurs par défaut < fichier YAML (--config / CONFIG_FILE) < variables
+// d'environnement < flags.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"miro-lite-standalone/backend/internal/board"
+	"miro-lite-standalone/backend/internal/logging"
+	"miro-lite-standalone/backend/internal/mirror"
+	"miro-lite-standalone/backend/internal/pubsub"
+	"miro-lite-standalone/backend/internal/tracing"
+)
+
+const (
+	StorageFile   = "file"
+	StorageMemory = "memory"
+
+	PubSubMemory = "memory"
+	PubSubRedis  = "redis"
+
+	// storeFileName est le nom du fichier de persistance dans DataDir.
+	storeFileName = "boards.json"
+)
+
+type Config struct {
+	Addr string `yaml:"addr"`
+	// ShutdownTimeout borne l'arrêt gracieux : au-delà, les connexions
+	// restantes sont coupées.
+	ShutdownTimeout time.Duration   `yaml:"shutdownTimeout"`
+	TLS             TLSConfig       `yaml:"tls"`
+	HTTP2           HTTP2Config     `yaml:"http2"`
+	Storage         StorageConfig   `yaml:"storage"`
+	Boards          BoardsConfig    `yaml:"boards"`
+	CORS            CORSConfig      `yaml:"cors"`
+	Websocket       WebsocketConfig `yaml:"websocket"`
+	Limits          LimitsConfig    `yaml:"limits"`
+	GraphQL         GraphQLConfig   `yaml:"graphql"`
+	Mirror          MirrorConfig    `yaml:"mirror"`
+	PubSub          PubSubConfig    `yaml:"pubsub"`
+	Features        FeaturesConfig  `yaml:"features"`
+	Log             LogConfig       `yaml:"log"`
+	Tracing         TracingConfig   `yaml:"tracing"`
+
+	// PrintConfig affiche la configuration effective puis quitte.
+	PrintConfig bool `yaml:"-"`
+}
+
+// TLSConfig active TLS avec une paire de fichiers (rechargée quand elle
+// change) ou un certificat auto-signé généré au démarrage.
+type TLSConfig struct {
+	CertFile   string `yaml:"certFile"`
+	KeyFile    string `yaml:"keyFile"`
+	SelfSigned bool   `yaml:"selfSigned"`
+}
+
+func (t TLSConfig) Enabled() bool {
+	return t.SelfSigned || t.CertFile != "" || t.KeyFile != ""
+}
+
+type HTTP2Config struct {
+	// H2C sert HTTP/2 en clair (prior knowledge), derrière un proxy qui
+	// termine TLS. Avec TLS, HTTP/2 est négocié automatiquement via ALPN.
+	H2C bool `yaml:"h2c"`
+}
+
+type StorageConfig struct {
+	// Backend vaut "file" (DataDir/boards.json) ou "memory" (rien n'est persisté).
+	Backend string `yaml:"backend"`
+	// DataDir n'a pas de valeur par défaut : un chemin implicite dépendrait
+	// du répertoire de lancement. Il est rendu absolu au chargement, relatif
+	// au fichier de config s'il vient de là, sinon au répertoire courant.
+	DataDir string `yaml:"dataDir"`
+	// OnCorrupt vaut fail, read-only ou recover : comportement au démarrage
+	// quand boards.json ne se décode pas.
+	OnCorrupt board.CorruptStorePolicy `yaml:"onCorrupt"`
+}
+
+type BoardsConfig struct {
+	MissingPolicy board.MissingBoardPolicy `yaml:"missingPolicy"`
+}
+
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowedOrigins"`
+	AllowedHeaders []string `yaml:"allowedHeaders"`
+}
+
+type WebsocketConfig struct {
+	PingInterval time.Duration `yaml:"pingInterval"`
+}
+
+type LimitsConfig struct {
+	// CompressMinSize : taille minimale d'une réponse avant compression.
+	CompressMinSize int `yaml:"compressMinSize"`
+	// RequestsPerSecond et Burst dimensionnent le seau à jetons de chaque
+	// client (utilisateur authentifié, sinon IP). 0 désactive la limite.
+	RequestsPerSecond float64 `yaml:"requestsPerSecond"`
+	Burst             int     `yaml:"burst"`
+	// TrustedProxies : nombre de reverse proxies devant le serveur, qui
+	// ajoutent chacun une entrée à X-Forwarded-For. Le client est lu à cette
+	// position en partant de la droite ; 0 ignore l'en-tête.
+	TrustedProxies int `yaml:"trustedProxies"`
+	// MaxBodyBytes borne le corps des requêtes, MaxUploadBytes celui des
+	// envois multipart. 0 : pas de limite.
+	MaxBodyBytes   int64 `yaml:"maxBodyBytes"`
+	MaxUploadBytes int64 `yaml:"maxUploadBytes"`
+	// MaxWidgetsPerBoard : 0, pas de limite.
+	MaxWidgetsPerBoard int `yaml:"maxWidgetsPerBoard"`
+	// MaxSubscriptionsPerConnection borne les subscriptions d'une websocket.
+	MaxSubscriptionsPerConnection int `yaml:"maxSubscriptionsPerConnection"`
+}
+
+type GraphQLConfig struct {
+	// MaxComplexity et MaxDepth bornent le coût estimé et l'imbrication
+	// d'une opération. 0 désactive la limite.
+	MaxComplexity int `yaml:"maxComplexity"`
+	MaxDepth      int `yaml:"maxDepth"`
+	// QueryCacheSize : requêtes parsées et validées gardées en cache (LRU).
+	QueryCacheSize int `yaml:"queryCacheSize"`
+	// APQCacheSize : persisted queries automatiques retenues (LRU). 0
+	// désactive l'APQ.
+	APQCacheSize int `yaml:"apqCacheSize"`
+	// PersistedQueries : manifeste des opérations connues (format Apollo ou
+	// objet {sha256: requête}), servies à partir de leur hash.
+	PersistedQueries string `yaml:"persistedQueries"`
+	// Allowlist refuse toute opération absente de PersistedQueries.
+	Allowlist bool `yaml:"allowlist"`
+}
+
+// MirrorConfig fait de ce serveur le miroir de boards d'une autre instance
+// (voir le package mirror). Sans Upstream, tous les boards sont locaux.
+type MirrorConfig struct {
+	// Upstream : URL GraphQL de l'instance source (https://host/graphql).
+	Upstream string `yaml:"upstream"`
+	// Boards : IDs des boards mirrorés, ou préfixes terminés par * ("*" :
+	// tous).
+	Boards []string `yaml:"boards"`
+	// Mode vaut proxy (copie relue après CacheTTL) ou replicate (copie
+	// tenue à jour par la subscription de l'upstream).
+	Mode     mirror.Mode   `yaml:"mode"`
+	CacheTTL time.Duration `yaml:"cacheTTL"`
+	// Token est envoyé en Authorization: Bearer à l'upstream. Pas de flag :
+	// il apparaîtrait dans la liste des processus.
+	Token string `yaml:"token"`
+}
+
+func (m MirrorConfig) Enabled() bool {
+	return m.Upstream != ""
+}
+
+// PubSubConfig : avec plusieurs réplicas, backend redis relaie les mises à
+// jour de boards entre instances (subscriptions et copies en mémoire).
+type PubSubConfig struct {
+	// Backend vaut memory (instance seule) ou redis.
+	Backend string `yaml:"backend"`
+	// RedisURL : redis://[:password@]host:port/db, rediss:// pour TLS.
+	RedisURL string `yaml:"redisURL"`
+	// Channel est partagé par les instances d'un même déploiement.
+	Channel string `yaml:"channel"`
+	// ReplayBuffer : mises à jour gardées par board pour les reprises d'un
+	// abonné (sinceVersion), 0 pour n'en garder aucune.
+	ReplayBuffer int `yaml:"replayBuffer"`
+	// Overflow vaut coalesce (l'abonné lent reçoit le dernier état) ou close
+	// (sa subscription est fermée avec RESYNC_REQUIRED).
+	Overflow pubsub.Overflow `yaml:"overflow"`
+}
+
+type FeaturesConfig struct {
+	Playground  bool `yaml:"playground"`
+	REST        bool `yaml:"rest"`
+	Compression bool `yaml:"compression"`
+	Metrics     bool `yaml:"metrics"`
+	// UserIDHeader prend X-User-ID pour l'utilisateur authentifié (seau de
+	// limitation, historique d'annulation). Sans proxy d'authentification
+	// qui le fixe lui-même, l'en-tête est choisi par le client : à laisser
+	// désactivé.
+	UserIDHeader bool `yaml:"userIdHeader"`
+}
+
+type LogConfig struct {
+	// Level vaut debug, info, warn ou error.
+	Level string `yaml:"level"`
+	// Format vaut text ou json.
+	Format string `yaml:"format"`
+	// AccessLog écrit une ligne par requête HTTP, au niveau info.
+	AccessLog bool `yaml:"accessLog"`
+}
+
+type TracingConfig struct {
+	// Exporter vaut none, stdout ou otlp.
+	Exporter string `yaml:"exporter"`
+	// Endpoint : URL OTLP/HTTP du collecteur. Vide, les variables
+	// OTEL_EXPORTER_OTLP_* standard s'appliquent.
+	Endpoint    string  `yaml:"endpoint"`
+	SampleRatio float64 `yaml:"sampleRatio"`
+	ServiceName string  `yaml:"serviceName"`
+}
+
+func Default() Config {
+	return Config{
+		Addr:            ":8091",
+		ShutdownTimeout: 15 * time.Second,
+		Storage: StorageConfig{
+			Backend:   StorageFile,
+			OnCorrupt: board.CorruptStoreFail,
+		},
+		Boards: BoardsConfig{MissingPolicy: board.MissingBoardAutoCreate},
+		CORS: CORSConfig{
+			// :8091 pour que le Playground fonctionne sans config.
+			AllowedOrigins: []string{"http://localhost:4200", "http://localhost:4201", "http://localhost:8091"},
+			AllowedHeaders: []string{
+				"Content-Type", "Authorization", "Apollo-Require-Preflight", "X-Requested-With",
+				"Accept", "Origin", "If-Match", "If-None-Match", "X-Request-ID", "X-User-ID",
+			},
+		},
+		Websocket: WebsocketConfig{PingInterval: 15 * time.Second},
+		Limits: LimitsConfig{
+			CompressMinSize:   1024,
+			RequestsPerSecond: 20,
+			Burst:             40,
+			// Les boards avec images embarquées pèsent plusieurs Mo.
+			MaxBodyBytes:                  16 << 20,
+			MaxUploadBytes:                10 << 20,
+			MaxWidgetsPerBoard:            5000,
+			MaxSubscriptionsPerConnection: 20,
+		},
+		GraphQL: GraphQLConfig{
+			MaxComplexity:  1000,
+			MaxDepth:       10,
+			QueryCacheSize: 1000,
+			APQCacheSize:   1000,
+		},
+		Mirror:   MirrorConfig{Mode: mirror.ModeProxy, CacheTTL: mirror.DefaultCacheTTL},
+		PubSub:   PubSubConfig{Backend: PubSubMemory, Channel: pubsub.DefaultChannel, ReplayBuffer: pubsub.DefaultReplay, Overflow: pubsub.OverflowCoalesce},
+		Features: FeaturesConfig{Playground: true, REST: true, Compression: true, Metrics: true},
+		Log:      LogConfig{Level: "info", Format: "text", AccessLog: true},
+		Tracing:  TracingConfig{Exporter: tracing.ExporterNone, SampleRatio: 1, ServiceName: "miro-lite-backend"},
+	}
+}
+
+// StorePath renvoie le chemin du fichier de persistance, vide pour le backend
+// mémoire (board.Service ne persiste alors rien).
+func (c Config) StorePath() string {
+	if c.Storage.Backend == StorageMemory {
+		return ""
+	}
+	return filepath.Join(c.Storage.DataDir, storeFileName)
+}
+
+// Load construit la configuration à partir des arguments (sans le nom du
+// programme) et de l'environnement.
+func Load(args []string, getenv func(string) string) (Config, error) {
+	cfg := Default()
+
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	configFile := fs.String("config", getenv("CONFIG_FILE"), "YAML config file")
+	addr := fs.String("addr", "", "listen address (env ADDR)")
+	shutdownTimeout := fs.Duration("shutdown-timeout", 0, "graceful shutdown deadline (env SHUTDOWN_TIMEOUT)")
+	tlsCert := fs.String("tls-cert", "", "TLS certificate file (env TLS_CERT_FILE)")
+	tlsKey := fs.String("tls-key", "", "TLS private key file (env TLS_KEY_FILE)")
+	tlsSelfSigned := fs.String("tls-self-signed", "", "serve TLS with a generated dev certificate: true/false (env TLS_SELF_SIGNED)")
+	h2c := fs.String("h2c", "", "serve cleartext HTTP/2: true/false (env H2C)")
+	dataDir := fs.String("data-dir", "", "directory holding boards.json (env DATA_DIR)")
+	backend := fs.String("storage", "", "storage backend: file or memory (env STORAGE_BACKEND)")
+	onCorrupt := fs.String("on-corrupt-store", "", "fail, read-only or recover when boards.json is corrupt (env STORE_ON_CORRUPT)")
+	recoverStore := fs.Bool("recover", false, "salvage readable boards from a corrupt boards.json (same as --on-corrupt-store=recover)")
+	missingPolicy := fs.String("missing-board-policy", "", "strict or auto-create (env MISSING_BOARD_POLICY)")
+	origins := fs.String("allowed-origins", "", "comma-separated CORS origins (env ALLOWED_ORIGINS)")
+	headers := fs.String("allowed-headers", "", "comma-separated CORS headers (env ALLOWED_HEADERS)")
+	ping := fs.Duration("ws-ping-interval", 0, "websocket keep-alive ping interval (env WS_PING_INTERVAL)")
+	compressMin := fs.Int("compress-min-size", -1, "minimum response size to compress (env COMPRESS_MIN_SIZE)")
+	rateLimit := fs.Float64("rate-limit", -1, "requests per second per client, 0 disables (env RATE_LIMIT_RPS)")
+	rateBurst := fs.Int("rate-burst", -1, "token bucket size per client (env RATE_LIMIT_BURST)")
+	trustedProxies := fs.Int("trusted-proxies", -1, "reverse proxies in front of the server appending to X-Forwarded-For, 0 ignores it (env TRUSTED_PROXIES)")
+	maxBody := fs.Int64("max-body-bytes", -1, "maximum request body size, 0 disables (env MAX_BODY_BYTES)")
+	maxUpload := fs.Int64("max-upload-bytes", -1, "maximum multipart upload size, 0 disables (env MAX_UPLOAD_BYTES)")
+	maxWidgets := fs.Int("max-widgets", -1, "maximum widgets per board, 0 disables (env MAX_WIDGETS_PER_BOARD)")
+	maxSubs := fs.Int("max-ws-subscriptions", -1, "maximum subscriptions per websocket, 0 disables (env MAX_WS_SUBSCRIPTIONS)")
+	maxComplexity := fs.Int("max-complexity", -1, "maximum GraphQL operation complexity, 0 disables (env GRAPHQL_MAX_COMPLEXITY)")
+	maxDepth := fs.Int("max-depth", -1, "maximum GraphQL selection depth, 0 disables (env GRAPHQL_MAX_DEPTH)")
+	apqCache := fs.Int("apq-cache-size", -1, "automatic persisted queries kept, 0 disables APQ (env GRAPHQL_APQ_CACHE_SIZE)")
+	persisted := fs.String("persisted-queries", "", "persisted query manifest (env GRAPHQL_PERSISTED_QUERIES)")
+	allowlist := fs.String("allowlist", "", "only accept operations from the persisted query manifest: true/false (env GRAPHQL_ALLOWLIST)")
+	mirrorUpstream := fs.String("mirror-upstream", "", "GraphQL URL of the instance to mirror boards from (env MIRROR_UPSTREAM)")
+	mirrorBoards := fs.String("mirror-boards", "", "comma-separated mirrored board IDs or prefixes ending in * (env MIRROR_BOARDS)")
+	mirrorMode := fs.String("mirror-mode", "", "proxy or replicate (env MIRROR_MODE)")
+	mirrorTTL := fs.Duration("mirror-cache-ttl", -1, "how long a proxied board is served before being refetched (env MIRROR_CACHE_TTL)")
+	pubsubBackend := fs.String("pubsub", "", "subscription fan-out: memory or redis (env PUBSUB_BACKEND)")
+	redisURL := fs.String("redis-url", "", "Redis URL for the redis pub/sub backend (env REDIS_URL)")
+	replayBuffer := fs.Int("subscription-replay", -1, "updates kept per board for subscription resumes, 0 disables (env PUBSUB_REPLAY_BUFFER)")
+	overflow := fs.String("subscription-overflow", "", "slow subscribers: coalesce or close (env PUBSUB_OVERFLOW)")
+	playground := fs.String("playground", "", "serve the GraphQL playground: true/false (env ENABLE_PLAYGROUND)")
+	restAPI := fs.String("rest", "", "serve the REST API: true/false (env ENABLE_REST)")
+	userIDHeader := fs.String("user-id-header", "", "trust X-User-ID as the user authenticated by a proxy: true/false (env TRUST_USER_ID_HEADER)")
+	compression := fs.String("compression", "", "compress responses: true/false (env ENABLE_COMPRESSION)")
+	metricsFlag := fs.String("metrics", "", "serve Prometheus metrics on /metrics: true/false (env ENABLE_METRICS)")
+	logLevel := fs.String("log-level", "", "debug, info, warn or error (env LOG_LEVEL)")
+	logFormat := fs.String("log-format", "", "text or json (env LOG_FORMAT)")
+	accessLog := fs.String("access-log", "", "log every HTTP request: true/false (env ACCESS_LOG)")
+	traceExporter := fs.String("tracing", "", "trace exporter: none, stdout or otlp (env TRACING_EXPORTER)")
+	otlpEndpoint := fs.String("otlp-endpoint", "", "OTLP/HTTP collector URL (env TRACING_OTLP_ENDPOINT)")
+	sampleRatio := fs.Float64("trace-sample-ratio", -1, "share of root traces kept, 0 to 1 (env TRACING_SAMPLE_RATIO)")
+	fs.BoolVar(&cfg.PrintConfig, "print-config", false, "print the effective configuration and exit")
+	if err := fs.Parse(args); err != nil {
+		return cfg, err
+	}
+
+	// Fichier : les chemins relatifs qu'il contient sont relatifs à lui.
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return cfg, err
+		}
+	}
+
+	// Environnement puis flags : même traitement, les flags passent en dernier.
+	sources := []map[string]string{
+		{
+			"addr":        getenv("ADDR"),
+			"shutdown":    getenv("SHUTDOWN_TIMEOUT"),
+			"tlsCert":     getenv("TLS_CERT_FILE"),
+			"tlsKey":      getenv("TLS_KEY_FILE"),
+			"selfSigned":  getenv("TLS_SELF_SIGNED"),
+			"h2c":         getenv("H2C"),
+			"dataDir":     getenv("DATA_DIR"),
+			"backend":     getenv("STORAGE_BACKEND"),
+			"onCorrupt":   getenv("STORE_ON_CORRUPT"),
+			"policy":      getenv("MISSING_BOARD_POLICY"),
+			"origins":     getenv("ALLOWED_ORIGINS"),
+			"headers":     getenv("ALLOWED_HEADERS"),
+			"ping":        getenv("WS_PING_INTERVAL"),
+			"compressMin": getenv("COMPRESS_MIN_SIZE"),
+			"rateLimit":   getenv("RATE_LIMIT_RPS"),
+			"rateBurst":   getenv("RATE_LIMIT_BURST"),
+			"proxies":     getenv("TRUSTED_PROXIES"),
+			"maxBody":     getenv("MAX_BODY_BYTES"),
+			"maxUpload":   getenv("MAX_UPLOAD_BYTES"),
+			"maxWidgets":  getenv("MAX_WIDGETS_PER_BOARD"),
+			"maxSubs":     getenv("MAX_WS_SUBSCRIPTIONS"),
+			"complexity":  getenv("GRAPHQL_MAX_COMPLEXITY"),
+			"depth":       getenv("GRAPHQL_MAX_DEPTH"),
+			"queryCache":  getenv("GRAPHQL_QUERY_CACHE_SIZE"),
+			"apqCache":    getenv("GRAPHQL_APQ_CACHE_SIZE"),
+			"persisted":   getenv("GRAPHQL_PERSISTED_QUERIES"),
+			"allowlist":   getenv("GRAPHQL_ALLOWLIST"),
+			"upstream":    getenv("MIRROR_UPSTREAM"),
+			"mirrored":    getenv("MIRROR_BOARDS"),
+			"mirrorMode":  getenv("MIRROR_MODE"),
+			"mirrorTTL":   getenv("MIRROR_CACHE_TTL"),
+			"mirrorToken": getenv("MIRROR_TOKEN"),
+			"pubsub":      getenv("PUBSUB_BACKEND"),
+			"redisURL":    getenv("REDIS_URL"),
+			"channel":     getenv("PUBSUB_CHANNEL"),
+			"replay":      getenv("PUBSUB_REPLAY_BUFFER"),
+			"overflow":    getenv("PUBSUB_OVERFLOW"),
+			"playground":  getenv("ENABLE_PLAYGROUND"),
+			"rest":        getenv("ENABLE_REST"),
+			"compression": getenv("ENABLE_COMPRESSION"),
+			"metrics":     getenv("ENABLE_METRICS"),
+			"userID":      getenv("TRUST_USER_ID_HEADER"),
+			"logLevel":    getenv("LOG_LEVEL"),
+			"logFormat":   getenv("LOG_FORMAT"),
+			"accessLog":   getenv("ACCESS_LOG"),
+			"tracing":     getenv("TRACING_EXPORTER"),
+			"otlp":        getenv("TRACING_OTLP_ENDPOINT"),
+			"sampleRatio": getenv("TRACING_SAMPLE_RATIO"),
+			"serviceName": getenv("OTEL_SERVICE_NAME"),
+		},
+		{
+			"addr":        *addr,
+			"tlsCert":     *tlsCert,
+			"tlsKey":      *tlsKey,
+			"selfSigned":  *tlsSelfSigned,
+			"h2c":         *h2c,
+			"dataDir":     *dataDir,
+			"backend":     *backend,
+			"onCorrupt":   *onCorrupt,
+			"policy":      *missingPolicy,
+			"origins":     *origins,
+			"headers":     *headers,
+			"persisted":   *persisted,
+			"allowlist":   *allowlist,
+			"upstream":    *mirrorUpstream,
+			"mirrored":    *mirrorBoards,
+			"mirrorMode":  *mirrorMode,
+			"pubsub":      *pubsubBackend,
+			"redisURL":    *redisURL,
+			"overflow":    *overflow,
+			"playground":  *playground,
+			"rest":        *restAPI,
+			"compression": *compression,
+			"metrics":     *metricsFlag,
+			"userID":      *userIDHeader,
+			"logLevel":    *logLevel,
+			"logFormat":   *logFormat,
+			"accessLog":   *accessLog,
+			"tracing":     *traceExporter,
+			"otlp":        *otlpEndpoint,
+		},
+	}
+	if *shutdownTimeout != 0 {
+		sources[1]["shutdown"] = shutdownTimeout.String()
+	}
+	if *ping != 0 {
+		sources[1]["ping"] = ping.String()
+	}
+	if *mirrorTTL >= 0 {
+		sources[1]["mirrorTTL"] = mirrorTTL.String()
+	}
+	if *recoverStore {
+		sources[1]["onCorrupt"] = string(board.CorruptStoreRecover)
+	}
+	if *sampleRatio >= 0 {
+		sources[1]["sampleRatio"] = strconv.FormatFloat(*sampleRatio, 'g', -1, 64)
+	}
+	if *compressMin >= 0 {
+		sources[1]["compressMin"] = strconv.Itoa(*compressMin)
+	}
+	if *rateLimit >= 0 {
+		sources[1]["rateLimit"] = strconv.FormatFloat(*rateLimit, 'g', -1, 64)
+	}
+	for key, v := range map[string]int64{"rateBurst": int64(*rateBurst), "maxBody": *maxBody, "maxUpload": *maxUpload, "maxWidgets": int64(*maxWidgets), "maxSubs": int64(*maxSubs),
+		"proxies": int64(*trustedProxies), "complexity": int64(*maxComplexity), "depth": int64(*maxDepth), "apqCache": int64(*apqCache), "replay": int64(*replayBuffer)} {
+		if v >= 0 {
+			sources[1][key] = strconv.FormatInt(v, 10)
+		}
+	}
+	for _, values := range sources {
+		if err := cfg.apply(values); err != nil {
+			return cfg, err
+		}
+	}
+
+	if cfg.Storage.DataDir != "" {
+		abs, err := filepath.Abs(cfg.Storage.DataDir)
+		if err != nil {
+			return cfg, fmt.Errorf("resolve data dir: %w", err)
+		}
+		cfg.Storage.DataDir = abs
+	}
+	return cfg, cfg.Validate()
+}
+
+func (c *Config) loadFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	dec := yaml.NewDecoder(strings.NewReader(string(content)))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	for _, p := range []*string{&c.Storage.DataDir, &c.TLS.CertFile, &c.TLS.KeyFile, &c.GraphQL.PersistedQueries} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(filepath.Dir(path), *p)
+		}
+	}
+	return nil
+}
+
+func (c *Config) apply(values map[string]string) error {
+	var err error
+	set := func(key string, fn func(string) error) {
+		if err != nil || values[key] == "" {
+			return
+		}
+		if e := fn(strings.TrimSpace(values[key])); e != nil {
+			err = fmt.Errorf("%s: %w", key, e)
+		}
+	}
+	set("addr", func(v string) error { c.Addr = v; return nil })
+	set("shutdown", func(v string) (e error) { c.ShutdownTimeout, e = time.ParseDuration(v); return })
+	set("tlsCert", func(v string) error { c.TLS.CertFile = v; return nil })
+	set("tlsKey", func(v string) error { c.TLS.KeyFile = v; return nil })
+	set("selfSigned", func(v string) (e error) { c.TLS.SelfSigned, e = strconv.ParseBool(v); return })
+	set("h2c", func(v string) (e error) { c.HTTP2.H2C, e = strconv.ParseBool(v); return })
+	set("dataDir", func(v string) error { c.Storage.DataDir = v; return nil })
+	set("backend", func(v string) error { c.Storage.Backend = strings.ToLower(v); return nil })
+	set("onCorrupt", func(v string) error { c.Storage.OnCorrupt = board.CorruptStorePolicy(v); return nil })
+	set("policy", func(v string) error { c.Boards.MissingPolicy = board.MissingBoardPolicy(v); return nil })
+	// Une liste vide (",") garde la valeur précédente, comme avant.
+	set("origins", func(v string) error {
+		if list := SplitList(v); len(list) > 0 {
+			c.CORS.AllowedOrigins = list
+		}
+		return nil
+	})
+	set("headers", func(v string) error {
+		if list := SplitList(v); len(list) > 0 {
+			c.CORS.AllowedHeaders = list
+		}
+		return nil
+	})
+	set("ping", func(v string) (e error) { c.Websocket.PingInterval, e = time.ParseDuration(v); return })
+	set("compressMin", func(v string) (e error) { c.Limits.CompressMinSize, e = strconv.Atoi(v); return })
+	set("rateLimit", func(v string) (e error) { c.Limits.RequestsPerSecond, e = strconv.ParseFloat(v, 64); return })
+	set("rateBurst", func(v string) (e error) { c.Limits.Burst, e = strconv.Atoi(v); return })
+	set("proxies", func(v string) (e error) { c.Limits.TrustedProxies, e = strconv.Atoi(v); return })
+	set("maxBody", func(v string) (e error) { c.Limits.MaxBodyBytes, e = strconv.ParseInt(v, 10, 64); return })
+	set("maxUpload", func(v string) (e error) { c.Limits.MaxUploadBytes, e = strconv.ParseInt(v, 10, 64); return })
+	set("maxWidgets", func(v string) (e error) { c.Limits.MaxWidgetsPerBoard, e = strconv.Atoi(v); return })
+	set("maxSubs", func(v string) (e error) { c.Limits.MaxSubscriptionsPerConnection, e = strconv.Atoi(v); return })
+	set("complexity", func(v string) (e error) { c.GraphQL.MaxComplexity, e = strconv.Atoi(v); return })
+	set("depth", func(v string) (e error) { c.GraphQL.MaxDepth, e = strconv.Atoi(v); return })
+	set("queryCache", func(v string) (e error) { c.GraphQL.QueryCacheSize, e = strconv.Atoi(v); return })
+	set("apqCache", func(v string) (e error) { c.GraphQL.APQCacheSize, e = strconv.Atoi(v); return })
+	set("persisted", func(v string) error { c.GraphQL.PersistedQueries = v; return nil })
+	set("allowlist", func(v string) (e error) { c.GraphQL.Allowlist, e = strconv.ParseBool(v); return })
+	set("upstream", func(v string) error { c.Mirror.Upstream = v; return nil })
+	set("mirrored", func(v string) error {
+		if list := SplitList(v); len(list) > 0 {
+			c.Mirror.Boards = list
+		}
+		return nil
+	})
+	set("mirrorMode", func(v string) error { c.Mirror.Mode = mirror.Mode(v); return nil })
+	set("mirrorTTL", func(v string) (e error) { c.Mirror.CacheTTL, e = time.ParseDuration(v); return })
+	set("mirrorToken", func(v string) error { c.Mirror.Token = v; return nil })
+	set("pubsub", func(v string) error { c.PubSub.Backend = strings.ToLower(v); return nil })
+	set("redisURL", func(v string) error { c.PubSub.RedisURL = v; return nil })
+	set("channel", func(v string) error { c.PubSub.Channel = v; return nil })
+	set("replay", func(v string) (e error) { c.PubSub.ReplayBuffer, e = strconv.Atoi(v); return })
+	set("overflow", func(v string) error { c.PubSub.Overflow = pubsub.Overflow(v); return nil })
+	set("playground", func(v string) (e error) { c.Features.Playground, e = strconv.ParseBool(v); return })
+	set("rest", func(v string) (e error) { c.Features.REST, e = strconv.ParseBool(v); return })
+	set("compression", func(v string) (e error) { c.Features.Compression, e = strconv.ParseBool(v); return })
+	set("metrics", func(v string) (e error) { c.Features.Metrics, e = strconv.ParseBool(v); return })
+	set("userID", func(v string) (e error) { c.Features.UserIDHeader, e = strconv.ParseBool(v); return })
+	set("logLevel", func(v string) error { c.Log.Level = strings.ToLower(v); return nil })
+	set("logFormat", func(v string) error { c.Log.Format = strings.ToLower(v); return nil })
+	set("accessLog", func(v string) (e error) { c.Log.AccessLog, e = strconv.ParseBool(v); return })
+	set("tracing", func(v string) error { c.Tracing.Exporter = strings.ToLower(v); return nil })
+	set("otlp", func(v string) error { c.Tracing.Endpoint = v; return nil })
+	set("sampleRatio", func(v string) (e error) { c.Tracing.SampleRatio, e = strconv.ParseFloat(v, 64); return })
+	set("serviceName", func(v string) error { c.Tracing.ServiceName = v; return nil })
+	return err
+}
+
+func (c *Config) Validate() error {
+	var errs []error
+	if strings.TrimSpace(c.Addr) == "" {
+		errs = append(errs, errors.New("addr must not be empty"))
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("tls: certFile and keyFile must be set together"))
+	}
+	if c.TLS.SelfSigned && c.TLS.CertFile != "" {
+		errs = append(errs, errors.New("tls: selfSigned and certFile/keyFile are mutually exclusive"))
+	}
+	if c.HTTP2.H2C && c.TLS.Enabled() {
+		errs = append(errs, errors.New("http2.h2c only applies without TLS (HTTP/2 is negotiated over TLS)"))
+	}
+	switch c.Storage.Backend {
+	case StorageFile:
+		if c.Storage.DataDir == "" {
+			errs = append(errs, errors.New("storage.dataDir is required with the file backend (--data-dir, DATA_DIR or the config file; --storage memory persists nothing)"))
+		}
+	case StorageMemory:
+	default:
+		errs = append(errs, fmt.Errorf("storage.backend: unknown backend %q (want %q or %q)", c.Storage.Backend, StorageFile, StorageMemory))
+	}
+	onCorrupt, err := board.ParseCorruptStorePolicy(string(c.Storage.OnCorrupt))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("storage.onCorrupt: %w", err))
+	}
+	c.Storage.OnCorrupt = onCorrupt
+	policy, err := board.ParseMissingBoardPolicy(string(c.Boards.MissingPolicy))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("boards.missingPolicy: %w", err))
+	}
+	c.Boards.MissingPolicy = policy
+	for _, origin := range c.CORS.AllowedOrigins {
+		u, err := url.Parse(origin)
+		if err != nil || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			errs = append(errs, fmt.Errorf("cors.allowedOrigins: %q is not an origin (scheme://host[:port])", origin))
+			continue
+		}
+		switch u.Scheme {
+		case "http", "https", "ws", "wss":
+		default:
+			errs = append(errs, fmt.Errorf("cors.allowedOrigins: %q: unsupported scheme %q", origin, u.Scheme))
+		}
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("shutdownTimeout must be positive"))
+	}
+	if c.Websocket.PingInterval <= 0 {
+		errs = append(errs, errors.New("websocket.pingInterval must be positive"))
+	}
+	if c.Limits.CompressMinSize < 0 {
+		errs = append(errs, errors.New("limits.compressMinSize must not be negative"))
+	}
+	if c.Limits.RequestsPerSecond < 0 || c.Limits.Burst < 0 || c.Limits.MaxBodyBytes < 0 || c.Limits.MaxUploadBytes < 0 ||
+		c.Limits.MaxWidgetsPerBoard < 0 || c.Limits.MaxSubscriptionsPerConnection < 0 || c.Limits.TrustedProxies < 0 {
+		errs = append(errs, errors.New("limits: values must not be negative (0 disables a limit)"))
+	}
+	if c.Limits.RequestsPerSecond > 0 && c.Limits.Burst < 1 {
+		errs = append(errs, errors.New("limits.burst must be at least 1 when rate limiting is enabled"))
+	}
+	if c.GraphQL.MaxComplexity < 0 || c.GraphQL.MaxDepth < 0 || c.GraphQL.QueryCacheSize < 0 || c.GraphQL.APQCacheSize < 0 {
+		errs = append(errs, errors.New("graphql: limits and cache sizes must not be negative (0 disables)"))
+	}
+	if c.GraphQL.Allowlist && c.GraphQL.PersistedQueries == "" {
+		errs = append(errs, errors.New("graphql.allowlist requires graphql.persistedQueries"))
+	}
+	mode, err := mirror.ParseMode(string(c.Mirror.Mode))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("mirror.mode: %w", err))
+	}
+	c.Mirror.Mode = mode
+	if c.Mirror.Enabled() {
+		if u, err := url.Parse(c.Mirror.Upstream); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			errs = append(errs, fmt.Errorf("mirror.upstream: %q is not an http(s) URL", c.Mirror.Upstream))
+		}
+		if len(c.Mirror.Boards) == 0 {
+			errs = append(errs, errors.New("mirror.boards: at least one board ID or prefix is required"))
+		}
+	} else if len(c.Mirror.Boards) > 0 {
+		errs = append(errs, errors.New("mirror.boards requires mirror.upstream"))
+	}
+	if c.Mirror.CacheTTL < 0 {
+		errs = append(errs, errors.New("mirror.cacheTTL must not be negative"))
+	}
+	switch c.PubSub.Backend {
+	case PubSubMemory:
+	case PubSubRedis:
+		if u, err := url.Parse(c.PubSub.RedisURL); err != nil || u.Host == "" || (u.Scheme != "redis" && u.Scheme != "rediss") {
+			errs = append(errs, errors.New("pubsub.redisURL: a redis:// or rediss:// URL is required with the redis backend"))
+		}
+		if strings.TrimSpace(c.PubSub.Channel) == "" {
+			errs = append(errs, errors.New("pubsub.channel must not be empty"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("pubsub.backend: unknown backend %q (want %q or %q)", c.PubSub.Backend, PubSubMemory, PubSubRedis))
+	}
+	if c.PubSub.ReplayBuffer < 0 {
+		errs = append(errs, errors.New("pubsub.replayBuffer must not be negative"))
+	}
+	overflow, err := pubsub.ParseOverflow(string(c.PubSub.Overflow))
+	if err != nil {
+		errs = append(errs, fmt.Errorf("pubsub.overflow: %w", err))
+	}
+	c.PubSub.Overflow = overflow
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		errs = append(errs, fmt.Errorf("log.level: %w", err))
+	}
+	c.Log.Format = strings.ToLower(strings.TrimSpace(c.Log.Format))
+	switch c.Log.Format {
+	case "text", "json":
+	default:
+		errs = append(errs, fmt.Errorf("log.format: unknown format %q (want text or json)", c.Log.Format))
+	}
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP:
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter: unknown exporter %q (want none, stdout or otlp)", c.Tracing.Exporter))
+	}
+	if c.Tracing.Endpoint != "" {
+		if u, err := url.Parse(c.Tracing.Endpoint); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			errs = append(errs, fmt.Errorf("tracing.endpoint: %q is not an http(s) URL", c.Tracing.Endpoint))
+		}
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing.sampleRatio must be between 0 and 1"))
+	}
+	return errors.Join(errs...)
+}
+
+// Print écrit la configuration effective en YAML, secrets masqués.
+func (c Config) Print(w io.Writer) error {
+	if c.Mirror.Token != "" {
+		c.Mirror.Token = "<redacted>"
+	}
+	if u, err := url.Parse(c.PubSub.RedisURL); err == nil && u.User != nil {
+		if _, ok := u.User.Password(); ok {
+			u.User = url.UserPassword(u.User.Username(), "redacted")
+			c.PubSub.RedisURL = u.String()
+		}
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// SplitList découpe une liste séparée par des virgules, sans doublons
+// (insensible à la casse) ni éléments vides.
+func SplitList(raw string) []string {
+	items := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, value := range strings.Split(raw, ",") {
+		item := strings.TrimSpace(value)
+		if item == "" || seen[strings.ToLower(item)] {
+			continue
+		}
+		seen[strings.ToLower(item)] = true
+		items = append(items, item)
+	}
+	return items
+}
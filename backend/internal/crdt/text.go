@@ -0,0 +1,346 @@
+// Package crdt implémente un texte collaboratif RGA (Replicated Growable
+// Array). Chaque caractère a un identifiant unique (client, horloge) et
+// retient le caractère à droite duquel il a été inséré ; une suppression ne
+// fait que le marquer. Deux répliques qui ont reçu les mêmes opérations, dans
+// n'importe quel ordre respectant leurs dépendances, ont le même texte.
+//
+// Les opérations circulent en JSON (voir Update). Un client choisit un
+// identifiant de client unique et, pour chaque insertion, une horloge
+// supérieure à toutes celles qu'il a vues (Doc.Clock).
+package crdt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// ErrInvalidUpdate est enveloppée par toutes les erreurs de Apply et de
+// DecodeUpdate.
+var ErrInvalidUpdate = errors.New("invalid text update")
+
+// ID identifie un caractère.
+type ID struct {
+	Client string `json:"client"`
+	Clock  int    `json:"clock"`
+}
+
+func (id ID) String() string { return fmt.Sprintf("%s@%d", id.Client, id.Clock) }
+
+// after donne l'ordre entre insertions concurrentes au même endroit : la
+// plus récente passe devant.
+func (id ID) after(other ID) bool {
+	if id.Clock != other.Clock {
+		return id.Clock > other.Clock
+	}
+	return id.Client > other.Client
+}
+
+// Insert insère Text à droite de Origin (au début si nil). Le premier
+// caractère a l'identifiant ID, les suivants les horloges consécutives, chacun
+// à droite du précédent.
+type Insert struct {
+	ID     ID     `json:"id"`
+	Origin *ID    `json:"origin,omitempty"`
+	Text   string `json:"text"`
+}
+
+// Delete supprime les Len caractères ID.Client aux horloges ID.Clock,
+// ID.Clock+1, etc.
+type Delete struct {
+	ID  ID  `json:"id"`
+	Len int `json:"len"`
+}
+
+// Update est un lot d'opérations, appliqué d'un bloc par Doc.Apply.
+type Update struct {
+	Inserts []Insert `json:"inserts,omitempty"`
+	Deletes []Delete `json:"deletes,omitempty"`
+}
+
+func (u Update) Empty() bool {
+	return len(u.Inserts) == 0 && len(u.Deletes) == 0
+}
+
+func DecodeUpdate(raw string) (Update, error) {
+	var u Update
+	if err := json.Unmarshal([]byte(raw), &u); err != nil {
+		return Update{}, fmt.Errorf("%w: %w", ErrInvalidUpdate, err)
+	}
+	return u, nil
+}
+
+func (u Update) Encode() string {
+	raw, _ := json.Marshal(u)
+	return string(raw)
+}
+
+type item struct {
+	id      ID
+	origin  *ID
+	r       rune
+	deleted bool
+	next    *item
+}
+
+// Doc est une réplique du texte. Elle n'est pas sûre en concurrence.
+//
+// Les caractères forment une liste chaînée, indexée par identifiant :
+// intégrer une opération ne coûte que le parcours des insertions
+// concurrentes qui suivent son origine.
+type Doc struct {
+	// head précède le premier caractère.
+	head  item
+	items map[ID]*item
+	clock int
+	// size : caractères visibles ; tombstones : caractères supprimés.
+	size, tombstones int
+}
+
+// NewDoc part de text, attribué au client seed : deux répliques créées avec
+// les mêmes arguments sont identiques.
+func NewDoc(seed, text string) *Doc {
+	d := &Doc{items: make(map[ID]*item)}
+	last := &d.head
+	var origin *ID
+	for i, r := range []rune(text) {
+		it := &item{id: ID{Client: seed, Clock: i + 1}, origin: origin, r: r}
+		last.next = it
+		last = it
+		d.items[it.id] = it
+		d.clock = it.id.Clock
+		d.size++
+		origin = &it.id
+	}
+	return d
+}
+
+// Text renvoie le texte visible.
+func (d *Doc) Text() string {
+	var b strings.Builder
+	for it := d.head.next; it != nil; it = it.next {
+		if !it.deleted {
+			b.WriteRune(it.r)
+		}
+	}
+	return b.String()
+}
+
+// Clock renvoie la plus grande horloge connue.
+func (d *Doc) Clock() int { return d.clock }
+
+// Len renvoie le nombre de caractères visibles.
+func (d *Doc) Len() int { return d.size }
+
+// Tombstones renvoie le nombre de caractères supprimés. Ils restent dans la
+// réplique, où des insertions concurrentes peuvent encore les désigner
+// comme origine : seule une nouvelle réplique (NewDoc à partir de Text) les
+// oublie, et ses clients doivent alors repartir de son état.
+func (d *Doc) Tombstones() int { return d.tombstones }
+
+// InsertAt insère text avant le pos-ième caractère visible (en runes) pour
+// le compte de client, et renvoie l'opération à diffuser.
+func (d *Doc) InsertAt(client string, pos int, text string) (Update, error) {
+	var origin *ID
+	if pos > 0 {
+		it := d.visible(pos - 1)
+		if it == nil {
+			return Update{}, fmt.Errorf("%w: position %d out of range", ErrInvalidUpdate, pos)
+		}
+		id := it.id
+		origin = &id
+	}
+	u := Update{Inserts: []Insert{{ID: ID{Client: client, Clock: d.clock + 1}, Origin: origin, Text: text}}}
+	if _, err := d.Apply(u); err != nil {
+		return Update{}, err
+	}
+	return u, nil
+}
+
+// DeleteAt supprime n caractères visibles à partir de pos et renvoie
+// l'opération à diffuser.
+func (d *Doc) DeleteAt(pos, n int) (Update, error) {
+	var u Update
+	it := d.visible(pos)
+	for k := 0; k < n; k++ {
+		for it != nil && it.deleted {
+			it = it.next
+		}
+		if it == nil {
+			return Update{}, fmt.Errorf("%w: position %d out of range", ErrInvalidUpdate, pos+k)
+		}
+		id := it.id
+		if last := len(u.Deletes) - 1; last >= 0 && u.Deletes[last].ID.Client == id.Client && u.Deletes[last].ID.Clock+u.Deletes[last].Len == id.Clock {
+			u.Deletes[last].Len++
+		} else {
+			u.Deletes = append(u.Deletes, Delete{ID: id, Len: 1})
+		}
+		it = it.next
+	}
+	if _, err := d.Apply(u); err != nil {
+		return Update{}, err
+	}
+	return u, nil
+}
+
+// visible renvoie le pos-ième caractère visible, nil s'il n'existe pas.
+func (d *Doc) visible(pos int) *item {
+	if pos < 0 {
+		return nil
+	}
+	for it := d.head.next; it != nil; it = it.next {
+		if it.deleted {
+			continue
+		}
+		if pos == 0 {
+			return it
+		}
+		pos--
+	}
+	return nil
+}
+
+// Apply intègre u et renvoie les opérations qui ont changé la réplique : les
+// insertions et suppressions déjà connues sont ignorées, ce qui permet de
+// renvoyer un lot sans risque. Un lot invalide (origine inconnue, horloge
+// qui ne dépasse pas celle de l'origine, identifiant réutilisé) est refusé
+// en entier.
+func (d *Doc) Apply(u Update) (Update, error) {
+	inserts, pending, err := d.checkInserts(u.Inserts)
+	if err != nil {
+		return Update{}, err
+	}
+	for _, del := range u.Deletes {
+		if del.Len < 1 {
+			return Update{}, fmt.Errorf("%w: delete %s: len must be positive", ErrInvalidUpdate, del.ID)
+		}
+		for i := 0; i < del.Len; i++ {
+			id := ID{Client: del.ID.Client, Clock: del.ID.Clock + i}
+			if d.items[id] == nil && !pending[id] {
+				return Update{}, fmt.Errorf("%w: delete %s: unknown character", ErrInvalidUpdate, id)
+			}
+		}
+	}
+	var applied Update
+	for _, ins := range inserts {
+		origin := ins.Origin
+		for i, r := range []rune(ins.Text) {
+			it := &item{id: ID{Client: ins.ID.Client, Clock: ins.ID.Clock + i}, origin: origin, r: r}
+			d.integrate(it)
+			origin = &it.id
+		}
+		applied.Inserts = append(applied.Inserts, ins)
+	}
+	for _, del := range u.Deletes {
+		applied.Deletes = append(applied.Deletes, d.delete(del)...)
+	}
+	return applied, nil
+}
+
+// checkInserts valide les insertions avant toute modification et renvoie
+// celles qui sont nouvelles, avec les identifiants qu'elles vont créer.
+func (d *Doc) checkInserts(inserts []Insert) ([]Insert, map[ID]bool, error) {
+	pending := make(map[ID]bool)
+	exists := func(id ID) bool { return d.items[id] != nil || pending[id] }
+	var fresh []Insert
+	for _, ins := range inserts {
+		n := utf8.RuneCountInString(ins.Text)
+		switch {
+		case ins.ID.Client == "":
+			return nil, nil, fmt.Errorf("%w: insert %s: client must not be empty", ErrInvalidUpdate, ins.ID)
+		case n == 0 || !utf8.ValidString(ins.Text):
+			return nil, nil, fmt.Errorf("%w: insert %s: text must be non-empty UTF-8", ErrInvalidUpdate, ins.ID)
+		case ins.Origin != nil && !exists(*ins.Origin):
+			return nil, nil, fmt.Errorf("%w: insert %s: unknown origin %s, reload the text", ErrInvalidUpdate, ins.ID, *ins.Origin)
+		case ins.Origin != nil && ins.ID.Clock <= ins.Origin.Clock:
+			return nil, nil, fmt.Errorf("%w: insert %s: clock must be greater than origin %s", ErrInvalidUpdate, ins.ID, *ins.Origin)
+		case ins.ID.Clock < 1:
+			return nil, nil, fmt.Errorf("%w: insert %s: clock must be positive", ErrInvalidUpdate, ins.ID)
+		}
+		// Un lot renvoyé est ignoré ; un lot qui recouvre en partie des
+		// caractères connus est une erreur du client.
+		known := 0
+		for i := 0; i < n; i++ {
+			if exists(ID{Client: ins.ID.Client, Clock: ins.ID.Clock + i}) {
+				known++
+			}
+		}
+		if known == n {
+			continue
+		}
+		if known > 0 {
+			return nil, nil, fmt.Errorf("%w: insert %s: overlaps existing characters", ErrInvalidUpdate, ins.ID)
+		}
+		for i := 0; i < n; i++ {
+			pending[ID{Client: ins.ID.Client, Clock: ins.ID.Clock + i}] = true
+		}
+		fresh = append(fresh, ins)
+	}
+	return fresh, pending, nil
+}
+
+// integrate place it après son origine, derrière les insertions concurrentes
+// plus récentes (et tout ce qui a été inséré à leur suite, plus récent
+// encore).
+func (d *Doc) integrate(it *item) {
+	prev := &d.head
+	if it.origin != nil {
+		prev = d.items[*it.origin]
+	}
+	for prev.next != nil && prev.next.id.after(it.id) {
+		prev = prev.next
+	}
+	it.next = prev.next
+	prev.next = it
+	d.items[it.id] = it
+	d.clock = max(d.clock, it.id.Clock)
+	d.size++
+}
+
+func (d *Doc) delete(del Delete) []Delete {
+	var applied []Delete
+	for i := 0; i < del.Len; i++ {
+		id := ID{Client: del.ID.Client, Clock: del.ID.Clock + i}
+		it := d.items[id]
+		if it.deleted {
+			continue
+		}
+		it.deleted = true
+		d.size--
+		d.tombstones++
+		if n := len(applied); n > 0 && applied[n-1].ID.Clock+applied[n-1].Len == id.Clock {
+			applied[n-1].Len++
+		} else {
+			applied = append(applied, Delete{ID: id, Len: 1})
+		}
+	}
+	return applied
+}
+
+// State renvoie un Update qui reconstruit la réplique à partir de rien,
+// suppressions comprises : c'est le point de départ d'un client.
+func (d *Doc) State() Update {
+	var u Update
+	var prev *item
+	for it := d.head.next; it != nil; it = it.next {
+		// Un caractère prolonge l'insertion précédente s'il en est la suite
+		// directe : même client, horloge suivante, inséré juste après.
+		if n := len(u.Inserts); n > 0 && prev != nil && it.origin != nil && *it.origin == prev.id &&
+			it.id.Client == prev.id.Client && it.id.Clock == prev.id.Clock+1 {
+			u.Inserts[n-1].Text += string(it.r)
+		} else {
+			u.Inserts = append(u.Inserts, Insert{ID: it.id, Origin: it.origin, Text: string(it.r)})
+		}
+		if it.deleted {
+			if n := len(u.Deletes); n > 0 && u.Deletes[n-1].ID.Client == it.id.Client && u.Deletes[n-1].ID.Clock+u.Deletes[n-1].Len == it.id.Clock {
+				u.Deletes[n-1].Len++
+			} else {
+				u.Deletes = append(u.Deletes, Delete{ID: it.id, Len: 1})
+			}
+		}
+		prev = it
+	}
+	return u
+}
@@ -0,0 +1,248 @@
+package crdt
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// edit produit une opération sur la réplique d'un client.
+type edit func(d *Doc) (Update, error)
+
+func insertAt(client string, pos int, text string) edit {
+	return func(d *Doc) (Update, error) { return d.InsertAt(client, pos, text) }
+}
+
+func deleteAt(pos, n int) edit {
+	return func(d *Doc) (Update, error) { return d.DeleteAt(pos, n) }
+}
+
+// script : les éditions de chaque client, faites dans l'ordre sur sa propre
+// réplique, sans voir celles des autres.
+type script map[string][]edit
+
+func (sc script) updates(t *testing.T, base string) []Update {
+	t.Helper()
+	var all []Update
+	for client, edits := range sc {
+		d := NewDoc("seed", base)
+		for i, e := range edits {
+			u, err := e(d)
+			if err != nil {
+				t.Fatalf("client %s, edit %d: %v", client, i, err)
+			}
+			all = append(all, u)
+		}
+	}
+	return all
+}
+
+// deliver applique updates dans l'ordre donné ; une opération dont une
+// dépendance n'est pas encore arrivée est mise de côté puis retentée, comme
+// le ferait un client qui relit l'état.
+func deliver(t *testing.T, d *Doc, updates []Update) {
+	t.Helper()
+	pending := updates
+	for len(pending) > 0 {
+		var later []Update
+		for _, u := range pending {
+			if _, err := d.Apply(u); err != nil {
+				later = append(later, u)
+			}
+		}
+		if len(later) == len(pending) {
+			t.Fatalf("%d updates never applicable", len(later))
+		}
+		pending = later
+	}
+}
+
+func permutations(n int) [][]int {
+	if n == 0 {
+		return [][]int{{}}
+	}
+	var out [][]int
+	for _, p := range permutations(n - 1) {
+		for i := 0; i <= len(p); i++ {
+			q := append(append(append([]int{}, p[:i]...), n-1), p[i:]...)
+			out = append(out, q)
+		}
+	}
+	return out
+}
+
+func TestConvergence(t *testing.T) {
+	tests := []struct {
+		name   string
+		base   string
+		script script
+		want   string
+	}{
+		{
+			name: "concurrent inserts at the same place",
+			base: "ac",
+			script: script{
+				"alice": {insertAt("alice", 1, "b")},
+				"bob":   {insertAt("bob", 1, "x")},
+				"carol": {insertAt("carol", 1, "yz")},
+			},
+		},
+		{
+			name: "insert inside a concurrent deletion",
+			base: "hello",
+			script: script{
+				"alice": {deleteAt(1, 3)},
+				"bob":   {insertAt("bob", 2, "X")},
+				"carol": {insertAt("carol", 5, "!")},
+			},
+			want: "hXo!",
+		},
+		{
+			name: "causal chains",
+			base: "",
+			script: script{
+				"alice": {insertAt("alice", 0, "a"), insertAt("alice", 1, "b"), deleteAt(0, 1)},
+				"bob":   {insertAt("bob", 0, "c"), insertAt("bob", 1, "d")},
+			},
+		},
+		{
+			name: "same character deleted twice",
+			base: "abc",
+			script: script{
+				"alice": {deleteAt(1, 1)},
+				"bob":   {deleteAt(1, 2), insertAt("bob", 1, "Z")},
+			},
+			want: "aZ",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updates := tt.script.updates(t, tt.base)
+			want := tt.want
+			for _, order := range permutations(len(updates)) {
+				d := NewDoc("seed", tt.base)
+				shuffled := make([]Update, len(order))
+				for i, k := range order {
+					shuffled[i] = updates[k]
+				}
+				deliver(t, d, shuffled)
+				got := d.Text()
+				if want == "" {
+					want = got
+				}
+				if got != want {
+					t.Fatalf("order %v: text = %q, want %q", order, got, want)
+				}
+				// Rejouer tout le lot ne change rien.
+				deliver(t, d, updates)
+				if d.Text() != want {
+					t.Fatalf("order %v: replay changed text to %q", order, d.Text())
+				}
+			}
+		})
+	}
+}
+
+func TestState(t *testing.T) {
+	d := NewDoc("seed", "héllo")
+	for _, e := range []edit{insertAt("a", 5, " wörld"), deleteAt(0, 2), insertAt("b", 0, "Y")} {
+		if _, err := e(d); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rebuilt := NewDoc("", "")
+	if _, err := rebuilt.Apply(d.State()); err != nil {
+		t.Fatalf("Apply(State): %v", err)
+	}
+	if rebuilt.Text() != d.Text() || rebuilt.Len() != d.Len() || rebuilt.Tombstones() != d.Tombstones() {
+		t.Fatalf("rebuilt %q (%d, %d), want %q (%d, %d)", rebuilt.Text(), rebuilt.Len(), rebuilt.Tombstones(), d.Text(), d.Len(), d.Tombstones())
+	}
+	// Les deux répliques continuent d'accepter les mêmes opérations.
+	u, err := d.InsertAt("c", 3, "+")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rebuilt.Apply(u); err != nil || rebuilt.Text() != d.Text() {
+		t.Fatalf("after insert: %q, %v, want %q", rebuilt.Text(), err, d.Text())
+	}
+}
+
+func TestTombstones(t *testing.T) {
+	tests := []struct {
+		name           string
+		edits          []edit
+		wantLen        int
+		wantTombstones int
+	}{
+		{"seed only", nil, 3, 0},
+		{"insert", []edit{insertAt("a", 3, "de")}, 5, 0},
+		{"delete", []edit{deleteAt(0, 2)}, 1, 2},
+		{"delete twice", []edit{deleteAt(0, 1), deleteAt(0, 1)}, 1, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDoc("seed", "abc")
+			for _, e := range tt.edits {
+				if _, err := e(d); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if d.Len() != tt.wantLen || d.Tombstones() != tt.wantTombstones {
+				t.Fatalf("Len, Tombstones = %d, %d, want %d, %d", d.Len(), d.Tombstones(), tt.wantLen, tt.wantTombstones)
+			}
+		})
+	}
+}
+
+func TestApplyRejects(t *testing.T) {
+	seed := func(clock int) *ID { return &ID{Client: "seed", Clock: clock} }
+	tests := []struct {
+		name   string
+		update Update
+	}{
+		{"unknown origin", Update{Inserts: []Insert{{ID: ID{"a", 5}, Origin: &ID{"other", 1}, Text: "x"}}}},
+		{"clock not after origin", Update{Inserts: []Insert{{ID: ID{"a", 2}, Origin: seed(2), Text: "x"}}}},
+		{"empty client", Update{Inserts: []Insert{{ID: ID{"", 5}, Text: "x"}}}},
+		{"empty text", Update{Inserts: []Insert{{ID: ID{"a", 5}, Text: ""}}}},
+		{"overlap", Update{Inserts: []Insert{{ID: ID{"seed", 3}, Origin: seed(2), Text: "xy"}}}},
+		{"unknown deletion", Update{Deletes: []Delete{{ID: ID{"seed", 3}, Len: 2}}}},
+		{"empty deletion", Update{Deletes: []Delete{{ID: ID{"seed", 1}, Len: 0}}}},
+		{"whole batch refused", Update{
+			Inserts: []Insert{{ID: ID{"a", 5}, Origin: seed(1), Text: "ok"}},
+			Deletes: []Delete{{ID: ID{"b", 1}, Len: 1}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := NewDoc("seed", "abc")
+			if _, err := d.Apply(tt.update); !errors.Is(err, ErrInvalidUpdate) {
+				t.Fatalf("Apply err = %v, want ErrInvalidUpdate", err)
+			}
+			if d.Text() != "abc" {
+				t.Fatalf("text = %q after a refused update", d.Text())
+			}
+		})
+	}
+}
+
+func BenchmarkTyping(b *testing.B) {
+	for _, n := range []int{1_000, 10_000} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			for range b.N {
+				d := NewDoc("seed", "")
+				for i := 0; i < n; i++ {
+					if _, err := d.Apply(Update{Inserts: []Insert{{ID: ID{"a", i + 1}, Origin: originOf(i), Text: "x"}}}); err != nil {
+						b.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func originOf(clock int) *ID {
+	if clock == 0 {
+		return nil
+	}
+	return &ID{"a", clock}
+}
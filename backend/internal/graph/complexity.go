@@ -0,0 +1,105 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"miro-lite-standalone/backend/internal/graph/model"
+)
+
+// CodeDepthLimit accompagne le refus d'une opération trop imbriquée ; le
+// dépassement de complexité utilise le code de gqlgen,
+// COMPLEXITY_LIMIT_EXCEEDED.
+const CodeDepthLimit = "DEPTH_LIMIT_EXCEEDED"
+
+// Les listes ne sont pas paginées : leur coût est celui d'un élément
+// multiplié par une taille estimée. Avec les valeurs par défaut, charger un
+// board complet coûte environ 350, lister les boards avec tous leurs widgets
+// environ 7000.
+const (
+	estimatedBoards  = 20
+	estimatedWidgets = 50
+	// exportCost rend coûteux le rendu d'un board : avec la limite par
+	// défaut, une opération ne peut pas en demander plus de quatre.
+	exportCost = 200
+)
+
+// NewConfig renvoie la configuration du schéma avec les coûts utilisés par
+// extension.ComplexityLimit. Les champs absents gardent le coût par défaut :
+// 1 + le coût des sous-champs.
+func NewConfig(r *Resolver) Config {
+	cfg := Config{Resolvers: r}
+	cfg.Complexity.Query.Boards = func(childComplexity int) int {
+		return 1 + estimatedBoards*childComplexity
+	}
+	cfg.Complexity.Board.Widgets = func(childComplexity int) int {
+		return 1 + estimatedWidgets*childComplexity
+	}
+	cfg.Complexity.Mutation.ExportBoard = func(childComplexity int, _ string, _ model.ExportFormat, _ *model.ExportOptions) int {
+		return exportCost + childComplexity
+	}
+	return cfg
+}
+
+// DepthLimit refuse les opérations dont la sélection dépasse limit niveaux
+// (fragments dépliés). L'introspection (__schema, __type) n'est pas comptée :
+// la requête standard des outils GraphQL descend à 13 niveaux, et la
+// validation de gqlparser (MaxIntrospectionDepth) refuse déjà les
+// introspections récursives. 0 désactive la limite.
+func DepthLimit(limit int) graphql.HandlerExtension {
+	return depthLimit{limit: limit}
+}
+
+type depthLimit struct {
+	limit int
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = depthLimit{}
+
+func (depthLimit) ExtensionName() string { return "DepthLimit" }
+
+func (depthLimit) Validate(graphql.ExecutableSchema) error { return nil }
+
+func (d depthLimit) MutateOperationContext(_ context.Context, opCtx *graphql.OperationContext) *gqlerror.Error {
+	if d.limit <= 0 || opCtx.Operation == nil {
+		return nil
+	}
+	if depth := selectionDepth(opCtx.Operation.SelectionSet); depth > d.limit {
+		err := gqlerror.Errorf("operation has depth %d, which exceeds the limit of %d", depth, d.limit)
+		errcode.Set(err, CodeDepthLimit)
+		return err
+	}
+	return nil
+}
+
+// selectionDepth compte les niveaux de champs ; les fragments et les
+// sous-arbres d'introspection ne comptent pas. La validation a déjà rejeté
+// les cycles de fragments.
+func selectionDepth(set ast.SelectionSet) int {
+	depth := 0
+	for _, sel := range set {
+		var d int
+		switch sel := sel.(type) {
+		case *ast.Field:
+			if sel.Name == "__schema" || sel.Name == "__type" {
+				continue
+			}
+			d = 1 + selectionDepth(sel.SelectionSet)
+		case *ast.InlineFragment:
+			d = selectionDepth(sel.SelectionSet)
+		case *ast.FragmentSpread:
+			if sel.Definition != nil {
+				d = selectionDepth(sel.Definition.SelectionSet)
+			}
+		}
+		depth = max(depth, d)
+	}
+	return depth
+}
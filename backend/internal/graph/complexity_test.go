@@ -0,0 +1,72 @@
+package graph
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/99designs/gqlgen/graphql/introspection"
+
+	"miro-lite-standalone/backend/internal/board"
+)
+
+// newLimitedClient sert le schéma avec l'introspection et les limites de
+// complexité et de profondeur données.
+func newLimitedClient(t *testing.T, maxComplexity, maxDepth int) *client.Client {
+	t.Helper()
+	svc, err := board.NewService("")
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	srv := handler.New(NewExecutableSchema(NewConfig(NewResolver(svc, nil))))
+	srv.AddTransport(transport.POST{})
+	srv.Use(extension.Introspection{})
+	srv.Use(extension.FixedComplexityLimit(maxComplexity))
+	srv.Use(DepthLimit(maxDepth))
+	return client.New(srv)
+}
+
+func TestQueryLimits(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxDepth int
+		query    string
+		wantCode string
+	}{
+		{"board", 10, `{ board(id: "b1") { id title version widgets { id type x y width height configJson } } }`, ""},
+		{"standard introspection query", 10, introspection.Query, ""},
+		{"introspection ignores a low limit", 2, introspection.Query, ""},
+		{"type introspection", 1, `{ __type(name: "Board") { fields { name type { ofType { ofType { name } } } } } }`, ""},
+		{"too deep", 2, `{ board(id: "b1") { widgets { id } } }`, CodeDepthLimit},
+		{"too deep through a fragment", 2, `{ board(id: "b1") { ...F } } fragment F on Board { widgets { id } }`, CodeDepthLimit},
+		{"introspection beside a deep field", 2, `{ __typename __schema { queryType { name } } board(id: "b1") { widgets { id } } }`, CodeDepthLimit},
+		{"disabled", 0, `{ board(id: "b1") { widgets { id } } }`, ""},
+		{"too complex", 10, `{ a: boards { widgets { id type configJson } } b: boards { widgets { id type configJson } } }`, "COMPLEXITY_LIMIT_EXCEEDED"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := newLimitedClient(t, 1000, tt.maxDepth).RawPost(tt.query)
+			if err != nil {
+				t.Fatalf("post: %v", err)
+			}
+			var errs []struct {
+				Extensions map[string]interface{} `json:"extensions"`
+			}
+			if resp.Errors != nil {
+				if err := json.Unmarshal(resp.Errors, &errs); err != nil {
+					t.Fatalf("errors = %s", resp.Errors)
+				}
+			}
+			var code interface{} = ""
+			if len(errs) > 0 {
+				code = errs[0].Extensions["code"]
+			}
+			if code != tt.wantCode {
+				t.Fatalf("code = %v, want %q (errors: %s)", code, tt.wantCode, resp.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"miro-lite-standalone/backend/internal/board"
+	"miro-lite-standalone/backend/internal/ratelimit"
+)
+
+// NewErrorPresenter enveloppe ErrorPresenter et logge les erreurs internes
+// remontées par les resolvers (celles que le client ne voit que comme un
+// message générique). Les erreurs de parsing ou de validation de la requête ne
+// sont pas loggées.
+func NewErrorPresenter(logger *slog.Logger) graphql.ErrorPresenterFunc {
+	return func(ctx context.Context, err error) *gqlerror.Error {
+		gqlErr := ErrorPresenter(ctx, err)
+		if gqlErr.Err != nil && board.ErrorCode(err) == board.CodeInternal {
+			logger.ErrorContext(ctx, "graphql: resolver error", "path", gqlErr.Path.String(), "err", err)
+		}
+		return gqlErr
+	}
+}
+
+// ErrorPresenter traduit les erreurs du domaine board en erreurs GraphQL
+// typées (extensions.code + données utiles comme currentVersion).
+func ErrorPresenter(ctx context.Context, err error) *gqlerror.Error {
+	gqlErr := graphql.DefaultErrorPresenter(ctx, err)
+	var limited *ratelimit.Error
+	if errors.As(err, &limited) {
+		ext := rateLimitError(limited).Extensions
+		if gqlErr.Extensions == nil {
+			gqlErr.Extensions = make(map[string]interface{}, len(ext))
+		}
+		for k, v := range ext {
+			gqlErr.Extensions[k] = v
+		}
+		return gqlErr
+	}
+	code := board.ErrorCode(err)
+	if code == board.CodeInternal {
+		return gqlErr
+	}
+	extensions := map[string]interface{}{"code": code}
+
+	var notFound *board.NotFoundError
+	var conflict *board.VersionConflictError
+	var validation *board.ValidationError
+	switch {
+	case errors.As(err, &conflict):
+		extensions["boardId"] = conflict.BoardID
+		extensions["currentVersion"] = conflict.CurrentVersion
+		extensions["providedVersion"] = conflict.ProvidedVersion
+	case errors.As(err, &notFound):
+		extensions["boardId"] = notFound.BoardID
+		if notFound.WidgetID != "" {
+			extensions["widgetId"] = notFound.WidgetID
+		}
+	case errors.As(err, &validation):
+		extensions["field"] = validation.Field
+	}
+
+	if gqlErr.Extensions == nil {
+		gqlErr.Extensions = make(map[string]interface{}, len(extensions))
+	}
+	for k, v := range extensions {
+		gqlErr.Extensions[k] = v
+	}
+	return gqlErr
+}
+
+func rateLimitError(err *ratelimit.Error) *gqlerror.Error {
+	extensions := map[string]interface{}{"code": ratelimit.CodeRateLimited}
+	if err.RetryAfter > 0 {
+		extensions["retryAfter"] = err.RetryAfterSeconds()
+	}
+	return &gqlerror.Error{Message: err.Error(), Extensions: extensions}
+}
@@ -0,0 +1,91 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+
+	"miro-lite-standalone/backend/internal/board"
+	"miro-lite-standalone/backend/internal/ratelimit"
+)
+
+func newTestClient(t *testing.T, opts ...board.Option) (*client.Client, *board.Service) {
+	t.Helper()
+	svc, err := board.NewService("", opts...)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	srv := handler.New(NewExecutableSchema(NewConfig(NewResolver(svc, nil))))
+	srv.AddTransport(transport.POST{})
+	srv.SetErrorPresenter(ErrorPresenter)
+	return client.New(srv), svc
+}
+
+func TestErrorPresenter(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want map[string]interface{}
+	}{
+		{"not found", &board.NotFoundError{BoardID: "b1"}, map[string]interface{}{"code": board.CodeNotFound, "boardId": "b1"}},
+		{"widget not found", &board.NotFoundError{BoardID: "b1", WidgetID: "w1"}, map[string]interface{}{"code": board.CodeNotFound, "boardId": "b1", "widgetId": "w1"}},
+		{"version conflict", &board.VersionConflictError{BoardID: "b1", CurrentVersion: 3, ProvidedVersion: 2}, map[string]interface{}{"code": board.CodeVersionConflict, "boardId": "b1", "currentVersion": 3, "providedVersion": 2}},
+		{"validation", &board.ValidationError{Field: "widgets[0].id", Message: "must not be empty"}, map[string]interface{}{"code": board.CodeValidation, "field": "widgets[0].id"}},
+		{"wrapped", fmt.Errorf("save: %w", &board.ValidationError{Field: "title"}), map[string]interface{}{"code": board.CodeValidation, "field": "title"}},
+		{"rate limited", &ratelimit.Error{RetryAfter: 2 * time.Second}, map[string]interface{}{"code": ratelimit.CodeRateLimited, "retryAfter": 2}},
+		{"internal", fmt.Errorf("disk full"), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ErrorPresenter(context.Background(), tt.err)
+			if len(got.Extensions) == 0 && tt.want == nil {
+				return
+			}
+			if !reflect.DeepEqual(got.Extensions, tt.want) {
+				t.Fatalf("extensions = %#v, want %#v", got.Extensions, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorCodesOverGraphQL(t *testing.T) {
+	c, svc := newTestClient(t, board.WithMissingBoardPolicy(board.MissingBoardStrict))
+	if _, err := svc.CreateBoard(context.Background(), "b1", "t"); err != nil {
+		t.Fatalf("CreateBoard: %v", err)
+	}
+	tests := []struct {
+		name  string
+		query string
+		code  string
+	}{
+		{"missing board", `{ board(id: "nope") { id } }`, board.CodeNotFound},
+		{"stale version", `mutation { saveBoard(boardId: "b1", version: 7, widgets: []) { id } }`, board.CodeVersionConflict},
+		{"missing version", `mutation { saveBoard(boardId: "b1", version: 0, widgets: []) { id } }`, board.CodeVersionConflict},
+		{"negative version", `mutation { saveBoard(boardId: "b1", version: -1, widgets: []) { id } }`, board.CodeValidation},
+		{"empty widget id", `mutation { saveBoard(boardId: "b1", version: 1, widgets: [{id: "", type: "text", x: 0, y: 0, width: 1, height: 1, configJson: "{}"}]) { id } }`, board.CodeValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := c.RawPost(tt.query)
+			if err != nil {
+				t.Fatalf("post: %v", err)
+			}
+			var errs []struct {
+				Extensions map[string]interface{} `json:"extensions"`
+			}
+			if err := json.Unmarshal(resp.Errors, &errs); err != nil || len(errs) != 1 {
+				t.Fatalf("errors = %s", resp.Errors)
+			}
+			if got := errs[0].Extensions["code"]; got != tt.code {
+				t.Fatalf("code = %v, want %s (%s)", got, tt.code, resp.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package graph
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"strings"
+
+	"miro-lite-standalone/backend/internal/board"
+	"miro-lite-standalone/backend/internal/graph/model"
+	"miro-lite-standalone/backend/internal/render"
+)
+
+// ExportBoard dessine le board comme GET /api/boards/{id}/export.svg|png|pdf
+// et renvoie le fichier encodé en base64.
+func (r *mutationResolver) ExportBoard(ctx context.Context, boardID string, format model.ExportFormat, options *model.ExportOptions) (*model.BoardExport, error) {
+	opts, err := exportOptions(options)
+	if err != nil {
+		return nil, err
+	}
+	b, err := r.BoardService.ResolveBoard(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+	scene, err := render.Layout(b, opts...)
+	if err != nil {
+		return nil, err
+	}
+	f := render.Format(strings.ToLower(format.String()))
+	var buf bytes.Buffer
+	if err := render.Write(&buf, scene, f); err != nil {
+		return nil, err
+	}
+	return &model.BoardExport{
+		BoardID:   b.ID,
+		Version:   b.Version,
+		MediaType: f.MediaType(),
+		Filename:  b.ID + "." + string(f),
+		Data:      base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+func exportOptions(in *model.ExportOptions) ([]render.Option, error) {
+	if in == nil {
+		return nil, nil
+	}
+	var opts []render.Option
+	if v := in.Viewport; v != nil {
+		opts = append(opts, render.WithViewport(render.Rect{X: v.X, Y: v.Y, W: v.Width, H: v.Height}))
+	}
+	if in.Scale != nil {
+		opts = append(opts, render.WithScale(*in.Scale))
+	}
+	if in.Paper != nil {
+		paper, err := render.ParsePaper(*in.Paper)
+		if err != nil {
+			return nil, &board.ValidationError{Field: "paper", Message: err.Error()}
+		}
+		opts = append(opts, render.WithPaper(paper))
+	}
+	if in.Orientation != nil {
+		orientation, err := render.ParseOrientation(*in.Orientation)
+		if err != nil {
+			return nil, &board.ValidationError{Field: "orientation", Message: err.Error()}
+		}
+		opts = append(opts, render.WithOrientation(orientation))
+	}
+	if in.Margin != nil {
+		opts = append(opts, render.WithMargin(*in.Margin))
+	}
+	if in.FitToPage != nil {
+		opts = append(opts, render.WithFitToPage(*in.FitToPage))
+	}
+	return opts, nil
+}
@@ -44,15 +44,28 @@ type ComplexityRoot struct {
 		Widgets func(childComplexity int) int
 	}
 
+	BoardExport struct {
+		BoardID   func(childComplexity int) int
+		Data      func(childComplexity int) int
+		Filename  func(childComplexity int) int
+		MediaType func(childComplexity int) int
+		Version   func(childComplexity int) int
+	}
+
 	Mutation struct {
-		AddStickyNote func(childComplexity int, boardID string, item model.AddStickyNoteInput) int
-		CreateBoard   func(childComplexity int, title string) int
-		SaveBoard     func(childComplexity int, boardID string, version int, widgets []*model.WidgetInput) int
+		AddStickyNote   func(childComplexity int, boardID string, item model.AddStickyNoteInput) int
+		ApplyTextUpdate func(childComplexity int, boardID string, widgetID string, field string, update string) int
+		CreateBoard     func(childComplexity int, title string) int
+		ExportBoard     func(childComplexity int, boardID string, format model.ExportFormat, options *model.ExportOptions) int
+		Redo            func(childComplexity int, boardID string) int
+		SaveBoard       func(childComplexity int, boardID string, version int, widgets []*model.WidgetInput) int
+		Undo            func(childComplexity int, boardID string) int
 	}
 
 	Query struct {
-		Board  func(childComplexity int, id string) int
-		Boards func(childComplexity int) int
+		Board     func(childComplexity int, id string) int
+		Boards    func(childComplexity int) int
+		TextState func(childComplexity int, boardID string, widgetID string, field string) int
 	}
 
 	StickyNote struct {
@@ -68,7 +81,17 @@ type ComplexityRoot struct {
 	}
 
 	Subscription struct {
-		BoardUpdated func(childComplexity int, boardID string) int
+		BoardUpdated func(childComplexity int, boardID string, sinceVersion *int) int
+		TextUpdated  func(childComplexity int, boardID string, widgetID string, field string) int
+	}
+
+	TextUpdate struct {
+		BoardID  func(childComplexity int) int
+		Field    func(childComplexity int) int
+		Text     func(childComplexity int) int
+		Update   func(childComplexity int) int
+		Version  func(childComplexity int) int
+		WidgetID func(childComplexity int) int
 	}
 
 	WidgetPayload struct {
@@ -86,13 +109,19 @@ type MutationResolver interface {
 	CreateBoard(ctx context.Context, title string) (*model.Board, error)
 	AddStickyNote(ctx context.Context, boardID string, item model.AddStickyNoteInput) (*model.StickyNote, error)
 	SaveBoard(ctx context.Context, boardID string, version int, widgets []*model.WidgetInput) (*model.Board, error)
+	ApplyTextUpdate(ctx context.Context, boardID string, widgetID string, field string, update string) (*model.TextUpdate, error)
+	Undo(ctx context.Context, boardID string) (*model.Board, error)
+	Redo(ctx context.Context, boardID string) (*model.Board, error)
+	ExportBoard(ctx context.Context, boardID string, format model.ExportFormat, options *model.ExportOptions) (*model.BoardExport, error)
 }
 type QueryResolver interface {
 	Board(ctx context.Context, id string) (*model.Board, error)
 	Boards(ctx context.Context) ([]*model.Board, error)
+	TextState(ctx context.Context, boardID string, widgetID string, field string) (*model.TextUpdate, error)
 }
 type SubscriptionResolver interface {
-	BoardUpdated(ctx context.Context, boardID string) (<-chan *model.Board, error)
+	BoardUpdated(ctx context.Context, boardID string, sinceVersion *int) (<-chan *model.Board, error)
+	TextUpdated(ctx context.Context, boardID string, widgetID string, field string) (<-chan *model.TextUpdate, error)
 }
 
 type executableSchema graphql.ExecutableSchemaState[ResolverRoot, DirectiveRoot, ComplexityRoot]
@@ -134,6 +163,37 @@ func (e *executableSchema) Complexity(ctx context.Context, typeName, field strin
 
 		return e.ComplexityRoot.Board.Widgets(childComplexity), true
 
+	case "BoardExport.boardId":
+		if e.ComplexityRoot.BoardExport.BoardID == nil {
+			break
+		}
+
+		return e.ComplexityRoot.BoardExport.BoardID(childComplexity), true
+	case "BoardExport.data":
+		if e.ComplexityRoot.BoardExport.Data == nil {
+			break
+		}
+
+		return e.ComplexityRoot.BoardExport.Data(childComplexity), true
+	case "BoardExport.filename":
+		if e.ComplexityRoot.BoardExport.Filename == nil {
+			break
+		}
+
+		return e.ComplexityRoot.BoardExport.Filename(childComplexity), true
+	case "BoardExport.mediaType":
+		if e.ComplexityRoot.BoardExport.MediaType == nil {
+			break
+		}
+
+		return e.ComplexityRoot.BoardExport.MediaType(childComplexity), true
+	case "BoardExport.version":
+		if e.ComplexityRoot.BoardExport.Version == nil {
+			break
+		}
+
+		return e.ComplexityRoot.BoardExport.Version(childComplexity), true
+
 	case "Mutation.addStickyNote":
 		if e.ComplexityRoot.Mutation.AddStickyNote == nil {
 			break
@@ -145,6 +205,17 @@ func (e *executableSchema) Complexity(ctx context.Context, typeName, field strin
 		}
 
 		return e.ComplexityRoot.Mutation.AddStickyNote(childComplexity, args["boardId"].(string), args["item"].(model.AddStickyNoteInput)), true
+	case "Mutation.applyTextUpdate":
+		if e.ComplexityRoot.Mutation.ApplyTextUpdate == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_applyTextUpdate_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.ComplexityRoot.Mutation.ApplyTextUpdate(childComplexity, args["boardId"].(string), args["widgetId"].(string), args["field"].(string), args["update"].(string)), true
 	case "Mutation.createBoard":
 		if e.ComplexityRoot.Mutation.CreateBoard == nil {
 			break
@@ -156,6 +227,28 @@ func (e *executableSchema) Complexity(ctx context.Context, typeName, field strin
 		}
 
 		return e.ComplexityRoot.Mutation.CreateBoard(childComplexity, args["title"].(string)), true
+	case "Mutation.exportBoard":
+		if e.ComplexityRoot.Mutation.ExportBoard == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_exportBoard_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.ComplexityRoot.Mutation.ExportBoard(childComplexity, args["boardId"].(string), args["format"].(model.ExportFormat), args["options"].(*model.ExportOptions)), true
+	case "Mutation.redo":
+		if e.ComplexityRoot.Mutation.Redo == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_redo_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.ComplexityRoot.Mutation.Redo(childComplexity, args["boardId"].(string)), true
 	case "Mutation.saveBoard":
 		if e.ComplexityRoot.Mutation.SaveBoard == nil {
 			break
@@ -167,6 +260,17 @@ func (e *executableSchema) Complexity(ctx context.Context, typeName, field strin
 		}
 
 		return e.ComplexityRoot.Mutation.SaveBoard(childComplexity, args["boardId"].(string), args["version"].(int), args["widgets"].([]*model.WidgetInput)), true
+	case "Mutation.undo":
+		if e.ComplexityRoot.Mutation.Undo == nil {
+			break
+		}
+
+		args, err := ec.field_Mutation_undo_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.ComplexityRoot.Mutation.Undo(childComplexity, args["boardId"].(string)), true
 
 	case "Query.board":
 		if e.ComplexityRoot.Query.Board == nil {
@@ -186,6 +290,18 @@ func (e *executableSchema) Complexity(ctx context.Context, typeName, field strin
 
 		return e.ComplexityRoot.Query.Boards(childComplexity), true
 
+	case "Query.textState":
+		if e.ComplexityRoot.Query.TextState == nil {
+			break
+		}
+
+		args, err := ec.field_Query_textState_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.ComplexityRoot.Query.TextState(childComplexity, args["boardId"].(string), args["widgetId"].(string), args["field"].(string)), true
+
 	case "StickyNote.color":
 		if e.ComplexityRoot.StickyNote.Color == nil {
 			break
@@ -251,7 +367,55 @@ func (e *executableSchema) Complexity(ctx context.Context, typeName, field strin
 			return 0, false
 		}
 
-		return e.ComplexityRoot.Subscription.BoardUpdated(childComplexity, args["boardId"].(string)), true
+		return e.ComplexityRoot.Subscription.BoardUpdated(childComplexity, args["boardId"].(string), args["sinceVersion"].(*int)), true
+	case "Subscription.textUpdated":
+		if e.ComplexityRoot.Subscription.TextUpdated == nil {
+			break
+		}
+
+		args, err := ec.field_Subscription_textUpdated_args(ctx, rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.ComplexityRoot.Subscription.TextUpdated(childComplexity, args["boardId"].(string), args["widgetId"].(string), args["field"].(string)), true
+
+	case "TextUpdate.boardId":
+		if e.ComplexityRoot.TextUpdate.BoardID == nil {
+			break
+		}
+
+		return e.ComplexityRoot.TextUpdate.BoardID(childComplexity), true
+	case "TextUpdate.field":
+		if e.ComplexityRoot.TextUpdate.Field == nil {
+			break
+		}
+
+		return e.ComplexityRoot.TextUpdate.Field(childComplexity), true
+	case "TextUpdate.text":
+		if e.ComplexityRoot.TextUpdate.Text == nil {
+			break
+		}
+
+		return e.ComplexityRoot.TextUpdate.Text(childComplexity), true
+	case "TextUpdate.update":
+		if e.ComplexityRoot.TextUpdate.Update == nil {
+			break
+		}
+
+		return e.ComplexityRoot.TextUpdate.Update(childComplexity), true
+	case "TextUpdate.version":
+		if e.ComplexityRoot.TextUpdate.Version == nil {
+			break
+		}
+
+		return e.ComplexityRoot.TextUpdate.Version(childComplexity), true
+	case "TextUpdate.widgetId":
+		if e.ComplexityRoot.TextUpdate.WidgetID == nil {
+			break
+		}
+
+		return e.ComplexityRoot.TextUpdate.WidgetID(childComplexity), true
 
 	case "WidgetPayload.configJson":
 		if e.ComplexityRoot.WidgetPayload.ConfigJSON == nil {
@@ -305,6 +469,8 @@ func (e *executableSchema) Exec(ctx context.Context) graphql.ResponseHandler {
 	ec := newExecutionContext(opCtx, e, make(chan graphql.DeferredResult))
 	inputUnmarshalMap := graphql.BuildUnmarshalerMap(
 		ec.unmarshalInputAddStickyNoteInput,
+		ec.unmarshalInputExportOptions,
+		ec.unmarshalInputViewportInput,
 		ec.unmarshalInputWidgetInput,
 	)
 	first := true
@@ -433,6 +599,32 @@ func (ec *executionContext) field_Mutation_addStickyNote_args(ctx context.Contex
 	return args, nil
 }
 
+func (ec *executionContext) field_Mutation_applyTextUpdate_args(ctx context.Context, rawArgs map[string]any) (map[string]any, error) {
+	var err error
+	args := map[string]any{}
+	arg0, err := graphql.ProcessArgField(ctx, rawArgs, "boardId", ec.unmarshalNID2string)
+	if err != nil {
+		return nil, err
+	}
+	args["boardId"] = arg0
+	arg1, err := graphql.ProcessArgField(ctx, rawArgs, "widgetId", ec.unmarshalNID2string)
+	if err != nil {
+		return nil, err
+	}
+	args["widgetId"] = arg1
+	arg2, err := graphql.ProcessArgField(ctx, rawArgs, "field", ec.unmarshalNString2string)
+	if err != nil {
+		return nil, err
+	}
+	args["field"] = arg2
+	arg3, err := graphql.ProcessArgField(ctx, rawArgs, "update", ec.unmarshalNString2string)
+	if err != nil {
+		return nil, err
+	}
+	args["update"] = arg3
+	return args, nil
+}
+
 func (ec *executionContext) field_Mutation_createBoard_args(ctx context.Context, rawArgs map[string]any) (map[string]any, error) {
 	var err error
 	args := map[string]any{}
@@ -444,6 +636,38 @@ func (ec *executionContext) field_Mutation_createBoard_args(ctx context.Context,
 	return args, nil
 }
 
+func (ec *executionContext) field_Mutation_exportBoard_args(ctx context.Context, rawArgs map[string]any) (map[string]any, error) {
+	var err error
+	args := map[string]any{}
+	arg0, err := graphql.ProcessArgField(ctx, rawArgs, "boardId", ec.unmarshalNID2string)
+	if err != nil {
+		return nil, err
+	}
+	args["boardId"] = arg0
+	arg1, err := graphql.ProcessArgField(ctx, rawArgs, "format", ec.unmarshalNExportFormat2miroᚑliteᚑstandaloneᚋbackendᚋinternalᚋgraphᚋmodelᚐExportFormat)
+	if err != nil {
+		return nil, err
+	}
+	args["format"] = arg1
+	arg2, err := graphql.ProcessArgField(ctx, rawArgs, "options", ec.unmarshalOExportOptions2ᚖmiroᚑliteᚑstandaloneᚋbackendᚋinternalᚋgraphᚋmodelᚐExportOptions)
+	if err != nil {
+		return nil, err
+	}
+	args["options"] = arg2
+	return args, nil
+}
+
+func (ec *executionContext) field_Mutation_redo_args(ctx context.Context, rawArgs map[string]any) (map[string]any, error) {
+	var err error
+	args := map[string]any{}
+	arg0, err := graphql.ProcessArgField(ctx, rawArgs, "boardId", ec.unmarshalNID2string)
+	if err != nil {
+		return nil, err
+	}
+	args["boardId"] = arg0
+	return args, nil
+}
+
 func (ec *executionContext) field_Mutation_saveBoard_args(ctx context.Context, rawArgs map[string]any) (map[string]any, error) {
 	var err error
 	args := map[string]any{}
@@ -465,6 +689,17 @@ func (ec *executionContext) field_Mutation_saveBoard_args(ctx context.Context, r
 	return args, nil
 }
 
+func (ec *executionContext) field_Mutation_undo_args(ctx context.Context, rawArgs map[string]any) (map[string]any, error) {
+	var err error
+	args := map[string]any{}
+	arg0, err := graphql.ProcessArgField(ctx, rawArgs, "boardId", ec.unmarshalNID2string)
+	if err != nil {
+		return nil, err
+	}
+	args["boardId"] = arg0
+	return args, nil
+}
+
 func (ec *executionContext) field_Query___type_args(ctx context.Context, rawArgs map[string]any) (map[string]any, error) {
 	var err error
 	args := map[string]any{}
@@ -487,6 +722,27 @@ func (ec *executionContext) field_Query_board_args(ctx context.Context, rawArgs
 	return args, nil
 }
 
+func (ec *executionContext) field_Query_textState_args(ctx context.Context, rawArgs map[string]any) (map[string]any, error) {
+	var err error
+	args := map[string]any{}
+	arg0, err := graphql.ProcessArgField(ctx, rawArgs, "boardId", ec.unmarshalNID2string)
+	if err != nil {
+		return nil, err
+	}
+	args["boardId"] = arg0
+	arg1, err := graphql.ProcessArgField(ctx, rawArgs, "widgetId", ec.unmarshalNID2string)
+	if err != nil {
+		return nil, err
+	}
+	args["widgetId"] = arg1
+	arg2, err := graphql.ProcessArgField(ctx, rawArgs, "field", ec.unmarshalNString2string)
+	if err != nil {
+		return nil, err
+	}
+	args["field"] = arg2
+	return args, nil
+}
+
 func (ec *executionContext) field_Subscription_boardUpdated_args(ctx context.Context, rawArgs map[string]any) (map[string]any, error) {
 	var err error
 	args := map[string]any{}
@@ -495,6 +751,32 @@ func (ec *executionContext) field_Subscription_boardUpdated_args(ctx context.Con
 		return nil, err
 	}
 	args["boardId"] = arg0
+	arg1, err := graphql.ProcessArgField(ctx, rawArgs, "sinceVersion", ec.unmarshalOInt2ᚖint)
+	if err != nil {
+		return nil, err
+	}
+	args["sinceVersion"] = arg1
+	return args, nil
+}
+
+func (ec *executionContext) field_Subscription_textUpdated_args(ctx context.Context, rawArgs map[string]any) (map[string]any, error) {
+	var err error
+	args := map[string]any{}
+	arg0, err := graphql.ProcessArgField(ctx, rawArgs, "boardId", ec.unmarshalNID2string)
+	if err != nil {
+		return nil, err
+	}
+	args["boardId"] = arg0
+	arg1, err := graphql.ProcessArgField(ctx, rawArgs, "widgetId", ec.unmarshalNID2string)
+	if err != nil {
+		return nil, err
+	}
+	args["widgetId"] = arg1
+	arg2, err := graphql.ProcessArgField(ctx, rawArgs, "field", ec.unmarshalNString2string)
+	if err != nil {
+		return nil, err
+	}
+	args["field"] = arg2
 	return args, nil
 }
 
@@ -682,136 +964,489 @@ func (ec *executionContext) fieldContext_Board_widgets(_ context.Context, field
 	return fc, nil
 }
 
-func (ec *executionContext) _Mutation_createBoard(ctx context.Context, field graphql.CollectedField) (ret graphql.Marshaler) {
+func (ec *executionContext) _BoardExport_boardId(ctx context.Context, field graphql.CollectedField, obj *model.BoardExport) (ret graphql.Marshaler) {
 	return graphql.ResolveField(
 		ctx,
 		ec.OperationContext,
 		field,
-		ec.fieldContext_Mutation_createBoard,
+		ec.fieldContext_BoardExport_boardId,
 		func(ctx context.Context) (any, error) {
-			fc := graphql.GetFieldContext(ctx)
-			return ec.Resolvers.Mutation().CreateBoard(ctx, fc.Args["title"].(string))
+			return obj.BoardID, nil
 		},
 		nil,
-		ec.marshalNBoard2ᚖmiroᚑliteᚑstandaloneᚋbackendᚋinternalᚋgraphᚋmodelᚐBoard,
+		ec.marshalNID2string,
 		true,
 		true,
 	)
 }
 
-func (ec *executionContext) fieldContext_Mutation_createBoard(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+func (ec *executionContext) fieldContext_BoardExport_boardId(_ context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
 	fc = &graphql.FieldContext{
-		Object:     "Mutation",
+		Object:     "BoardExport",
 		Field:      field,
-		IsMethod:   true,
-		IsResolver: true,
+		IsMethod:   false,
+		IsResolver: false,
 		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
-			switch field.Name {
-			case "id":
-				return ec.fieldContext_Board_id(ctx, field)
-			case "title":
-				return ec.fieldContext_Board_title(ctx, field)
-			case "version":
-				return ec.fieldContext_Board_version(ctx, field)
-			case "widgets":
-				return ec.fieldContext_Board_widgets(ctx, field)
-			}
-			return nil, fmt.Errorf("no field named %q was found under type Board", field.Name)
+			return nil, errors.New("field of type ID does not have child fields")
 		},
 	}
-	defer func() {
-		if r := recover(); r != nil {
-			err = ec.Recover(ctx, r)
-			ec.Error(ctx, err)
-		}
-	}()
-	ctx = graphql.WithFieldContext(ctx, fc)
-	if fc.Args, err = ec.field_Mutation_createBoard_args(ctx, field.ArgumentMap(ec.Variables)); err != nil {
-		ec.Error(ctx, err)
-		return fc, err
-	}
 	return fc, nil
 }
 
-func (ec *executionContext) _Mutation_addStickyNote(ctx context.Context, field graphql.CollectedField) (ret graphql.Marshaler) {
+func (ec *executionContext) _BoardExport_version(ctx context.Context, field graphql.CollectedField, obj *model.BoardExport) (ret graphql.Marshaler) {
 	return graphql.ResolveField(
 		ctx,
 		ec.OperationContext,
 		field,
-		ec.fieldContext_Mutation_addStickyNote,
+		ec.fieldContext_BoardExport_version,
 		func(ctx context.Context) (any, error) {
-			fc := graphql.GetFieldContext(ctx)
-			return ec.Resolvers.Mutation().AddStickyNote(ctx, fc.Args["boardId"].(string), fc.Args["item"].(model.AddStickyNoteInput))
+			return obj.Version, nil
 		},
 		nil,
-		ec.marshalNStickyNote2ᚖmiroᚑliteᚑstandaloneᚋbackendᚋinternalᚋgraphᚋmodelᚐStickyNote,
+		ec.marshalNInt2int,
 		true,
 		true,
 	)
 }
 
-func (ec *executionContext) fieldContext_Mutation_addStickyNote(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+func (ec *executionContext) fieldContext_BoardExport_version(_ context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
 	fc = &graphql.FieldContext{
-		Object:     "Mutation",
+		Object:     "BoardExport",
 		Field:      field,
-		IsMethod:   true,
-		IsResolver: true,
+		IsMethod:   false,
+		IsResolver: false,
 		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
-			switch field.Name {
-			case "id":
-				return ec.fieldContext_StickyNote_id(ctx, field)
-			case "x":
-				return ec.fieldContext_StickyNote_x(ctx, field)
-			case "y":
-				return ec.fieldContext_StickyNote_y(ctx, field)
-			case "width":
-				return ec.fieldContext_StickyNote_width(ctx, field)
-			case "height":
-				return ec.fieldContext_StickyNote_height(ctx, field)
-			case "rotation":
-				return ec.fieldContext_StickyNote_rotation(ctx, field)
-			case "zIndex":
-				return ec.fieldContext_StickyNote_zIndex(ctx, field)
-			case "text":
-				return ec.fieldContext_StickyNote_text(ctx, field)
-			case "color":
-				return ec.fieldContext_StickyNote_color(ctx, field)
-			}
-			return nil, fmt.Errorf("no field named %q was found under type StickyNote", field.Name)
+			return nil, errors.New("field of type Int does not have child fields")
 		},
 	}
-	defer func() {
-		if r := recover(); r != nil {
-			err = ec.Recover(ctx, r)
-			ec.Error(ctx, err)
-		}
-	}()
-	ctx = graphql.WithFieldContext(ctx, fc)
-	if fc.Args, err = ec.field_Mutation_addStickyNote_args(ctx, field.ArgumentMap(ec.Variables)); err != nil {
-		ec.Error(ctx, err)
-		return fc, err
-	}
 	return fc, nil
 }
 
-func (ec *executionContext) _Mutation_saveBoard(ctx context.Context, field graphql.CollectedField) (ret graphql.Marshaler) {
+func (ec *executionContext) _BoardExport_mediaType(ctx context.Context, field graphql.CollectedField, obj *model.BoardExport) (ret graphql.Marshaler) {
 	return graphql.ResolveField(
 		ctx,
 		ec.OperationContext,
 		field,
-		ec.fieldContext_Mutation_saveBoard,
+		ec.fieldContext_BoardExport_mediaType,
+		func(ctx context.Context) (any, error) {
+			return obj.MediaType, nil
+		},
+		nil,
+		ec.marshalNString2string,
+		true,
+		true,
+	)
+}
+
+func (ec *executionContext) fieldContext_BoardExport_mediaType(_ context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "BoardExport",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type String does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _BoardExport_filename(ctx context.Context, field graphql.CollectedField, obj *model.BoardExport) (ret graphql.Marshaler) {
+	return graphql.ResolveField(
+		ctx,
+		ec.OperationContext,
+		field,
+		ec.fieldContext_BoardExport_filename,
+		func(ctx context.Context) (any, error) {
+			return obj.Filename, nil
+		},
+		nil,
+		ec.marshalNString2string,
+		true,
+		true,
+	)
+}
+
+func (ec *executionContext) fieldContext_BoardExport_filename(_ context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "BoardExport",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type String does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _BoardExport_data(ctx context.Context, field graphql.CollectedField, obj *model.BoardExport) (ret graphql.Marshaler) {
+	return graphql.ResolveField(
+		ctx,
+		ec.OperationContext,
+		field,
+		ec.fieldContext_BoardExport_data,
+		func(ctx context.Context) (any, error) {
+			return obj.Data, nil
+		},
+		nil,
+		ec.marshalNString2string,
+		true,
+		true,
+	)
+}
+
+func (ec *executionContext) fieldContext_BoardExport_data(_ context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "BoardExport",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type String does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _Mutation_createBoard(ctx context.Context, field graphql.CollectedField) (ret graphql.Marshaler) {
+	return graphql.ResolveField(
+		ctx,
+		ec.OperationContext,
+		field,
+		ec.fieldContext_Mutation_createBoard,
+		func(ctx context.Context) (any, error) {
+			fc := graphql.GetFieldContext(ctx)
+			return ec.Resolvers.Mutation().CreateBoard(ctx, fc.Args["title"].(string))
+		},
+		nil,
+		ec.marshalNBoard2ᚖmiroᚑliteᚑstandaloneᚋbackendᚋinternalᚋgraphᚋmodelᚐBoard,
+		true,
+		true,
+	)
+}
+
+func (ec *executionContext) fieldContext_Mutation_createBoard(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Mutation",
+		Field:      field,
+		IsMethod:   true,
+		IsResolver: true,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			switch field.Name {
+			case "id":
+				return ec.fieldContext_Board_id(ctx, field)
+			case "title":
+				return ec.fieldContext_Board_title(ctx, field)
+			case "version":
+				return ec.fieldContext_Board_version(ctx, field)
+			case "widgets":
+				return ec.fieldContext_Board_widgets(ctx, field)
+			}
+			return nil, fmt.Errorf("no field named %q was found under type Board", field.Name)
+		},
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = ec.Recover(ctx, r)
+			ec.Error(ctx, err)
+		}
+	}()
+	ctx = graphql.WithFieldContext(ctx, fc)
+	if fc.Args, err = ec.field_Mutation_createBoard_args(ctx, field.ArgumentMap(ec.Variables)); err != nil {
+		ec.Error(ctx, err)
+		return fc, err
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _Mutation_addStickyNote(ctx context.Context, field graphql.CollectedField) (ret graphql.Marshaler) {
+	return graphql.ResolveField(
+		ctx,
+		ec.OperationContext,
+		field,
+		ec.fieldContext_Mutation_addStickyNote,
+		func(ctx context.Context) (any, error) {
+			fc := graphql.GetFieldContext(ctx)
+			return ec.Resolvers.Mutation().AddStickyNote(ctx, fc.Args["boardId"].(string), fc.Args["item"].(model.AddStickyNoteInput))
+		},
+		nil,
+		ec.marshalNStickyNote2ᚖmiroᚑliteᚑstandaloneᚋbackendᚋinternalᚋgraphᚋmodelᚐStickyNote,
+		true,
+		true,
+	)
+}
+
+func (ec *executionContext) fieldContext_Mutation_addStickyNote(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Mutation",
+		Field:      field,
+		IsMethod:   true,
+		IsResolver: true,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			switch field.Name {
+			case "id":
+				return ec.fieldContext_StickyNote_id(ctx, field)
+			case "x":
+				return ec.fieldContext_StickyNote_x(ctx, field)
+			case "y":
+				return ec.fieldContext_StickyNote_y(ctx, field)
+			case "width":
+				return ec.fieldContext_StickyNote_width(ctx, field)
+			case "height":
+				return ec.fieldContext_StickyNote_height(ctx, field)
+			case "rotation":
+				return ec.fieldContext_StickyNote_rotation(ctx, field)
+			case "zIndex":
+				return ec.fieldContext_StickyNote_zIndex(ctx, field)
+			case "text":
+				return ec.fieldContext_StickyNote_text(ctx, field)
+			case "color":
+				return ec.fieldContext_StickyNote_color(ctx, field)
+			}
+			return nil, fmt.Errorf("no field named %q was found under type StickyNote", field.Name)
+		},
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = ec.Recover(ctx, r)
+			ec.Error(ctx, err)
+		}
+	}()
+	ctx = graphql.WithFieldContext(ctx, fc)
+	if fc.Args, err = ec.field_Mutation_addStickyNote_args(ctx, field.ArgumentMap(ec.Variables)); err != nil {
+		ec.Error(ctx, err)
+		return fc, err
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _Mutation_saveBoard(ctx context.Context, field graphql.CollectedField) (ret graphql.Marshaler) {
+	return graphql.ResolveField(
+		ctx,
+		ec.OperationContext,
+		field,
+		ec.fieldContext_Mutation_saveBoard,
+		func(ctx context.Context) (any, error) {
+			fc := graphql.GetFieldContext(ctx)
+			return ec.Resolvers.Mutation().SaveBoard(ctx, fc.Args["boardId"].(string), fc.Args["version"].(int), fc.Args["widgets"].([]*model.WidgetInput))
+		},
+		nil,
+		ec.marshalNBoard2ᚖmiroᚑliteᚑstandaloneᚋbackendᚋinternalᚋgraphᚋmodelᚐBoard,
+		true,
+		true,
+	)
+}
+
+func (ec *executionContext) fieldContext_Mutation_saveBoard(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Mutation",
+		Field:      field,
+		IsMethod:   true,
+		IsResolver: true,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			switch field.Name {
+			case "id":
+				return ec.fieldContext_Board_id(ctx, field)
+			case "title":
+				return ec.fieldContext_Board_title(ctx, field)
+			case "version":
+				return ec.fieldContext_Board_version(ctx, field)
+			case "widgets":
+				return ec.fieldContext_Board_widgets(ctx, field)
+			}
+			return nil, fmt.Errorf("no field named %q was found under type Board", field.Name)
+		},
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = ec.Recover(ctx, r)
+			ec.Error(ctx, err)
+		}
+	}()
+	ctx = graphql.WithFieldContext(ctx, fc)
+	if fc.Args, err = ec.field_Mutation_saveBoard_args(ctx, field.ArgumentMap(ec.Variables)); err != nil {
+		ec.Error(ctx, err)
+		return fc, err
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _Mutation_applyTextUpdate(ctx context.Context, field graphql.CollectedField) (ret graphql.Marshaler) {
+	return graphql.ResolveField(
+		ctx,
+		ec.OperationContext,
+		field,
+		ec.fieldContext_Mutation_applyTextUpdate,
+		func(ctx context.Context) (any, error) {
+			fc := graphql.GetFieldContext(ctx)
+			return ec.Resolvers.Mutation().ApplyTextUpdate(ctx, fc.Args["boardId"].(string), fc.Args["widgetId"].(string), fc.Args["field"].(string), fc.Args["update"].(string))
+		},
+		nil,
+		ec.marshalNTextUpdate2ᚖmiroᚑliteᚑstandaloneᚋbackendᚋinternalᚋgraphᚋmodelᚐTextUpdate,
+		true,
+		true,
+	)
+}
+
+func (ec *executionContext) fieldContext_Mutation_applyTextUpdate(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Mutation",
+		Field:      field,
+		IsMethod:   true,
+		IsResolver: true,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			switch field.Name {
+			case "boardId":
+				return ec.fieldContext_TextUpdate_boardId(ctx, field)
+			case "widgetId":
+				return ec.fieldContext_TextUpdate_widgetId(ctx, field)
+			case "field":
+				return ec.fieldContext_TextUpdate_field(ctx, field)
+			case "version":
+				return ec.fieldContext_TextUpdate_version(ctx, field)
+			case "update":
+				return ec.fieldContext_TextUpdate_update(ctx, field)
+			case "text":
+				return ec.fieldContext_TextUpdate_text(ctx, field)
+			}
+			return nil, fmt.Errorf("no field named %q was found under type TextUpdate", field.Name)
+		},
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = ec.Recover(ctx, r)
+			ec.Error(ctx, err)
+		}
+	}()
+	ctx = graphql.WithFieldContext(ctx, fc)
+	if fc.Args, err = ec.field_Mutation_applyTextUpdate_args(ctx, field.ArgumentMap(ec.Variables)); err != nil {
+		ec.Error(ctx, err)
+		return fc, err
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _Mutation_undo(ctx context.Context, field graphql.CollectedField) (ret graphql.Marshaler) {
+	return graphql.ResolveField(
+		ctx,
+		ec.OperationContext,
+		field,
+		ec.fieldContext_Mutation_undo,
+		func(ctx context.Context) (any, error) {
+			fc := graphql.GetFieldContext(ctx)
+			return ec.Resolvers.Mutation().Undo(ctx, fc.Args["boardId"].(string))
+		},
+		nil,
+		ec.marshalNBoard2ᚖmiroᚑliteᚑstandaloneᚋbackendᚋinternalᚋgraphᚋmodelᚐBoard,
+		true,
+		true,
+	)
+}
+
+func (ec *executionContext) fieldContext_Mutation_undo(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Mutation",
+		Field:      field,
+		IsMethod:   true,
+		IsResolver: true,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			switch field.Name {
+			case "id":
+				return ec.fieldContext_Board_id(ctx, field)
+			case "title":
+				return ec.fieldContext_Board_title(ctx, field)
+			case "version":
+				return ec.fieldContext_Board_version(ctx, field)
+			case "widgets":
+				return ec.fieldContext_Board_widgets(ctx, field)
+			}
+			return nil, fmt.Errorf("no field named %q was found under type Board", field.Name)
+		},
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = ec.Recover(ctx, r)
+			ec.Error(ctx, err)
+		}
+	}()
+	ctx = graphql.WithFieldContext(ctx, fc)
+	if fc.Args, err = ec.field_Mutation_undo_args(ctx, field.ArgumentMap(ec.Variables)); err != nil {
+		ec.Error(ctx, err)
+		return fc, err
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _Mutation_redo(ctx context.Context, field graphql.CollectedField) (ret graphql.Marshaler) {
+	return graphql.ResolveField(
+		ctx,
+		ec.OperationContext,
+		field,
+		ec.fieldContext_Mutation_redo,
+		func(ctx context.Context) (any, error) {
+			fc := graphql.GetFieldContext(ctx)
+			return ec.Resolvers.Mutation().Redo(ctx, fc.Args["boardId"].(string))
+		},
+		nil,
+		ec.marshalNBoard2ᚖmiroᚑliteᚑstandaloneᚋbackendᚋinternalᚋgraphᚋmodelᚐBoard,
+		true,
+		true,
+	)
+}
+
+func (ec *executionContext) fieldContext_Mutation_redo(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Mutation",
+		Field:      field,
+		IsMethod:   true,
+		IsResolver: true,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			switch field.Name {
+			case "id":
+				return ec.fieldContext_Board_id(ctx, field)
+			case "title":
+				return ec.fieldContext_Board_title(ctx, field)
+			case "version":
+				return ec.fieldContext_Board_version(ctx, field)
+			case "widgets":
+				return ec.fieldContext_Board_widgets(ctx, field)
+			}
+			return nil, fmt.Errorf("no field named %q was found under type Board", field.Name)
+		},
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = ec.Recover(ctx, r)
+			ec.Error(ctx, err)
+		}
+	}()
+	ctx = graphql.WithFieldContext(ctx, fc)
+	if fc.Args, err = ec.field_Mutation_redo_args(ctx, field.ArgumentMap(ec.Variables)); err != nil {
+		ec.Error(ctx, err)
+		return fc, err
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _Mutation_exportBoard(ctx context.Context, field graphql.CollectedField) (ret graphql.Marshaler) {
+	return graphql.ResolveField(
+		ctx,
+		ec.OperationContext,
+		field,
+		ec.fieldContext_Mutation_exportBoard,
 		func(ctx context.Context) (any, error) {
 			fc := graphql.GetFieldContext(ctx)
-			return ec.Resolvers.Mutation().SaveBoard(ctx, fc.Args["boardId"].(string), fc.Args["version"].(int), fc.Args["widgets"].([]*model.WidgetInput))
+			return ec.Resolvers.Mutation().ExportBoard(ctx, fc.Args["boardId"].(string), fc.Args["format"].(model.ExportFormat), fc.Args["options"].(*model.ExportOptions))
 		},
 		nil,
-		ec.marshalNBoard2ᚖmiroᚑliteᚑstandaloneᚋbackendᚋinternalᚋgraphᚋmodelᚐBoard,
+		ec.marshalNBoardExport2ᚖmiroᚑliteᚑstandaloneᚋbackendᚋinternalᚋgraphᚋmodelᚐBoardExport,
 		true,
 		true,
 	)
 }
 
-func (ec *executionContext) fieldContext_Mutation_saveBoard(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+func (ec *executionContext) fieldContext_Mutation_exportBoard(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
 	fc = &graphql.FieldContext{
 		Object:     "Mutation",
 		Field:      field,
@@ -819,16 +1454,18 @@ func (ec *executionContext) fieldContext_Mutation_saveBoard(ctx context.Context,
 		IsResolver: true,
 		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
 			switch field.Name {
-			case "id":
-				return ec.fieldContext_Board_id(ctx, field)
-			case "title":
-				return ec.fieldContext_Board_title(ctx, field)
+			case "boardId":
+				return ec.fieldContext_BoardExport_boardId(ctx, field)
 			case "version":
-				return ec.fieldContext_Board_version(ctx, field)
-			case "widgets":
-				return ec.fieldContext_Board_widgets(ctx, field)
+				return ec.fieldContext_BoardExport_version(ctx, field)
+			case "mediaType":
+				return ec.fieldContext_BoardExport_mediaType(ctx, field)
+			case "filename":
+				return ec.fieldContext_BoardExport_filename(ctx, field)
+			case "data":
+				return ec.fieldContext_BoardExport_data(ctx, field)
 			}
-			return nil, fmt.Errorf("no field named %q was found under type Board", field.Name)
+			return nil, fmt.Errorf("no field named %q was found under type BoardExport", field.Name)
 		},
 	}
 	defer func() {
@@ -838,7 +1475,7 @@ func (ec *executionContext) fieldContext_Mutation_saveBoard(ctx context.Context,
 		}
 	}()
 	ctx = graphql.WithFieldContext(ctx, fc)
-	if fc.Args, err = ec.field_Mutation_saveBoard_args(ctx, field.ArgumentMap(ec.Variables)); err != nil {
+	if fc.Args, err = ec.field_Mutation_exportBoard_args(ctx, field.ArgumentMap(ec.Variables)); err != nil {
 		ec.Error(ctx, err)
 		return fc, err
 	}
@@ -935,6 +1572,61 @@ func (ec *executionContext) fieldContext_Query_boards(_ context.Context, field g
 	return fc, nil
 }
 
+func (ec *executionContext) _Query_textState(ctx context.Context, field graphql.CollectedField) (ret graphql.Marshaler) {
+	return graphql.ResolveField(
+		ctx,
+		ec.OperationContext,
+		field,
+		ec.fieldContext_Query_textState,
+		func(ctx context.Context) (any, error) {
+			fc := graphql.GetFieldContext(ctx)
+			return ec.Resolvers.Query().TextState(ctx, fc.Args["boardId"].(string), fc.Args["widgetId"].(string), fc.Args["field"].(string))
+		},
+		nil,
+		ec.marshalNTextUpdate2ᚖmiroᚑliteᚑstandaloneᚋbackendᚋinternalᚋgraphᚋmodelᚐTextUpdate,
+		true,
+		true,
+	)
+}
+
+func (ec *executionContext) fieldContext_Query_textState(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Query",
+		Field:      field,
+		IsMethod:   true,
+		IsResolver: true,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			switch field.Name {
+			case "boardId":
+				return ec.fieldContext_TextUpdate_boardId(ctx, field)
+			case "widgetId":
+				return ec.fieldContext_TextUpdate_widgetId(ctx, field)
+			case "field":
+				return ec.fieldContext_TextUpdate_field(ctx, field)
+			case "version":
+				return ec.fieldContext_TextUpdate_version(ctx, field)
+			case "update":
+				return ec.fieldContext_TextUpdate_update(ctx, field)
+			case "text":
+				return ec.fieldContext_TextUpdate_text(ctx, field)
+			}
+			return nil, fmt.Errorf("no field named %q was found under type TextUpdate", field.Name)
+		},
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = ec.Recover(ctx, r)
+			ec.Error(ctx, err)
+		}
+	}()
+	ctx = graphql.WithFieldContext(ctx, fc)
+	if fc.Args, err = ec.field_Query_textState_args(ctx, field.ArgumentMap(ec.Variables)); err != nil {
+		ec.Error(ctx, err)
+		return fc, err
+	}
+	return fc, nil
+}
+
 func (ec *executionContext) _Query___type(ctx context.Context, field graphql.CollectedField) (ret graphql.Marshaler) {
 	return graphql.ResolveField(
 		ctx,
@@ -1312,7 +2004,7 @@ func (ec *executionContext) _Subscription_boardUpdated(ctx context.Context, fiel
 		ec.fieldContext_Subscription_boardUpdated,
 		func(ctx context.Context) (any, error) {
 			fc := graphql.GetFieldContext(ctx)
-			return ec.Resolvers.Subscription().BoardUpdated(ctx, fc.Args["boardId"].(string))
+			return ec.Resolvers.Subscription().BoardUpdated(ctx, fc.Args["boardId"].(string), fc.Args["sinceVersion"].(*int))
 		},
 		nil,
 		ec.marshalNBoard2ᚖmiroᚑliteᚑstandaloneᚋbackendᚋinternalᚋgraphᚋmodelᚐBoard,
@@ -1355,6 +2047,235 @@ func (ec *executionContext) fieldContext_Subscription_boardUpdated(ctx context.C
 	return fc, nil
 }
 
+func (ec *executionContext) _Subscription_textUpdated(ctx context.Context, field graphql.CollectedField) (ret func(ctx context.Context) graphql.Marshaler) {
+	return graphql.ResolveFieldStream(
+		ctx,
+		ec.OperationContext,
+		field,
+		ec.fieldContext_Subscription_textUpdated,
+		func(ctx context.Context) (any, error) {
+			fc := graphql.GetFieldContext(ctx)
+			return ec.Resolvers.Subscription().TextUpdated(ctx, fc.Args["boardId"].(string), fc.Args["widgetId"].(string), fc.Args["field"].(string))
+		},
+		nil,
+		ec.marshalNTextUpdate2ᚖmiroᚑliteᚑstandaloneᚋbackendᚋinternalᚋgraphᚋmodelᚐTextUpdate,
+		true,
+		true,
+	)
+}
+
+func (ec *executionContext) fieldContext_Subscription_textUpdated(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Subscription",
+		Field:      field,
+		IsMethod:   true,
+		IsResolver: true,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			switch field.Name {
+			case "boardId":
+				return ec.fieldContext_TextUpdate_boardId(ctx, field)
+			case "widgetId":
+				return ec.fieldContext_TextUpdate_widgetId(ctx, field)
+			case "field":
+				return ec.fieldContext_TextUpdate_field(ctx, field)
+			case "version":
+				return ec.fieldContext_TextUpdate_version(ctx, field)
+			case "update":
+				return ec.fieldContext_TextUpdate_update(ctx, field)
+			case "text":
+				return ec.fieldContext_TextUpdate_text(ctx, field)
+			}
+			return nil, fmt.Errorf("no field named %q was found under type TextUpdate", field.Name)
+		},
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = ec.Recover(ctx, r)
+			ec.Error(ctx, err)
+		}
+	}()
+	ctx = graphql.WithFieldContext(ctx, fc)
+	if fc.Args, err = ec.field_Subscription_textUpdated_args(ctx, field.ArgumentMap(ec.Variables)); err != nil {
+		ec.Error(ctx, err)
+		return fc, err
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _TextUpdate_boardId(ctx context.Context, field graphql.CollectedField, obj *model.TextUpdate) (ret graphql.Marshaler) {
+	return graphql.ResolveField(
+		ctx,
+		ec.OperationContext,
+		field,
+		ec.fieldContext_TextUpdate_boardId,
+		func(ctx context.Context) (any, error) {
+			return obj.BoardID, nil
+		},
+		nil,
+		ec.marshalNID2string,
+		true,
+		true,
+	)
+}
+
+func (ec *executionContext) fieldContext_TextUpdate_boardId(_ context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "TextUpdate",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type ID does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _TextUpdate_widgetId(ctx context.Context, field graphql.CollectedField, obj *model.TextUpdate) (ret graphql.Marshaler) {
+	return graphql.ResolveField(
+		ctx,
+		ec.OperationContext,
+		field,
+		ec.fieldContext_TextUpdate_widgetId,
+		func(ctx context.Context) (any, error) {
+			return obj.WidgetID, nil
+		},
+		nil,
+		ec.marshalNID2string,
+		true,
+		true,
+	)
+}
+
+func (ec *executionContext) fieldContext_TextUpdate_widgetId(_ context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "TextUpdate",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type ID does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _TextUpdate_field(ctx context.Context, field graphql.CollectedField, obj *model.TextUpdate) (ret graphql.Marshaler) {
+	return graphql.ResolveField(
+		ctx,
+		ec.OperationContext,
+		field,
+		ec.fieldContext_TextUpdate_field,
+		func(ctx context.Context) (any, error) {
+			return obj.Field, nil
+		},
+		nil,
+		ec.marshalNString2string,
+		true,
+		true,
+	)
+}
+
+func (ec *executionContext) fieldContext_TextUpdate_field(_ context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "TextUpdate",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type String does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _TextUpdate_version(ctx context.Context, field graphql.CollectedField, obj *model.TextUpdate) (ret graphql.Marshaler) {
+	return graphql.ResolveField(
+		ctx,
+		ec.OperationContext,
+		field,
+		ec.fieldContext_TextUpdate_version,
+		func(ctx context.Context) (any, error) {
+			return obj.Version, nil
+		},
+		nil,
+		ec.marshalNInt2int,
+		true,
+		true,
+	)
+}
+
+func (ec *executionContext) fieldContext_TextUpdate_version(_ context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "TextUpdate",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type Int does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _TextUpdate_update(ctx context.Context, field graphql.CollectedField, obj *model.TextUpdate) (ret graphql.Marshaler) {
+	return graphql.ResolveField(
+		ctx,
+		ec.OperationContext,
+		field,
+		ec.fieldContext_TextUpdate_update,
+		func(ctx context.Context) (any, error) {
+			return obj.Update, nil
+		},
+		nil,
+		ec.marshalNString2string,
+		true,
+		true,
+	)
+}
+
+func (ec *executionContext) fieldContext_TextUpdate_update(_ context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "TextUpdate",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type String does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _TextUpdate_text(ctx context.Context, field graphql.CollectedField, obj *model.TextUpdate) (ret graphql.Marshaler) {
+	return graphql.ResolveField(
+		ctx,
+		ec.OperationContext,
+		field,
+		ec.fieldContext_TextUpdate_text,
+		func(ctx context.Context) (any, error) {
+			return obj.Text, nil
+		},
+		nil,
+		ec.marshalNString2string,
+		true,
+		true,
+	)
+}
+
+func (ec *executionContext) fieldContext_TextUpdate_text(_ context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "TextUpdate",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type String does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
 func (ec *executionContext) _WidgetPayload_id(ctx context.Context, field graphql.CollectedField, obj *model.WidgetPayload) (ret graphql.Marshaler) {
 	return graphql.ResolveField(
 		ctx,
@@ -3051,6 +3972,114 @@ func (ec *executionContext) unmarshalInputAddStickyNoteInput(ctx context.Context
 	return it, nil
 }
 
+func (ec *executionContext) unmarshalInputExportOptions(ctx context.Context, obj any) (model.ExportOptions, error) {
+	var it model.ExportOptions
+	asMap := map[string]any{}
+	for k, v := range obj.(map[string]any) {
+		asMap[k] = v
+	}
+
+	fieldsInOrder := [...]string{"viewport", "scale", "paper", "orientation", "margin", "fitToPage"}
+	for _, k := range fieldsInOrder {
+		v, ok := asMap[k]
+		if !ok {
+			continue
+		}
+		switch k {
+		case "viewport":
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("viewport"))
+			data, err := ec.unmarshalOViewportInput2ᚖmiroᚑliteᚑstandaloneᚋbackendᚋinternalᚋgraphᚋmodelᚐViewportInput(ctx, v)
+			if err != nil {
+				return it, err
+			}
+			it.Viewport = data
+		case "scale":
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("scale"))
+			data, err := ec.unmarshalOFloat2ᚖfloat64(ctx, v)
+			if err != nil {
+				return it, err
+			}
+			it.Scale = data
+		case "paper":
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("paper"))
+			data, err := ec.unmarshalOString2ᚖstring(ctx, v)
+			if err != nil {
+				return it, err
+			}
+			it.Paper = data
+		case "orientation":
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("orientation"))
+			data, err := ec.unmarshalOString2ᚖstring(ctx, v)
+			if err != nil {
+				return it, err
+			}
+			it.Orientation = data
+		case "margin":
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("margin"))
+			data, err := ec.unmarshalOFloat2ᚖfloat64(ctx, v)
+			if err != nil {
+				return it, err
+			}
+			it.Margin = data
+		case "fitToPage":
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("fitToPage"))
+			data, err := ec.unmarshalOBoolean2ᚖbool(ctx, v)
+			if err != nil {
+				return it, err
+			}
+			it.FitToPage = data
+		}
+	}
+	return it, nil
+}
+
+func (ec *executionContext) unmarshalInputViewportInput(ctx context.Context, obj any) (model.ViewportInput, error) {
+	var it model.ViewportInput
+	asMap := map[string]any{}
+	for k, v := range obj.(map[string]any) {
+		asMap[k] = v
+	}
+
+	fieldsInOrder := [...]string{"x", "y", "width", "height"}
+	for _, k := range fieldsInOrder {
+		v, ok := asMap[k]
+		if !ok {
+			continue
+		}
+		switch k {
+		case "x":
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("x"))
+			data, err := ec.unmarshalNFloat2float64(ctx, v)
+			if err != nil {
+				return it, err
+			}
+			it.X = data
+		case "y":
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("y"))
+			data, err := ec.unmarshalNFloat2float64(ctx, v)
+			if err != nil {
+				return it, err
+			}
+			it.Y = data
+		case "width":
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("width"))
+			data, err := ec.unmarshalNFloat2float64(ctx, v)
+			if err != nil {
+				return it, err
+			}
+			it.Width = data
+		case "height":
+			ctx := graphql.WithPathContext(ctx, graphql.NewPathWithField("height"))
+			data, err := ec.unmarshalNFloat2float64(ctx, v)
+			if err != nil {
+				return it, err
+			}
+			it.Height = data
+		}
+	}
+	return it, nil
+}
+
 func (ec *executionContext) unmarshalInputWidgetInput(ctx context.Context, obj any) (model.WidgetInput, error) {
 	var it model.WidgetInput
 	asMap := map[string]any{}
@@ -3181,6 +4210,65 @@ func (ec *executionContext) _Board(ctx context.Context, sel ast.SelectionSet, ob
 	return out
 }
 
+var boardExportImplementors = []string{"BoardExport"}
+
+func (ec *executionContext) _BoardExport(ctx context.Context, sel ast.SelectionSet, obj *model.BoardExport) graphql.Marshaler {
+	fields := graphql.CollectFields(ec.OperationContext, sel, boardExportImplementors)
+
+	out := graphql.NewFieldSet(fields)
+	deferred := make(map[string]*graphql.FieldSet)
+	for i, field := range fields {
+		switch field.Name {
+		case "__typename":
+			out.Values[i] = graphql.MarshalString("BoardExport")
+		case "boardId":
+			out.Values[i] = ec._BoardExport_boardId(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				out.Invalids++
+			}
+		case "version":
+			out.Values[i] = ec._BoardExport_version(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				out.Invalids++
+			}
+		case "mediaType":
+			out.Values[i] = ec._BoardExport_mediaType(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				out.Invalids++
+			}
+		case "filename":
+			out.Values[i] = ec._BoardExport_filename(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				out.Invalids++
+			}
+		case "data":
+			out.Values[i] = ec._BoardExport_data(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				out.Invalids++
+			}
+		default:
+			panic("unknown field " + strconv.Quote(field.Name))
+		}
+	}
+	out.Dispatch(ctx)
+	if out.Invalids > 0 {
+		return graphql.Null
+	}
+
+	atomic.AddInt32(&ec.Deferred, int32(len(deferred)))
+
+	for label, dfs := range deferred {
+		ec.ProcessDeferredGroup(graphql.DeferredGroup{
+			Label:    label,
+			Path:     graphql.GetPath(ctx),
+			FieldSet: dfs,
+			Context:  ctx,
+		})
+	}
+
+	return out
+}
+
 var mutationImplementors = []string{"Mutation"}
 
 func (ec *executionContext) _Mutation(ctx context.Context, sel ast.SelectionSet) graphql.Marshaler {
@@ -3221,6 +4309,34 @@ func (ec *executionContext) _Mutation(ctx context.Context, sel ast.SelectionSet)
 			if out.Values[i] == graphql.Null {
 				out.Invalids++
 			}
+		case "applyTextUpdate":
+			out.Values[i] = ec.OperationContext.RootResolverMiddleware(innerCtx, func(ctx context.Context) (res graphql.Marshaler) {
+				return ec._Mutation_applyTextUpdate(ctx, field)
+			})
+			if out.Values[i] == graphql.Null {
+				out.Invalids++
+			}
+		case "undo":
+			out.Values[i] = ec.OperationContext.RootResolverMiddleware(innerCtx, func(ctx context.Context) (res graphql.Marshaler) {
+				return ec._Mutation_undo(ctx, field)
+			})
+			if out.Values[i] == graphql.Null {
+				out.Invalids++
+			}
+		case "redo":
+			out.Values[i] = ec.OperationContext.RootResolverMiddleware(innerCtx, func(ctx context.Context) (res graphql.Marshaler) {
+				return ec._Mutation_redo(ctx, field)
+			})
+			if out.Values[i] == graphql.Null {
+				out.Invalids++
+			}
+		case "exportBoard":
+			out.Values[i] = ec.OperationContext.RootResolverMiddleware(innerCtx, func(ctx context.Context) (res graphql.Marshaler) {
+				return ec._Mutation_exportBoard(ctx, field)
+			})
+			if out.Values[i] == graphql.Null {
+				out.Invalids++
+			}
 		default:
 			panic("unknown field " + strconv.Quote(field.Name))
 		}
@@ -3303,6 +4419,28 @@ func (ec *executionContext) _Query(ctx context.Context, sel ast.SelectionSet) gr
 					func(ctx context.Context) graphql.Marshaler { return innerFunc(ctx, out) })
 			}
 
+			out.Concurrently(i, func(ctx context.Context) graphql.Marshaler { return rrm(innerCtx) })
+		case "textState":
+			field := field
+
+			innerFunc := func(ctx context.Context, fs *graphql.FieldSet) (res graphql.Marshaler) {
+				defer func() {
+					if r := recover(); r != nil {
+						ec.Error(ctx, ec.Recover(ctx, r))
+					}
+				}()
+				res = ec._Query_textState(ctx, field)
+				if res == graphql.Null {
+					atomic.AddUint32(&fs.Invalids, 1)
+				}
+				return res
+			}
+
+			rrm := func(ctx context.Context) graphql.Marshaler {
+				return ec.OperationContext.RootResolverMiddleware(ctx,
+					func(ctx context.Context) graphql.Marshaler { return innerFunc(ctx, out) })
+			}
+
 			out.Concurrently(i, func(ctx context.Context) graphql.Marshaler { return rrm(innerCtx) })
 		case "__type":
 			out.Values[i] = ec.OperationContext.RootResolverMiddleware(innerCtx, func(ctx context.Context) (res graphql.Marshaler) {
@@ -3417,11 +4555,77 @@ func (ec *executionContext) _Subscription(ctx context.Context, sel ast.Selection
 	switch fields[0].Name {
 	case "boardUpdated":
 		return ec._Subscription_boardUpdated(ctx, fields[0])
+	case "textUpdated":
+		return ec._Subscription_textUpdated(ctx, fields[0])
 	default:
 		panic("unknown field " + strconv.Quote(fields[0].Name))
 	}
 }
 
+var textUpdateImplementors = []string{"TextUpdate"}
+
+func (ec *executionContext) _TextUpdate(ctx context.Context, sel ast.SelectionSet, obj *model.TextUpdate) graphql.Marshaler {
+	fields := graphql.CollectFields(ec.OperationContext, sel, textUpdateImplementors)
+
+	out := graphql.NewFieldSet(fields)
+	deferred := make(map[string]*graphql.FieldSet)
+	for i, field := range fields {
+		switch field.Name {
+		case "__typename":
+			out.Values[i] = graphql.MarshalString("TextUpdate")
+		case "boardId":
+			out.Values[i] = ec._TextUpdate_boardId(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				out.Invalids++
+			}
+		case "widgetId":
+			out.Values[i] = ec._TextUpdate_widgetId(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				out.Invalids++
+			}
+		case "field":
+			out.Values[i] = ec._TextUpdate_field(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				out.Invalids++
+			}
+		case "version":
+			out.Values[i] = ec._TextUpdate_version(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				out.Invalids++
+			}
+		case "update":
+			out.Values[i] = ec._TextUpdate_update(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				out.Invalids++
+			}
+		case "text":
+			out.Values[i] = ec._TextUpdate_text(ctx, field, obj)
+			if out.Values[i] == graphql.Null {
+				out.Invalids++
+			}
+		default:
+			panic("unknown field " + strconv.Quote(field.Name))
+		}
+	}
+	out.Dispatch(ctx)
+	if out.Invalids > 0 {
+		return graphql.Null
+	}
+
+	atomic.AddInt32(&ec.Deferred, int32(len(deferred)))
+
+	for label, dfs := range deferred {
+		ec.ProcessDeferredGroup(graphql.DeferredGroup{
+			Label:    label,
+			Path:     graphql.GetPath(ctx),
+			FieldSet: dfs,
+			Context:  ctx,
+		})
+	}
+
+	return out
+}
+
 var widgetPayloadImplementors = []string{"WidgetPayload"}
 
 func (ec *executionContext) _WidgetPayload(ctx context.Context, sel ast.SelectionSet, obj *model.WidgetPayload) graphql.Marshaler {
@@ -3861,6 +5065,20 @@ func (ec *executionContext) marshalNBoard2ᚖmiroᚑliteᚑstandaloneᚋbackend
 	return ec._Board(ctx, sel, v)
 }
 
+func (ec *executionContext) marshalNBoardExport2miroᚑliteᚑstandaloneᚋbackendᚋinternalᚋgraphᚋmodelᚐBoardExport(ctx context.Context, sel ast.SelectionSet, v model.BoardExport) graphql.Marshaler {
+	return ec._BoardExport(ctx, sel, &v)
+}
+
+func (ec *executionContext) marshalNBoardExport2ᚖmiroᚑliteᚑstandaloneᚋbackendᚋinternalᚋgraphᚋmodelᚐBoardExport(ctx context.Context, sel ast.SelectionSet, v *model.BoardExport) graphql.Marshaler {
+	if v == nil {
+		if !graphql.HasFieldError(ctx, graphql.GetFieldContext(ctx)) {
+			graphql.AddErrorf(ctx, "the requested element is null which the schema does not allow")
+		}
+		return graphql.Null
+	}
+	return ec._BoardExport(ctx, sel, v)
+}
+
 func (ec *executionContext) unmarshalNBoolean2bool(ctx context.Context, v any) (bool, error) {
 	res, err := graphql.UnmarshalBoolean(v)
 	return res, graphql.ErrorOnPath(ctx, err)
@@ -3877,6 +5095,16 @@ func (ec *executionContext) marshalNBoolean2bool(ctx context.Context, sel ast.Se
 	return res
 }
 
+func (ec *executionContext) unmarshalNExportFormat2miroᚑliteᚑstandaloneᚋbackendᚋinternalᚋgraphᚋmodelᚐExportFormat(ctx context.Context, v any) (model.ExportFormat, error) {
+	var res model.ExportFormat
+	err := res.UnmarshalGQL(v)
+	return res, graphql.ErrorOnPath(ctx, err)
+}
+
+func (ec *executionContext) marshalNExportFormat2miroᚑliteᚑstandaloneᚋbackendᚋinternalᚋgraphᚋmodelᚐExportFormat(ctx context.Context, sel ast.SelectionSet, v model.ExportFormat) graphql.Marshaler {
+	return v
+}
+
 func (ec *executionContext) unmarshalNFloat2float64(ctx context.Context, v any) (float64, error) {
 	res, err := graphql.UnmarshalFloatContext(ctx, v)
 	return res, graphql.ErrorOnPath(ctx, err)
@@ -3955,6 +5183,20 @@ func (ec *executionContext) marshalNString2string(ctx context.Context, sel ast.S
 	return res
 }
 
+func (ec *executionContext) marshalNTextUpdate2miroᚑliteᚑstandaloneᚋbackendᚋinternalᚋgraphᚋmodelᚐTextUpdate(ctx context.Context, sel ast.SelectionSet, v model.TextUpdate) graphql.Marshaler {
+	return ec._TextUpdate(ctx, sel, &v)
+}
+
+func (ec *executionContext) marshalNTextUpdate2ᚖmiroᚑliteᚑstandaloneᚋbackendᚋinternalᚋgraphᚋmodelᚐTextUpdate(ctx context.Context, sel ast.SelectionSet, v *model.TextUpdate) graphql.Marshaler {
+	if v == nil {
+		if !graphql.HasFieldError(ctx, graphql.GetFieldContext(ctx)) {
+			graphql.AddErrorf(ctx, "the requested element is null which the schema does not allow")
+		}
+		return graphql.Null
+	}
+	return ec._TextUpdate(ctx, sel, v)
+}
+
 func (ec *executionContext) unmarshalNWidgetInput2ᚕᚖmiroᚑliteᚑstandaloneᚋbackendᚋinternalᚋgraphᚋmodelᚐWidgetInputᚄ(ctx context.Context, v any) ([]*model.WidgetInput, error) {
 	var vSlice []any
 	vSlice = graphql.CoerceList(v)
@@ -4179,6 +5421,14 @@ func (ec *executionContext) marshalOBoolean2ᚖbool(ctx context.Context, sel ast
 	return res
 }
 
+func (ec *executionContext) unmarshalOExportOptions2ᚖmiroᚑliteᚑstandaloneᚋbackendᚋinternalᚋgraphᚋmodelᚐExportOptions(ctx context.Context, v any) (*model.ExportOptions, error) {
+	if v == nil {
+		return nil, nil
+	}
+	res, err := ec.unmarshalInputExportOptions(ctx, v)
+	return &res, graphql.ErrorOnPath(ctx, err)
+}
+
 func (ec *executionContext) unmarshalOFloat2ᚖfloat64(ctx context.Context, v any) (*float64, error) {
 	if v == nil {
 		return nil, nil
@@ -4232,6 +5482,14 @@ func (ec *executionContext) marshalOString2ᚖstring(ctx context.Context, sel as
 	return res
 }
 
+func (ec *executionContext) unmarshalOViewportInput2ᚖmiroᚑliteᚑstandaloneᚋbackendᚋinternalᚋgraphᚋmodelᚐViewportInput(ctx context.Context, v any) (*model.ViewportInput, error) {
+	if v == nil {
+		return nil, nil
+	}
+	res, err := ec.unmarshalInputViewportInput(ctx, v)
+	return &res, graphql.ErrorOnPath(ctx, err)
+}
+
 func (ec *executionContext) marshalO__EnumValue2ᚕgithubᚗcomᚋ99designsᚋgqlgenᚋgraphqlᚋintrospectionᚐEnumValueᚄ(ctx context.Context, sel ast.SelectionSet, v []introspection.EnumValue) graphql.Marshaler {
 	if v == nil {
 		return graphql.Null
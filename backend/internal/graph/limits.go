@@ -0,0 +1,70 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+
+	"miro-lite-standalone/backend/internal/ratelimit"
+)
+
+// wsConn est rattaché au contexte d'une connexion websocket par
+// WebsocketInit ; toutes les opérations de la connexion le partagent.
+type wsConn struct {
+	subscriptions atomic.Int32
+}
+
+type wsConnKey struct{}
+
+func wsConnFrom(ctx context.Context) *wsConn {
+	conn, _ := ctx.Value(wsConnKey{}).(*wsConn)
+	return conn
+}
+
+// acquireSubscription réserve une place de subscription sur la connexion
+// websocket de ctx. release est à appeler à la fin de la subscription.
+func (r *Resolver) acquireSubscription(ctx context.Context) (release func(), err error) {
+	conn := wsConnFrom(ctx)
+	if conn == nil || r.MaxSubscriptionsPerConn <= 0 {
+		return func() {}, nil
+	}
+	if conn.subscriptions.Add(1) > int32(r.MaxSubscriptionsPerConn) {
+		conn.subscriptions.Add(-1)
+		return nil, &ratelimit.Error{Message: fmt.Sprintf("too many subscriptions on this connection (max %d)", r.MaxSubscriptionsPerConn)}
+	}
+	return func() { conn.subscriptions.Add(-1) }, nil
+}
+
+// WebsocketRateLimit décompte chaque opération reçue sur une websocket dans
+// le seau du client : la requête d'upgrade n'a consommé qu'un jeton pour
+// toute la connexion. Les opérations HTTP sont déjà limitées par
+// middleware.RateLimit.
+func WebsocketRateLimit(l *ratelimit.Limiter) graphql.HandlerExtension {
+	return wsRateLimit{l: l}
+}
+
+type wsRateLimit struct {
+	l *ratelimit.Limiter
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationInterceptor
+} = wsRateLimit{}
+
+func (wsRateLimit) ExtensionName() string { return "WebsocketRateLimit" }
+
+func (wsRateLimit) Validate(graphql.ExecutableSchema) error { return nil }
+
+func (e wsRateLimit) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	if wsConnFrom(ctx) == nil {
+		return next(ctx)
+	}
+	if err := e.l.Allow(ratelimit.KeyFrom(ctx)); err != nil {
+		return graphql.OneShot(&graphql.Response{Errors: gqlerror.List{rateLimitError(err.(*ratelimit.Error))}})
+	}
+	return next(ctx)
+}
@@ -2,6 +2,13 @@
 
 package model
 
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+)
+
 type AddStickyNoteInput struct {
 	X     float64 `json:"x"`
 	Y     float64 `json:"y"`
@@ -16,6 +23,23 @@ type Board struct {
 	Widgets []*WidgetPayload `json:"widgets"`
 }
 
+type BoardExport struct {
+	BoardID   string `json:"boardId"`
+	Version   int    `json:"version"`
+	MediaType string `json:"mediaType"`
+	Filename  string `json:"filename"`
+	Data      string `json:"data"`
+}
+
+type ExportOptions struct {
+	Viewport    *ViewportInput `json:"viewport,omitempty"`
+	Scale       *float64       `json:"scale,omitempty"`
+	Paper       *string        `json:"paper,omitempty"`
+	Orientation *string        `json:"orientation,omitempty"`
+	Margin      *float64       `json:"margin,omitempty"`
+	FitToPage   *bool          `json:"fitToPage,omitempty"`
+}
+
 type Mutation struct {
 }
 
@@ -37,6 +61,22 @@ type StickyNote struct {
 type Subscription struct {
 }
 
+type TextUpdate struct {
+	BoardID  string `json:"boardId"`
+	WidgetID string `json:"widgetId"`
+	Field    string `json:"field"`
+	Version  int    `json:"version"`
+	Update   string `json:"update"`
+	Text     string `json:"text"`
+}
+
+type ViewportInput struct {
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
+
 type WidgetInput struct {
 	ID         string  `json:"id"`
 	Type       string  `json:"type"`
@@ -56,3 +96,60 @@ type WidgetPayload struct {
 	Height     float64 `json:"height"`
 	ConfigJSON string  `json:"configJson"`
 }
+
+type ExportFormat string
+
+const (
+	ExportFormatSVG ExportFormat = "SVG"
+	ExportFormatPng ExportFormat = "PNG"
+	ExportFormatPDF ExportFormat = "PDF"
+)
+
+var AllExportFormat = []ExportFormat{
+	ExportFormatSVG,
+	ExportFormatPng,
+	ExportFormatPDF,
+}
+
+func (e ExportFormat) IsValid() bool {
+	switch e {
+	case ExportFormatSVG, ExportFormatPng, ExportFormatPDF:
+		return true
+	}
+	return false
+}
+
+func (e ExportFormat) String() string {
+	return string(e)
+}
+
+func (e *ExportFormat) UnmarshalGQL(v any) error {
+	str, ok := v.(string)
+	if !ok {
+		return fmt.Errorf("enums must be strings")
+	}
+
+	*e = ExportFormat(str)
+	if !e.IsValid() {
+		return fmt.Errorf("%s is not a valid ExportFormat", str)
+	}
+	return nil
+}
+
+func (e ExportFormat) MarshalGQL(w io.Writer) {
+	fmt.Fprint(w, strconv.Quote(e.String()))
+}
+
+func (e *ExportFormat) UnmarshalJSON(b []byte) error {
+	s, err := strconv.Unquote(string(b))
+	if err != nil {
+		return err
+	}
+	return e.UnmarshalGQL(s)
+}
+
+func (e ExportFormat) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	e.MarshalGQL(&buf)
+	return buf.Bytes(), nil
+}
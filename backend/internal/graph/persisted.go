@@ -0,0 +1,141 @@
+package graph
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/errcode"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// CodePersistedQueryNotAllowed : en mode allowlist, l'opération ne figure pas
+// dans le manifeste.
+const CodePersistedQueryNotAllowed = "PERSISTED_QUERY_NOT_ALLOWED"
+
+// PersistedQueries est un manifeste d'opérations connues, indexées par le
+// SHA-256 hexadécimal de leur texte (le hash des persisted queries
+// d'Apollo).
+type PersistedQueries struct {
+	queries map[string]string
+}
+
+// LoadPersistedQueries lit un manifeste au format
+// apollo-persisted-query-manifest ({"operations": [{"id", "body"}]}) ou un
+// simple objet {hash: requête}. Chaque hash est vérifié.
+func LoadPersistedQueries(path string) (*PersistedQueries, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read persisted queries: %w", err)
+	}
+	var manifest struct {
+		Operations []struct {
+			ID   string `json:"id"`
+			Body string `json:"body"`
+		} `json:"operations"`
+	}
+	queries := make(map[string]string)
+	if err := json.Unmarshal(content, &manifest); err == nil && manifest.Operations != nil {
+		for _, op := range manifest.Operations {
+			queries[op.ID] = op.Body
+		}
+	} else if err := json.Unmarshal(content, &queries); err != nil {
+		return nil, fmt.Errorf("parse persisted queries %s: %w", path, err)
+	}
+	for hash, query := range queries {
+		if queryHash(query) != hash {
+			return nil, fmt.Errorf("persisted queries %s: hash %q does not match its query", path, hash)
+		}
+	}
+	return &PersistedQueries{queries: queries}, nil
+}
+
+func (p *PersistedQueries) Len() int {
+	return len(p.queries)
+}
+
+// OperationNames renvoie les noms des opérations du manifeste, triés et sans
+// doublons. Une requête qui ne se parse pas est ignorée : elle sera refusée
+// à l'exécution.
+func (p *PersistedQueries) OperationNames() []string {
+	seen := make(map[string]bool)
+	for _, query := range p.queries {
+		doc, err := parser.ParseQuery(&ast.Source{Input: query})
+		if err != nil {
+			continue
+		}
+		for _, op := range doc.Operations {
+			if op.Name != "" {
+				seen[op.Name] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func queryHash(query string) string {
+	sum := sha256.Sum256([]byte(query))
+	return hex.EncodeToString(sum[:])
+}
+
+// PersistedQueryExtension sert les opérations du manifeste à partir de leur
+// seul hash (extensions.persistedQuery.sha256Hash). Avec enforce, toute
+// opération absente du manifeste est refusée, qu'elle arrive par hash ou en
+// texte. À ajouter avant extension.AutomaticPersistedQuery, qui gère les
+// hashes inconnus hors mode allowlist.
+func PersistedQueryExtension(p *PersistedQueries, enforce bool) graphql.HandlerExtension {
+	return persistedQueries{p: p, enforce: enforce}
+}
+
+type persistedQueries struct {
+	p       *PersistedQueries
+	enforce bool
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationParameterMutator
+} = persistedQueries{}
+
+func (persistedQueries) ExtensionName() string { return "PersistedQueries" }
+
+func (persistedQueries) Validate(graphql.ExecutableSchema) error { return nil }
+
+func (e persistedQueries) MutateOperationParameters(_ context.Context, params *graphql.RawParams) *gqlerror.Error {
+	if params.Query != "" {
+		if e.enforce {
+			if _, ok := e.p.queries[queryHash(params.Query)]; !ok {
+				return notAllowed()
+			}
+		}
+		return nil
+	}
+	pq, _ := params.Extensions["persistedQuery"].(map[string]interface{})
+	hash, _ := pq["sha256Hash"].(string)
+	if query, ok := e.p.queries[hash]; ok {
+		params.Query = query
+		return nil
+	}
+	if e.enforce {
+		return notAllowed()
+	}
+	return nil
+}
+
+func notAllowed() *gqlerror.Error {
+	err := gqlerror.Errorf("operation is not in the persisted query allowlist")
+	errcode.Set(err, CodePersistedQueryNotAllowed)
+	return err
+}
@@ -0,0 +1,135 @@
+package graph
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+
+	"miro-lite-standalone/backend/internal/board"
+)
+
+// writeManifest écrit un manifeste {hash: requête} et renvoie son chemin.
+func writeManifest(t *testing.T, queries ...string) string {
+	t.Helper()
+	manifest := make(map[string]string, len(queries))
+	for _, q := range queries {
+		manifest[queryHash(q)] = q
+	}
+	content, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "persisted.json")
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestPersistedOperationNames(t *testing.T) {
+	pq, err := LoadPersistedQueries(writeManifest(t,
+		`query GetBoard { board(id: "b1") { id } }`,
+		`query GetBoard { boards { id } }`,
+		`mutation SaveBoard { createBoard(title: "t") { id } } query Other { boards { id } }`,
+		`{ boards { id } }`,
+		`query Broken {`,
+	))
+	if err != nil {
+		t.Fatalf("LoadPersistedQueries: %v", err)
+	}
+	if got := strings.Join(pq.OperationNames(), " "); got != "GetBoard Other SaveBoard" {
+		t.Fatalf("OperationNames() = %s", got)
+	}
+}
+
+func TestLoadPersistedQueries(t *testing.T) {
+	const query = `{ boards { id } }`
+	tests := []struct {
+		name     string
+		manifest string
+		wantLen  int
+		wantErr  string
+	}{
+		{"hash map", `{"` + queryHash(query) + `": "{ boards { id } }"}`, 1, ""},
+		{"apollo manifest", `{"format": "apollo-persisted-query-manifest", "operations": [{"id": "` + queryHash(query) + `", "body": "{ boards { id } }"}]}`, 1, ""},
+		{"wrong hash", `{"abc": "{ boards { id } }"}`, 0, "does not match"},
+		{"not json", `boards`, 0, "parse persisted queries"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "persisted.json")
+			if err := os.WriteFile(path, []byte(tt.manifest), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			pq, err := LoadPersistedQueries(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadPersistedQueries: %v", err)
+			}
+			if pq.Len() != tt.wantLen {
+				t.Errorf("Len() = %d, want %d", pq.Len(), tt.wantLen)
+			}
+		})
+	}
+}
+
+func TestPersistedQueryExtension(t *testing.T) {
+	const known = `query ListBoards { boards { id } }`
+	pq, err := LoadPersistedQueries(writeManifest(t, known))
+	if err != nil {
+		t.Fatalf("LoadPersistedQueries: %v", err)
+	}
+	byHash := func(hash string) client.Option {
+		return client.Extensions(map[string]any{"persistedQuery": map[string]any{"version": 1, "sha256Hash": hash}})
+	}
+	tests := []struct {
+		name     string
+		enforce  bool
+		query    string
+		opts     []client.Option
+		wantCode string
+	}{
+		{"known hash", false, "", []client.Option{byHash(queryHash(known))}, ""},
+		{"known text", true, known, nil, ""},
+		{"known hash with allowlist", true, "", []client.Option{byHash(queryHash(known))}, ""},
+		{"unknown text", false, `{ boards { title } }`, nil, ""},
+		{"unknown text with allowlist", true, `{ boards { title } }`, nil, CodePersistedQueryNotAllowed},
+		{"unknown hash with allowlist", true, "", []client.Option{byHash(queryHash("{ boards { title } }"))}, CodePersistedQueryNotAllowed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, err := board.NewService("")
+			if err != nil {
+				t.Fatalf("NewService: %v", err)
+			}
+			srv := handler.New(NewExecutableSchema(NewConfig(NewResolver(svc, nil))))
+			srv.AddTransport(transport.POST{})
+			srv.Use(PersistedQueryExtension(pq, tt.enforce))
+			resp, err := client.New(srv).RawPost(tt.query, tt.opts...)
+			var code string
+			if err != nil || resp.Errors != nil {
+				var errs []struct {
+					Extensions map[string]string `json:"extensions"`
+				}
+				if resp == nil || json.Unmarshal(resp.Errors, &errs) != nil || len(errs) == 0 {
+					t.Fatalf("post: %v", err)
+				}
+				code = errs[0].Extensions["code"]
+			}
+			if code != tt.wantCode {
+				t.Fatalf("code = %q, want %q", code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -3,20 +3,42 @@ package graph
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"sync"
 
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+
 	"miro-lite-standalone/backend/internal/board"
 	"miro-lite-standalone/backend/internal/graph/model"
-
-	"github.com/google/uuid"
+	"miro-lite-standalone/backend/internal/pubsub"
 )
 
 type Resolver struct {
 	BoardService *board.Service
-	mu          sync.RWMutex
-	nextSubID   int
-	subscribers map[string]map[int]chan *model.Board
+	Logger       *slog.Logger
+	// MaxSubscriptionsPerConn borne les subscriptions ouvertes sur une même
+	// websocket (0 : pas de limite).
+	MaxSubscriptionsPerConn int
+	broker                  pubsub.Broker
+	mu                      sync.RWMutex
+	shutdown                chan struct{}
+	closing                 bool
+}
+
+// NewResolver branche les subscriptions sur les écritures du service, qu'elles
+// viennent des mutations GraphQL ou de l'API REST, à travers broker
+// (pubsub.NewMemory() si nil).
+func NewResolver(svc *board.Service, broker pubsub.Broker) *Resolver {
+	if broker == nil {
+		broker = pubsub.NewMemory()
+	}
+	r := &Resolver{BoardService: svc, Logger: slog.New(slog.DiscardHandler), broker: broker, shutdown: make(chan struct{})}
+	svc.OnBoardUpdated(r.publishBoardUpdated)
+	svc.OnBoardDeleted(r.publishBoardDeleted)
+	svc.OnTextUpdated(r.publishTextUpdated)
+	return r
 }
 
 func (r *Resolver) Query() QueryResolver       { return &queryResolver{r} }
@@ -28,18 +50,18 @@ func (r *Resolver) Subscription() SubscriptionResolver {
 type queryResolver struct{ *Resolver }
 
 func (r *queryResolver) Board(ctx context.Context, id string) (*model.Board, error) {
-	b, ok := r.BoardService.GetBoard(id)
-	if !ok {
-		return nil, nil
+	b, err := r.BoardService.ResolveBoard(ctx, id)
+	if err != nil {
+		return nil, err
 	}
-	return boardToGraphQL(b), nil
+	return r.boardToGraphQL(ctx, b), nil
 }
 
 func (r *queryResolver) Boards(ctx context.Context) ([]*model.Board, error) {
 	boards := r.BoardService.ListBoards()
 	result := make([]*model.Board, 0, len(boards))
 	for _, b := range boards {
-		result = append(result, boardToGraphQL(b))
+		result = append(result, r.boardToGraphQL(ctx, b))
 	}
 	return result, nil
 }
@@ -47,10 +69,11 @@ func (r *queryResolver) Boards(ctx context.Context) ([]*model.Board, error) {
 type mutationResolver struct{ *Resolver }
 
 func (r *mutationResolver) CreateBoard(ctx context.Context, title string) (*model.Board, error) {
-	id := fmt.Sprintf("board-%s", uuid.NewString()[:8])
-	b := r.BoardService.CreateBoard(id, title)
-	r.publishBoardUpdated(b)
-	return boardToGraphQL(b), nil
+	b, err := r.BoardService.CreateBoard(ctx, board.NewBoardID(), title)
+	if err != nil {
+		return nil, err
+	}
+	return r.boardToGraphQL(ctx, b), nil
 }
 
 func (r *mutationResolver) AddStickyNote(ctx context.Context, boardID string, item model.AddStickyNoteInput) (*model.StickyNote, error) {
@@ -58,8 +81,8 @@ func (r *mutationResolver) AddStickyNote(ctx context.Context, boardID string, it
 	if item.Color != nil && *item.Color != "" {
 		color = *item.Color
 	}
-	w, err := r.BoardService.AddWidget(boardID, board.Widget{
-		ID:     fmt.Sprintf("widget-%s", uuid.NewString()[:8]),
+	_, w, err := r.BoardService.AddWidget(ctx, boardID, board.LatestVersion, board.Widget{
+		ID:     board.NewWidgetID(),
 		Type:   "text",
 		X:      item.X,
 		Y:      item.Y,
@@ -68,9 +91,6 @@ func (r *mutationResolver) AddStickyNote(ctx context.Context, boardID string, it
 	if err != nil {
 		return nil, err
 	}
-	if b, ok := r.BoardService.GetBoard(boardID); ok {
-		r.publishBoardUpdated(b)
-	}
 	text, _ := w.Config["text"].(string)
 	col, _ := w.Config["color"].(string)
 	return &model.StickyNote{ID: w.ID, X: w.X, Y: w.Y, Text: text, Color: col}, nil
@@ -78,10 +98,13 @@ func (r *mutationResolver) AddStickyNote(ctx context.Context, boardID string, it
 
 func (r *mutationResolver) SaveBoard(ctx context.Context, boardID string, version int, widgets []*model.WidgetInput) (*model.Board, error) {
 	boardWidgets := make([]board.Widget, 0, len(widgets))
-	for _, w := range widgets {
+	for i, w := range widgets {
 		var config map[string]interface{}
 		if err := json.Unmarshal([]byte(w.ConfigJSON), &config); err != nil {
-			config = map[string]interface{}{}
+			return nil, &board.ValidationError{
+				Field:   fmt.Sprintf("widgets[%d].configJson", i),
+				Message: "invalid JSON: " + err.Error(),
+			}
 		}
 		boardWidgets = append(boardWidgets, board.Widget{
 			ID:     w.ID,
@@ -93,31 +116,116 @@ func (r *mutationResolver) SaveBoard(ctx context.Context, boardID string, versio
 			Config: config,
 		})
 	}
-	b, err := r.BoardService.SaveBoard(boardID, version, boardWidgets)
+	b, err := r.BoardService.SaveBoard(ctx, boardID, version, boardWidgets)
 	if err != nil {
 		return nil, err
 	}
-	r.publishBoardUpdated(b)
-	return boardToGraphQL(b), nil
+	return r.boardToGraphQL(ctx, b), nil
+}
+
+func (r *mutationResolver) Undo(ctx context.Context, boardID string) (*model.Board, error) {
+	b, err := r.BoardService.Undo(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+	return r.boardToGraphQL(ctx, b), nil
+}
+
+func (r *mutationResolver) Redo(ctx context.Context, boardID string) (*model.Board, error) {
+	b, err := r.BoardService.Redo(ctx, boardID)
+	if err != nil {
+		return nil, err
+	}
+	return r.boardToGraphQL(ctx, b), nil
 }
 
 type subscriptionResolver struct{ *Resolver }
 
-func (r *subscriptionResolver) BoardUpdated(ctx context.Context, boardID string) (<-chan *model.Board, error) {
-	ch, subID := r.addSubscriber(boardID)
+func (r *subscriptionResolver) BoardUpdated(ctx context.Context, boardID string, sinceVersion *int) (<-chan *model.Board, error) {
+	since := 0
+	if sinceVersion != nil {
+		if *sinceVersion < 0 {
+			return nil, &board.ValidationError{Field: "sinceVersion", Message: "must not be negative"}
+		}
+		since = *sinceVersion
+	}
+	release, err := r.acquireSubscription(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sub, err := r.broker.Subscribe(boardID, since)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	// Historique incomplet : l'état courant remplace les versions manquées.
+	// Il est lu après l'abonnement pour ne rien perdre entre les deux ; les
+	// mises à jour déjà couvertes sont ensuite ignorées.
+	var current *board.Model
+	if sub.Gap {
+		current, err = r.BoardService.ResolveBoard(ctx, boardID)
+		if err != nil && !errors.Is(err, board.ErrNotFound) {
+			sub.Cancel()
+			release()
+			return nil, err
+		}
+	}
+	// Fermer ch termine la subscription côté client (complete), par exemple
+	// quand Shutdown ferme le broker.
+	ch := make(chan *model.Board, 1)
 	go func() {
-		<-ctx.Done()
-		r.removeSubscriber(boardID, subID)
+		defer close(ch)
+		defer release()
+		defer sub.Cancel()
+		last, covered := since, 0
+		send := func(b *board.Model) bool {
+			select {
+			case ch <- r.boardToGraphQL(ctx, b):
+				last = b.Version
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		if current != nil && current.Version > since {
+			if !send(current) {
+				return
+			}
+			covered = current.Version
+		}
+		for {
+			select {
+			case b, ok := <-sub.Updates():
+				if !ok {
+					if errors.Is(sub.Err(), pubsub.ErrOverflow) {
+						requireResync(ctx, boardID, last)
+					}
+					return
+				}
+				if b.Version <= covered {
+					continue
+				}
+				if !send(b) {
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
 	}()
 	return ch, nil
 }
 
 // ─── Helpers ──────────────────────────────────────────────────────────────────
 
-func boardToGraphQL(b *board.Model) *model.Board {
+func (r *Resolver) boardToGraphQL(ctx context.Context, b *board.Model) *model.Board {
 	widgets := make([]*model.WidgetPayload, 0, len(b.Widgets))
 	for _, w := range b.Widgets {
-		widgets = append(widgets, widgetToPayload(w))
+		payload, err := widgetToPayload(w)
+		if err != nil {
+			r.Logger.WarnContext(ctx, "graphql: widget config not encodable, sent as {}", "board", b.ID, "widget", w.ID, "err", err)
+		}
+		widgets = append(widgets, payload)
 	}
 	return &model.Board{
 		ID:      b.ID,
@@ -127,7 +235,9 @@ func boardToGraphQL(b *board.Model) *model.Board {
 	}
 }
 
-func widgetToPayload(w board.Widget) *model.WidgetPayload {
+// widgetToPayload renvoie toujours un payload utilisable ; err signale une
+// config remplacée par {}.
+func widgetToPayload(w board.Widget) (*model.WidgetPayload, error) {
 	rawConfig, err := json.Marshal(w.Config)
 	if err != nil {
 		rawConfig = []byte("{}")
@@ -140,56 +250,68 @@ func widgetToPayload(w board.Widget) *model.WidgetPayload {
 		Width:      w.Width,
 		Height:     w.Height,
 		ConfigJSON: string(rawConfig),
+	}, err
+}
+
+func (r *Resolver) publishBoardUpdated(b *board.Model) {
+	if b == nil {
+		return
+	}
+	if err := r.broker.Publish(context.Background(), b); err != nil {
+		r.Logger.Warn("graphql: update not sent to other instances", "board", b.ID, "version", b.Version, "err", err)
 	}
 }
 
-func (r *Resolver) addSubscriber(boardID string) (chan *model.Board, int) {
-	r.mu.Lock()
-	defer r.mu.Unlock()
-	if r.subscribers == nil {
-		r.subscribers = make(map[string]map[int]chan *model.Board)
+func (r *Resolver) publishBoardDeleted(id string) {
+	if err := r.broker.PublishDeleted(context.Background(), id); err != nil {
+		r.Logger.Warn("graphql: deletion not sent to other instances", "board", id, "err", err)
 	}
-	if r.subscribers[boardID] == nil {
-		r.subscribers[boardID] = make(map[int]chan *model.Board)
+}
+
+func (r *Resolver) publishTextUpdated(change *board.TextChange) {
+	if err := r.broker.PublishText(context.Background(), change); err != nil {
+		r.Logger.Warn("graphql: text update not sent to other instances", "board", change.BoardID, "widget", change.WidgetID, "field", change.Field, "err", err)
 	}
-	r.nextSubID++
-	subID := r.nextSubID
-	ch := make(chan *model.Board, 4)
-	r.subscribers[boardID][subID] = ch
-	return ch, subID
 }
 
-func (r *Resolver) removeSubscriber(boardID string, subID int) {
+// Shutdown termine toutes les subscriptions (gqlgen envoie "complete" au
+// client quand le canal est fermé), refuse les nouvelles et ferme les
+// connexions websocket ouvertes via WebsocketInit.
+func (r *Resolver) Shutdown() {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	boardSubs, ok := r.subscribers[boardID]
-	if !ok {
+	if r.closing {
 		return
 	}
-	ch, ok := boardSubs[subID]
-	if !ok {
-		return
+	r.closing = true
+	if err := r.broker.Close(); err != nil {
+		r.Logger.Warn("graphql: closing broker failed", "err", err)
 	}
-	delete(boardSubs, subID)
-	close(ch)
-	if len(boardSubs) == 0 {
-		delete(r.subscribers, boardID)
+	if r.shutdown != nil {
+		close(r.shutdown)
 	}
 }
 
-func (r *Resolver) publishBoardUpdated(boardModel *board.Model) {
-	if boardModel == nil {
-		return
-	}
-	payload := boardToGraphQL(boardModel)
-
+// WebsocketInit est à brancher sur transport.Websocket.InitFunc : le contexte
+// de la connexion est annulé au Shutdown, ce qui envoie une trame close.
+func (r *Resolver) WebsocketInit(ctx context.Context, payload transport.InitPayload) (context.Context, *transport.InitPayload, error) {
 	r.mu.RLock()
-	boardSubs := r.subscribers[boardModel.ID]
-	for _, ch := range boardSubs {
+	closing := r.closing
+	r.mu.RUnlock()
+	if closing {
+		return ctx, nil, board.ErrClosed
+	}
+	ctx = context.WithValue(ctx, wsConnKey{}, &wsConn{})
+	if r.shutdown == nil {
+		return ctx, nil, nil
+	}
+	connCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		defer cancel()
 		select {
-		case ch <- payload:
-		default:
+		case <-r.shutdown:
+		case <-connCtx.Done():
 		}
-	}
-	r.mu.RUnlock()
+	}()
+	return connCtx, nil, nil
 }
@@ -0,0 +1,53 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"miro-lite-standalone/backend/internal/board"
+)
+
+func newTestResolver(t *testing.T, opts ...board.Option) *Resolver {
+	t.Helper()
+	svc, err := board.NewService("", opts...)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	r := NewResolver(svc, nil)
+	t.Cleanup(r.Shutdown)
+	return r
+}
+
+func TestShutdown(t *testing.T) {
+	r := newTestResolver(t)
+	ctx := context.Background()
+	updates, err := r.Subscription().BoardUpdated(ctx, "b1", nil)
+	if err != nil {
+		t.Fatalf("BoardUpdated: %v", err)
+	}
+	connCtx, _, err := r.WebsocketInit(ctx, nil)
+	if err != nil {
+		t.Fatalf("WebsocketInit: %v", err)
+	}
+
+	r.Shutdown()
+	r.Shutdown()
+	select {
+	case _, ok := <-updates:
+		if ok {
+			t.Fatal("update received instead of completion")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscription still open after Shutdown")
+	}
+	select {
+	case <-connCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("websocket context not cancelled by Shutdown")
+	}
+	if _, _, err := r.WebsocketInit(ctx, nil); !errors.Is(err, board.ErrClosed) {
+		t.Fatalf("WebsocketInit after Shutdown: err = %v", err)
+	}
+}
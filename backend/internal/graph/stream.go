@@ -0,0 +1,31 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// CodeResyncRequired : la subscription a été fermée parce que le client ne
+// suivait plus. Il doit relire le board (ou textState) puis se réabonner,
+// avec sinceVersion pour boardUpdated.
+const CodeResyncRequired = "RESYNC_REQUIRED"
+
+// requireResync termine la subscription par un message error
+// RESYNC_REQUIRED au lieu de complete, une fois son canal fermé. version est
+// la dernière version du board reçue par le client. Les subscriptions ne
+// passent que par transport.Websocket, qui prépare ctx pour
+// AddSubscriptionError.
+func requireResync(ctx context.Context, boardID string, version int) {
+	transport.AddSubscriptionError(ctx, &gqlerror.Error{
+		Message: "subscription closed: updates were too slow to deliver, reload and resubscribe",
+		Path:    graphql.GetPath(ctx),
+		Extensions: map[string]interface{}{
+			"code":    CodeResyncRequired,
+			"boardId": boardID,
+			"version": version,
+		},
+	})
+}
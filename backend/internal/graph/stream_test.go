@@ -0,0 +1,234 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/gorilla/websocket"
+
+	"miro-lite-standalone/backend/internal/board"
+	"miro-lite-standalone/backend/internal/crdt"
+	"miro-lite-standalone/backend/internal/pubsub"
+)
+
+// openedObserver signale l'ouverture des subscriptions du broker.
+type openedObserver chan string
+
+func (o openedObserver) SubscriptionOpened(boardID string) { o <- boardID }
+func (openedObserver) SubscriptionClosed(string, int)      {}
+func (openedObserver) MessageDropped(string)               {}
+
+// subscribe sert r sur un serveur de test et y ouvre la subscription query
+// (protocole graphql-transport-ws).
+func subscribe(t *testing.T, r *Resolver, query string) *websocket.Conn {
+	t.Helper()
+	srv := handler.New(NewExecutableSchema(NewConfig(r)))
+	srv.AddTransport(transport.Websocket{InitFunc: r.WebsocketInit})
+	ts := httptest.NewServer(srv)
+	t.Cleanup(func() {
+		r.Shutdown()
+		ts.Close()
+	})
+	dialer := websocket.Dialer{Subprotocols: []string{"graphql-transport-ws"}}
+	ws, _, err := dialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { _ = ws.Close() })
+	payload, _ := json.Marshal(map[string]string{"query": query})
+	for _, msg := range []wsMessage{{Type: "connection_init"}, {ID: "1", Type: "subscribe", Payload: payload}} {
+		if err := ws.WriteJSON(msg); err != nil {
+			t.Fatalf("WriteJSON: %v", err)
+		}
+	}
+	return ws
+}
+
+// resyncError décode le message error RESYNC_REQUIRED d'une subscription.
+type resyncError []struct {
+	Extensions struct {
+		Code    string
+		BoardID string
+		Version int
+	}
+}
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+func TestSubscriptionOverflow(t *testing.T) {
+	const lastVersion = 60
+	tests := []struct {
+		name   string
+		policy pubsub.Overflow
+		// wantResync : la subscription se termine par RESYNC_REQUIRED ; sinon
+		// le client finit par recevoir la dernière version.
+		wantResync bool
+	}{
+		{"close", pubsub.OverflowClose, true},
+		{"coalesce", pubsub.OverflowCoalesce, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opened := make(openedObserver, 1)
+			broker := pubsub.NewMemory(pubsub.WithBuffer(1), pubsub.WithOverflow(tt.policy), pubsub.WithObserver(opened))
+			svc, err := board.NewService("")
+			if err != nil {
+				t.Fatalf("NewService: %v", err)
+			}
+			ws := subscribe(t, NewResolver(svc, broker), `subscription { boardUpdated(boardId: "b1") { version widgets { configJson } } }`)
+			select {
+			case <-opened:
+			case <-time.After(5 * time.Second):
+				t.Fatal("subscription not opened")
+			}
+
+			// Le client ne lit pas : une fois les tampons réseau pleins, la
+			// file de l'abonné déborde.
+			big := strings.Repeat("x", 256<<10)
+			for v := 2; v <= lastVersion; v++ {
+				_ = broker.Publish(context.Background(), &board.Model{ID: "b1", Version: v, Widgets: []board.Widget{
+					{ID: "w1", Type: "text", Config: map[string]interface{}{"text": big}},
+				}})
+			}
+
+			_ = ws.SetReadDeadline(time.Now().Add(10 * time.Second))
+			last := 0
+			for {
+				var msg wsMessage
+				if err := ws.ReadJSON(&msg); err != nil {
+					t.Fatalf("ReadJSON after version %d: %v", last, err)
+				}
+				switch msg.Type {
+				case "connection_ack", "ka", "ping":
+					continue
+				case "next":
+					var resp struct {
+						Data struct {
+							BoardUpdated struct{ Version int } `json:"boardUpdated"`
+						} `json:"data"`
+					}
+					if err := json.Unmarshal(msg.Payload, &resp); err != nil {
+						t.Fatalf("decode next: %v", err)
+					}
+					last = resp.Data.BoardUpdated.Version
+					if !tt.wantResync && last == lastVersion {
+						return
+					}
+				case "error":
+					if !tt.wantResync {
+						t.Fatalf("error message: %s", msg.Payload)
+					}
+					var errs resyncError
+					if err := json.Unmarshal(msg.Payload, &errs); err != nil || len(errs) != 1 {
+						t.Fatalf("error payload %s: %v", msg.Payload, err)
+					}
+					if ext := errs[0].Extensions; ext.Code != CodeResyncRequired || ext.BoardID != "b1" || ext.Version != last {
+						t.Fatalf("error extensions = %+v, want %s for b1 at version %d", ext, CodeResyncRequired, last)
+					}
+					return
+				default:
+					t.Fatalf("unexpected %s message after version %d", msg.Type, last)
+				}
+			}
+		})
+	}
+}
+
+// textOpened signale l'ouverture des subscriptions textUpdated.
+type textOpened struct {
+	pubsub.Broker
+	opened chan struct{}
+}
+
+func (b textOpened) SubscribeText(boardID, widgetID, field string) (*pubsub.TextSubscription, error) {
+	sub, err := b.Broker.SubscribeText(boardID, widgetID, field)
+	b.opened <- struct{}{}
+	return sub, err
+}
+
+func TestTextSubscription(t *testing.T) {
+	ctx := context.Background()
+	svc, err := board.NewService("", board.WithTextFlushDelay(time.Hour))
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	t.Cleanup(func() { _ = svc.Close() })
+	if _, _, err := svc.AddWidget(ctx, "b1", board.AnyVersion, board.Widget{ID: "w1", Type: "text", Config: map[string]interface{}{"text": "ab"}}); err != nil {
+		t.Fatalf("AddWidget: %v", err)
+	}
+	broker := textOpened{Broker: pubsub.NewMemory(), opened: make(chan struct{}, 1)}
+	ws := subscribe(t, NewResolver(svc, broker), `subscription { textUpdated(boardId: "b1", widgetId: "w1", field: "text") { version text } }`)
+	select {
+	case <-broker.opened:
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription not opened")
+	}
+
+	state, err := svc.TextState(ctx, "b1", "w1", "text")
+	if err != nil {
+		t.Fatalf("TextState: %v", err)
+	}
+	client := crdt.NewDoc("", "")
+	if _, err := client.Apply(state.Update); err != nil {
+		t.Fatal(err)
+	}
+	u, err := client.InsertAt("alice", 2, "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.ApplyTextUpdate(ctx, "b1", "w1", "text", u); err != nil {
+		t.Fatalf("ApplyTextUpdate: %v", err)
+	}
+	// Le champ remplacé par une autre écriture, la réplique est recréée.
+	replaced, _, err := svc.UpdateWidget(ctx, "b1", board.AnyVersion, board.Widget{ID: "w1", Type: "text", Config: map[string]interface{}{"text": "new"}})
+	if err != nil {
+		t.Fatalf("UpdateWidget: %v", err)
+	}
+
+	_ = ws.SetReadDeadline(time.Now().Add(10 * time.Second))
+	var texts []string
+	for {
+		var msg wsMessage
+		if err := ws.ReadJSON(&msg); err != nil {
+			t.Fatalf("ReadJSON after %q: %v", texts, err)
+		}
+		switch msg.Type {
+		case "connection_ack", "ka", "ping":
+			continue
+		case "next":
+			var resp struct {
+				Data struct {
+					TextUpdated struct{ Text string } `json:"textUpdated"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(msg.Payload, &resp); err != nil {
+				t.Fatalf("decode next: %v", err)
+			}
+			texts = append(texts, resp.Data.TextUpdated.Text)
+		case "error":
+			var errs resyncError
+			if err := json.Unmarshal(msg.Payload, &errs); err != nil || len(errs) != 1 {
+				t.Fatalf("error payload %s: %v", msg.Payload, err)
+			}
+			if ext := errs[0].Extensions; ext.Code != CodeResyncRequired || ext.Version != replaced.Version {
+				t.Fatalf("error extensions = %+v, want %s at version %d", ext, CodeResyncRequired, replaced.Version)
+			}
+			if len(texts) != 1 || texts[0] != "abc" {
+				t.Fatalf("texts before resync = %q", texts)
+			}
+			return
+		default:
+			t.Fatalf("unexpected %s message after %q", msg.Type, texts)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package graph
+
+import (
+	"context"
+	"errors"
+
+	"miro-lite-standalone/backend/internal/board"
+	"miro-lite-standalone/backend/internal/crdt"
+	"miro-lite-standalone/backend/internal/graph/model"
+	"miro-lite-standalone/backend/internal/pubsub"
+)
+
+func (r *queryResolver) TextState(ctx context.Context, boardID string, widgetID string, field string) (*model.TextUpdate, error) {
+	state, err := r.BoardService.TextState(ctx, boardID, widgetID, field)
+	if err != nil {
+		return nil, err
+	}
+	return textToGraphQL(state), nil
+}
+
+func (r *mutationResolver) ApplyTextUpdate(ctx context.Context, boardID string, widgetID string, field string, update string) (*model.TextUpdate, error) {
+	u, err := crdt.DecodeUpdate(update)
+	if err != nil {
+		return nil, &board.ValidationError{Field: "update", Message: err.Error()}
+	}
+	change, err := r.BoardService.ApplyTextUpdate(ctx, boardID, widgetID, field, u)
+	if err != nil {
+		return nil, err
+	}
+	return textToGraphQL(change), nil
+}
+
+// TextUpdated diffuse les opérations appliquées au champ, sur toutes les
+// instances. Pour ne rien manquer, un client s'abonne avant de lire
+// textState : les opérations reçues en double sont ignorées par sa
+// réplique. Un abonné trop lent, ou dont la réplique a été recréée côté
+// serveur, est fermé avec RESYNC_REQUIRED.
+func (r *subscriptionResolver) TextUpdated(ctx context.Context, boardID string, widgetID string, field string) (<-chan *model.TextUpdate, error) {
+	release, err := r.acquireSubscription(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sub, err := r.broker.SubscribeText(boardID, widgetID, field)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	ch := make(chan *model.TextUpdate, 1)
+	go func() {
+		defer close(ch)
+		defer release()
+		defer sub.Cancel()
+		last := 0
+		for {
+			select {
+			case change, ok := <-sub.Changes():
+				if !ok {
+					if errors.Is(sub.Err(), pubsub.ErrOverflow) {
+						requireResync(ctx, boardID, last)
+					}
+					return
+				}
+				if change.Reset {
+					requireResync(ctx, boardID, change.Version)
+					return
+				}
+				select {
+				case ch <- textToGraphQL(change):
+					last = change.Version
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return ch, nil
+}
+
+func textToGraphQL(change *board.TextChange) *model.TextUpdate {
+	return &model.TextUpdate{
+		BoardID:  change.BoardID,
+		WidgetID: change.WidgetID,
+		Field:    change.Field,
+		Version:  change.Version,
+		Update:   change.Update.Encode(),
+		Text:     change.Text,
+	}
+}
@@ -0,0 +1,218 @@
+// Package health sert /livez et /readyz. Liveness ne dépend que du processus
+// lui-même ; readiness exécute les vérifications des dépendances (store,
+// disque) et répond 503 dès que l'une échoue, pour que l'orchestrateur cesse
+// d'envoyer du trafic à l'instance.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// checkTimeout borne chaque vérification : une sonde qui ne répond pas est
+// un échec, pas une requête pendante.
+const checkTimeout = 2 * time.Second
+
+// CheckFunc renvoie nil si le composant est disponible.
+type CheckFunc func(ctx context.Context) error
+
+// Cached renvoie une vérification qui réutilise le résultat de fn pendant
+// ttl : une sonde coûteuse (écriture disque, aller-retour réseau) n'est pas
+// refaite à chaque requête /readyz, qui n'est pas authentifiée. Les appels
+// concurrents attendent la même sonde ; un échec dû au contexte de
+// l'appelant n'est pas gardé.
+func Cached(ttl time.Duration, fn CheckFunc) CheckFunc {
+	var (
+		mu      sync.Mutex
+		checked time.Time
+		last    error
+	)
+	return func(ctx context.Context) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if !checked.IsZero() && time.Since(checked) < ttl {
+			return last
+		}
+		err := fn(ctx)
+		if ctx.Err() != nil {
+			return err
+		}
+		checked, last = time.Now(), err
+		return err
+	}
+}
+
+type BuildInfo struct {
+	Version   string    `json:"version"`
+	Commit    string    `json:"commit,omitempty"`
+	Modified  bool      `json:"modified,omitempty"`
+	GoVersion string    `json:"goVersion"`
+	StartedAt time.Time `json:"startedAt"`
+}
+
+// ReadBuildInfo complète version avec les informations VCS embarquées par
+// go build.
+func ReadBuildInfo(version string) BuildInfo {
+	info := BuildInfo{Version: version, GoVersion: runtime.Version(), StartedAt: time.Now().UTC()}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				info.Commit = s.Value
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+	}
+	return info
+}
+
+type Checker struct {
+	build    BuildInfo
+	draining atomic.Bool
+
+	mu       sync.RWMutex
+	liveness map[string]CheckFunc
+	ready    map[string]CheckFunc
+}
+
+func NewChecker(build BuildInfo) *Checker {
+	return &Checker{build: build, liveness: map[string]CheckFunc{}, ready: map[string]CheckFunc{}}
+}
+
+// AddLiveness enregistre une vérification de /livez. À réserver à ce qui ne
+// se répare que par un redémarrage (verrou bloqué, par exemple).
+func (c *Checker) AddLiveness(name string, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.liveness[name] = fn
+}
+
+// AddReadiness enregistre une vérification de /readyz.
+func (c *Checker) AddReadiness(name string, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ready[name] = fn
+}
+
+// Drain fait échouer /readyz jusqu'à la fin du processus : appelé au début
+// de l'arrêt gracieux, pour que le trafic parte avant la fermeture.
+func (c *Checker) Drain() {
+	c.draining.Store(true)
+}
+
+func (c *Checker) LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.serve(w, r, c.snapshot(c.liveness), nil)
+	})
+}
+
+func (c *Checker) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var pre error
+		if c.draining.Load() {
+			pre = errors.New("shutting down")
+		}
+		c.serve(w, r, c.snapshot(c.ready), pre)
+	})
+}
+
+func (c *Checker) snapshot(checks map[string]CheckFunc) map[string]CheckFunc {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	out := make(map[string]CheckFunc, len(checks))
+	for name, fn := range checks {
+		out[name] = fn
+	}
+	return out
+}
+
+type report struct {
+	Status string                 `json:"status"`
+	Reason string                 `json:"reason,omitempty"`
+	Checks map[string]checkResult `json:"checks"`
+	Build  BuildInfo              `json:"build"`
+	Uptime string                 `json:"uptime"`
+}
+
+type checkResult struct {
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// serve exécute les vérifications en parallèle. pre, s'il est non nil, rend
+// le rapport en échec quel que soit le résultat des vérifications.
+func (c *Checker) serve(w http.ResponseWriter, r *http.Request, checks map[string]CheckFunc, pre error) {
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]checkResult, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = run(r.Context(), checks[name])
+		}()
+	}
+	wg.Wait()
+
+	rep := report{
+		Status: "ok",
+		Checks: make(map[string]checkResult, len(names)),
+		Build:  c.build,
+		Uptime: time.Since(c.build.StartedAt).Round(time.Second).String(),
+	}
+	if pre != nil {
+		rep.Status, rep.Reason = "fail", pre.Error()
+	}
+	for i, name := range names {
+		rep.Checks[name] = results[i]
+		if results[i].Status != "ok" {
+			rep.Status = "fail"
+		}
+	}
+
+	status := http.StatusOK
+	if rep.Status != "ok" {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if r.Method == http.MethodHead {
+		return
+	}
+	_ = json.NewEncoder(w).Encode(rep)
+}
+
+func run(ctx context.Context, fn CheckFunc) checkResult {
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- fn(ctx) }()
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	res := checkResult{Status: "ok", Duration: time.Since(start).Round(time.Microsecond).String()}
+	if err != nil {
+		res.Status, res.Error = "fail", err.Error()
+	}
+	return res
+}
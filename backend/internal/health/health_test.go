@@ -0,0 +1,125 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCached(t *testing.T) {
+	errDown := errors.New("down")
+	tests := []struct {
+		name      string
+		ttl       time.Duration
+		results   []error
+		cancelled bool
+		wantCalls int
+		wantErr   error
+	}{
+		{"success reused", time.Hour, []error{nil, errDown}, false, 1, nil},
+		{"failure reused", time.Hour, []error{errDown, nil}, false, 1, errDown},
+		{"expired", 0, []error{errDown, nil}, false, 2, nil},
+		{"cancelled caller not cached", time.Hour, []error{context.Canceled, nil}, true, 2, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			check := Cached(tt.ttl, func(ctx context.Context) error {
+				err := tt.results[calls]
+				calls++
+				return err
+			})
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.cancelled {
+				cancel()
+			}
+			_ = check(ctx)
+			cancel()
+			err := check(context.Background())
+			if calls != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", calls, tt.wantCalls)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("err = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadyHandler(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	failing := func(context.Context) error { return errors.New("disk full") }
+	hanging := func(ctx context.Context) error { <-ctx.Done(); return ctx.Err() }
+
+	tests := []struct {
+		name       string
+		checks     map[string]CheckFunc
+		drain      bool
+		wantStatus int
+		wantFailed []string
+	}{
+		{"no checks", nil, false, http.StatusOK, nil},
+		{"all ok", map[string]CheckFunc{"store": ok, "storage": ok}, false, http.StatusOK, nil},
+		{"one failing", map[string]CheckFunc{"store": ok, "storage": failing}, false, http.StatusServiceUnavailable, []string{"storage"}},
+		{"draining", map[string]CheckFunc{"store": ok}, true, http.StatusServiceUnavailable, nil},
+		{"cancelled by caller", map[string]CheckFunc{"pubsub": hanging}, false, http.StatusServiceUnavailable, []string{"pubsub"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewChecker(ReadBuildInfo("test"))
+			for name, fn := range tt.checks {
+				c.AddReadiness(name, fn)
+			}
+			if tt.drain {
+				c.Drain()
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			rec := httptest.NewRecorder()
+			c.ReadyHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil).WithContext(ctx))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			var rep report
+			if err := json.Unmarshal(rec.Body.Bytes(), &rep); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if len(rep.Checks) != len(tt.checks) {
+				t.Errorf("checks = %v, want %d entries", rep.Checks, len(tt.checks))
+			}
+			failed := 0
+			for _, name := range tt.wantFailed {
+				if got := rep.Checks[name]; got.Status != "fail" || got.Error == "" {
+					t.Errorf("check %s = %+v, want a failure", name, got)
+				}
+			}
+			for _, res := range rep.Checks {
+				if res.Status == "fail" {
+					failed++
+				}
+			}
+			if failed != len(tt.wantFailed) {
+				t.Errorf("%d checks failed, want %d", failed, len(tt.wantFailed))
+			}
+			if tt.drain && rep.Reason == "" {
+				t.Error("draining report has no reason")
+			}
+		})
+	}
+}
+
+func TestLiveHandlerHead(t *testing.T) {
+	c := NewChecker(ReadBuildInfo("test"))
+	c.AddLiveness("lock", func(context.Context) error { return nil })
+	c.Drain() // ne concerne que /readyz
+	rec := httptest.NewRecorder()
+	c.LiveHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/livez", nil))
+	if rec.Code != http.StatusOK || rec.Body.Len() != 0 {
+		t.Errorf("HEAD /livez = %d with %d bytes, want 200 without a body", rec.Code, rec.Body.Len())
+	}
+}
@@ -0,0 +1,44 @@
+// Package identity porte dans le contexte d'une requête l'identité de
+// l'appelant : l'utilisateur authentifié (Subject), s'il y en a un, et
+// l'acteur des écritures (Actor), propriétaire de l'historique d'annulation.
+// Les deux sont posés par middleware.Identity.
+package identity
+
+import "context"
+
+type subjectKey struct{}
+
+// WithSubject marque la requête comme authentifiée pour subject : seul un
+// utilisateur vérifié a un seau de limitation à son nom (voir
+// ratelimit.ClientKey).
+func WithSubject(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, subjectKey{}, subject)
+}
+
+func SubjectFrom(ctx context.Context) string {
+	subject, _ := ctx.Value(subjectKey{}).(string)
+	return subject
+}
+
+// SubjectKey renvoie "user:" suivi de l'utilisateur authentifié, "" pour
+// une requête anonyme.
+func SubjectKey(ctx context.Context) string {
+	if subject := SubjectFrom(ctx); subject != "" {
+		return "user:" + subject
+	}
+	return ""
+}
+
+type actorKey struct{}
+
+// WithActor rattache l'auteur des écritures : ses écritures sont alors
+// annulables par board.Service.Undo, depuis toute requête qui présente la
+// même identité.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+func ActorFrom(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
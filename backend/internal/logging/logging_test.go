@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    slog.Level
+		wantErr bool
+	}{
+		{"debug", slog.LevelDebug, false},
+		{" INFO ", slog.LevelInfo, false},
+		{"warn", slog.LevelWarn, false},
+		{"error", slog.LevelError, false},
+		{"loud", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLevel(tt.raw)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseLevel(%q) = %v, %v", tt.raw, got, err)
+		}
+	}
+}
+
+func TestContextAttributes(t *testing.T) {
+	traceID, _ := trace.TraceIDFromHex("0102030405060708090a0b0c0d0e0f10")
+	spanID, _ := trace.SpanIDFromHex("0102030405060708")
+	traced := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: spanID}))
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want map[string]string
+	}{
+		{"no request", context.Background(), map[string]string{}},
+		{"request id", WithRequestID(context.Background(), "req-1"), map[string]string{"request_id": "req-1"}},
+		{"trace", WithRequestID(traced, "req-2"), map[string]string{"request_id": "req-2", "trace_id": traceID.String(), "span_id": spanID.String()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, err := New(&buf, slog.LevelInfo, "json")
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			logger.With("component", "test").InfoContext(tt.ctx, "hello")
+			var record map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatalf("log line %q: %v", buf.String(), err)
+			}
+			for _, key := range []string{"request_id", "trace_id", "span_id"} {
+				got, _ := record[key].(string)
+				if got != tt.want[key] {
+					t.Errorf("%s = %q, want %q", key, got, tt.want[key])
+				}
+			}
+			if record["component"] != "test" {
+				t.Errorf("WithAttrs lost: %v", record)
+			}
+		})
+	}
+}
+
+func TestNewFormats(t *testing.T) {
+	for _, format := range []string{"", "text", "JSON"} {
+		if _, err := New(&bytes.Buffer{}, slog.LevelInfo, format); err != nil {
+			t.Errorf("New(%q): %v", format, err)
+		}
+	}
+	if _, err := New(&bytes.Buffer{}, slog.LevelInfo, "xml"); err == nil {
+		t.Error("New accepted format xml")
+	}
+}
@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+)
+
+// otherOperation remplace dans le label operation les noms inconnus : le nom
+// vient du client, qui pourrait sinon créer une série par requête.
+const otherOperation = "other"
+
+// GraphQLExtension renvoie l'extension gqlgen qui alimente
+// mirolite_graphql_*. À ajouter avec handler.Server.Use. Seuls les noms
+// d'opérations de operations (manifeste de persisted queries, clients
+// connus) sont repris dans les labels, les autres deviennent "other".
+func (m *Metrics) GraphQLExtension(operations []string) graphql.HandlerExtension {
+	known := make(map[string]bool, len(operations))
+	for _, name := range operations {
+		known[name] = true
+	}
+	return graphqlExtension{m: m, known: known}
+}
+
+type graphqlExtension struct {
+	m     *Metrics
+	known map[string]bool
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+} = graphqlExtension{}
+
+func (graphqlExtension) ExtensionName() string { return "PrometheusMetrics" }
+
+func (graphqlExtension) Validate(graphql.ExecutableSchema) error { return nil }
+
+func (e graphqlExtension) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	resp := next(ctx)
+	if e.m == nil || resp == nil || !graphql.HasOperationContext(ctx) {
+		return resp
+	}
+	oc := graphql.GetOperationContext(ctx)
+	operation := oc.OperationName
+	opType := "unknown"
+	if oc.Operation != nil {
+		opType = string(oc.Operation.Operation)
+		if operation == "" {
+			operation = oc.Operation.Name
+		}
+	}
+	switch {
+	case operation == "":
+		operation = "anonymous"
+	case !e.known[operation]:
+		operation = otherOperation
+	}
+
+	e.m.graphqlOperations.WithLabelValues(operation, opType, responseCode(resp)).Inc()
+	// Pour une subscription, la durée depuis le début n'a pas de sens.
+	if opType != "subscription" && !oc.Stats.OperationStart.IsZero() {
+		e.m.graphqlDuration.WithLabelValues(operation, opType).Observe(time.Since(oc.Stats.OperationStart).Seconds())
+	}
+	return resp
+}
+
+// responseCode reprend extensions.code de la première erreur (posé par
+// graph.ErrorPresenter ou par gqlgen), OK sinon.
+func responseCode(resp *graphql.Response) string {
+	if len(resp.Errors) == 0 {
+		return "OK"
+	}
+	if code, ok := resp.Errors[0].Extensions["code"].(string); ok && code != "" {
+		return code
+	}
+	return "INTERNAL"
+}
@@ -0,0 +1,226 @@
+// Package metrics expose les métriques Prometheus du serveur sur /metrics.
+// Toutes les méthodes acceptent un *Metrics nil, pour les usages (outils,
+// embarqué) qui n'en veulent pas.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "mirolite"
+
+type Metrics struct {
+	registry *prometheus.Registry
+
+	graphqlOperations *prometheus.CounterVec
+	graphqlDuration   *prometheus.HistogramVec
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	storeSaveDuration prometheus.Histogram
+	storeBytesWritten prometheus.Counter
+	storeSaveErrors   prometheus.Counter
+
+	subscriptions   *prometheus.GaugeVec
+	droppedMessages *prometheus.CounterVec
+
+	rateLimited *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		graphqlOperations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "graphql_responses_total",
+			Help:      "GraphQL responses by operation name, type and error code (OK without error). Subscriptions count one per pushed event.",
+		}, []string{"operation", "type", "code"}),
+		graphqlDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "graphql_operation_duration_seconds",
+			Help:      "Duration of GraphQL queries and mutations.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"operation", "type"}),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "REST requests by route, method and status code.",
+		}, []string{"route", "method", "code"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Duration of REST requests by route and method.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method"}),
+		storeSaveDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "store_save_duration_seconds",
+			Help:      "Duration of board store writes to disk.",
+			Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}),
+		storeBytesWritten: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "store_bytes_written_total",
+			Help:      "Bytes written to the board store.",
+		}),
+		storeSaveErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "store_save_errors_total",
+			Help:      "Failed board store writes.",
+		}),
+		subscriptions: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "subscriptions_active",
+			Help:      "Open boardUpdated subscriptions per board.",
+		}, []string{"board"}),
+		droppedMessages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "subscription_messages_dropped_total",
+			Help:      "boardUpdated events dropped because a subscriber was too slow.",
+		}, []string{"board"}),
+		rateLimited: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limited_requests_total",
+			Help:      "HTTP requests rejected with 429, by transport (graphql, rest, other).",
+		}, []string{"transport"}),
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.graphqlOperations, m.graphqlDuration,
+		m.httpRequests, m.httpDuration,
+		m.storeSaveDuration, m.storeBytesWritten, m.storeSaveErrors,
+		m.subscriptions, m.droppedMessages,
+		m.rateLimited,
+	)
+	return m
+}
+
+func (m *Metrics) Handler() http.Handler {
+	if m == nil {
+		return http.NotFoundHandler()
+	}
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RegisterBoardCount publie le nombre de boards, lu à chaque scrape.
+func (m *Metrics) RegisterBoardCount(count func() int) {
+	if m == nil {
+		return
+	}
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "boards",
+		Help:      "Number of boards held by the service.",
+	}, func() float64 { return float64(count()) }))
+}
+
+// ObserveSave a la signature attendue par board.WithSaveObserver.
+func (m *Metrics) ObserveSave(d time.Duration, bytes int, err error) {
+	if m == nil {
+		return
+	}
+	m.storeSaveDuration.Observe(d.Seconds())
+	if err != nil {
+		m.storeSaveErrors.Inc()
+		return
+	}
+	m.storeBytesWritten.Add(float64(bytes))
+}
+
+func (m *Metrics) SubscriptionOpened(boardID string) {
+	if m == nil {
+		return
+	}
+	m.subscriptions.WithLabelValues(boardID).Inc()
+}
+
+// SubscriptionClosed retire les séries du board quand il n'a plus d'abonné,
+// pour ne pas garder des séries par board jamais ouvert depuis. Le compteur
+// d'abandons repart alors de zéro, ce que rate() traite comme un reset.
+func (m *Metrics) SubscriptionClosed(boardID string, remaining int) {
+	if m == nil {
+		return
+	}
+	if remaining == 0 {
+		m.forgetBoard(boardID)
+		return
+	}
+	m.subscriptions.WithLabelValues(boardID).Set(float64(remaining))
+}
+
+// BoardDeleted a la signature attendue par board.Service.OnBoardDeleted.
+func (m *Metrics) BoardDeleted(boardID string) {
+	if m == nil {
+		return
+	}
+	m.forgetBoard(boardID)
+}
+
+func (m *Metrics) forgetBoard(boardID string) {
+	m.subscriptions.DeleteLabelValues(boardID)
+	m.droppedMessages.DeleteLabelValues(boardID)
+}
+
+func (m *Metrics) MessageDropped(boardID string) {
+	if m == nil {
+		return
+	}
+	m.droppedMessages.WithLabelValues(boardID).Inc()
+}
+
+// InstrumentHandler compte et chronomètre les requêtes d'une route REST.
+// route est le motif du ServeMux, pas le chemin, pour borner la cardinalité.
+func (m *Metrics) InstrumentHandler(route string, next http.Handler) http.Handler {
+	if m == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+		m.httpRequests.WithLabelValues(route, r.Method, strconv.Itoa(sw.status)).Inc()
+		m.httpDuration.WithLabelValues(route, r.Method).Observe(time.Since(start).Seconds())
+	})
+}
+
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// RateLimited a la signature attendue par middleware.RateLimit.
+func (m *Metrics) RateLimited(r *http.Request) {
+	if m == nil {
+		return
+	}
+	transport := "other"
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/graphql"):
+		transport = "graphql"
+	case strings.HasPrefix(r.URL.Path, "/api/"):
+		transport = "rest"
+	}
+	m.rateLimited.WithLabelValues(transport).Inc()
+}
@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"miro-lite-standalone/backend/internal/board"
+	"miro-lite-standalone/backend/internal/graph"
+)
+
+func TestGraphQLOperationLabel(t *testing.T) {
+	m := New()
+	svc, err := board.NewService("")
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	srv := handler.New(graph.NewExecutableSchema(graph.NewConfig(graph.NewResolver(svc, nil))))
+	srv.AddTransport(transport.POST{})
+	srv.SetErrorPresenter(graph.ErrorPresenter)
+	srv.Use(m.GraphQLExtension([]string{"GetBoard"}))
+	c := client.New(srv)
+
+	tests := []struct {
+		name      string
+		query     string
+		operation string
+		code      string
+	}{
+		{"known name", `query GetBoard { board(id: "b1") { id } }`, "GetBoard", "OK"},
+		{"unknown name", `query Spam123 { board(id: "b1") { id } }`, "other", "OK"},
+		{"anonymous", `{ board(id: "b1") { id } }`, "anonymous", "OK"},
+		{"known name with error", `mutation GetBoard { saveBoard(boardId: "b1", version: 9, widgets: []) { id } }`, "GetBoard", board.CodeVersionConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _ = c.RawPost(tt.query)
+			opType := "query"
+			if tt.code != "OK" {
+				opType = "mutation"
+			}
+			if got := testutil.ToFloat64(m.graphqlOperations.WithLabelValues(tt.operation, opType, tt.code)); got != 1 {
+				t.Fatalf("graphql_responses_total{operation=%q,code=%q} = %v, want 1", tt.operation, tt.code, got)
+			}
+		})
+	}
+	if n := testutil.CollectAndCount(m.graphqlOperations); n != len(tests) {
+		t.Fatalf("%d series, want %d", n, len(tests))
+	}
+}
+
+func TestBoardSeriesDeleted(t *testing.T) {
+	tests := []struct {
+		name   string
+		forget func(m *Metrics)
+		want   int
+	}{
+		{"subscriber left", func(m *Metrics) { m.SubscriptionClosed("b1", 1) }, 2},
+		{"last subscriber left", func(m *Metrics) { m.SubscriptionClosed("b1", 0) }, 0},
+		{"board deleted", func(m *Metrics) { m.BoardDeleted("b1") }, 0},
+		{"other board deleted", func(m *Metrics) { m.BoardDeleted("b2") }, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New()
+			m.SubscriptionOpened("b1")
+			m.SubscriptionOpened("b1")
+			m.MessageDropped("b1")
+			tt.forget(m)
+			if n := testutil.CollectAndCount(m.subscriptions) + testutil.CollectAndCount(m.droppedMessages); n != tt.want {
+				t.Fatalf("%d board series left, want %d", n, tt.want)
+			}
+		})
+	}
+}
+
+func TestNilMetrics(t *testing.T) {
+	var m *Metrics
+	m.SubscriptionOpened("b1")
+	m.SubscriptionClosed("b1", 0)
+	m.MessageDropped("b1")
+	m.BoardDeleted("b1")
+	m.ObserveSave(0, 0, nil)
+	m.RegisterBoardCount(func() int { return 0 })
+}
+
+func TestInstrumentHandler(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		code    string
+	}{
+		{"implicit 200", func(w http.ResponseWriter, _ *http.Request) { _, _ = w.Write([]byte("ok")) }, "200"},
+		{"explicit status", func(w http.ResponseWriter, _ *http.Request) { w.WriteHeader(http.StatusConflict) }, "409"},
+		{"first status wins", func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			w.WriteHeader(http.StatusOK)
+		}, "404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New()
+			h := m.InstrumentHandler("/api/boards/{id}", tt.handler)
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/boards/b1", nil))
+			if got := testutil.ToFloat64(m.httpRequests.WithLabelValues("/api/boards/{id}", http.MethodGet, tt.code)); got != 1 {
+				t.Fatalf("http_requests_total{code=%q} = %v, want 1", tt.code, got)
+			}
+		})
+	}
+}
+
+func TestHandler(t *testing.T) {
+	m := New()
+	m.RegisterBoardCount(func() int { return 3 })
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), "mirolite_boards 3") {
+		t.Fatalf("/metrics without mirolite_boards:\n%s", rec.Body)
+	}
+	var nilMetrics *Metrics
+	rec = httptest.NewRecorder()
+	nilMetrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("nil metrics: status %d", rec.Code)
+	}
+}
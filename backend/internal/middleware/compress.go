@@ -0,0 +1,282 @@
+// Package middleware regroupe les middlewares HTTP du serveur.
+package middleware
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+)
+
+// DefaultCompressMinSize : en dessous, l'en-tête et le coût CPU de la
+// compression ne valent pas le gain.
+const DefaultCompressMinSize = 1024
+
+// brotliLevel : sur data/boards.json (PNG en base64), le niveau 4 compresse
+// autant que le 6 (71 % contre 70 %) pour 1,6x moins de CPU.
+const brotliLevel = 4
+
+var (
+	gzipPool   = sync.Pool{New: func() interface{} { return gzip.NewWriter(io.Discard) }}
+	brotliPool = sync.Pool{New: func() interface{} { return brotli.NewWriterLevel(io.Discard, brotliLevel) }}
+)
+
+// Compress compresse les réponses en br ou gzip selon Accept-Encoding. La
+// réponse est mise en tampon jusqu'à minSize octets : si elle se termine avant,
+// elle part telle quelle. Les upgrades websocket ne sont pas touchés.
+func Compress(minSize int) func(http.Handler) http.Handler {
+	if minSize <= 0 {
+		minSize = DefaultCompressMinSize
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Add("Vary", "Accept-Encoding")
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" || r.Method == http.MethodHead || isUpgrade(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			cw := &compressWriter{ResponseWriter: w, encoding: encoding, minSize: minSize, ifNoneMatch: r.Header.Get("If-None-Match")}
+			defer cw.Close()
+			next.ServeHTTP(cw, r)
+		})
+	}
+}
+
+func isUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") ||
+		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
+
+// negotiateEncoding choisit br puis gzip, en respectant les q-values (q=0
+// exclut un codage).
+func negotiateEncoding(header string) string {
+	best, bestQ := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "br" && name != "gzip" {
+			continue
+		}
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q <= 0 {
+			continue
+		}
+		if q > bestQ || (q == bestQ && name == "br") {
+			best, bestQ = name, q
+		}
+	}
+	return best
+}
+
+// EncodedETag ajoute le codage à un ETag fort : "b1.3" servi en gzip devient
+// "b1.3-gzip". Les octets compressés diffèrent de ceux de la représentation
+// d'origine, ils ne peuvent pas partager son ETag fort. Un ETag faible, ou
+// sans codage, est renvoyé tel quel.
+func EncodedETag(etag, encoding string) string {
+	if encoding == "" || !strings.HasPrefix(etag, `"`) || len(etag) < 2 || !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return etag[:len(etag)-1] + "-" + encoding + `"`
+}
+
+// TrimETagEncoding retire le suffixe posé par EncodedETag : un ETag renvoyé
+// par le client (If-None-Match, If-Match) se compare alors à celui de la
+// ressource, quel que soit le codage sous lequel il a été reçu.
+func TrimETagEncoding(tag string) string {
+	for _, encoding := range []string{"br", "gzip"} {
+		if base, ok := strings.CutSuffix(tag, "-"+encoding+`"`); ok && strings.HasPrefix(base, `"`) && len(base) > 1 {
+			return base + `"`
+		}
+	}
+	return tag
+}
+
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	minSize  int
+	// ifNoneMatch : l'en-tête de la requête, pour rendre dans un 304 l'ETag
+	// que le client a reçu.
+	ifNoneMatch string
+
+	status      int
+	buf         []byte
+	compressor  io.WriteCloser
+	passthrough bool
+	wroteHeader bool
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.status != 0 {
+		return
+	}
+	cw.status = status
+	h := cw.Header()
+	// Rien à compresser, ou déjà compressé par le handler / par nature.
+	if status < 200 || status == http.StatusNoContent || status == http.StatusNotModified ||
+		h.Get("Content-Encoding") != "" || isIncompressible(h.Get("Content-Type")) {
+		cw.passthrough = true
+	}
+	// Le 304 confirme la représentation que le client a en cache : s'il l'a
+	// reçue compressée, il retrouve l'ETag de cette version-là.
+	if status == http.StatusNotModified {
+		if etag := EncodedETag(h.Get("ETag"), cw.encoding); etagListed(cw.ifNoneMatch, etag) {
+			h.Set("ETag", etag)
+		}
+	}
+}
+
+// etagListed indique si etag figure dans la liste d'un If-None-Match, à la
+// comparaison faible près.
+func etagListed(header, etag string) bool {
+	want := strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		if strings.TrimPrefix(strings.TrimSpace(tag), "W/") == want {
+			return true
+		}
+	}
+	return false
+}
+
+func isIncompressible(contentType string) bool {
+	ct := strings.ToLower(contentType)
+	switch {
+	case ct == "image/svg+xml":
+		return false
+	case strings.HasPrefix(ct, "image/"), strings.HasPrefix(ct, "video/"), strings.HasPrefix(ct, "audio/"),
+		strings.HasPrefix(ct, "application/zip"), strings.HasPrefix(ct, "application/gzip"),
+		strings.HasPrefix(ct, "text/event-stream"):
+		return true
+	}
+	return false
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.passthrough {
+		cw.flushHeader()
+		return cw.ResponseWriter.Write(p)
+	}
+	if cw.compressor != nil {
+		return cw.compressor.Write(p)
+	}
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) >= cw.minSize {
+		if err := cw.startCompression(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+func (cw *compressWriter) flushHeader() {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+	cw.ResponseWriter.WriteHeader(cw.status)
+}
+
+func (cw *compressWriter) startCompression() error {
+	h := cw.Header()
+	if h.Get("Content-Type") == "" {
+		h.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+	h.Set("Content-Encoding", cw.encoding)
+	h.Del("Content-Length")
+	if etag := h.Get("ETag"); etag != "" {
+		h.Set("ETag", EncodedETag(etag, cw.encoding))
+	}
+	cw.flushHeader()
+
+	switch cw.encoding {
+	case "br":
+		bw := brotliPool.Get().(*brotli.Writer)
+		bw.Reset(cw.ResponseWriter)
+		cw.compressor = bw
+	default:
+		gw := gzipPool.Get().(*gzip.Writer)
+		gw.Reset(cw.ResponseWriter)
+		cw.compressor = gw
+	}
+	buffered := cw.buf
+	cw.buf = nil
+	_, err := cw.compressor.Write(buffered)
+	return err
+}
+
+// Close termine le flux compressé, ou envoie tel quel une réponse restée sous
+// le seuil.
+func (cw *compressWriter) Close() error {
+	if cw.compressor != nil {
+		err := cw.compressor.Close()
+		switch c := cw.compressor.(type) {
+		case *brotli.Writer:
+			brotliPool.Put(c)
+		case *gzip.Writer:
+			gzipPool.Put(c)
+		}
+		cw.compressor = nil
+		return err
+	}
+	if cw.status == 0 {
+		return nil
+	}
+	cw.flushHeader()
+	if len(cw.buf) > 0 {
+		_, err := cw.ResponseWriter.Write(cw.buf)
+		cw.buf = nil
+		return err
+	}
+	return nil
+}
+
+func (cw *compressWriter) Flush() {
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.compressor == nil && !cw.passthrough && len(cw.buf) > 0 {
+		// Un flush explicite (streaming) force la décision de compresser.
+		_ = cw.startCompression()
+	}
+	switch c := cw.compressor.(type) {
+	case *gzip.Writer:
+		_ = c.Flush()
+	case *brotli.Writer:
+		_ = c.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		cw.flushHeader()
+		f.Flush()
+	}
+}
+
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := cw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("middleware: underlying ResponseWriter does not support hijacking")
+	}
+	cw.passthrough = true
+	return hj.Hijack()
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
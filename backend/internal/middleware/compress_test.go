@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"identity", ""},
+		{"gzip", "gzip"},
+		{"gzip, br", "br"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"br;q=0, gzip;q=0.1", "gzip"},
+		{"GZIP;q=0.8, BR;q=0.8", "br"},
+		{"br;q=oops, gzip", "gzip"},
+		{"gzip;q=0", ""},
+	}
+	for _, tt := range tests {
+		if got := negotiateEncoding(tt.header); got != tt.want {
+			t.Errorf("negotiateEncoding(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+func TestCompress(t *testing.T) {
+	large := strings.Repeat(`{"id":"w1","type":"text"},`, 100)
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		status         int
+		body           string
+		wantEncoding   string
+	}{
+		{"gzip", "gzip", "application/json", http.StatusOK, large, "gzip"},
+		{"brotli", "gzip, br", "application/json", http.StatusOK, large, "br"},
+		{"svg", "gzip", "image/svg+xml", http.StatusOK, large, "gzip"},
+		{"not accepted", "", "application/json", http.StatusOK, large, ""},
+		{"under threshold", "gzip", "application/json", http.StatusOK, `{"id":"w1"}`, ""},
+		{"png", "gzip", "image/png", http.StatusOK, large, ""},
+		{"not modified", "gzip", "application/json", http.StatusNotModified, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Compress(0)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(tt.status)
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("Content-Encoding = %q, want %q", got, tt.wantEncoding)
+			}
+			if got := decode(t, tt.wantEncoding, rec.Body); got != tt.body {
+				t.Fatalf("decoded body differs: %d bytes, want %d", len(got), len(tt.body))
+			}
+		})
+	}
+}
+
+func TestCompressETag(t *testing.T) {
+	large := strings.Repeat(`{"id":"w1","type":"text"},`, 100)
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		etag           string
+		ifNoneMatch    string
+		body           string
+		wantStatus     int
+		wantETag       string
+	}{
+		{"identity", "", `"b1.2"`, "", large, http.StatusOK, `"b1.2"`},
+		{"gzip", "gzip", `"b1.2"`, "", large, http.StatusOK, `"b1.2-gzip"`},
+		{"brotli", "br", `"b1.2"`, "", large, http.StatusOK, `"b1.2-br"`},
+		{"under threshold", "gzip", `"b1.2"`, "", `{}`, http.StatusOK, `"b1.2"`},
+		{"weak", "gzip", `W/"list"`, "", large, http.StatusOK, `W/"list"`},
+		{"not modified gzip", "gzip", `"b1.2"`, `"b1.2-gzip"`, large, http.StatusNotModified, `"b1.2-gzip"`},
+		{"not modified identity", "gzip", `"b1.2"`, `"b1.2"`, large, http.StatusNotModified, `"b1.2"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Compress(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("ETag", tt.etag)
+				if r.Header.Get("If-None-Match") != "" {
+					w.WriteHeader(http.StatusNotModified)
+					return
+				}
+				_, _ = io.WriteString(w, tt.body)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("Accept-Encoding", tt.acceptEncoding)
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus || rec.Header().Get("ETag") != tt.wantETag {
+				t.Fatalf("status, ETag = %d, %s, want %d, %s", rec.Code, rec.Header().Get("ETag"), tt.wantStatus, tt.wantETag)
+			}
+		})
+	}
+}
+
+func TestTrimETagEncoding(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{`"b1.2-gzip"`, `"b1.2"`},
+		{`"b1.2-br"`, `"b1.2"`},
+		{`"b1.2"`, `"b1.2"`},
+		{`"b1.2-zstd"`, `"b1.2-zstd"`},
+		{`"-gzip"`, `"-gzip"`},
+	}
+	for _, tt := range tests {
+		if got := TrimETagEncoding(tt.tag); got != tt.want {
+			t.Errorf("TrimETagEncoding(%s) = %s, want %s", tt.tag, got, tt.want)
+		}
+		if got := TrimETagEncoding(EncodedETag(tt.want, "gzip")); got != tt.want {
+			t.Errorf("TrimETagEncoding(EncodedETag(%s)) = %s", tt.want, got)
+		}
+	}
+}
+
+func decode(t *testing.T, encoding string, body io.Reader) string {
+	t.Helper()
+	var r io.Reader = body
+	switch encoding {
+	case "gzip":
+		gr, err := gzip.NewReader(body)
+		if err != nil {
+			t.Fatalf("gzip: %v", err)
+		}
+		r = gr
+	case "br":
+		r = brotli.NewReader(body)
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("decode %s: %v", encoding, err)
+	}
+	return string(b)
+}
+
+// BenchmarkCompress mesure la compression du store d'exemple
+// data/boards.json, servi comme un gros board : go test -bench Compress
+// ./internal/middleware affiche le débit (MB/s, sur la taille non
+// compressée) et le ratio obtenu par codage.
+func BenchmarkCompress(b *testing.B) {
+	body, err := os.ReadFile("../../data/boards.json")
+	if err != nil {
+		b.Fatalf("sample store: %v", err)
+	}
+	h := Compress(0)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}))
+	for _, encoding := range []string{"identity", "gzip", "br"} {
+		b.Run(encoding, func(b *testing.B) {
+			req := httptest.NewRequest(http.MethodGet, "/api/boards/demo-board", nil)
+			req.Header.Set("Accept-Encoding", encoding)
+			var out bytes.Buffer
+			b.SetBytes(int64(len(body)))
+			b.ReportAllocs()
+			for b.Loop() {
+				out.Reset()
+				h.ServeHTTP(&bufferWriter{header: http.Header{}, buf: &out}, req)
+			}
+			b.ReportMetric(float64(out.Len())/float64(len(body)), "ratio")
+		})
+	}
+}
+
+// bufferWriter est un ResponseWriter minimal, pour que le benchmark ne
+// mesure pas l'enregistreur de httptest.
+type bufferWriter struct {
+	header http.Header
+	buf    *bytes.Buffer
+}
+
+func (w *bufferWriter) Header() http.Header         { return w.header }
+func (w *bufferWriter) WriteHeader(int)             {}
+func (w *bufferWriter) Write(p []byte) (int, error) { return w.buf.Write(p) }
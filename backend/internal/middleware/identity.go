@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"miro-lite-standalone/backend/internal/identity"
+)
+
+// UserIDHeader porte l'utilisateur authentifié par un proxy placé devant le
+// serveur, quand Identity lui fait confiance. Sans un tel proxy, le client
+// le choisit : rien n'empêche de l'usurper.
+const UserIDHeader = "X-User-ID"
+
+// SessionCookie identifie un client sans utilisateur authentifié, le temps
+// que son navigateur (ou son cookie jar) le garde.
+const SessionCookie = "miro_session"
+
+// sessionIDBytes : 128 bits aléatoires, impossibles à deviner.
+const sessionIDBytes = 16
+
+// Identity rattache au contexte l'identité de l'appelant. À placer avant
+// RateLimit :
+//   - si trustUserID, X-User-ID est l'utilisateur authentifié
+//     (identity.WithSubject), qui a son propre seau de limitation ;
+//   - l'auteur des écritures (identity.WithActor), propriétaire de
+//     l'historique d'annulation, est cet utilisateur, sinon la session du
+//     cookie SessionCookie, posé à la première requête qui n'en a pas.
+//
+// Les chemins de skip (sondes, /metrics) n'ont ni identité ni cookie.
+func Identity(trustUserID bool, skip ...string) func(http.Handler) http.Handler {
+	skipped := make(map[string]bool, len(skip))
+	for _, path := range skip {
+		skipped[path] = true
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skipped[r.URL.Path] || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx := r.Context()
+			if id := r.Header.Get(UserIDHeader); trustUserID && validRequestID(id) {
+				ctx = identity.WithSubject(ctx, id)
+			}
+			actor := identity.SubjectKey(ctx)
+			if actor == "" {
+				actor = "session:" + session(w, r)
+			}
+			next.ServeHTTP(w, r.WithContext(identity.WithActor(ctx, actor)))
+		})
+	}
+}
+
+// session renvoie l'identifiant de session de r, et en pose un nouveau
+// quand le cookie manque ou n'a pas été émis par ce serveur.
+func session(w http.ResponseWriter, r *http.Request) string {
+	if c, err := r.Cookie(SessionCookie); err == nil && validSessionID(c.Value) {
+		return c.Value
+	}
+	b := make([]byte, sessionIDBytes)
+	_, _ = rand.Read(b) // ne renvoie jamais d'erreur
+	id := hex.EncodeToString(b)
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookie,
+		Value:    id,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	return id
+}
+
+func validSessionID(id string) bool {
+	b, err := hex.DecodeString(id)
+	return err == nil && len(b) == sessionIDBytes
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"miro-lite-standalone/backend/internal/identity"
+	"miro-lite-standalone/backend/internal/ratelimit"
+)
+
+func TestIdentity(t *testing.T) {
+	const known = "0123456789abcdef0123456789abcdef"
+	tests := []struct {
+		name        string
+		path        string
+		cookie      string
+		userID      string
+		trustUserID bool
+		wantSubject string
+		// wantActor "session:new" : une nouvelle session, posée en cookie.
+		wantActor string
+	}{
+		{"new session", "/graphql", "", "", false, "", "session:new"},
+		{"known session", "/graphql", known, "", false, "", "session:" + known},
+		{"forged session", "/graphql", "alice", "", false, "", "session:new"},
+		{"header ignored by default", "/graphql", known, "bob", false, "", "session:" + known},
+		{"trusted header", "/graphql", known, "bob", true, "bob", "user:bob"},
+		{"invalid header", "/graphql", "", strings.Repeat("b", 129), true, "", "session:new"},
+		{"skipped path", "/readyz", "", "bob", true, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var subject, actor, key string
+			h := Identity(tt.trustUserID, "/readyz")(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				subject, actor = identity.SubjectFrom(r.Context()), identity.ActorFrom(r.Context())
+				key = ratelimit.ClientKey(r, 0)
+			}))
+			req := httptest.NewRequest(http.MethodPost, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer unverified")
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: SessionCookie, Value: tt.cookie})
+			}
+			if tt.userID != "" {
+				req.Header.Set(UserIDHeader, tt.userID)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			var issued string
+			for _, c := range rec.Result().Cookies() {
+				if c.Name == SessionCookie {
+					issued = c.Value
+					if !c.HttpOnly || c.Path != "/" || c.SameSite != http.SameSiteLaxMode {
+						t.Fatalf("cookie = %+v", c)
+					}
+				}
+			}
+			want := tt.wantActor
+			if want == "session:new" {
+				if !validSessionID(issued) || issued == tt.cookie {
+					t.Fatalf("issued session = %q, want a new one", issued)
+				}
+				want = "session:" + issued
+			} else if issued != "" {
+				t.Fatalf("issued session %q, want none", issued)
+			}
+			if subject != tt.wantSubject || actor != want {
+				t.Fatalf("subject, actor = %q, %q, want %q, %q", subject, actor, tt.wantSubject, want)
+			}
+			// Seul l'utilisateur authentifié a son propre seau : une session
+			// se renouvelle en jetant le cookie.
+			if wantKey := "ip:192.0.2.1"; tt.wantSubject != "" {
+				if key != actor {
+					t.Fatalf("rate limit key = %q, want %q", key, actor)
+				}
+			} else if tt.path != "/readyz" && key != wantKey {
+				t.Fatalf("rate limit key = %q, want %q", key, wantKey)
+			}
+		})
+	}
+}
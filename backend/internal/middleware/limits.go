@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"miro-lite-standalone/backend/internal/ratelimit"
+)
+
+// CodePayloadTooLarge accompagne les réponses 413.
+const CodePayloadTooLarge = "PAYLOAD_TOO_LARGE"
+
+// RateLimit refuse en 429 les requêtes d'un client qui a épuisé son seau, et
+// rattache son identité au contexte. onLimited (peut être nil) est appelé à
+// chaque refus. Les chemins de skip (sondes, /metrics) ne sont pas comptés.
+// trustedProxies : voir ratelimit.ClientIP.
+func RateLimit(l *ratelimit.Limiter, trustedProxies int, onLimited func(r *http.Request), skip ...string) func(http.Handler) http.Handler {
+	skipped := make(map[string]bool, len(skip))
+	for _, path := range skip {
+		skipped[path] = true
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skipped[r.URL.Path] || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key := ratelimit.ClientKey(r, trustedProxies)
+			if err := l.Allow(key); err != nil {
+				if onLimited != nil {
+					onLimited(r)
+				}
+				rl := err.(*ratelimit.Error)
+				w.Header().Set("Retry-After", strconv.Itoa(rl.RetryAfterSeconds()))
+				writeLimitError(w, r, http.StatusTooManyRequests, ratelimit.CodeRateLimited, rl.Error())
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ratelimit.WithKey(r.Context(), key)))
+		})
+	}
+}
+
+// MaxBody borne le corps des requêtes : maxUpload pour multipart/form-data,
+// maxBody pour le reste. Un Content-Length trop grand est refusé d'emblée en
+// 413 ; sinon la lecture échoue au-delà de la limite (*http.MaxBytesError).
+func MaxBody(maxBody, maxUpload int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+			limit := maxBody
+			if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+				limit = maxUpload
+			}
+			if limit <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if r.ContentLength > limit {
+				writeLimitError(w, r, http.StatusRequestEntityTooLarge, CodePayloadTooLarge,
+					fmt.Sprintf("request body exceeds %d bytes", limit))
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// writeLimitError répond au format du transport visé : erreurs GraphQL sur
+// /graphql, corps d'erreur REST ailleurs.
+func writeLimitError(w http.ResponseWriter, r *http.Request, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	var body interface{}
+	if strings.HasPrefix(r.URL.Path, "/graphql") {
+		body = map[string]interface{}{
+			"errors": []map[string]interface{}{{
+				"message":    message,
+				"extensions": map[string]interface{}{"code": code},
+			}},
+		}
+	} else {
+		body = map[string]interface{}{
+			"error": map[string]interface{}{"code": code, "message": message},
+		}
+	}
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"miro-lite-standalone/backend/internal/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		method     string
+		auth       []string
+		wantStatus []int
+	}{
+		{"burst exhausted", "/api/boards", http.MethodGet, []string{"", ""}, []int{http.StatusOK, http.StatusTooManyRequests}},
+		{"forged tokens share the ip bucket", "/api/boards", http.MethodGet, []string{"Bearer a", "Bearer b"}, []int{http.StatusOK, http.StatusTooManyRequests}},
+		{"skipped path", "/readyz", http.MethodGet, []string{"", ""}, []int{http.StatusOK, http.StatusOK}},
+		{"preflight", "/api/boards", http.MethodOptions, []string{"", ""}, []int{http.StatusOK, http.StatusOK}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var key string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { key = ratelimit.KeyFrom(r.Context()) })
+			h := RateLimit(ratelimit.New(0.001, 1), 0, nil, "/readyz")(next)
+			for i, auth := range tt.auth {
+				r := httptest.NewRequest(tt.method, tt.path, nil)
+				if auth != "" {
+					r.Header.Set("Authorization", auth)
+				}
+				rec := httptest.NewRecorder()
+				h.ServeHTTP(rec, r)
+				if rec.Code != tt.wantStatus[i] {
+					t.Fatalf("request %d: status = %d, want %d", i, rec.Code, tt.wantStatus[i])
+				}
+				if rec.Code == http.StatusTooManyRequests && rec.Header().Get("Retry-After") == "" {
+					t.Errorf("request %d: 429 without Retry-After", i)
+				}
+			}
+			if tt.wantStatus[0] == http.StatusOK && tt.path != "/readyz" && tt.method != http.MethodOptions && !strings.HasPrefix(key, "ip:") {
+				t.Errorf("context key = %q, want the client IP", key)
+			}
+		})
+	}
+}
+
+func TestLimitErrorFormat(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/graphql", `"errors":[{`},
+		{"/api/boards", `"error":{"code":"RATE_LIMITED"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			h := RateLimit(ratelimit.New(0.001, 1), 0, nil)(http.NotFoundHandler())
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, tt.path, nil))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, tt.path, nil))
+			if !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("body = %s, want %s", rec.Body, tt.want)
+			}
+		})
+	}
+}
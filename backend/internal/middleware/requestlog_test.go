@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"miro-lite-standalone/backend/internal/logging"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		keep   bool
+	}{
+		{"missing", "", false},
+		{"client id", "abc-123", true},
+		{"too long", strings.Repeat("a", 129), false},
+		{"control characters", "abc\tdef", false},
+		{"non ascii", "réq", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			h := RequestID(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				seen = logging.RequestID(r.Context())
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if got := rec.Header().Get(RequestIDHeader); got != seen || seen == "" {
+				t.Fatalf("response id %q, context id %q", got, seen)
+			}
+			if (seen == tt.header) != tt.keep {
+				t.Fatalf("id = %q, client sent %q", seen, tt.header)
+			}
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := logging.New(&buf, slog.LevelInfo, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := RequestID(AccessLog(logger, slog.LevelInfo)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("hello"))
+	})))
+	req := httptest.NewRequest(http.MethodPost, "/api/boards", nil)
+	req.Header.Set(RequestIDHeader, "req-42")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	var line struct {
+		Msg       string  `json:"msg"`
+		Method    string  `json:"method"`
+		Path      string  `json:"path"`
+		Status    int     `json:"status"`
+		Bytes     int     `json:"bytes"`
+		RequestID string  `json:"request_id"`
+		Duration  float64 `json:"duration"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("access log %q: %v", buf.String(), err)
+	}
+	if line.Msg != "http request" || line.Method != http.MethodPost || line.Path != "/api/boards" ||
+		line.Status != http.StatusCreated || line.Bytes != 5 || line.RequestID != "req-42" {
+		t.Fatalf("access log = %+v", line)
+	}
+}
@@ -0,0 +1,359 @@
+// Package mirror fait de ce serveur le miroir de boards choisis d'une autre
+// instance miro-lite, l'upstream. Il implémente board.Upstream : les
+// lectures sont servies depuis la copie locale tant qu'elle est fraîche, les
+// écritures sont transmises à l'upstream (saveBoard avec la version
+// attendue) et les conflits sont ceux de l'upstream.
+//
+// En mode proxy, la copie locale est relue après CacheTTL. En mode
+// replicate, chaque board mirroré est suivi par la subscription boardUpdated
+// de l'upstream et reste frais tant qu'elle est ouverte ; les boards listés
+// explicitement sont chargés dès le démarrage. Pendant une coupure de la
+// websocket la copie peut prendre du retard : elle est relue à la
+// reconnexion, et une écriture partie d'une version périmée est refusée
+// par l'upstream.
+package mirror
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"miro-lite-standalone/backend/internal/board"
+	"miro-lite-standalone/backend/internal/boardclient"
+	"miro-lite-standalone/backend/internal/remote"
+)
+
+type Mode string
+
+const (
+	ModeProxy     Mode = "proxy"
+	ModeReplicate Mode = "replicate"
+)
+
+func ParseMode(raw string) (Mode, error) {
+	switch Mode(strings.ToLower(strings.TrimSpace(raw))) {
+	case "", ModeProxy:
+		return ModeProxy, nil
+	case ModeReplicate:
+		return ModeReplicate, nil
+	default:
+		return "", fmt.Errorf("unknown mirror mode %q (want %q or %q)", raw, ModeProxy, ModeReplicate)
+	}
+}
+
+// DefaultCacheTTL : durée de validité d'une copie en mode proxy.
+const DefaultCacheTTL = 5 * time.Second
+
+type Mirror struct {
+	patterns []string
+	mode     Mode
+	ttl      time.Duration
+	logger   *slog.Logger
+	token    string
+	remote   remote.RemoteGraphQL
+	api      boardclient.API
+	subs     *remote.SubscriptionClient
+
+	mu      sync.Mutex
+	fetched map[string]time.Time
+	// live : boards suivis dont la copie a été chargée depuis l'ouverture de
+	// la subscription.
+	live     map[string]bool
+	watching map[string]*watched
+	svc      *board.Service
+	ctx      context.Context
+}
+
+var _ board.Upstream = (*Mirror)(nil)
+
+// watched est la subscription boardUpdated d'un board, ouverte depuis la
+// version since de sa copie (0 : ouverte avant la première lecture).
+type watched struct {
+	sub   *remote.Subscription
+	since int
+}
+
+type Option func(*Mirror)
+
+func WithMode(mode Mode) Option {
+	return func(m *Mirror) { m.mode = mode }
+}
+
+// WithCacheTTL règle la fraîcheur d'une copie lue (DefaultCacheTTL par
+// défaut, 0 : relire à chaque lecture).
+func WithCacheTTL(d time.Duration) Option {
+	return func(m *Mirror) { m.ttl = d }
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(m *Mirror) { m.logger = logger }
+}
+
+// WithToken authentifie les appels à l'upstream (Authorization: Bearer),
+// requêtes HTTP comme websocket.
+func WithToken(token string) Option {
+	return func(m *Mirror) { m.token = token }
+}
+
+// New prépare le miroir des boards désignés par patterns sur l'upstream
+// endpoint (URL de son /graphql). Un motif est un ID de board ou un préfixe
+// terminé par * ("team-*", "*" pour tous les boards).
+func New(endpoint string, patterns []string, opts ...Option) *Mirror {
+	m := &Mirror{
+		patterns: patterns,
+		mode:     ModeProxy,
+		ttl:      DefaultCacheTTL,
+		logger:   slog.New(slog.DiscardHandler),
+		fetched:  make(map[string]time.Time),
+		live:     make(map[string]bool),
+		watching: make(map[string]*watched),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	var httpOpts []remote.Option
+	var subOpts []remote.SubscriptionOption
+	if m.token != "" {
+		httpOpts = append(httpOpts, remote.WithBearerToken(m.token))
+		subOpts = append(subOpts,
+			remote.WithDialHeader("Authorization", "Bearer "+m.token),
+			remote.WithInitPayload(map[string]interface{}{"Authorization": "Bearer " + m.token}))
+	}
+	m.remote = remote.NewHTTPRemoteGraphQL(endpoint, httpOpts...)
+	m.api = boardclient.New(m.remote)
+	if m.mode == ModeReplicate {
+		subOpts = append(subOpts, remote.WithOnReconnect(func() { go m.resync() }))
+		m.subs = remote.NewSubscriptionClient(endpoint, subOpts...)
+	}
+	return m
+}
+
+// Mirrors indique si id correspond à l'un des motifs.
+func (m *Mirror) Mirrors(id string) bool {
+	for _, p := range m.patterns {
+		if prefix, ok := strings.CutSuffix(p, "*"); ok {
+			if strings.HasPrefix(id, prefix) {
+				return true
+			}
+		} else if id == p {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Mirror) Fresh(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.live[id] {
+		return true
+	}
+	at, ok := m.fetched[id]
+	return ok && time.Since(at) < m.ttl
+}
+
+func (m *Mirror) Fetch(ctx context.Context, id string) (*board.Model, error) {
+	b, err := m.api.GetBoard(ctx, id)
+	if err != nil {
+		return nil, upstreamError(err)
+	}
+	model, err := toModel(b)
+	if err != nil {
+		return nil, err
+	}
+	m.touch(id)
+	m.watch(id, model.Version)
+	return model, nil
+}
+
+func (m *Mirror) Save(ctx context.Context, id string, version int, next *board.Model) (*board.Model, error) {
+	inputs := make([]*boardclient.WidgetInput, len(next.Widgets))
+	for i, w := range next.Widgets {
+		input, err := boardclient.NewWidgetInput(w.ID, w.Type, w.X, w.Y, w.Width, w.Height, w.Config)
+		if err != nil {
+			return nil, &board.ValidationError{Field: fmt.Sprintf("widgets[%d].config", i), Message: err.Error()}
+		}
+		inputs[i] = input
+	}
+	saved, err := m.api.SaveBoard(ctx, id, version, inputs)
+	if err != nil {
+		return nil, upstreamError(err)
+	}
+	model, err := toModel(saved)
+	if err != nil {
+		return nil, err
+	}
+	m.touch(id)
+	return model, nil
+}
+
+// Run suit l'upstream jusqu'à l'annulation de ctx. En mode replicate, les
+// boards listés sans * sont chargés puis suivis ; les autres le sont à leur
+// première lecture. En mode proxy, Run rend la main aussitôt.
+func (m *Mirror) Run(ctx context.Context, svc *board.Service) error {
+	if m.mode != ModeReplicate {
+		return nil
+	}
+	m.mu.Lock()
+	m.svc, m.ctx = svc, ctx
+	m.mu.Unlock()
+	for _, p := range m.patterns {
+		if strings.HasSuffix(p, "*") {
+			continue
+		}
+		// Suivre avant de lire : si l'upstream ne répond pas encore, la copie
+		// sera relue à la reconnexion.
+		m.watch(p, 0)
+		if _, err := svc.ResolveBoard(ctx, p); err != nil {
+			m.logger.WarnContext(ctx, "mirror: initial fetch failed", "board", p, "err", err)
+		}
+	}
+	<-ctx.Done()
+	m.subs.Close()
+	return nil
+}
+
+// pingQuery est acceptée par tout serveur GraphQL.
+const pingQuery = `query Ping { __typename }`
+
+// CheckUpstream vérifie que l'upstream répond. Une réponse GraphQL, même en
+// erreur (requête hors liste blanche, par exemple), suffit : seules les
+// pannes réseau et les statuts HTTP inattendus font échouer la vérification.
+func (m *Mirror) CheckUpstream(ctx context.Context) error {
+	err := m.remote.Do(ctx, pingQuery, nil, nil)
+	var gqlErrs remote.GraphQLErrors
+	if err == nil || errors.As(err, &gqlErrs) {
+		return nil
+	}
+	return fmt.Errorf("mirror upstream: %w", err)
+}
+
+func (m *Mirror) touch(id string) {
+	m.mu.Lock()
+	m.fetched[id] = time.Now()
+	m.mu.Unlock()
+}
+
+// watch ouvre la subscription boardUpdated de id si Run tourne et qu'elle ne
+// l'est pas déjà. version est celle de la copie qui vient d'être lue (0 si
+// elle ne l'a pas encore été) : l'upstream rejoue les écritures qui l'ont
+// suivie, y compris celles arrivées avant que la subscription ne soit
+// établie, et la copie suivie reste alors fraîche. Une subscription ouverte
+// avant la première lecture est rouverte depuis cette version.
+func (m *Mirror) watch(id string, version int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.subs == nil || m.svc == nil {
+		return
+	}
+	w := m.watching[id]
+	if w != nil && w.since == 0 && version > 0 {
+		w.sub.Close()
+		w = nil
+	}
+	if w == nil {
+		variables := map[string]interface{}{"boardId": id}
+		if version > 0 {
+			variables["sinceVersion"] = version
+		}
+		sub, err := m.subs.Subscribe(m.ctx, boardclient.BoardUpdatedSubscription, variables)
+		if err != nil {
+			m.logger.WarnContext(m.ctx, "mirror: subscribe failed", "board", id, "err", err)
+			return
+		}
+		m.watching[id] = &watched{sub: sub, since: version}
+		go m.follow(id, sub, m.svc)
+	}
+	if version > 0 {
+		m.live[id] = true
+	}
+}
+
+// follow applique les événements de sub jusqu'à sa fin ; le board est alors
+// relu à sa prochaine lecture, ce qui rouvre la subscription.
+func (m *Mirror) follow(id string, sub *remote.Subscription, svc *board.Service) {
+	for ev := range sub.Events() {
+		if len(ev.Errors) > 0 {
+			m.logger.Warn("mirror: upstream event error", "board", id, "err", ev.Errors)
+			continue
+		}
+		var data boardclient.BoardUpdatedEvent
+		if err := ev.Decode(&data); err != nil || data.BoardUpdated == nil {
+			m.logger.Warn("mirror: undecodable upstream event", "board", id, "err", err)
+			continue
+		}
+		model, err := toModel(data.BoardUpdated)
+		if err == nil {
+			_, err = svc.ApplyUpstream(m.ctx, model)
+		}
+		if errors.Is(err, board.ErrClosed) {
+			return
+		}
+		if err != nil {
+			m.logger.Warn("mirror: applying upstream update failed", "board", id, "version", data.BoardUpdated.Version, "err", err)
+			continue
+		}
+		m.touch(id)
+	}
+	m.mu.Lock()
+	if w := m.watching[id]; w != nil && w.sub == sub {
+		delete(m.watching, id)
+		delete(m.live, id)
+	}
+	m.mu.Unlock()
+	if err := sub.Err(); err != nil {
+		m.logger.Warn("mirror: upstream subscription ended", "board", id, "err", err)
+	}
+}
+
+// resync relit les boards suivis après une reconnexion : les événements
+// émis pendant la coupure sont perdus.
+func (m *Mirror) resync() {
+	m.mu.Lock()
+	ids := make([]string, 0, len(m.watching))
+	for id := range m.watching {
+		ids = append(ids, id)
+	}
+	svc, ctx := m.svc, m.ctx
+	m.mu.Unlock()
+	for _, id := range ids {
+		m.mu.Lock()
+		delete(m.live, id)
+		m.mu.Unlock()
+		b, err := m.Fetch(ctx, id)
+		if err == nil {
+			_, err = svc.ApplyUpstream(ctx, b)
+		}
+		if err != nil {
+			m.logger.WarnContext(ctx, "mirror: resync failed", "board", id, "err", err)
+		}
+	}
+	m.logger.InfoContext(ctx, "mirror: reconnected to upstream", "boards", len(ids))
+}
+
+// upstreamError garde les erreurs du domaine (conflit, NOT_FOUND,
+// validation), que le client traite comme venant de ce serveur ; le reste
+// devient board.ErrUpstream.
+func upstreamError(err error) error {
+	if errors.Is(err, board.ErrVersionConflict) || errors.Is(err, board.ErrNotFound) || errors.Is(err, board.ErrValidation) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", board.ErrUpstream, err)
+}
+
+func toModel(b *boardclient.Board) (*board.Model, error) {
+	model := &board.Model{ID: b.ID, Title: b.Title, Version: b.Version, Widgets: make([]board.Widget, len(b.Widgets))}
+	for i, w := range b.Widgets {
+		var config map[string]interface{}
+		if err := json.Unmarshal([]byte(w.ConfigJSON), &config); err != nil {
+			return nil, fmt.Errorf("%w: board %s widget %s: invalid configJson: %w", board.ErrUpstream, b.ID, w.ID, err)
+		}
+		model.Widgets[i] = board.Widget{ID: w.ID, Type: w.Type, X: w.X, Y: w.Y, Width: w.Width, Height: w.Height, Config: config}
+	}
+	return model, nil
+}
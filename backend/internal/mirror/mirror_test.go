@@ -0,0 +1,343 @@
+package mirror
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+
+	"miro-lite-standalone/backend/internal/board"
+	"miro-lite-standalone/backend/internal/graph"
+	"miro-lite-standalone/backend/internal/remote"
+)
+
+func TestCheckUpstream(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr bool
+	}{
+		{"ok", http.StatusOK, `{"data":{"__typename":"Query"}}`, false},
+		{"graphql error", http.StatusOK, `{"errors":[{"message":"PersistedQueryNotFound"}]}`, false},
+		{"validation error", http.StatusUnprocessableEntity, `{"errors":[{"message":"unknown query"}]}`, false},
+		{"unauthorized", http.StatusUnauthorized, `unauthorized`, true},
+		{"unavailable", http.StatusServiceUnavailable, `down`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			err := New(srv.URL, []string{"*"}).CheckUpstream(ctx)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckUpstream = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckUpstreamUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := New(srv.URL, []string{"*"}).CheckUpstream(ctx); err == nil {
+		t.Fatal("CheckUpstream against a closed server succeeded")
+	}
+}
+
+// ─── Upstream de test ────────────────────────────────────────────────────────
+
+// connListener garde les connexions acceptées pour pouvoir les couper, y
+// compris celles détournées par l'upgrade websocket.
+type connListener struct {
+	net.Listener
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.mu.Lock()
+		l.conns = append(l.conns, conn)
+		l.mu.Unlock()
+	}
+	return conn, err
+}
+
+func (l *connListener) drop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, conn := range l.conns {
+		_ = conn.Close()
+	}
+	l.conns = nil
+}
+
+var operationName = regexp.MustCompile(`^\s*(?:query|mutation|subscription)\s+(\w+)`)
+
+// testUpstream est une instance miro-lite servie par httptest (HTTP et
+// websocket), avec le board b1. calls compte les opérations reçues par nom.
+type testUpstream struct {
+	url string
+	svc *board.Service
+	// drop coupe les connexions ouvertes, stop arrête le serveur.
+	drop, stop func()
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func newTestUpstream(t *testing.T) *testUpstream {
+	t.Helper()
+	svc, err := board.NewService("")
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	if _, _, err := svc.AddWidget(context.Background(), "b1", board.AnyVersion, board.Widget{ID: "w1", Type: "text"}); err != nil {
+		t.Fatalf("AddWidget: %v", err)
+	}
+	resolver := graph.NewResolver(svc, nil)
+	srv := handler.New(graph.NewExecutableSchema(graph.NewConfig(resolver)))
+	srv.AddTransport(transport.Websocket{InitFunc: resolver.WebsocketInit})
+	srv.AddTransport(transport.POST{})
+	srv.SetErrorPresenter(graph.ErrorPresenter)
+	u := &testUpstream{svc: svc, calls: make(map[string]int)}
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			body, _ := io.ReadAll(r.Body)
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			var req struct{ Query string }
+			_ = json.Unmarshal(body, &req)
+			if m := operationName.FindStringSubmatch(req.Query); m != nil {
+				u.mu.Lock()
+				u.calls[m[1]]++
+				u.mu.Unlock()
+			}
+		}
+		srv.ServeHTTP(w, r)
+	}))
+	ln := &connListener{Listener: ts.Listener}
+	ts.Listener = ln
+	ts.Start()
+	t.Cleanup(func() {
+		resolver.Shutdown()
+		u.stop()
+	})
+	u.url, u.drop = ts.URL, ln.drop
+	u.stop = func() {
+		ln.drop()
+		ts.Close()
+	}
+	return u
+}
+
+func (u *testUpstream) count(op string) int {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.calls[op]
+}
+
+// write modifie b1 directement sur l'upstream, comme un autre client, et
+// renvoie sa nouvelle version.
+func (u *testUpstream) write(t *testing.T) int {
+	t.Helper()
+	b, _, err := u.svc.AddWidget(context.Background(), "b1", board.AnyVersion, board.Widget{ID: board.NewWidgetID(), Type: "text"})
+	if err != nil {
+		t.Fatalf("upstream AddWidget: %v", err)
+	}
+	return b.Version
+}
+
+func newMirroredService(t *testing.T, m *Mirror) *board.Service {
+	t.Helper()
+	svc, err := board.NewService("", board.WithUpstream(m))
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	return svc
+}
+
+// eventually attend que cond soit vraie, au plus 5 secondes.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// waitVersion attend que la copie locale de b1 atteigne version.
+func waitVersion(t *testing.T, svc *board.Service, version int) {
+	t.Helper()
+	eventually(t, fmt.Sprintf("local copy at version %d", version), func() bool {
+		b, ok := svc.GetBoard("b1")
+		return ok && b.Version == version
+	})
+}
+
+// ─── Proxy ───────────────────────────────────────────────────────────────────
+
+func TestProxyReadThrough(t *testing.T) {
+	tests := []struct {
+		name        string
+		ttl         time.Duration
+		wantFetches int
+		wantFresh   bool
+	}{
+		{"within ttl", time.Hour, 1, false},
+		{"no cache", 0, 2, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newTestUpstream(t)
+			svc := newMirroredService(t, New(u.url+"/graphql", []string{"b*"}, WithCacheTTL(tt.ttl)))
+			ctx := context.Background()
+			first, err := svc.ResolveBoard(ctx, "b1")
+			if err != nil {
+				t.Fatalf("ResolveBoard: %v", err)
+			}
+			if first.Version != 2 || len(first.Widgets) != 1 {
+				t.Fatalf("first read = %+v, want the upstream board", first)
+			}
+			latest := u.write(t)
+			second, err := svc.ResolveBoard(ctx, "b1")
+			if err != nil {
+				t.Fatalf("ResolveBoard: %v", err)
+			}
+			if got := u.count("GetBoard"); got != tt.wantFetches {
+				t.Fatalf("upstream fetches = %d, want %d", got, tt.wantFetches)
+			}
+			if fresh := second.Version == latest; fresh != tt.wantFresh {
+				t.Fatalf("second read version = %d, upstream = %d, want fresh %v", second.Version, latest, tt.wantFresh)
+			}
+		})
+	}
+}
+
+func TestProxyServesStaleCopyWhenUpstreamDown(t *testing.T) {
+	u := newTestUpstream(t)
+	svc := newMirroredService(t, New(u.url+"/graphql", []string{"b1", "b2"}, WithCacheTTL(0)))
+	ctx := context.Background()
+	if _, err := svc.ResolveBoard(ctx, "b1"); err != nil {
+		t.Fatalf("ResolveBoard: %v", err)
+	}
+	u.stop()
+	b, err := svc.ResolveBoard(ctx, "b1")
+	if err != nil || b.Version != 2 {
+		t.Fatalf("ResolveBoard with upstream down = %+v, %v, want the stale copy", b, err)
+	}
+	if _, err := svc.ResolveBoard(ctx, "b2"); board.ErrorCode(err) != board.CodeUnavailable {
+		t.Fatalf("ResolveBoard of a board never read = %v, want %s", err, board.CodeUnavailable)
+	}
+}
+
+// ─── Écritures ───────────────────────────────────────────────────────────────
+
+func TestWriteForwarding(t *testing.T) {
+	tests := []struct {
+		name string
+		// concurrent : nombre d'écritures d'un autre client sur l'upstream
+		// après la lecture locale.
+		concurrent int
+		ifVersion  func(read int) int
+		wantErr    string
+		wantSaves  int
+	}{
+		{"current version", 0, func(read int) int { return read }, "", 1},
+		{"stale local copy", 1, func(read int) int { return read }, board.CodeVersionConflict, 1},
+		{"any version is not retried", 1, func(int) int { return board.AnyVersion }, board.CodeVersionConflict, 1},
+		{"internal write is retried", 2, func(int) int { return board.LatestVersion }, "", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := newTestUpstream(t)
+			svc := newMirroredService(t, New(u.url+"/graphql", []string{"b1"}, WithCacheTTL(time.Hour)))
+			ctx := context.Background()
+			read, err := svc.ResolveBoard(ctx, "b1")
+			if err != nil {
+				t.Fatalf("ResolveBoard: %v", err)
+			}
+			latest := read.Version
+			for range tt.concurrent {
+				latest = u.write(t)
+			}
+			saved, _, err := svc.AddWidget(ctx, "b1", tt.ifVersion(read.Version), board.Widget{ID: "local", Type: "text"})
+			if got := u.count("SaveBoard"); got != tt.wantSaves {
+				t.Fatalf("upstream saves = %d, want %d", got, tt.wantSaves)
+			}
+			if tt.wantErr != "" {
+				if board.ErrorCode(err) != tt.wantErr {
+					t.Fatalf("AddWidget err = %v, want %s", err, tt.wantErr)
+				}
+				// La copie locale a été relue pour le prochain essai du client.
+				waitVersion(t, svc, latest)
+				return
+			}
+			if err != nil {
+				t.Fatalf("AddWidget: %v", err)
+			}
+			upstream, _ := u.svc.GetBoard("b1")
+			if saved.Version != upstream.Version || len(upstream.Widgets) != len(saved.Widgets) {
+				t.Fatalf("saved = %+v, upstream = %+v", saved, upstream)
+			}
+			waitVersion(t, svc, upstream.Version)
+		})
+	}
+}
+
+// ─── Replicate ───────────────────────────────────────────────────────────────
+
+func TestReplicateFollowsUpstream(t *testing.T) {
+	u := newTestUpstream(t)
+	m := New(u.url+"/graphql", []string{"b1"}, WithMode(ModeReplicate))
+	m.subs = remote.NewSubscriptionClient(u.url+"/graphql",
+		remote.WithReconnectBackoff(10*time.Millisecond, 50*time.Millisecond),
+		remote.WithOnReconnect(func() { go m.resync() }))
+	svc := newMirroredService(t, m)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		_ = m.Run(ctx, svc)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	// Chargé au démarrage, puis suivi par la subscription.
+	waitVersion(t, svc, 2)
+	eventually(t, "b1 followed", func() bool { return m.Fresh("b1") })
+	waitVersion(t, svc, u.write(t))
+
+	// Après une coupure, les écritures manquées sont rattrapées par la
+	// relecture qui suit la reconnexion.
+	fetches := u.count("GetBoard")
+	u.drop()
+	latest := u.write(t)
+	eventually(t, "resync", func() bool { return u.count("GetBoard") > fetches })
+	waitVersion(t, svc, latest)
+	eventually(t, "b1 followed again", func() bool { return m.Fresh("b1") })
+	waitVersion(t, svc, u.write(t))
+}
@@ -0,0 +1,180 @@
+// Package pubsub diffuse les mises à jour de boards et les opérations des
+// textes collaboratifs aux subscriptions GraphQL. Memory suffit à une
+// instance seule ; avec plusieurs réplicas, Redis relaie chaque écriture,
+// chaque suppression et chaque opération de texte aux autres instances pour
+// que leurs abonnés la reçoivent et que leur board.Service mette sa copie à
+// jour. Redis garde aussi la dernière version de chaque board
+// (board.SharedState), qui départage les écritures concurrentes.
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"miro-lite-standalone/backend/internal/board"
+)
+
+// defaultBuffer : mises à jour en attente par abonné avant débordement.
+const defaultBuffer = 4
+
+// textBuffer : opérations de texte en attente par abonné. Elles ne se
+// remplacent pas les unes les autres : au-delà, l'abonné est fermé.
+const textBuffer = 64
+
+// DefaultReplay : mises à jour gardées par board pour les reprises
+// (sinceVersion).
+const DefaultReplay = 32
+
+// DefaultReplayTTL : durée pendant laquelle l'historique d'un board sans
+// abonné est gardé, le temps qu'un client déconnecté se réabonne.
+const DefaultReplayTTL = 5 * time.Minute
+
+// ErrOverflow est l'erreur d'une subscription fermée parce que son abonné ne
+// suivait plus (politique OverflowClose) : il doit relire le board.
+var ErrOverflow = errors.New("pubsub: subscriber too slow, resync required")
+
+// Overflow dit quoi faire quand la file d'un abonné est pleine.
+type Overflow string
+
+const (
+	// OverflowCoalesce remplace la plus ancienne mise à jour en attente par la
+	// nouvelle : chaque mise à jour porte le board entier, l'abonné saute des
+	// versions mais finit sur l'état courant.
+	OverflowCoalesce Overflow = "coalesce"
+	// OverflowClose ferme la subscription avec ErrOverflow.
+	OverflowClose Overflow = "close"
+)
+
+func ParseOverflow(raw string) (Overflow, error) {
+	switch Overflow(strings.ToLower(strings.TrimSpace(raw))) {
+	case "", OverflowCoalesce:
+		return OverflowCoalesce, nil
+	case OverflowClose:
+		return OverflowClose, nil
+	default:
+		return "", fmt.Errorf("unknown overflow policy %q (want %q or %q)", raw, OverflowCoalesce, OverflowClose)
+	}
+}
+
+// Broker relie les écritures (Publish) aux abonnés d'un board (Subscribe).
+type Broker interface {
+	// Publish diffuse b aux abonnés de b.ID, sur toutes les instances.
+	Publish(ctx context.Context, b *board.Model) error
+	// PublishDeleted annonce la suppression de boardID aux autres instances
+	// et oublie son historique.
+	PublishDeleted(ctx context.Context, boardID string) error
+	// Subscribe renvoie les mises à jour de boardID. Si sinceVersion > 0, les
+	// mises à jour postérieures encore dans l'historique sont rejouées d'abord
+	// (voir Subscription.Gap). Après Close, l'erreur est board.ErrClosed.
+	Subscribe(boardID string, sinceVersion int) (*Subscription, error)
+	// OnRemote enregistre fn, appelée pour chaque mise à jour publiée par
+	// une autre instance (voir board.Service.ApplyRemote).
+	OnRemote(fn func(*board.Model))
+	// OnRemoteDeleted enregistre fn, appelée pour chaque board supprimé par
+	// une autre instance (voir board.Service.ApplyRemoteDelete).
+	OnRemoteDeleted(fn func(boardID string))
+	// PublishText diffuse un changement de texte collaboratif aux abonnés
+	// de son champ, sur toutes les instances.
+	PublishText(ctx context.Context, change *board.TextChange) error
+	// SubscribeText renvoie les changements du champ field d'un widget. Une
+	// opération perdue casserait la réplique du client : un abonné qui ne
+	// suit plus est fermé avec ErrOverflow, quelle que soit WithOverflow.
+	SubscribeText(boardID, widgetID, field string) (*TextSubscription, error)
+	// OnRemoteText enregistre fn, appelée pour chaque changement de texte
+	// publié par une autre instance (voir board.Service.ApplyRemoteText).
+	OnRemoteText(fn func(*board.TextChange))
+	// Close ferme tous les abonnements et refuse les suivants.
+	Close() error
+}
+
+// Observer suit les abonnements et les abandons ; *metrics.Metrics
+// l'implémente.
+type Observer interface {
+	SubscriptionOpened(boardID string)
+	SubscriptionClosed(boardID string, remaining int)
+	MessageDropped(boardID string)
+}
+
+type options struct {
+	buffer    int
+	replay    int
+	replayTTL time.Duration
+	overflow  Overflow
+	observer  Observer
+	logger    *slog.Logger
+	channel   string
+}
+
+type Option func(*options)
+
+// WithBuffer règle la file de chaque abonné (4 par défaut). Quand elle est
+// pleine, la politique de WithOverflow s'applique.
+func WithBuffer(n int) Option {
+	return func(o *options) { o.buffer = n }
+}
+
+// WithReplay règle l'historique gardé par board (DefaultReplay par défaut,
+// 0 : aucune reprise, les abonnés en retard relisent le board).
+func WithReplay(n int) Option {
+	return func(o *options) { o.replay = n }
+}
+
+// WithReplayTTL règle la durée de vie de l'historique d'un board qui n'a
+// plus d'abonné, depuis sa dernière mise à jour ou le départ du dernier
+// abonné (DefaultReplayTTL par défaut). Seuls les boards suivis gardent leur
+// historique au-delà : la mémoire reste proportionnelle à l'activité.
+func WithReplayTTL(d time.Duration) Option {
+	return func(o *options) { o.replayTTL = d }
+}
+
+// WithOverflow choisit la politique de débordement (OverflowCoalesce par
+// défaut).
+func WithOverflow(policy Overflow) Option {
+	return func(o *options) { o.overflow = policy }
+}
+
+func WithObserver(observer Observer) Option {
+	return func(o *options) { o.observer = observer }
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) { o.logger = logger }
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		buffer:    defaultBuffer,
+		replay:    DefaultReplay,
+		replayTTL: DefaultReplayTTL,
+		overflow:  OverflowCoalesce,
+		observer:  nopObserver{},
+		logger:    slog.New(slog.DiscardHandler),
+		channel:   DefaultChannel,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.buffer < 1 {
+		o.buffer = 1
+	}
+	if o.replay < 0 {
+		o.replay = 0
+	}
+	if o.overflow != OverflowClose {
+		o.overflow = OverflowCoalesce
+	}
+	if o.observer == nil {
+		o.observer = nopObserver{}
+	}
+	return o
+}
+
+type nopObserver struct{}
+
+func (nopObserver) SubscriptionOpened(string)      {}
+func (nopObserver) SubscriptionClosed(string, int) {}
+func (nopObserver) MessageDropped(string)          {}
@@ -0,0 +1,344 @@
+package pubsub
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"miro-lite-standalone/backend/internal/board"
+)
+
+// Subscription est l'abonnement d'un client aux mises à jour d'un board.
+type Subscription struct {
+	updates chan *board.Model
+	cancel  func()
+	err     error
+
+	// Gap est vrai quand l'historique ne couvre pas toutes les versions
+	// postérieures à sinceVersion : l'abonné doit relire le board, les
+	// mises à jour rejouées ne suffisent pas.
+	Gap bool
+}
+
+// Updates est fermé par Cancel, par Close du broker ou par un débordement
+// (Err renvoie alors ErrOverflow).
+func (s *Subscription) Updates() <-chan *board.Model { return s.updates }
+
+// Err indique pourquoi Updates a été fermé ; nil pour Cancel ou Close. À
+// lire une fois Updates fermé.
+func (s *Subscription) Err() error { return s.err }
+
+func (s *Subscription) Cancel() { s.cancel() }
+
+// TextSubscription est l'abonnement d'un client aux changements d'un texte
+// collaboratif.
+type TextSubscription struct {
+	changes chan *board.TextChange
+	cancel  func()
+	err     error
+}
+
+// Changes est fermé par Cancel, par Close du broker ou par un débordement
+// (Err renvoie alors ErrOverflow).
+func (s *TextSubscription) Changes() <-chan *board.TextChange { return s.changes }
+
+// Err indique pourquoi Changes a été fermé ; nil pour Cancel ou Close. À
+// lire une fois Changes fermé.
+func (s *TextSubscription) Err() error { return s.err }
+
+func (s *TextSubscription) Cancel() { s.cancel() }
+
+type textKey struct{ board, widget, field string }
+
+// Memory distribue les mises à jour aux abonnés du processus et garde pour
+// chaque board les dernières, dans l'ordre des versions (voir WithReplayTTL
+// pour leur durée de vie).
+type Memory struct {
+	opts options
+	now  func() time.Time
+
+	mu      sync.Mutex
+	nextID  int
+	subs    map[string]map[int]*Subscription
+	history map[string]*history
+	texts   map[textKey]map[int]*TextSubscription
+	// swept : dernier passage de sweepLocked.
+	swept  time.Time
+	closed bool
+}
+
+// history est l'historique d'un board ; at date sa dernière mise à jour ou
+// le départ de son dernier abonné.
+type history struct {
+	updates []*board.Model
+	at      time.Time
+}
+
+var _ Broker = (*Memory)(nil)
+
+func NewMemory(opts ...Option) *Memory {
+	return newMemory(newOptions(opts))
+}
+
+func newMemory(opts options) *Memory {
+	return &Memory{
+		opts:    opts,
+		now:     time.Now,
+		subs:    make(map[string]map[int]*Subscription),
+		history: make(map[string]*history),
+		texts:   make(map[textKey]map[int]*TextSubscription),
+	}
+}
+
+func (m *Memory) Publish(_ context.Context, b *board.Model) error {
+	m.deliver(b)
+	return nil
+}
+
+func (m *Memory) PublishDeleted(_ context.Context, boardID string) error {
+	m.forget(boardID)
+	return nil
+}
+
+// forget oublie l'historique d'un board supprimé : recréé, il repartira de
+// la version 1. Ses abonnés restent abonnés.
+func (m *Memory) forget(boardID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.history, boardID)
+}
+
+// deliver n'attend aucun abonné : quand la file d'un abonné est pleine, la
+// politique de débordement s'applique.
+func (m *Memory) deliver(b *board.Model) {
+	if b == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+	m.record(b)
+	for id, sub := range m.subs[b.ID] {
+		select {
+		case sub.updates <- b:
+			continue
+		default:
+		}
+		m.opts.observer.MessageDropped(b.ID)
+		if m.opts.overflow == OverflowClose {
+			m.opts.logger.Warn("pubsub: subscriber too slow, subscription closed", "board", b.ID, "version", b.Version)
+			sub.err = ErrOverflow
+			m.removeLocked(b.ID, id)
+			continue
+		}
+		// Seul deliver écrit dans la file, sous m.mu : après en avoir retiré
+		// une, la place est garantie.
+		select {
+		case <-sub.updates:
+		default:
+		}
+		sub.updates <- b
+		m.opts.logger.Debug("pubsub: subscriber too slow, updates coalesced", "board", b.ID, "version", b.Version)
+	}
+}
+
+// record ajoute b à l'historique de son board. Une version qui n'avance pas
+// (board supprimé puis recréé, copie divergente) repart d'un historique vide.
+func (m *Memory) record(b *board.Model) {
+	if m.opts.replay == 0 {
+		return
+	}
+	now := m.now()
+	m.sweepLocked(now)
+	h := m.history[b.ID]
+	if h == nil {
+		h = &history{}
+		m.history[b.ID] = h
+	}
+	if n := len(h.updates); n > 0 && h.updates[n-1].Version >= b.Version {
+		h.updates = h.updates[:0]
+	}
+	if len(h.updates) == m.opts.replay {
+		h.updates = append(h.updates[:0], h.updates[1:]...)
+	}
+	h.updates = append(h.updates, b)
+	h.at = now
+}
+
+// sweepLocked oublie, au plus une fois par replayTTL, les historiques
+// expirés des boards sans abonné.
+func (m *Memory) sweepLocked(now time.Time) {
+	if now.Sub(m.swept) < m.opts.replayTTL {
+		return
+	}
+	m.swept = now
+	for id := range m.history {
+		m.expiredLocked(id, now)
+	}
+}
+
+// expiredLocked oublie l'historique de boardID s'il a expiré et indique si
+// c'est le cas.
+func (m *Memory) expiredLocked(boardID string, now time.Time) bool {
+	h := m.history[boardID]
+	if h == nil || len(m.subs[boardID]) > 0 || now.Sub(h.at) < m.opts.replayTTL {
+		return false
+	}
+	delete(m.history, boardID)
+	return true
+}
+
+func (m *Memory) Subscribe(boardID string, sinceVersion int) (*Subscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, board.ErrClosed
+	}
+	var replay []*board.Model
+	gap := false
+	if sinceVersion > 0 {
+		var h []*board.Model
+		if !m.expiredLocked(boardID, m.now()) && m.history[boardID] != nil {
+			h = m.history[boardID].updates
+		}
+		for i, b := range h {
+			if b.Version > sinceVersion {
+				replay = h[i:]
+				break
+			}
+		}
+		gap = len(h) == 0 || h[0].Version > sinceVersion+1
+	}
+	if m.subs[boardID] == nil {
+		m.subs[boardID] = make(map[int]*Subscription)
+	}
+	m.nextID++
+	id := m.nextID
+	sub := &Subscription{
+		updates: make(chan *board.Model, m.opts.buffer+len(replay)),
+		cancel:  func() { m.unsubscribe(boardID, id) },
+		Gap:     gap,
+	}
+	for _, b := range replay {
+		sub.updates <- b
+	}
+	m.subs[boardID][id] = sub
+	m.opts.observer.SubscriptionOpened(boardID)
+	return sub, nil
+}
+
+func (m *Memory) unsubscribe(boardID string, id int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.removeLocked(boardID, id)
+}
+
+func (m *Memory) removeLocked(boardID string, id int) {
+	boardSubs := m.subs[boardID]
+	sub, ok := boardSubs[id]
+	if !ok {
+		return
+	}
+	delete(boardSubs, id)
+	close(sub.updates)
+	m.opts.observer.SubscriptionClosed(boardID, len(boardSubs))
+	if len(boardSubs) == 0 {
+		delete(m.subs, boardID)
+		if h := m.history[boardID]; h != nil {
+			h.at = m.now()
+		}
+	}
+}
+
+func (m *Memory) PublishText(_ context.Context, change *board.TextChange) error {
+	m.deliverText(change)
+	return nil
+}
+
+func (m *Memory) deliverText(change *board.TextChange) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return
+	}
+	key := textKey{change.BoardID, change.WidgetID, change.Field}
+	for id, sub := range m.texts[key] {
+		select {
+		case sub.changes <- change:
+		default:
+			m.opts.observer.MessageDropped(change.BoardID)
+			m.opts.logger.Warn("pubsub: text subscriber too slow, subscription closed", "board", change.BoardID, "widget", change.WidgetID, "field", change.Field)
+			sub.err = ErrOverflow
+			m.removeTextLocked(key, id)
+		}
+	}
+}
+
+func (m *Memory) SubscribeText(boardID, widgetID, field string) (*TextSubscription, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, board.ErrClosed
+	}
+	key := textKey{boardID, widgetID, field}
+	if m.texts[key] == nil {
+		m.texts[key] = make(map[int]*TextSubscription)
+	}
+	m.nextID++
+	id := m.nextID
+	sub := &TextSubscription{
+		changes: make(chan *board.TextChange, textBuffer),
+		cancel: func() {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			m.removeTextLocked(key, id)
+		},
+	}
+	m.texts[key][id] = sub
+	return sub, nil
+}
+
+func (m *Memory) removeTextLocked(key textKey, id int) {
+	sub, ok := m.texts[key][id]
+	if !ok {
+		return
+	}
+	delete(m.texts[key], id)
+	close(sub.changes)
+	if len(m.texts[key]) == 0 {
+		delete(m.texts, key)
+	}
+}
+
+// OnRemote ne fait rien : une instance seule n'a pas de voisines.
+func (m *Memory) OnRemote(func(*board.Model)) {}
+
+func (m *Memory) OnRemoteDeleted(func(string)) {}
+
+func (m *Memory) OnRemoteText(func(*board.TextChange)) {}
+
+func (m *Memory) Close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil
+	}
+	m.closed = true
+	for boardID, boardSubs := range m.subs {
+		for _, sub := range boardSubs {
+			close(sub.updates)
+		}
+		m.opts.observer.SubscriptionClosed(boardID, 0)
+		delete(m.subs, boardID)
+	}
+	for key, subs := range m.texts {
+		for _, sub := range subs {
+			close(sub.changes)
+		}
+		delete(m.texts, key)
+	}
+	m.history = nil
+	return nil
+}
@@ -0,0 +1,260 @@
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"miro-lite-standalone/backend/internal/board"
+)
+
+// countingObserver compte les messages abandonnés.
+type countingObserver struct {
+	mu      sync.Mutex
+	dropped int
+}
+
+func (o *countingObserver) SubscriptionOpened(string)      {}
+func (o *countingObserver) SubscriptionClosed(string, int) {}
+func (o *countingObserver) MessageDropped(string) {
+	o.mu.Lock()
+	o.dropped++
+	o.mu.Unlock()
+}
+
+func publish(t *testing.T, b Broker, id string, versions ...int) {
+	t.Helper()
+	for _, v := range versions {
+		if err := b.Publish(context.Background(), &board.Model{ID: id, Version: v}); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+}
+
+// drain renvoie les versions en attente sur sub.
+func drain(sub *Subscription) []int {
+	var versions []int
+	for {
+		select {
+		case b, ok := <-sub.Updates():
+			if !ok {
+				return versions
+			}
+			versions = append(versions, b.Version)
+		default:
+			return versions
+		}
+	}
+}
+
+func equalVersions(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMemoryReplay(t *testing.T) {
+	tests := []struct {
+		name       string
+		since      int
+		wantReplay []int
+		wantGap    bool
+	}{
+		{"no resume", 0, nil, false},
+		{"covered", 4, []int{5, 6}, false},
+		{"up to date", 6, nil, false},
+		{"ahead", 10, nil, false},
+		{"beyond history", 2, []int{4, 5, 6}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMemory(WithReplay(3))
+			publish(t, m, "b1", 2, 3, 4, 5, 6)
+			sub, err := m.Subscribe("b1", tt.since)
+			if err != nil {
+				t.Fatalf("Subscribe: %v", err)
+			}
+			if got := drain(sub); !equalVersions(got, tt.wantReplay) || sub.Gap != tt.wantGap {
+				t.Fatalf("replay = %v, gap %v, want %v, gap %v", got, sub.Gap, tt.wantReplay, tt.wantGap)
+			}
+		})
+	}
+}
+
+func TestMemoryReplayTTL(t *testing.T) {
+	const ttl = time.Minute
+	tests := []struct {
+		name string
+		// run agit sur m entre la publication de b1 (version 2) et la reprise ;
+		// advance avance l'horloge.
+		run     func(t *testing.T, m *Memory, advance func(time.Duration))
+		wantGap bool
+	}{
+		{"within ttl", func(t *testing.T, m *Memory, advance func(time.Duration)) {
+			advance(ttl - time.Second)
+		}, false},
+		{"expired without subscriber", func(t *testing.T, m *Memory, advance func(time.Duration)) {
+			advance(ttl)
+		}, true},
+		{"kept while followed", func(t *testing.T, m *Memory, advance func(time.Duration)) {
+			sub, _ := m.Subscribe("b1", 0)
+			advance(2 * ttl)
+			publish(t, m, "b2", 2)
+			// Le départ du dernier abonné relance le délai.
+			sub.Cancel()
+			advance(ttl - time.Second)
+		}, false},
+		{"swept by another board", func(t *testing.T, m *Memory, advance func(time.Duration)) {
+			advance(ttl)
+			publish(t, m, "b2", 2)
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			if _, ok := m.history["b1"]; ok {
+				t.Fatal("expired history kept after a sweep")
+			}
+		}, true},
+		{"deleted", func(t *testing.T, m *Memory, advance func(time.Duration)) {
+			if err := m.PublishDeleted(context.Background(), "b1"); err != nil {
+				t.Fatalf("PublishDeleted: %v", err)
+			}
+		}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMemory(WithReplayTTL(ttl))
+			now := time.Unix(0, 0)
+			m.now = func() time.Time { return now }
+			publish(t, m, "b1", 2)
+			tt.run(t, m, func(d time.Duration) { now = now.Add(d) })
+			sub, err := m.Subscribe("b1", 1)
+			if err != nil {
+				t.Fatalf("Subscribe: %v", err)
+			}
+			if sub.Gap != tt.wantGap {
+				t.Fatalf("gap = %v, want %v", sub.Gap, tt.wantGap)
+			}
+		})
+	}
+}
+
+func TestMemoryOverflow(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      Overflow
+		wantPending []int
+		wantErr     error
+	}{
+		{"coalesce", OverflowCoalesce, []int{5}, nil},
+		{"close", OverflowClose, []int{2}, ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			observer := &countingObserver{}
+			m := NewMemory(WithBuffer(1), WithOverflow(tt.policy), WithObserver(observer))
+			sub, err := m.Subscribe("b1", 0)
+			if err != nil {
+				t.Fatalf("Subscribe: %v", err)
+			}
+			publish(t, m, "b1", 2, 3, 4, 5)
+			if got := drain(sub); !equalVersions(got, tt.wantPending) {
+				t.Fatalf("pending = %v, want %v", got, tt.wantPending)
+			}
+			if !errors.Is(sub.Err(), tt.wantErr) {
+				t.Fatalf("Err = %v, want %v", sub.Err(), tt.wantErr)
+			}
+			if observer.dropped == 0 {
+				t.Fatal("no drop observed")
+			}
+		})
+	}
+}
+
+func TestMemoryClose(t *testing.T) {
+	m := NewMemory()
+	sub, err := m.Subscribe("b1", 0)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, ok := <-sub.Updates(); ok || sub.Err() != nil {
+		t.Fatalf("subscription not closed cleanly: %v", sub.Err())
+	}
+	if _, err := m.Subscribe("b1", 0); !errors.Is(err, board.ErrClosed) {
+		t.Fatalf("Subscribe after Close: err = %v", err)
+	}
+	publish(t, m, "b1", 2)
+}
+
+func TestMemoryText(t *testing.T) {
+	change := func(widget string, version int) *board.TextChange {
+		return &board.TextChange{BoardID: "b1", WidgetID: widget, Field: "text", Version: version}
+	}
+	tests := []struct {
+		name        string
+		publish     []*board.TextChange
+		wantPending int
+		wantErr     error
+	}{
+		{"own field only", []*board.TextChange{change("w1", 2), change("w2", 3), change("w1", 4)}, 2, nil},
+		// Même avec OverflowCoalesce : une opération ne remplace pas l'autre.
+		{"overflow closes", func() []*board.TextChange {
+			var changes []*board.TextChange
+			for v := 0; v <= textBuffer; v++ {
+				changes = append(changes, change("w1", v))
+			}
+			return changes
+		}(), textBuffer, ErrOverflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMemory(WithOverflow(OverflowCoalesce))
+			sub, err := m.SubscribeText("b1", "w1", "text")
+			if err != nil {
+				t.Fatalf("SubscribeText: %v", err)
+			}
+			for _, c := range tt.publish {
+				if err := m.PublishText(context.Background(), c); err != nil {
+					t.Fatalf("PublishText: %v", err)
+				}
+			}
+			pending := 0
+			for done := false; !done; {
+				select {
+				case c, ok := <-sub.Changes():
+					if !ok {
+						done = true
+						break
+					}
+					if c.WidgetID != "w1" {
+						t.Fatalf("received %+v", c)
+					}
+					pending++
+				default:
+					done = true
+				}
+			}
+			if pending != tt.wantPending || !errors.Is(sub.Err(), tt.wantErr) {
+				t.Fatalf("pending = %d, err = %v, want %d, %v", pending, sub.Err(), tt.wantPending, tt.wantErr)
+			}
+			if err := m.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if _, ok := <-sub.Changes(); ok {
+				t.Fatal("text subscription open after Close")
+			}
+			if _, err := m.SubscribeText("b1", "w1", "text"); !errors.Is(err, board.ErrClosed) {
+				t.Fatalf("SubscribeText after Close: err = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+
+	"miro-lite-standalone/backend/internal/board"
+)
+
+// DefaultChannel : canal Redis partagé par les instances.
+const DefaultChannel = "miro-lite:boards"
+
+// WithChannel change le canal Redis, pour faire cohabiter plusieurs
+// déploiements sur le même serveur.
+func WithChannel(channel string) Option {
+	return func(o *options) { o.channel = channel }
+}
+
+// Redis relaie les mises à jour, les suppressions et les opérations de
+// texte par PUBLISH/SUBSCRIBE.
+// Les abonnés locaux sont servis tout de suite, sans aller-retour par
+// Redis ; chaque message porte l'identifiant de l'instance qui l'a publié
+// pour qu'elle l'ignore à son retour. Les messages reçus alimentent aussi
+// l'historique local des reprises. Pendant une coupure de Redis, les
+// instances ne se voient plus : go-redis rétablit l'abonnement mais les
+// messages manqués sont perdus, et les écritures échouent (voir Commit).
+type Redis struct {
+	*Memory
+	client  *redis.Client
+	pubsub  *redis.PubSub
+	channel string
+	origin  string
+	done    chan struct{}
+
+	remoteMu      sync.RWMutex
+	remote        []func(*board.Model)
+	remoteDeleted []func(string)
+	remoteText    []func(*board.TextChange)
+}
+
+var (
+	_ Broker            = (*Redis)(nil)
+	_ board.SharedState = (*Redis)(nil)
+)
+
+// envelope est le message publié sur le canal : un board écrit, l'ID d'un
+// board supprimé ou un changement de texte.
+type envelope struct {
+	Origin  string            `json:"origin"`
+	Board   *board.Model      `json:"board,omitempty"`
+	Deleted string            `json:"deleted,omitempty"`
+	Text    *board.TextChange `json:"text,omitempty"`
+}
+
+// NewRedis se connecte à rawURL (redis://[:password@]host:port/db, ou
+// rediss:// pour TLS) et s'abonne au canal. Un serveur injoignable au
+// démarrage est une erreur.
+func NewRedis(ctx context.Context, rawURL string, opts ...Option) (*Redis, error) {
+	redisOpts, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("pubsub: %w", err)
+	}
+	o := newOptions(opts)
+	client := redis.NewClient(redisOpts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		_ = client.Close()
+		return nil, fmt.Errorf("pubsub: redis %s: %w", redisOpts.Addr, err)
+	}
+	pubsub := client.Subscribe(ctx, o.channel)
+	// Attend la confirmation : les publications des autres instances sont
+	// reçues dès le retour.
+	if _, err := pubsub.Receive(ctx); err != nil {
+		_ = pubsub.Close()
+		_ = client.Close()
+		return nil, fmt.Errorf("pubsub: subscribe %s: %w", o.channel, err)
+	}
+	r := &Redis{
+		Memory:  newMemory(o),
+		client:  client,
+		pubsub:  pubsub,
+		channel: o.channel,
+		origin:  uuid.NewString(),
+		done:    make(chan struct{}),
+	}
+	go r.receive()
+	return r, nil
+}
+
+func (r *Redis) Publish(ctx context.Context, b *board.Model) error {
+	r.deliver(b)
+	return r.publish(ctx, b.ID, envelope{Origin: r.origin, Board: b})
+}
+
+func (r *Redis) PublishDeleted(ctx context.Context, boardID string) error {
+	r.forget(boardID)
+	return r.publish(ctx, boardID, envelope{Origin: r.origin, Deleted: boardID})
+}
+
+func (r *Redis) PublishText(ctx context.Context, change *board.TextChange) error {
+	r.deliverText(change)
+	return r.publish(ctx, change.BoardID, envelope{Origin: r.origin, Text: change})
+}
+
+func (r *Redis) publish(ctx context.Context, boardID string, env envelope) error {
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("pubsub: encode board %s: %w", boardID, err)
+	}
+	if err := r.client.Publish(ctx, r.channel, payload).Err(); err != nil {
+		return fmt.Errorf("pubsub: publish board %s: %w", boardID, err)
+	}
+	return nil
+}
+
+// Ping vérifie que le serveur Redis répond : sans lui, les écritures des
+// autres instances ne sont plus reçues.
+func (r *Redis) Ping(ctx context.Context) error {
+	if err := r.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("pubsub: redis: %w", err)
+	}
+	return nil
+}
+
+func (r *Redis) OnRemote(fn func(*board.Model)) {
+	r.remoteMu.Lock()
+	defer r.remoteMu.Unlock()
+	r.remote = append(r.remote, fn)
+}
+
+func (r *Redis) OnRemoteDeleted(fn func(string)) {
+	r.remoteMu.Lock()
+	defer r.remoteMu.Unlock()
+	r.remoteDeleted = append(r.remoteDeleted, fn)
+}
+
+func (r *Redis) OnRemoteText(fn func(*board.TextChange)) {
+	r.remoteMu.Lock()
+	defer r.remoteMu.Unlock()
+	r.remoteText = append(r.remoteText, fn)
+}
+
+func (r *Redis) receive() {
+	defer close(r.done)
+	for msg := range r.pubsub.Channel() {
+		var env envelope
+		if err := json.Unmarshal([]byte(msg.Payload), &env); err != nil || (env.Board == nil && env.Deleted == "" && env.Text == nil) {
+			r.opts.logger.Warn("pubsub: undecodable message", "channel", msg.Channel, "err", err)
+			continue
+		}
+		if env.Origin == r.origin {
+			continue
+		}
+		r.remoteMu.RLock()
+		remote, remoteDeleted, remoteText := r.remote, r.remoteDeleted, r.remoteText
+		r.remoteMu.RUnlock()
+		if env.Text != nil {
+			for _, fn := range remoteText {
+				fn(env.Text)
+			}
+			r.deliverText(env.Text)
+			continue
+		}
+		if env.Deleted != "" {
+			r.forget(env.Deleted)
+			for _, fn := range remoteDeleted {
+				fn(env.Deleted)
+			}
+			continue
+		}
+		for _, fn := range remote {
+			fn(env.Board)
+		}
+		r.deliver(env.Board)
+	}
+}
+
+// Close ferme les abonnements locaux puis la connexion à Redis.
+func (r *Redis) Close() error {
+	_ = r.Memory.Close()
+	err := r.pubsub.Close()
+	<-r.done
+	if cerr := r.client.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,371 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+
+	"miro-lite-standalone/backend/internal/board"
+	"miro-lite-standalone/backend/internal/crdt"
+)
+
+// newTestRedis démarre un serveur miniredis et y connecte un broker.
+func newTestRedis(t *testing.T, mr *miniredis.Miniredis, opts ...Option) *Redis {
+	t.Helper()
+	r, err := NewRedis(context.Background(), "redis://"+mr.Addr(), opts...)
+	if err != nil {
+		t.Fatalf("NewRedis: %v", err)
+	}
+	t.Cleanup(func() { _ = r.Close() })
+	return r
+}
+
+func TestRedisPing(t *testing.T) {
+	mr := miniredis.RunT(t)
+	r := newTestRedis(t, mr)
+	if err := r.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping = %v", err)
+	}
+	mr.Close()
+	if err := r.Ping(context.Background()); err == nil {
+		t.Fatal("Ping after the server stopped succeeded")
+	}
+}
+
+// next attend la mise à jour suivante de ch.
+func next[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("nothing received")
+	}
+	var zero T
+	return zero
+}
+
+// nothing vérifie que ch ne reçoit rien pendant un court instant.
+func nothing[T any](t *testing.T, ch <-chan T) {
+	t.Helper()
+	select {
+	case v := <-ch:
+		t.Fatalf("unexpected %v", v)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestRedisFanOut(t *testing.T) {
+	mr := miniredis.RunT(t)
+	a, b := newTestRedis(t, mr), newTestRedis(t, mr)
+	other := newTestRedis(t, mr, WithChannel("other"))
+	remoteA, remoteB, remoteOther := make(chan *board.Model, 1), make(chan *board.Model, 1), make(chan *board.Model, 1)
+	a.OnRemote(func(m *board.Model) { remoteA <- m })
+	b.OnRemote(func(m *board.Model) { remoteB <- m })
+	other.OnRemote(func(m *board.Model) { remoteOther <- m })
+	subA, err := a.Subscribe("b1", 0)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	subB, err := b.Subscribe("b1", 0)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	if err := a.Publish(context.Background(), &board.Model{ID: "b1", Version: 2}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	// Les abonnés des deux instances reçoivent la mise à jour une seule fois.
+	if got := next(t, subA.Updates()); got.Version != 2 {
+		t.Fatalf("local subscriber got version %d", got.Version)
+	}
+	if got := next(t, subB.Updates()); got.Version != 2 {
+		t.Fatalf("remote subscriber got version %d", got.Version)
+	}
+	if got := next(t, remoteB); got.Version != 2 {
+		t.Fatalf("OnRemote got version %d", got.Version)
+	}
+	nothing(t, subA.Updates())
+	// L'instance qui publie ignore son propre message ; un autre canal ne
+	// le voit pas.
+	nothing(t, remoteA)
+	nothing(t, remoteOther)
+
+	// Reçue par Redis, la mise à jour entre dans l'historique des reprises.
+	replay, err := b.Subscribe("b1", 1)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if got := next(t, replay.Updates()); got.Version != 2 || replay.Gap {
+		t.Fatalf("replayed version %d, gap %v", got.Version, replay.Gap)
+	}
+}
+
+func TestRedisPublishDeleted(t *testing.T) {
+	mr := miniredis.RunT(t)
+	a, b := newTestRedis(t, mr), newTestRedis(t, mr)
+	deletedA, deletedB := make(chan string, 1), make(chan string, 1)
+	a.OnRemoteDeleted(func(id string) { deletedA <- id })
+	b.OnRemoteDeleted(func(id string) { deletedB <- id })
+	remoteB := make(chan *board.Model, 1)
+	b.OnRemote(func(m *board.Model) { remoteB <- m })
+
+	if err := a.Publish(context.Background(), &board.Model{ID: "b1", Version: 2}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	next(t, remoteB)
+	if err := a.PublishDeleted(context.Background(), "b1"); err != nil {
+		t.Fatalf("PublishDeleted: %v", err)
+	}
+	if got := next(t, deletedB); got != "b1" {
+		t.Fatalf("OnRemoteDeleted got %q", got)
+	}
+	nothing(t, deletedA)
+	nothing(t, remoteB)
+	// L'historique du board supprimé est oublié des deux côtés.
+	for name, r := range map[string]*Redis{"publisher": a, "receiver": b} {
+		sub, err := r.Subscribe("b1", 1)
+		if err != nil {
+			t.Fatalf("Subscribe: %v", err)
+		}
+		if !sub.Gap {
+			t.Fatalf("%s still replays the deleted board", name)
+		}
+		sub.Cancel()
+	}
+}
+
+func TestRedisCommit(t *testing.T) {
+	tests := []struct {
+		name string
+		// stored : version déjà enregistrée (0 : aucune).
+		stored      int
+		commit      int
+		wantLatest  int
+		wantVersion int
+	}{
+		{"first write", 0, 3, 0, 3},
+		{"next version", 2, 3, 0, 3},
+		{"same version", 3, 3, 3, 3},
+		{"stale version", 5, 3, 5, 5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			r := newTestRedis(t, mr)
+			ctx := context.Background()
+			if tt.stored > 0 {
+				if latest, err := r.Commit(ctx, &board.Model{ID: "b1", Title: "stored", Version: tt.stored}); err != nil || latest != nil {
+					t.Fatalf("seed Commit = %v, %v", latest, err)
+				}
+			}
+			latest, err := r.Commit(ctx, &board.Model{ID: "b1", Title: "new", Version: tt.commit})
+			if err != nil {
+				t.Fatalf("Commit: %v", err)
+			}
+			if tt.wantLatest == 0 && latest != nil {
+				t.Fatalf("Commit refused, latest = %+v", latest)
+			}
+			if tt.wantLatest > 0 && (latest == nil || latest.Version != tt.wantLatest || latest.Title != "stored") {
+				t.Fatalf("Commit latest = %+v, want stored version %d", latest, tt.wantLatest)
+			}
+			loaded, err := r.Load(ctx, "b1")
+			if err != nil || loaded == nil || loaded.Version != tt.wantVersion {
+				t.Fatalf("Load = %+v, %v, want version %d", loaded, err, tt.wantVersion)
+			}
+			if err := r.Remove(ctx, "b1"); err != nil {
+				t.Fatalf("Remove: %v", err)
+			}
+			if loaded, err := r.Load(ctx, "b1"); err != nil || loaded != nil {
+				t.Fatalf("Load after Remove = %+v, %v", loaded, err)
+			}
+		})
+	}
+}
+
+func TestRedisCommitUnavailable(t *testing.T) {
+	mr := miniredis.RunT(t)
+	r := newTestRedis(t, mr)
+	mr.Close()
+	if _, err := r.Commit(context.Background(), &board.Model{ID: "b1", Version: 1}); err == nil {
+		t.Fatal("Commit with Redis down succeeded")
+	}
+}
+
+// ─── Réplication entre instances ─────────────────────────────────────────────
+
+// instance est un board.Service branché sur Redis comme dans cmd/server.
+type instance struct {
+	svc    *board.Service
+	broker *Redis
+}
+
+func newInstance(t *testing.T, mr *miniredis.Miniredis) *instance {
+	t.Helper()
+	r := newTestRedis(t, mr)
+	svc, err := board.NewService("", board.WithSharedState(r), board.WithTextFlushDelay(10*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	svc.OnBoardUpdated(func(b *board.Model) { _ = r.Publish(context.Background(), b) })
+	svc.OnBoardDeleted(func(id string) { _ = r.PublishDeleted(context.Background(), id) })
+	r.OnRemote(func(b *board.Model) { _, _ = svc.ApplyRemote(context.Background(), b) })
+	r.OnRemoteDeleted(func(id string) { _, _ = svc.ApplyRemoteDelete(context.Background(), id) })
+	svc.OnTextUpdated(func(c *board.TextChange) { _ = r.PublishText(context.Background(), c) })
+	r.OnRemoteText(func(c *board.TextChange) { _ = svc.ApplyRemoteText(context.Background(), c) })
+	t.Cleanup(func() { _ = svc.Close() })
+	return &instance{svc: svc, broker: r}
+}
+
+// waitVersion attend que la copie de b1 sur in atteigne version (0 : absente).
+func (in *instance) waitVersion(t *testing.T, version int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		b, ok := in.svc.GetBoard("b1")
+		if (!ok && version == 0) || (ok && b.Version == version) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("b1 = %+v, want version %d", b, version)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestReplication(t *testing.T) {
+	ctx := context.Background()
+	widget := func(id string) board.Widget { return board.Widget{ID: id, Type: "text"} }
+
+	t.Run("apply remote", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		a, b := newInstance(t, mr), newInstance(t, mr)
+		saved, _, err := a.svc.AddWidget(ctx, "b1", board.AnyVersion, widget("w1"))
+		if err != nil {
+			t.Fatalf("AddWidget: %v", err)
+		}
+		b.waitVersion(t, saved.Version)
+		// Une instance démarrée après l'écriture lit l'état partagé.
+		late := newInstance(t, mr)
+		got, err := late.svc.ResolveBoard(ctx, "b1")
+		if err != nil || got.Version != saved.Version || len(got.Widgets) != 1 {
+			t.Fatalf("late ResolveBoard = %+v, %v", got, err)
+		}
+	})
+
+	t.Run("stale write rejected", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		a, b := newInstance(t, mr), newInstance(t, mr)
+		first, _, err := a.svc.AddWidget(ctx, "b1", board.AnyVersion, widget("w1"))
+		if err != nil {
+			t.Fatalf("AddWidget: %v", err)
+		}
+		b.waitVersion(t, first.Version)
+		// b ne reçoit pas la suite : sa copie prend du retard.
+		_ = b.broker.pubsub.Unsubscribe(ctx, b.broker.channel)
+		second, _, err := a.svc.AddWidget(ctx, "b1", board.AnyVersion, widget("w2"))
+		if err != nil {
+			t.Fatalf("AddWidget: %v", err)
+		}
+		_, _, err = b.svc.AddWidget(ctx, "b1", first.Version, widget("w3"))
+		if board.ErrorCode(err) != board.CodeVersionConflict {
+			t.Fatalf("stale AddWidget err = %v, want a conflict", err)
+		}
+		// La copie de b a été relue sur l'état partagé.
+		b.waitVersion(t, second.Version)
+		// Une écriture interne est rejouée sur la copie à jour.
+		_ = b.broker.pubsub.Unsubscribe(ctx, b.broker.channel)
+		third, _, err := a.svc.AddWidget(ctx, "b1", board.AnyVersion, widget("w4"))
+		if err != nil {
+			t.Fatalf("AddWidget: %v", err)
+		}
+		saved, _, err := b.svc.AddWidget(ctx, "b1", board.LatestVersion, widget("w5"))
+		if err != nil {
+			t.Fatalf("internal AddWidget: %v", err)
+		}
+		if saved.Version != third.Version+1 || len(saved.Widgets) != 4 {
+			t.Fatalf("internal write = %+v, want version %d with 4 widgets", saved, third.Version+1)
+		}
+		a.waitVersion(t, saved.Version)
+	})
+
+	t.Run("text", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		a, b := newInstance(t, mr), newInstance(t, mr)
+		saved, _, err := a.svc.AddWidget(ctx, "b1", board.AnyVersion, widget("w1"))
+		if err != nil {
+			t.Fatalf("AddWidget: %v", err)
+		}
+		b.waitVersion(t, saved.Version)
+		sub, err := a.broker.SubscribeText("b1", "w1", "text")
+		if err != nil {
+			t.Fatalf("SubscribeText: %v", err)
+		}
+		// Un client de b tape ; les abonnés de a reçoivent l'opération.
+		state, err := b.svc.TextState(ctx, "b1", "w1", "text")
+		if err != nil {
+			t.Fatalf("TextState: %v", err)
+		}
+		client := crdt.NewDoc("", "")
+		if _, err := client.Apply(state.Update); err != nil {
+			t.Fatal(err)
+		}
+		for i, r := range "hi" {
+			u, err := client.InsertAt("bob", i, string(r))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := b.svc.ApplyTextUpdate(ctx, "b1", "w1", "text", u); err != nil {
+				t.Fatalf("ApplyTextUpdate: %v", err)
+			}
+			if got := next(t, sub.Changes()); got.Text != string([]rune("hi")[:i+1]) {
+				t.Fatalf("change = %+v", got)
+			}
+		}
+		// La réplique de a a suivi : le client peut continuer sur a.
+		u, err := client.InsertAt("bob", 2, "!")
+		if err != nil {
+			t.Fatal(err)
+		}
+		change, err := a.svc.ApplyTextUpdate(ctx, "b1", "w1", "text", u)
+		if err != nil || change.Text != "hi!" {
+			t.Fatalf("ApplyTextUpdate on a = %+v, %v", change, err)
+		}
+		// Le texte finit enregistré dans le board, sur les deux instances.
+		for _, in := range []*instance{a, b} {
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				m, _ := in.svc.GetBoard("b1")
+				if text, _ := m.Widgets[0].Config["text"].(string); text == "hi!" {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("saved board = %+v", m)
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+		}
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		mr := miniredis.RunT(t)
+		a, b := newInstance(t, mr), newInstance(t, mr)
+		saved, _, err := a.svc.AddWidget(ctx, "b1", board.AnyVersion, widget("w1"))
+		if err != nil {
+			t.Fatalf("AddWidget: %v", err)
+		}
+		b.waitVersion(t, saved.Version)
+		if err := b.svc.DeleteBoard(ctx, "b1", board.AnyVersion); err != nil {
+			t.Fatalf("DeleteBoard: %v", err)
+		}
+		a.waitVersion(t, 0)
+		// Recréé, le board repart de la version 1 sur toutes les instances.
+		created, err := a.svc.ResolveBoard(ctx, "b1")
+		if err != nil || created.Version != 1 {
+			t.Fatalf("ResolveBoard after delete = %+v, %v", created, err)
+		}
+		b.waitVersion(t, 1)
+	})
+}
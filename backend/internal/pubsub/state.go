@@ -0,0 +1,72 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+
+	"miro-lite-standalone/backend/internal/board"
+)
+
+// commitScript enregistre le board ARGV[2] à la version ARGV[1] si la
+// version enregistrée est inférieure, et renvoie sinon le board enregistré.
+var commitScript = redis.NewScript(`
+local current = tonumber(redis.call('HGET', KEYS[1], 'version'))
+if current and current >= tonumber(ARGV[1]) then
+  return redis.call('HGET', KEYS[1], 'board')
+end
+redis.call('HSET', KEYS[1], 'version', ARGV[1], 'board', ARGV[2])
+return false
+`)
+
+// boardKey : un hash par board (version, board en JSON), préfixé par le
+// canal pour que deux déploiements ne se mélangent pas.
+func (r *Redis) boardKey(id string) string {
+	return r.channel + ":board:" + id
+}
+
+func (r *Redis) Load(ctx context.Context, id string) (*board.Model, error) {
+	raw, err := r.client.HGet(ctx, r.boardKey(id), "board").Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("pubsub: load board %s: %w", id, err)
+	}
+	return decodeBoard(id, raw)
+}
+
+// Commit enregistre b de façon atomique (script Lua) : de deux instances
+// qui écrivent la même version d'un board, seule la première y parvient.
+func (r *Redis) Commit(ctx context.Context, b *board.Model) (*board.Model, error) {
+	payload, err := json.Marshal(b)
+	if err != nil {
+		return nil, fmt.Errorf("pubsub: encode board %s: %w", b.ID, err)
+	}
+	raw, err := commitScript.Run(ctx, r.client, []string{r.boardKey(b.ID)}, b.Version, payload).Text()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("pubsub: commit board %s: %w", b.ID, err)
+	}
+	return decodeBoard(b.ID, raw)
+}
+
+func (r *Redis) Remove(ctx context.Context, id string) error {
+	if err := r.client.Del(ctx, r.boardKey(id)).Err(); err != nil {
+		return fmt.Errorf("pubsub: remove board %s: %w", id, err)
+	}
+	return nil
+}
+
+func decodeBoard(id, raw string) (*board.Model, error) {
+	var b board.Model
+	if err := json.Unmarshal([]byte(raw), &b); err != nil {
+		return nil, fmt.Errorf("pubsub: decode board %s: %w", id, err)
+	}
+	return &b, nil
+}
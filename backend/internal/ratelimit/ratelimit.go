@@ -0,0 +1,164 @@
+// Package ratelimit limite le débit par client avec un seau à jetons par
+// identité : l'utilisateur authentifié s'il y en a un, sinon l'adresse IP.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"miro-lite-standalone/backend/internal/identity"
+)
+
+// CodeRateLimited est le code d'erreur partagé par le REST et le GraphQL.
+const CodeRateLimited = "RATE_LIMITED"
+
+// idleTTL : un seau inutilisé depuis plus longtemps est plein, on peut
+// l'oublier.
+const idleTTL = 10 * time.Minute
+
+// Error signale une requête refusée. RetryAfter est le délai avant qu'un
+// jeton soit de nouveau disponible.
+type Error struct {
+	RetryAfter time.Duration
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return fmt.Sprintf("rate limit exceeded, retry in %s", e.RetryAfter.Round(time.Millisecond))
+}
+
+// RetryAfterSeconds arrondit RetryAfter au-dessus, pour l'en-tête
+// Retry-After.
+func (e *Error) RetryAfterSeconds() int {
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter est sûr pour un usage concurrent. Un Limiter nil laisse tout
+// passer.
+type Limiter struct {
+	rate  float64
+	burst float64
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// New renvoie nil (pas de limite) si perSecond <= 0.
+func New(perSecond float64, burst int) *Limiter {
+	if perSecond <= 0 {
+		return nil
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{rate: perSecond, burst: float64(burst), buckets: make(map[string]*bucket), now: time.Now}
+}
+
+// Allow consomme un jeton pour key. En cas de refus, l'erreur indique quand
+// réessayer.
+func (l *Limiter) Allow(key string) error {
+	if l == nil {
+		return nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.pruneLocked(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+		return &Error{RetryAfter: wait}
+	}
+	b.tokens--
+	return nil
+}
+
+func (l *Limiter) pruneLocked(now time.Time) {
+	if now.Sub(l.lastPrune) < idleTTL {
+		return
+	}
+	l.lastPrune = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > idleTTL {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+type keyCtx struct{}
+
+// WithKey rattache l'identité du client au contexte : les opérations
+// GraphQL reçues ensuite sur la même websocket sont décomptées sur le même
+// seau.
+func WithKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, keyCtx{}, key)
+}
+
+func KeyFrom(ctx context.Context) string {
+	key, _ := ctx.Value(keyCtx{}).(string)
+	return key
+}
+
+// ClientKey identifie l'appelant : l'utilisateur authentifié
+// (identity.SubjectKey), sinon l'adresse IP (voir ClientIP). L'en-tête
+// Authorization seul n'est pas une identité : ce serveur ne le vérifie pas,
+// et chaque valeur inventée aurait son propre seau.
+func ClientKey(r *http.Request, trustedProxies int) string {
+	if key := identity.SubjectKey(r.Context()); key != "" {
+		return key
+	}
+	return "ip:" + ClientIP(r, trustedProxies)
+}
+
+// ClientIP renvoie l'adresse du client. trustedProxies est le nombre de
+// reverse proxies de confiance devant le serveur : chacun ajoute à droite de
+// X-Forwarded-For l'adresse qu'il a vue, le client est donc la
+// trustedProxies-ième entrée en partant de la droite. Ce que le client a
+// écrit lui-même plus à gauche est ignoré. 0 : RemoteAddr seule.
+func ClientIP(r *http.Request, trustedProxies int) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if trustedProxies <= 0 {
+		return host
+	}
+	var hops []string
+	for _, value := range r.Header.Values("X-Forwarded-For") {
+		for _, hop := range strings.Split(value, ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+	}
+	if len(hops) == 0 {
+		return host
+	}
+	// Moins d'entrées que de proxies : la requête n'est pas passée par tous,
+	// la plus à gauche vient quand même d'un proxy de confiance.
+	if ip := net.ParseIP(hops[max(len(hops)-trustedProxies, 0)]); ip != nil {
+		return ip.String()
+	}
+	return host
+}
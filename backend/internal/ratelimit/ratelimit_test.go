@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"miro-lite-standalone/backend/internal/identity"
+)
+
+func TestAllow(t *testing.T) {
+	start := time.Unix(0, 0)
+	tests := []struct {
+		name        string
+		rate        float64
+		burst       int
+		at          []time.Duration
+		wantAllowed []bool
+	}{
+		{"burst then refuse", 1, 2, []time.Duration{0, 0, 0}, []bool{true, true, false}},
+		{"refill", 2, 1, []time.Duration{0, 0, 500 * time.Millisecond}, []bool{true, false, true}},
+		{"refill capped at burst", 10, 2, []time.Duration{0, time.Hour, time.Hour, time.Hour}, []bool{true, true, true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(tt.rate, tt.burst)
+			for i, at := range tt.at {
+				l.now = func() time.Time { return start.Add(at) }
+				err := l.Allow("k")
+				if got := err == nil; got != tt.wantAllowed[i] {
+					t.Fatalf("call %d: allowed = %v, want %v", i, got, tt.wantAllowed[i])
+				}
+				var rl *Error
+				if err != nil && (!errors.As(err, &rl) || rl.RetryAfterSeconds() < 1) {
+					t.Fatalf("call %d: err = %v, want a Retry-After", i, err)
+				}
+			}
+		})
+	}
+}
+
+func TestNilLimiter(t *testing.T) {
+	if l := New(0, 10); l != nil {
+		t.Fatal("New(0) returned a limiter")
+	}
+	var l *Limiter
+	if err := l.Allow("k"); err != nil {
+		t.Fatalf("nil limiter refused: %v", err)
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		remote  string
+		xff     []string
+		proxies int
+		want    string
+	}{
+		{"remote addr", "203.0.113.7:5000", nil, 0, "203.0.113.7"},
+		{"header ignored without proxies", "203.0.113.7:5000", []string{"198.51.100.1"}, 0, "203.0.113.7"},
+		{"one proxy", "10.0.0.1:5000", []string{"198.51.100.1"}, 1, "198.51.100.1"},
+		{"spoofed left entries ignored", "10.0.0.1:5000", []string{"1.1.1.1, 2.2.2.2, 198.51.100.1"}, 1, "198.51.100.1"},
+		{"two proxies", "10.0.0.2:5000", []string{"1.1.1.1, 198.51.100.1, 10.0.0.1"}, 2, "198.51.100.1"},
+		{"repeated headers", "10.0.0.2:5000", []string{"1.1.1.1, 198.51.100.1", "10.0.0.1"}, 2, "198.51.100.1"},
+		{"fewer hops than proxies", "10.0.0.2:5000", []string{"198.51.100.1"}, 2, "198.51.100.1"},
+		{"no header behind proxy", "10.0.0.1:5000", nil, 1, "10.0.0.1"},
+		{"garbage hop", "10.0.0.1:5000", []string{"1.1.1.1, not-an-ip"}, 1, "10.0.0.1"},
+		{"ipv6", "[2001:db8::1]:5000", nil, 0, "2001:db8::1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remote
+			for _, v := range tt.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if got := ClientIP(r, tt.proxies); got != tt.want {
+				t.Errorf("ClientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		auth    string
+		subject string
+		want    string
+	}{
+		{"anonymous", "", "", "ip:203.0.113.7"},
+		{"unverified token keyed on ip", "Bearer made-up", "", "ip:203.0.113.7"},
+		{"authenticated", "Bearer real", "alice", "user:alice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = "203.0.113.7:5000"
+			if tt.auth != "" {
+				r.Header.Set("Authorization", tt.auth)
+			}
+			if tt.subject != "" {
+				r = r.WithContext(identity.WithSubject(r.Context(), tt.subject))
+			}
+			if got := ClientKey(r, 0); got != tt.want {
+				t.Errorf("ClientKey = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
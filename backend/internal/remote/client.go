@@ -4,52 +4,265 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand/v2"
 	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+
+	"miro-lite-standalone/backend/internal/tracing"
 )
 
 type RemoteGraphQL interface {
 	Do(ctx context.Context, query string, variables map[string]interface{}, resp interface{}) error
 }
 
+const (
+	defaultTimeout    = 30 * time.Second
+	defaultRetries    = 2
+	defaultMinBackoff = 200 * time.Millisecond
+	defaultMaxBackoff = 5 * time.Second
+	// maxErrorBody borne ce qu'on garde d'une réponse d'erreur non GraphQL.
+	maxErrorBody = 4 << 10
+)
+
+// HTTPRemoteGraphQL appelle un serveur GraphQL en POST JSON. La valeur zéro
+// avec seulement Endpoint reste utilisable ; NewHTTPRemoteGraphQL permet de
+// régler le client HTTP, l'authentification, le timeout et les retries.
 type HTTPRemoteGraphQL struct {
 	Endpoint string
+
+	client     *http.Client
+	timeout    time.Duration
+	retries    int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	headers    http.Header
+	configured bool
+}
+
+type Option func(*HTTPRemoteGraphQL)
+
+// WithHTTPClient remplace le client par défaut, qui trace les appels
+// sortants. Son propre Timeout s'ajoute au timeout par tentative.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *HTTPRemoteGraphQL) { c.client = client }
+}
+
+// WithTimeout borne chaque tentative (30 s par défaut, 0 : pas de limite
+// autre que le contexte de l'appelant).
+func WithTimeout(d time.Duration) Option {
+	return func(c *HTTPRemoteGraphQL) { c.timeout = d }
+}
+
+// WithRetries règle le nombre de nouvelles tentatives d'une requête après
+// une erreur réseau, un 429 ou un 5xx (2 par défaut), et les bornes du
+// backoff exponentiel. Les mutations ne sont pas rejouées, sauf appel par
+// AllowMutationRetries : une tentative qui a abouti côté serveur sans que la
+// réponse arrive serait exécutée deux fois.
+func WithRetries(n int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *HTTPRemoteGraphQL) {
+		c.retries, c.minBackoff, c.maxBackoff = n, minBackoff, maxBackoff
+	}
+}
+
+// WithBearerToken envoie Authorization: Bearer <token>.
+func WithBearerToken(token string) Option {
+	return WithHeader("Authorization", "Bearer "+token)
+}
+
+// WithAPIKey envoie la clé dans header (X-API-Key si vide).
+func WithAPIKey(header, key string) Option {
+	if header == "" {
+		header = "X-API-Key"
+	}
+	return WithHeader(header, key)
+}
+
+// WithHeader ajoute un en-tête à chaque requête.
+func WithHeader(key, value string) Option {
+	return func(c *HTTPRemoteGraphQL) { c.headers.Set(key, value) }
+}
+
+func NewHTTPRemoteGraphQL(endpoint string, opts ...Option) *HTTPRemoteGraphQL {
+	c := &HTTPRemoteGraphQL{
+		Endpoint:   endpoint,
+		client:     tracedClient,
+		timeout:    defaultTimeout,
+		retries:    defaultRetries,
+		minBackoff: defaultMinBackoff,
+		maxBackoff: defaultMaxBackoff,
+		headers:    make(http.Header),
+		configured: true,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// tracedClient propage le contexte de trace (traceparent) vers le serveur
+// distant et ouvre un span client par appel.
+var tracedClient = &http.Client{Transport: tracing.Transport(nil)}
+
+type mutationRetriesKey struct{}
+
+// AllowMutationRetries autorise Do à rejouer une mutation appelée avec ctx,
+// comme une requête. À réserver aux mutations idempotentes, ou dont la
+// répétition échoue sans effet (saveBoard avec sa version : la seconde
+// tentative finit en VERSION_CONFLICT).
+func AllowMutationRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, mutationRetriesKey{}, true)
 }
 
+// replayable indique si query peut être rejouée après une erreur
+// transitoire : un document fait seulement de requêtes, ou une mutation
+// autorisée par AllowMutationRetries. Un document qui ne se parse pas sera
+// refusé par le serveur, inutile de réessayer.
+func replayable(ctx context.Context, query string) bool {
+	if allowed, _ := ctx.Value(mutationRetriesKey{}).(bool); allowed {
+		return true
+	}
+	doc, err := parser.ParseQuery(&ast.Source{Input: query})
+	if err != nil || len(doc.Operations) == 0 {
+		return false
+	}
+	for _, op := range doc.Operations {
+		if op.Operation != ast.Query {
+			return false
+		}
+	}
+	return true
+}
+
+// Do exécute query et décode data dans resp. Les erreurs GraphQL sont
+// renvoyées en GraphQLErrors (data partielles tout de même décodées), les
+// réponses HTTP inattendues en *StatusError.
 func (c *HTTPRemoteGraphQL) Do(ctx context.Context, query string, variables map[string]interface{}, resp interface{}) error {
-	body := map[string]interface{}{
+	if !c.configured {
+		c = NewHTTPRemoteGraphQL(c.Endpoint)
+	}
+	body, err := json.Marshal(map[string]interface{}{
 		"query":     query,
 		"variables": variables,
+	})
+	if err != nil {
+		return fmt.Errorf("remote: encode request: %w", err)
 	}
-	b, _ := json.Marshal(body)
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint, bytes.NewReader(b))
+	retries := c.retries
+	if retries > 0 && !replayable(ctx, query) {
+		retries = 0
+	}
+	for attempt := 0; ; attempt++ {
+		out, delay, err := c.attempt(ctx, body)
+		if err == nil {
+			return decodeResponse(out, resp)
+		}
+		if attempt >= retries || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		wait := backoff(c.minBackoff, c.maxBackoff, attempt)
+		if delay > wait {
+			wait = delay
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+type response struct {
+	Data   json.RawMessage `json:"data"`
+	Errors GraphQLErrors   `json:"errors"`
+}
+
+func (c *HTTPRemoteGraphQL) attempt(ctx context.Context, body []byte) (*response, time.Duration, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint, bytes.NewReader(body))
 	if err != nil {
-		return err
+		return nil, 0, err
+	}
+	for key, values := range c.headers {
+		req.Header[key] = values
 	}
 	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
 
-	res, err := http.DefaultClient.Do(req)
+	res, err := c.client.Do(req)
 	if err != nil {
-		return err
+		return nil, 0, &networkError{err: err}
 	}
 	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(res.Body)
-		return fmt.Errorf("remote status %d: %s", res.StatusCode, string(bodyBytes))
+		content, _ := io.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+		statusErr := &StatusError{StatusCode: res.StatusCode, Body: string(content)}
+		// gqlgen répond 422 aux erreurs de validation, avec un corps GraphQL.
+		var out response
+		if json.Unmarshal(content, &out) == nil && len(out.Errors) > 0 && !statusErr.Temporary() {
+			return nil, 0, out.Errors
+		}
+		return nil, retryAfter(res.Header.Get("Retry-After")), statusErr
 	}
 
-	var out struct {
-		Data   json.RawMessage   `json:"data"`
-		Errors []json.RawMessage `json:"errors"`
-	}
+	var out response
 	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
-		return err
+		if ctx.Err() != nil {
+			return nil, 0, &networkError{err: err}
+		}
+		return nil, 0, fmt.Errorf("remote: decode response: %w", err)
+	}
+	return &out, 0, nil
+}
+
+func decodeResponse(out *response, resp interface{}) error {
+	if len(out.Data) > 0 && string(out.Data) != "null" && resp != nil {
+		if err := json.Unmarshal(out.Data, resp); err != nil {
+			return fmt.Errorf("remote: decode data: %w", err)
+		}
 	}
 	if len(out.Errors) > 0 {
-		return fmt.Errorf("remote error: %s", string(out.Errors[0]))
+		return out.Errors
+	}
+	return nil
+}
+
+// backoff double à chaque tentative, avec une gigue de ±25 % pour ne pas
+// synchroniser les clients.
+func backoff(minBackoff, maxBackoff time.Duration, attempt int) time.Duration {
+	d := minBackoff << attempt
+	if d < minBackoff || d > maxBackoff {
+		d = maxBackoff
+	}
+	jitter := time.Duration(rand.Int64N(int64(d)/2+1)) - d/4
+	return d + jitter
+}
+
+func retryable(err error) bool {
+	var netErr *networkError
+	var statusErr *StatusError
+	return errors.As(err, &netErr) || (errors.As(err, &statusErr) && statusErr.Temporary())
+}
+
+// retryAfter lit Retry-After en secondes ; la forme date HTTP est ignorée.
+func retryAfter(header string) time.Duration {
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds < 0 {
+		return 0
 	}
-	return json.Unmarshal(out.Data, resp)
+	return time.Duration(seconds) * time.Second
 }
@@ -0,0 +1,147 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReplayable(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		allow bool
+		want  bool
+	}{
+		{"query", `query GetBoard { board(id: "b1") { id } }`, false, true},
+		{"shorthand query", `{ boards { id } }`, false, true},
+		{"mutation", `mutation { createBoard(title: "t") { id } }`, false, false},
+		{"mutation allowed", `mutation { createBoard(title: "t") { id } }`, true, true},
+		{"mixed document", `query A { boards { id } } mutation B { createBoard(title: "t") { id } }`, false, false},
+		{"subscription", `subscription { boardUpdated(boardId: "b1") { id } }`, false, false},
+		{"unparsable", `query {`, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.allow {
+				ctx = AllowMutationRetries(ctx)
+			}
+			if got := replayable(ctx, tt.query); got != tt.want {
+				t.Errorf("replayable = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDoRetries(t *testing.T) {
+	const (
+		query    = `query { boards { id } }`
+		mutation = `mutation { createBoard(title: "t") { id } }`
+	)
+	tests := []struct {
+		name      string
+		query     string
+		allow     bool
+		statuses  []int
+		wantCalls int
+		wantErr   bool
+	}{
+		{"query recovers", query, false, []int{503, 502, 200}, 3, false},
+		{"query gives up", query, false, []int{503, 503, 503, 200}, 3, true},
+		{"query rate limited", query, false, []int{429, 200}, 2, false},
+		{"client error not retried", query, false, []int{400, 200}, 1, true},
+		{"mutation not retried", mutation, false, []int{503, 200}, 1, true},
+		{"mutation opted in", mutation, true, []int{503, 200}, 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := tt.statuses[calls.Add(1)-1]
+				w.WriteHeader(status)
+				if status == http.StatusOK {
+					_, _ = w.Write([]byte(`{"data":{"boards":[]}}`))
+				}
+			}))
+			defer srv.Close()
+			c := NewHTTPRemoteGraphQL(srv.URL, WithRetries(2, time.Millisecond, 2*time.Millisecond))
+			ctx := context.Background()
+			if tt.allow {
+				ctx = AllowMutationRetries(ctx)
+			}
+			err := c.Do(ctx, tt.query, nil, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Do = %v, want error %v", err, tt.wantErr)
+			}
+			if got := int(calls.Load()); got != tt.wantCalls {
+				t.Errorf("calls = %d, want %d", got, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDoResponses(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		body     string
+		wantData string
+		wantCode string
+		wantHTTP int
+	}{
+		{"data", 200, `{"data":{"id":"b1"}}`, "b1", "", 0},
+		{"partial data with errors", 200, `{"data":{"id":"b1"},"errors":[{"message":"boom","extensions":{"code":"NOT_FOUND"}}]}`, "b1", "NOT_FOUND", 0},
+		{"validation error", 422, `{"errors":[{"message":"bad","extensions":{"code":"GRAPHQL_VALIDATION_FAILED"}}]}`, "", "GRAPHQL_VALIDATION_FAILED", 0},
+		{"unexpected status", 401, `nope`, "", "", 401},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("Content-Type") != "application/json" {
+					t.Errorf("headers = %v", r.Header)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+			var out struct{ ID string }
+			err := NewHTTPRemoteGraphQL(srv.URL, WithBearerToken("secret")).Do(context.Background(), `{ id }`, nil, &out)
+			if out.ID != tt.wantData {
+				t.Errorf("data = %q, want %q", out.ID, tt.wantData)
+			}
+			var gqlErr *GraphQLError
+			if tt.wantCode != "" && (!errors.As(err, &gqlErr) || gqlErr.Code() != tt.wantCode) {
+				t.Errorf("err = %v, want code %s", err, tt.wantCode)
+			}
+			var statusErr *StatusError
+			if tt.wantHTTP != 0 && (!errors.As(err, &statusErr) || statusErr.StatusCode != tt.wantHTTP) {
+				t.Errorf("err = %v, want status %d", err, tt.wantHTTP)
+			}
+			if tt.wantCode == "" && tt.wantHTTP == 0 && err != nil {
+				t.Errorf("err = %v", err)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 0},
+		{"3", 3 * time.Second},
+		{"-1", 0},
+		{"Wed, 21 Oct 2015 07:28:00 GMT", 0},
+	}
+	for _, tt := range tests {
+		if got := retryAfter(tt.header); got != tt.want {
+			t.Errorf("retryAfter(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
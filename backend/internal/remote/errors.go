@@ -0,0 +1,96 @@
+package remote
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// GraphQLError est une entrée du tableau errors d'une réponse GraphQL.
+type GraphQLError struct {
+	Message    string                 `json:"message"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Locations  []Location             `json:"locations,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func (e *GraphQLError) Error() string {
+	var b strings.Builder
+	b.WriteString("remote: ")
+	if len(e.Path) > 0 {
+		for i, p := range e.Path {
+			if i > 0 {
+				b.WriteByte('.')
+			}
+			fmt.Fprint(&b, p)
+		}
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Message)
+	if code := e.Code(); code != "" {
+		fmt.Fprintf(&b, " (%s)", code)
+	}
+	return b.String()
+}
+
+// Code renvoie extensions.code, vide s'il est absent.
+func (e *GraphQLError) Code() string {
+	code, _ := e.Extensions["code"].(string)
+	return code
+}
+
+// GraphQLErrors regroupe les erreurs d'une réponse. errors.As(err,
+// &*GraphQLError) trouve la première.
+type GraphQLErrors []*GraphQLError
+
+func (errs GraphQLErrors) Error() string {
+	switch len(errs) {
+	case 0:
+		return "remote: no error"
+	case 1:
+		return errs[0].Error()
+	}
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return fmt.Sprintf("%d errors: %s", len(errs), strings.Join(msgs, "; "))
+}
+
+func (errs GraphQLErrors) Unwrap() []error {
+	out := make([]error, len(errs))
+	for i, e := range errs {
+		out[i] = e
+	}
+	return out
+}
+
+// StatusError : le serveur a répondu autre chose que 200 sans corps GraphQL
+// exploitable.
+type StatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("remote: status %d: %s", e.StatusCode, strings.TrimSpace(e.Body))
+}
+
+// Temporary est vrai pour les statuts qui justifient une nouvelle tentative.
+func (e *StatusError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// networkError : la requête n'a pas obtenu de réponse (connexion, timeout).
+type networkError struct {
+	err error
+}
+
+func (e *networkError) Error() string { return "remote: " + e.err.Error() }
+
+func (e *networkError) Unwrap() error { return e.err }
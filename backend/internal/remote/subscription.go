@@ -0,0 +1,690 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Sous-protocoles websocket GraphQL. Le client propose les deux et suit le
+// choix du serveur.
+const (
+	ProtocolGraphQLTransportWS = "graphql-transport-ws"
+	// ProtocolGraphQLWS est l'ancien protocole de subscriptions-transport-ws.
+	ProtocolGraphQLWS = "graphql-ws"
+)
+
+const (
+	defaultBufferSize  = 16
+	defaultAckTimeout  = 10 * time.Second
+	defaultReadTimeout = 60 * time.Second
+	writeTimeout       = 10 * time.Second
+	// maxInitRefusals : un refus de connection_init isolé (serveur en cours
+	// d'arrêt) est réessayé ; au-delà, le refus est définitif
+	// (authentification).
+	maxInitRefusals = 5
+)
+
+// ErrSubscriptionClosed est renvoyée par Subscribe après Close.
+var ErrSubscriptionClosed = errors.New("remote: subscription client closed")
+
+// Event est une réponse reçue sur une subscription. Errors porte les
+// erreurs GraphQL de cet événement ; la subscription continue.
+type Event struct {
+	Data   json.RawMessage
+	Errors GraphQLErrors
+}
+
+// Decode décode Data dans v.
+func (e Event) Decode(v interface{}) error {
+	return json.Unmarshal(e.Data, v)
+}
+
+// SubscriptionClient multiplexe des subscriptions GraphQL sur une
+// websocket. La connexion est ouverte au premier Subscribe et fermée quand
+// il n'en reste plus. Si elle tombe, le client se reconnecte avec un
+// backoff exponentiel et renouvelle toutes les subscriptions actives ; les
+// événements émis pendant la coupure sont perdus (voir WithOnReconnect).
+type SubscriptionClient struct {
+	endpoint    string
+	dialer      *websocket.Dialer
+	header      http.Header
+	initPayload map[string]interface{}
+	protocols   []string
+	bufferSize  int
+	block       bool
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+	ackTimeout  time.Duration
+	readTimeout time.Duration
+	onReconnect func()
+	resubscribe bool
+
+	mu      sync.Mutex
+	subs    map[string]*Subscription
+	nextID  int
+	conn    *subscriptionConn
+	running bool
+	closed  bool
+	// wake interrompt l'attente de reconnexion quand l'état change.
+	wake chan struct{}
+}
+
+type SubscriptionOption func(*SubscriptionClient)
+
+// WithDialer remplace websocket.DefaultDialer.
+func WithDialer(d *websocket.Dialer) SubscriptionOption {
+	return func(c *SubscriptionClient) { c.dialer = d }
+}
+
+// WithDialHeader ajoute un en-tête à la requête d'upgrade (Authorization,
+// Origin…).
+func WithDialHeader(key, value string) SubscriptionOption {
+	return func(c *SubscriptionClient) { c.header.Set(key, value) }
+}
+
+// WithInitPayload envoie payload dans connection_init, là où les serveurs
+// GraphQL attendent en général l'authentification des websockets.
+func WithInitPayload(payload map[string]interface{}) SubscriptionOption {
+	return func(c *SubscriptionClient) { c.initPayload = payload }
+}
+
+// WithProtocols restreint les sous-protocoles proposés, par ordre de
+// préférence.
+func WithProtocols(protocols ...string) SubscriptionOption {
+	return func(c *SubscriptionClient) { c.protocols = protocols }
+}
+
+// WithBuffer règle la file de chaque subscription (16 par défaut). Quand
+// elle est pleine, l'événement le plus ancien est abandonné : chaque
+// boardUpdated porte l'état complet du board, seul le dernier compte. Avec
+// block, la lecture de la websocket attend le consommateur, ce qui ralentit
+// toutes les subscriptions de la connexion.
+func WithBuffer(size int, block bool) SubscriptionOption {
+	return func(c *SubscriptionClient) { c.bufferSize, c.block = size, block }
+}
+
+// WithReconnectBackoff borne le délai entre deux tentatives de connexion.
+func WithReconnectBackoff(minBackoff, maxBackoff time.Duration) SubscriptionOption {
+	return func(c *SubscriptionClient) { c.minBackoff, c.maxBackoff = minBackoff, maxBackoff }
+}
+
+// WithReadTimeout : sans aucun message (keep-alive compris) pendant d, la
+// connexion est considérée comme morte et rouverte. 60 s par défaut, à
+// garder au-dessus de l'intervalle de ping du serveur.
+func WithReadTimeout(d time.Duration) SubscriptionOption {
+	return func(c *SubscriptionClient) { c.readTimeout = d }
+}
+
+// WithResubscribeOnComplete règle le traitement d'un complete envoyé par le
+// serveur. Par défaut (true), la subscription est renouvelée, comme par le
+// frontend : boardUpdated ne se termine jamais normalement, le serveur ne la
+// complète qu'à son arrêt. Avec false, complete ferme Events.
+func WithResubscribeOnComplete(enabled bool) SubscriptionOption {
+	return func(c *SubscriptionClient) { c.resubscribe = enabled }
+}
+
+// WithOnReconnect appelle fn après chaque reconnexion, une fois les
+// subscriptions renouvelées : c'est le moment de relire l'état manqué.
+func WithOnReconnect(fn func()) SubscriptionOption {
+	return func(c *SubscriptionClient) { c.onReconnect = fn }
+}
+
+// NewSubscriptionClient accepte une URL http(s) ou ws(s).
+func NewSubscriptionClient(endpoint string, opts ...SubscriptionOption) *SubscriptionClient {
+	c := &SubscriptionClient{
+		endpoint:    websocketURL(endpoint),
+		dialer:      websocket.DefaultDialer,
+		header:      make(http.Header),
+		protocols:   []string{ProtocolGraphQLTransportWS, ProtocolGraphQLWS},
+		bufferSize:  defaultBufferSize,
+		minBackoff:  defaultMinBackoff,
+		maxBackoff:  defaultMaxBackoff,
+		ackTimeout:  defaultAckTimeout,
+		readTimeout: defaultReadTimeout,
+		resubscribe: true,
+		subs:        make(map[string]*Subscription),
+		wake:        make(chan struct{}, 1),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.bufferSize < 1 {
+		c.bufferSize = 1
+	}
+	return c
+}
+
+func websocketURL(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return endpoint
+	}
+	switch u.Scheme {
+	case "http":
+		u.Scheme = "ws"
+	case "https":
+		u.Scheme = "wss"
+	}
+	return u.String()
+}
+
+// Subscription reçoit les événements d'une opération. Events est fermé
+// quand la subscription se termine : Err dit alors pourquoi (nil après
+// Close, l'annulation de ctx ou un complete du serveur avec
+// WithResubscribeOnComplete(false)).
+type Subscription struct {
+	id        string
+	client    *SubscriptionClient
+	query     string
+	variables map[string]interface{}
+	// retries compte les renouvellements sans événement reçu (protégé par
+	// client.mu).
+	retries int
+
+	events chan Event
+	done   chan struct{}
+	// sendMu : events n'est fermé qu'une fois les envois en cours terminés.
+	sendMu sync.Mutex
+
+	mu      sync.Mutex
+	closed  bool
+	err     error
+	dropped int
+}
+
+func (s *Subscription) Events() <-chan Event { return s.events }
+
+func (s *Subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Dropped compte les événements abandonnés parce que la file était pleine.
+func (s *Subscription) Dropped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// Close arrête la subscription côté serveur et ferme Events.
+func (s *Subscription) Close() {
+	s.client.unsubscribe(s, nil, true)
+}
+
+// Subscribe démarre query. La connexion est établie en arrière-plan : une
+// erreur de connexion définitive (4xx à l'upgrade, connexion refusée par le
+// serveur) termine la subscription avec Err. Annuler ctx équivaut à Close.
+func (c *SubscriptionClient) Subscribe(ctx context.Context, query string, variables map[string]interface{}) (*Subscription, error) {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil, ErrSubscriptionClosed
+	}
+	c.nextID++
+	sub := &Subscription{
+		id:        strconv.Itoa(c.nextID),
+		client:    c,
+		query:     query,
+		variables: variables,
+		events:    make(chan Event, c.bufferSize),
+		done:      make(chan struct{}),
+	}
+	c.subs[sub.id] = sub
+	conn := c.conn
+	if !c.running {
+		c.running = true
+		go c.run()
+	}
+	c.mu.Unlock()
+
+	if conn != nil {
+		if err := conn.subscribe(sub); err != nil {
+			// La boucle de lecture voit la même panne et renouvellera la
+			// subscription après reconnexion.
+			conn.close()
+		}
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.unsubscribe(sub, nil, true)
+		case <-sub.done:
+		}
+	}()
+	return sub, nil
+}
+
+// Close termine toutes les subscriptions et ferme la connexion.
+func (c *SubscriptionClient) Close() {
+	c.mu.Lock()
+	c.closed = true
+	subs := make([]*Subscription, 0, len(c.subs))
+	for _, sub := range c.subs {
+		subs = append(subs, sub)
+	}
+	c.mu.Unlock()
+	for _, sub := range subs {
+		c.unsubscribe(sub, nil, true)
+	}
+	c.signal()
+}
+
+func (c *SubscriptionClient) signal() {
+	select {
+	case c.wake <- struct{}{}:
+	default:
+	}
+}
+
+// unsubscribe retire sub et ferme sa file. notify envoie l'arrêt au
+// serveur (inutile quand c'est lui qui a terminé la subscription).
+func (c *SubscriptionClient) unsubscribe(sub *Subscription, err error, notify bool) {
+	c.mu.Lock()
+	if c.subs[sub.id] != sub {
+		c.mu.Unlock()
+		return
+	}
+	delete(c.subs, sub.id)
+	conn, empty := c.conn, len(c.subs) == 0
+	c.mu.Unlock()
+
+	if conn != nil {
+		if empty {
+			conn.close()
+		} else if notify {
+			_ = conn.stop(sub.id)
+		}
+	}
+	if empty {
+		c.signal()
+	}
+	sub.finish(err)
+}
+
+func (s *Subscription) finish(err error) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return
+	}
+	s.closed, s.err = true, err
+	s.mu.Unlock()
+	// done débloque un envoi en attente avant la fermeture de events.
+	close(s.done)
+	s.sendMu.Lock()
+	close(s.events)
+	s.sendMu.Unlock()
+}
+
+// deliver applique la politique de WithBuffer.
+func (s *Subscription) deliver(ev Event, block bool) {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	select {
+	case <-s.done:
+		return
+	default:
+	}
+	if block {
+		select {
+		case s.events <- ev:
+		case <-s.done:
+		}
+		return
+	}
+	for {
+		select {
+		case s.events <- ev:
+			return
+		default:
+		}
+		select {
+		case <-s.events:
+			s.mu.Lock()
+			s.dropped++
+			s.mu.Unlock()
+		default:
+		}
+	}
+}
+
+// run maintient la connexion tant qu'il reste des subscriptions.
+func (c *SubscriptionClient) run() {
+	attempt, refusals, connected := 0, 0, false
+	for {
+		c.mu.Lock()
+		if c.closed || len(c.subs) == 0 {
+			c.running = false
+			c.mu.Unlock()
+			return
+		}
+		c.mu.Unlock()
+
+		conn, err := c.connect()
+		if err == nil {
+			if connected && c.onReconnect != nil {
+				c.onReconnect()
+			}
+			attempt, refusals, connected = 0, 0, true
+			err = conn.readLoop()
+			c.mu.Lock()
+			c.conn = nil
+			c.mu.Unlock()
+			conn.close()
+		}
+		var fatal *fatalError
+		var refused *initRefusedError
+		if errors.As(err, &refused) {
+			if refusals++; refusals >= maxInitRefusals {
+				fatal = &fatalError{err: refused}
+			}
+		}
+		if fatal != nil || errors.As(err, &fatal) {
+			c.failAll(fatal.err)
+			continue
+		}
+
+		wait := backoff(c.minBackoff, c.maxBackoff, attempt)
+		attempt++
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-c.wake:
+			timer.Stop()
+		}
+	}
+}
+
+func (c *SubscriptionClient) failAll(err error) {
+	c.mu.Lock()
+	subs := make([]*Subscription, 0, len(c.subs))
+	for _, sub := range c.subs {
+		subs = append(subs, sub)
+	}
+	c.mu.Unlock()
+	for _, sub := range subs {
+		c.unsubscribe(sub, err, false)
+	}
+}
+
+// fatalError : réessayer ne changera rien (authentification refusée,
+// protocole non supporté).
+type fatalError struct {
+	err error
+}
+
+func (e *fatalError) Error() string { return e.err.Error() }
+
+// initRefusedError : le serveur a répondu connection_error à
+// connection_init.
+type initRefusedError struct {
+	message string
+}
+
+func (e *initRefusedError) Error() string { return "remote: connection refused: " + e.message }
+
+// connect ouvre la websocket, attend connection_ack puis renouvelle les
+// subscriptions actives.
+func (c *SubscriptionClient) connect() (*subscriptionConn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.ackTimeout)
+	defer cancel()
+	dialer := *c.dialer
+	dialer.Subprotocols = c.protocols
+	ws, res, err := dialer.DialContext(ctx, c.endpoint, c.header)
+	if err != nil {
+		if res != nil && res.StatusCode >= 400 && res.StatusCode < 500 && res.StatusCode != http.StatusTooManyRequests {
+			return nil, &fatalError{err: &StatusError{StatusCode: res.StatusCode, Body: "websocket upgrade refused"}}
+		}
+		return nil, fmt.Errorf("remote: dial %s: %w", c.endpoint, err)
+	}
+	conn := &subscriptionConn{client: c, ws: ws, protocol: ws.Subprotocol()}
+	switch conn.protocol {
+	case ProtocolGraphQLTransportWS, ProtocolGraphQLWS:
+	default:
+		ws.Close()
+		return nil, &fatalError{err: fmt.Errorf("remote: server did not accept %s", strings.Join(c.protocols, " or "))}
+	}
+	if err := conn.init(); err != nil {
+		ws.Close()
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || len(c.subs) == 0 {
+		ws.Close()
+		return nil, errors.New("remote: no active subscription")
+	}
+	c.conn = conn
+	for _, sub := range c.subs {
+		if err := conn.subscribe(sub); err != nil {
+			c.conn = nil
+			ws.Close()
+			return nil, err
+		}
+	}
+	return conn, nil
+}
+
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// subscriptionConn est une websocket établie. gorilla n'accepte qu'un
+// écrivain à la fois : writeMu sérialise les envois.
+type subscriptionConn struct {
+	client   *SubscriptionClient
+	ws       *websocket.Conn
+	protocol string
+
+	writeMu   sync.Mutex
+	closeOnce sync.Once
+}
+
+func (s *subscriptionConn) legacy() bool { return s.protocol == ProtocolGraphQLWS }
+
+func (s *subscriptionConn) write(msg wsMessage) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	_ = s.ws.SetWriteDeadline(time.Now().Add(writeTimeout))
+	return s.ws.WriteJSON(msg)
+}
+
+func (s *subscriptionConn) close() {
+	s.closeOnce.Do(func() {
+		s.writeMu.Lock()
+		_ = s.ws.SetWriteDeadline(time.Now().Add(time.Second))
+		_ = s.ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+		s.writeMu.Unlock()
+		_ = s.ws.Close()
+	})
+}
+
+func (s *subscriptionConn) init() error {
+	var payload json.RawMessage
+	if s.client.initPayload != nil {
+		raw, err := json.Marshal(s.client.initPayload)
+		if err != nil {
+			return &fatalError{err: fmt.Errorf("remote: encode init payload: %w", err)}
+		}
+		payload = raw
+	}
+	if err := s.write(wsMessage{Type: "connection_init", Payload: payload}); err != nil {
+		return err
+	}
+	_ = s.ws.SetReadDeadline(time.Now().Add(s.client.ackTimeout))
+	for {
+		var msg wsMessage
+		if err := s.ws.ReadJSON(&msg); err != nil {
+			return closeError(err)
+		}
+		switch msg.Type {
+		case "connection_ack":
+			return nil
+		case "connection_error":
+			return &initRefusedError{message: payloadMessage(msg.Payload)}
+		case "ka", "ping", "pong":
+			if msg.Type == "ping" {
+				if err := s.write(wsMessage{Type: "pong"}); err != nil {
+					return err
+				}
+			}
+		default:
+			return fmt.Errorf("remote: unexpected %q before connection_ack", msg.Type)
+		}
+	}
+}
+
+func (s *subscriptionConn) subscribe(sub *Subscription) error {
+	payload, err := json.Marshal(map[string]interface{}{"query": sub.query, "variables": sub.variables})
+	if err != nil {
+		return fmt.Errorf("remote: encode subscription: %w", err)
+	}
+	msgType := "subscribe"
+	if s.legacy() {
+		msgType = "start"
+	}
+	return s.write(wsMessage{ID: sub.id, Type: msgType, Payload: payload})
+}
+
+func (s *subscriptionConn) stop(id string) error {
+	msgType := "complete"
+	if s.legacy() {
+		msgType = "stop"
+	}
+	return s.write(wsMessage{ID: id, Type: msgType})
+}
+
+func (s *subscriptionConn) readLoop() error {
+	c := s.client
+	_ = s.ws.SetReadDeadline(time.Time{})
+	for {
+		if c.readTimeout > 0 {
+			_ = s.ws.SetReadDeadline(time.Now().Add(c.readTimeout))
+		}
+		var msg wsMessage
+		if err := s.ws.ReadJSON(&msg); err != nil {
+			return closeError(err)
+		}
+		switch msg.Type {
+		case "next", "data":
+			var resp struct {
+				Data   json.RawMessage `json:"data"`
+				Errors GraphQLErrors   `json:"errors"`
+			}
+			if err := json.Unmarshal(msg.Payload, &resp); err != nil {
+				return fmt.Errorf("remote: decode %s message: %w", msg.Type, err)
+			}
+			if sub := c.lookup(msg.ID); sub != nil {
+				c.mu.Lock()
+				sub.retries = 0
+				c.mu.Unlock()
+				sub.deliver(Event{Data: resp.Data, Errors: resp.Errors}, c.block)
+			}
+		case "error":
+			if sub := c.lookup(msg.ID); sub != nil {
+				errs := decodeErrors(msg.Payload)
+				if unavailable(errs) {
+					c.resubscribeLater(s, sub)
+				} else {
+					c.unsubscribe(sub, errs, false)
+				}
+			}
+		case "complete":
+			if sub := c.lookup(msg.ID); sub != nil {
+				if c.resubscribe {
+					c.resubscribeLater(s, sub)
+				} else {
+					c.unsubscribe(sub, nil, false)
+				}
+			}
+		case "ping":
+			if err := s.write(wsMessage{Type: "pong", Payload: msg.Payload}); err != nil {
+				return err
+			}
+		case "ka", "pong":
+		case "connection_error":
+			return &fatalError{err: fmt.Errorf("remote: connection error: %s", payloadMessage(msg.Payload))}
+		}
+	}
+}
+
+// resubscribeLater renouvelle sub sur conn après un backoff, si conn est
+// toujours la connexion courante : sinon la reconnexion s'en est chargée.
+func (c *SubscriptionClient) resubscribeLater(conn *subscriptionConn, sub *Subscription) {
+	c.mu.Lock()
+	wait := backoff(c.minBackoff, c.maxBackoff, sub.retries)
+	sub.retries++
+	c.mu.Unlock()
+	time.AfterFunc(wait, func() {
+		c.mu.Lock()
+		current := c.subs[sub.id] == sub && c.conn == conn
+		c.mu.Unlock()
+		if current {
+			if err := conn.subscribe(sub); err != nil {
+				conn.close()
+			}
+		}
+	})
+}
+
+// unavailable : toutes les erreurs portent le code UNAVAILABLE (service en
+// cours d'arrêt), une nouvelle tentative a des chances d'aboutir.
+func unavailable(errs GraphQLErrors) bool {
+	for _, e := range errs {
+		if e.Code() != "UNAVAILABLE" {
+			return false
+		}
+	}
+	return len(errs) > 0
+}
+
+func (c *SubscriptionClient) lookup(id string) *Subscription {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.subs[id]
+}
+
+// closeError rend définitives les fermetures de graphql-transport-ws qui ne
+// se résolvent pas en réessayant.
+func closeError(err error) error {
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		switch closeErr.Code {
+		case 4400, 4401, 4403, 4406:
+			return &fatalError{err: fmt.Errorf("remote: websocket closed: %d %s", closeErr.Code, closeErr.Text)}
+		}
+	}
+	return err
+}
+
+// decodeErrors accepte un tableau d'erreurs (graphql-transport-ws) ou une
+// erreur seule (graphql-ws).
+func decodeErrors(payload json.RawMessage) GraphQLErrors {
+	var errs GraphQLErrors
+	if json.Unmarshal(payload, &errs) == nil && len(errs) > 0 {
+		return errs
+	}
+	var single GraphQLError
+	if json.Unmarshal(payload, &single) == nil && single.Message != "" {
+		return GraphQLErrors{&single}
+	}
+	return GraphQLErrors{{Message: string(payload)}}
+}
+
+func payloadMessage(payload json.RawMessage) string {
+	return decodeErrors(payload).Error()
+}
@@ -0,0 +1,400 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/gorilla/websocket"
+
+	"miro-lite-standalone/backend/internal/board"
+	"miro-lite-standalone/backend/internal/graph"
+)
+
+const boardUpdated = `subscription($boardId: ID!, $since: Int) { boardUpdated(boardId: $boardId, sinceVersion: $since) { id version } }`
+
+// connListener garde les connexions acceptées pour pouvoir les couper, y
+// compris celles détournées par l'upgrade websocket.
+type connListener struct {
+	net.Listener
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.mu.Lock()
+		l.conns = append(l.conns, conn)
+		l.mu.Unlock()
+	}
+	return conn, err
+}
+
+func (l *connListener) drop() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, conn := range l.conns {
+		_ = conn.Close()
+	}
+	l.conns = nil
+}
+
+// newGraphQLServer sert le schéma des boards en websocket (les deux
+// sous-protocoles, comme le serveur) et renvoie son URL, le service et de
+// quoi couper toutes les connexions ouvertes.
+func newGraphQLServer(t *testing.T) (string, *board.Service, func()) {
+	t.Helper()
+	svc, err := board.NewService("")
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	resolver := graph.NewResolver(svc, nil)
+	srv := handler.New(graph.NewExecutableSchema(graph.NewConfig(resolver)))
+	srv.AddTransport(transport.Websocket{InitFunc: resolver.WebsocketInit})
+	ts := httptest.NewUnstartedServer(srv)
+	ln := &connListener{Listener: ts.Listener}
+	ts.Listener = ln
+	ts.Start()
+	t.Cleanup(func() {
+		resolver.Shutdown()
+		ln.drop()
+		ts.Close()
+	})
+	return ts.URL, svc, ln.drop
+}
+
+// nextVersion lit l'événement suivant de sub et renvoie la version du board.
+func nextVersion(t *testing.T, sub *Subscription) int {
+	t.Helper()
+	select {
+	case ev, ok := <-sub.Events():
+		if !ok {
+			t.Fatalf("subscription ended: %v", sub.Err())
+		}
+		var data struct {
+			BoardUpdated struct{ Version int } `json:"boardUpdated"`
+		}
+		if err := ev.Decode(&data); err != nil || len(ev.Errors) > 0 {
+			t.Fatalf("event = %s %v, decode: %v", ev.Data, ev.Errors, err)
+		}
+		return data.BoardUpdated.Version
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+	}
+	return 0
+}
+
+// save écrit le board b1 et renvoie sa nouvelle version.
+func save(t *testing.T, svc *board.Service) int {
+	t.Helper()
+	current, _ := svc.GetBoard("b1")
+	saved, err := svc.SaveBoard(context.Background(), "b1", current.Version, nil)
+	if err != nil {
+		t.Fatalf("SaveBoard: %v", err)
+	}
+	return saved.Version
+}
+
+func TestSubscriptionProtocols(t *testing.T) {
+	for _, protocol := range []string{ProtocolGraphQLTransportWS, ProtocolGraphQLWS} {
+		t.Run(protocol, func(t *testing.T) {
+			url, svc, _ := newGraphQLServer(t)
+			if _, err := svc.CreateBoard(context.Background(), "b1", "t"); err != nil {
+				t.Fatal(err)
+			}
+			v2 := save(t, svc)
+			c := NewSubscriptionClient(url, WithProtocols(protocol))
+			defer c.Close()
+			// sinceVersion rejoue v2 : pas de course avec l'ouverture de la
+			// subscription.
+			sub, err := c.Subscribe(context.Background(), boardUpdated, map[string]interface{}{"boardId": "b1", "since": 1})
+			if err != nil {
+				t.Fatalf("Subscribe: %v", err)
+			}
+			if got := nextVersion(t, sub); got != v2 {
+				t.Fatalf("first event version = %d, want %d", got, v2)
+			}
+			v3 := save(t, svc)
+			if got := nextVersion(t, sub); got != v3 {
+				t.Fatalf("live event version = %d, want %d", got, v3)
+			}
+			sub.Close()
+			if _, ok := <-sub.Events(); ok || sub.Err() != nil {
+				t.Fatalf("after Close: events still open or err = %v", sub.Err())
+			}
+		})
+	}
+}
+
+func TestSubscriptionReconnect(t *testing.T) {
+	url, svc, drop := newGraphQLServer(t)
+	if _, err := svc.CreateBoard(context.Background(), "b1", "t"); err != nil {
+		t.Fatal(err)
+	}
+	v2 := save(t, svc)
+	reconnected := make(chan struct{}, 1)
+	c := NewSubscriptionClient(url,
+		WithReconnectBackoff(time.Millisecond, 10*time.Millisecond),
+		WithOnReconnect(func() { reconnected <- struct{}{} }))
+	defer c.Close()
+	sub, err := c.Subscribe(context.Background(), boardUpdated, map[string]interface{}{"boardId": "b1", "since": 1})
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if got := nextVersion(t, sub); got != v2 {
+		t.Fatalf("first event version = %d, want %d", got, v2)
+	}
+
+	drop()
+	v3 := save(t, svc) // pendant la coupure
+	select {
+	case <-reconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no reconnection")
+	}
+	// La subscription renouvelée rejoue depuis sinceVersion : v3 arrive
+	// même s'il a été écrit pendant la coupure.
+	for got := 0; got != v3; {
+		if got = nextVersion(t, sub); got > v3 {
+			t.Fatalf("version %d after %d", got, v3)
+		}
+	}
+	v4 := save(t, svc)
+	if got := nextVersion(t, sub); got != v4 {
+		t.Fatalf("event after reconnect = %d, want %d", got, v4)
+	}
+}
+
+// scriptServer accepte les websockets avec le sous-protocole demandé par le
+// client, répond à connection_init puis laisse script mener la connexion n
+// (à partir de 1).
+func scriptServer(t *testing.T, script func(ws *websocket.Conn, n int)) (string, *atomic.Int32) {
+	t.Helper()
+	var dials atomic.Int32
+	upgrader := websocket.Upgrader{Subprotocols: []string{ProtocolGraphQLTransportWS}}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		var init wsMessage
+		if err := ws.ReadJSON(&init); err != nil || init.Type != "connection_init" {
+			return
+		}
+		script(ws, int(dials.Add(1)))
+	}))
+	t.Cleanup(ts.Close)
+	return ts.URL, &dials
+}
+
+// ack accepte la connexion et renvoie l'ID de la première subscription.
+func ack(ws *websocket.Conn) string {
+	if err := ws.WriteJSON(wsMessage{Type: "connection_ack"}); err != nil {
+		return ""
+	}
+	var sub wsMessage
+	if err := ws.ReadJSON(&sub); err != nil || sub.Type != "subscribe" {
+		return ""
+	}
+	return sub.ID
+}
+
+// next envoie un événement {"n": n} à la subscription id.
+func next(ws *websocket.Conn, id string, n int) error {
+	payload, _ := json.Marshal(map[string]interface{}{"data": map[string]int{"n": n}})
+	return ws.WriteJSON(wsMessage{ID: id, Type: "next", Payload: payload})
+}
+
+func TestSubscriptionFatalClose(t *testing.T) {
+	tests := []struct {
+		name      string
+		code      int
+		wantFatal bool
+	}{
+		{"unauthorized", 4401, true},
+		{"forbidden", 4403, true},
+		{"going away", websocket.CloseGoingAway, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url, dials := scriptServer(t, func(ws *websocket.Conn, n int) {
+				if n == 1 {
+					_ = ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(tt.code, "closed"))
+					return
+				}
+				if id := ack(ws); id != "" {
+					_ = next(ws, id, n)
+					_, _, _ = ws.ReadMessage()
+				}
+			})
+			c := NewSubscriptionClient(url, WithReconnectBackoff(time.Millisecond, 5*time.Millisecond))
+			defer c.Close()
+			sub, err := c.Subscribe(context.Background(), "subscription { boardUpdated(boardId: \"b1\") { id } }", nil)
+			if err != nil {
+				t.Fatalf("Subscribe: %v", err)
+			}
+			select {
+			case _, ok := <-sub.Events():
+				if ok == tt.wantFatal {
+					t.Fatalf("events open = %v, want %v (err %v)", ok, !tt.wantFatal, sub.Err())
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("no outcome")
+			}
+			if tt.wantFatal {
+				if err := sub.Err(); err == nil || !strings.Contains(err.Error(), "closed") {
+					t.Errorf("Err() = %v, want the close code", err)
+				}
+				if n := dials.Load(); n != 1 {
+					t.Errorf("dials = %d, want no reconnection", n)
+				}
+			}
+		})
+	}
+}
+
+func TestSubscriptionInitRefused(t *testing.T) {
+	url, dials := scriptServer(t, func(ws *websocket.Conn, n int) {
+		payload, _ := json.Marshal(map[string]string{"message": "bad token"})
+		_ = ws.WriteJSON(wsMessage{Type: "connection_error", Payload: payload})
+	})
+	c := NewSubscriptionClient(url, WithReconnectBackoff(time.Millisecond, 5*time.Millisecond))
+	defer c.Close()
+	sub, err := c.Subscribe(context.Background(), "subscription { boardUpdated(boardId: \"b1\") { id } }", nil)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	select {
+	case <-sub.Events():
+	case <-time.After(5 * time.Second):
+		t.Fatal("subscription still running")
+	}
+	var refused *initRefusedError
+	if !errors.As(sub.Err(), &refused) || !strings.Contains(refused.message, "bad token") {
+		t.Errorf("Err() = %v, want the refusal", sub.Err())
+	}
+	if n := dials.Load(); n != maxInitRefusals {
+		t.Errorf("dials = %d, want %d", n, maxInitRefusals)
+	}
+}
+
+func TestSubscriptionResubscribeOnComplete(t *testing.T) {
+	url, dials := scriptServer(t, func(ws *websocket.Conn, n int) {
+		id := ack(ws)
+		// Le serveur complète la subscription (arrêt) : le client la
+		// renouvelle sur la même connexion.
+		for i := range 2 {
+			if id == "" || next(ws, id, i) != nil || ws.WriteJSON(wsMessage{ID: id, Type: "complete"}) != nil {
+				return
+			}
+			var again wsMessage
+			if err := ws.ReadJSON(&again); err != nil || again.Type != "subscribe" {
+				return
+			}
+			id = again.ID
+		}
+		_ = next(ws, id, 2)
+		_, _, _ = ws.ReadMessage()
+	})
+	c := NewSubscriptionClient(url, WithReconnectBackoff(time.Millisecond, 5*time.Millisecond))
+	defer c.Close()
+	sub, err := c.Subscribe(context.Background(), "subscription { boardUpdated(boardId: \"b1\") { id } }", nil)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	for want := range 3 {
+		select {
+		case ev, ok := <-sub.Events():
+			var data struct{ N int }
+			if !ok || ev.Decode(&data) != nil || data.N != want {
+				t.Fatalf("event %d = %s (open %v, err %v)", want, ev.Data, ok, sub.Err())
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no event %d", want)
+		}
+	}
+	if n := dials.Load(); n != 1 {
+		t.Errorf("dials = %d, want one connection", n)
+	}
+}
+
+func TestSubscriptionBuffer(t *testing.T) {
+	tests := []struct {
+		name        string
+		size        int
+		block       bool
+		wantEvents  []int
+		wantDropped int
+	}{
+		{"drop oldest", 2, false, []int{3, 4}, 3},
+		{"block", 1, true, []int{0, 1, 2, 3, 4}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := make(chan struct{})
+			url, _ := scriptServer(t, func(ws *websocket.Conn, n int) {
+				id := ack(ws)
+				for i := range 5 {
+					if next(ws, id, i) != nil {
+						return
+					}
+				}
+				close(sent)
+				_, _, _ = ws.ReadMessage()
+			})
+			c := NewSubscriptionClient(url, WithBuffer(tt.size, tt.block))
+			defer c.Close()
+			sub, err := c.Subscribe(context.Background(), "subscription { boardUpdated(boardId: \"b1\") { id } }", nil)
+			if err != nil {
+				t.Fatalf("Subscribe: %v", err)
+			}
+			if !tt.block {
+				// Tout est reçu avant la première lecture.
+				<-sent
+				deadline := time.Now().Add(5 * time.Second)
+				for sub.Dropped() < tt.wantDropped && time.Now().Before(deadline) {
+					time.Sleep(time.Millisecond)
+				}
+			}
+			for _, want := range tt.wantEvents {
+				select {
+				case ev := <-sub.Events():
+					var data struct{ N int }
+					if ev.Decode(&data) != nil || data.N != want {
+						t.Fatalf("event = %s, want n=%d", ev.Data, want)
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatalf("no event n=%d", want)
+				}
+			}
+			if got := sub.Dropped(); got != tt.wantDropped {
+				t.Errorf("Dropped() = %d, want %d", got, tt.wantDropped)
+			}
+		})
+	}
+}
+
+func TestWebsocketURL(t *testing.T) {
+	tests := map[string]string{
+		"http://host/graphql":  "ws://host/graphql",
+		"https://host/graphql": "wss://host/graphql",
+		"ws://host/graphql":    "ws://host/graphql",
+	}
+	for in, want := range tests {
+		if got := websocketURL(in); got != want {
+			t.Errorf("websocketURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package render
+
+import (
+	"fmt"
+	"io"
+)
+
+// Format est un format d'export, aussi utilisé comme extension de fichier.
+type Format string
+
+const (
+	FormatSVG Format = "svg"
+	FormatPNG Format = "png"
+	FormatPDF Format = "pdf"
+)
+
+func (f Format) MediaType() string {
+	switch f {
+	case FormatSVG:
+		return "image/svg+xml"
+	case FormatPNG:
+		return "image/png"
+	case FormatPDF:
+		return "application/pdf"
+	}
+	return "application/octet-stream"
+}
+
+// Write écrit s au format f.
+func Write(w io.Writer, s *Scene, f Format) error {
+	switch f {
+	case FormatSVG:
+		return SVG(w, s)
+	case FormatPNG:
+		return PNG(w, s)
+	case FormatPDF:
+		return PDF(w, s)
+	}
+	return fmt.Errorf("render: unknown format %q", f)
+}
@@ -0,0 +1,125 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"miro-lite-standalone/backend/internal/board"
+)
+
+func TestWrite(t *testing.T) {
+	b := &board.Model{ID: "b1", Title: "Plan <1> & co", Widgets: []board.Widget{
+		{ID: "w1", Type: "text", Text: "a < b & c", Width: 100, Height: 60},
+		{ID: "w2", Type: "image", X: 120, Config: map[string]interface{}{"src": "https://example.com/a.png?x=1&y=2", "alt": "Logo"}},
+		{ID: "w3", Type: "kanban", Y: 200},
+	}}
+	tests := []struct {
+		format    Format
+		scale     float64
+		mediaType string
+		check     func(t *testing.T, data []byte)
+	}{
+		{FormatSVG, 1, "image/svg+xml", func(t *testing.T, data []byte) {
+			dec := xml.NewDecoder(bytes.NewReader(data))
+			var text strings.Builder
+			for {
+				tok, err := dec.Token()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("invalid SVG: %v", err)
+				}
+				if c, ok := tok.(xml.CharData); ok {
+					text.Write(c)
+				}
+			}
+			for _, want := range []string{"Plan <1> & co", "a < b & c", "Logo", "kanban widget"} {
+				if !strings.Contains(text.String(), want) {
+					t.Fatalf("SVG text %q lacks %q", text.String(), want)
+				}
+			}
+			if !bytes.Contains(data, []byte(`width="400" height="400" viewBox="-20 -20 400 400"`)) {
+				t.Fatalf("SVG size: %s", data[:min(len(data), 200)])
+			}
+		}},
+		{FormatPNG, 2, "image/png", func(t *testing.T, data []byte) {
+			img, err := png.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("invalid PNG: %v", err)
+			}
+			if got := img.Bounds().Size(); got.X != 800 || got.Y != 800 {
+				t.Fatalf("PNG size = %v, want 800×800", got)
+			}
+		}},
+		{FormatPDF, 1, "application/pdf", checkXref},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			s, err := Layout(b, WithScale(tt.scale))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			if err := Write(&buf, s, tt.format); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+			if got := tt.format.MediaType(); got != tt.mediaType {
+				t.Fatalf("MediaType = %s, want %s", got, tt.mediaType)
+			}
+			tt.check(t, buf.Bytes())
+		})
+	}
+	s, _ := Layout(b)
+	if err := Write(io.Discard, s, "gif"); err == nil {
+		t.Fatal("Write gif: want an error")
+	}
+}
+
+func TestParsePaper(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr bool
+	}{
+		{"A4", "A4", false},
+		{"letter", "Letter", false},
+		{"TABLOID", "Tabloid", false},
+		{"B5", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			p, err := ParsePaper(tt.raw)
+			if (err != nil) != tt.wantErr || p.Name != tt.want {
+				t.Fatalf("ParsePaper = %q, %v, want %q (error %v)", p.Name, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseOrientation(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    Orientation
+		wantErr bool
+	}{
+		{"auto", OrientationAuto, false},
+		{"Portrait", OrientationPortrait, false},
+		{"LANDSCAPE", OrientationLandscape, false},
+		{"sideways", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseOrientation(tt.raw)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Fatalf("ParseOrientation = %q, %v, want %q (error %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
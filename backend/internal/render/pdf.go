@@ -0,0 +1,162 @@
+package render
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"miro-lite-standalone/backend/internal/board"
+)
+
+const (
+	// pointsPerUnit : une unité du board est un pixel CSS, soit 0,75 point
+	// à 96 dpi.
+	pointsPerUnit = 0.75
+	pointsPerMM   = 72 / 25.4
+	// maxPages borne le découpage d'un board en pages.
+	maxPages = 100
+)
+
+// Paper est un format de papier, en points et en portrait.
+type Paper struct {
+	Name          string
+	Width, Height float64
+}
+
+var papers = []Paper{
+	{"A3", 841.89, 1190.55},
+	{"A4", 595.28, 841.89},
+	{"A5", 419.53, 595.28},
+	{"Letter", 612, 792},
+	{"Legal", 612, 1008},
+	{"Tabloid", 792, 1224},
+}
+
+// ParsePaper reconnaît A3, A4, A5, Letter, Legal et Tabloid, sans tenir
+// compte de la casse.
+func ParsePaper(name string) (Paper, error) {
+	names := make([]string, len(papers))
+	for i, p := range papers {
+		if strings.EqualFold(p.Name, name) {
+			return p, nil
+		}
+		names[i] = p.Name
+	}
+	return Paper{}, fmt.Errorf("unknown paper %q (expected %s)", name, strings.Join(names, ", "))
+}
+
+// Orientation oriente la page. OrientationAuto suit la forme du viewport.
+type Orientation string
+
+const (
+	OrientationAuto      Orientation = "auto"
+	OrientationPortrait  Orientation = "portrait"
+	OrientationLandscape Orientation = "landscape"
+)
+
+func ParseOrientation(raw string) (Orientation, error) {
+	switch o := Orientation(strings.ToLower(raw)); o {
+	case OrientationAuto, OrientationPortrait, OrientationLandscape:
+		return o, nil
+	}
+	return "", fmt.Errorf("unknown orientation %q (expected auto, portrait or landscape)", raw)
+}
+
+// PageSetup règle la mise en pages du PDF. Sans Fit, le board est dessiné
+// à l'échelle de la Scene (1 : 0,75 point par unité) et découpé en autant
+// de pages que nécessaire ; avec Fit, il est réduit ou agrandi pour tenir
+// sur une seule page.
+type PageSetup struct {
+	Paper       Paper
+	Orientation Orientation
+	// Margin est la marge autour de chaque page, en millimètres.
+	Margin float64
+	Fit    bool
+}
+
+var defaultPage = PageSetup{Paper: papers[1], Orientation: OrientationAuto, Margin: 10}
+
+// WithPaper choisit le papier du PDF (A4 par défaut).
+func WithPaper(p Paper) Option {
+	return func(o *options) { o.page.Paper = p }
+}
+
+func WithOrientation(or Orientation) Option {
+	return func(o *options) { o.page.Orientation = or }
+}
+
+// WithMargin fixe la marge des pages du PDF, en millimètres (10 par défaut).
+func WithMargin(mm float64) Option {
+	return func(o *options) { o.page.Margin = mm }
+}
+
+// WithFitToPage fait tenir le PDF sur une seule page.
+func WithFitToPage(fit bool) Option {
+	return func(o *options) { o.page.Fit = fit }
+}
+
+func (p PageSetup) validate() error {
+	side := math.Min(p.Paper.Width, p.Paper.Height)
+	if math.IsNaN(p.Margin) || p.Margin < 0 || 2*p.Margin*pointsPerMM >= side-pointsPerMM {
+		return &board.ValidationError{Field: "margin", Message: fmt.Sprintf("must be between 0 and %g mm for %s paper", math.Floor(side/pointsPerMM/2-1), p.Paper.Name)}
+	}
+	return nil
+}
+
+// pageSize renvoie la taille de page, orientée pour vp.
+func (p PageSetup) pageSize(vp Rect) (float64, float64) {
+	w, h := p.Paper.Width, p.Paper.Height
+	landscape := p.Orientation == OrientationLandscape || (p.Orientation == OrientationAuto && vp.W > vp.H)
+	if landscape {
+		w, h = h, w
+	}
+	return w, h
+}
+
+// pdfTile place le board sur une page : (tx, ty) est l'origine du board
+// en points, clipW × clipH la zone imprimée depuis le coin de la marge.
+type pdfTile struct {
+	tx, ty       float64
+	clipW, clipH float64
+}
+
+// PDF écrit s en PDF. Le board est dessiné une fois, dans un XObject que
+// chaque page reprend, décalé et coupé à sa zone imprimable. Les polices Go
+// et les images embarquées sont incluses ; les images externes sont
+// remplacées par leur texte alternatif.
+func PDF(w io.Writer, s *Scene) error {
+	vp, page := s.Viewport, s.Page
+	pageW, pageH := page.pageSize(vp)
+	margin := page.Margin * pointsPerMM
+	areaW, areaH := pageW-2*margin, pageH-2*margin
+
+	if page.Fit {
+		k := math.Min(areaW/vp.W, areaH/vp.H)
+		tile := pdfTile{
+			tx:    margin + (areaW-vp.W*k)/2 - vp.X*k,
+			ty:    pageH - margin - (areaH-vp.H*k)/2 + vp.Y*k,
+			clipW: areaW, clipH: areaH,
+		}
+		return writePDF(w, s, pageW, pageH, margin, k, []pdfTile{tile})
+	}
+	k := s.Scale * pointsPerUnit
+	contentW, contentH := vp.W*k, vp.H*k
+	cols := max(1, int(math.Ceil(contentW/areaW-1e-9)))
+	rows := max(1, int(math.Ceil(contentH/areaH-1e-9)))
+	if cols*rows > maxPages {
+		return &board.ValidationError{Field: "scale", Message: fmt.Sprintf("%d pages exceeds the %d page limit: reduce scale or viewport, or fit to one page", cols*rows, maxPages)}
+	}
+	tiles := make([]pdfTile, 0, cols*rows)
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			tiles = append(tiles, pdfTile{
+				tx:    margin - vp.X*k - float64(col)*areaW,
+				ty:    pageH - margin + vp.Y*k + float64(row)*areaH,
+				clipW: math.Min(areaW, contentW-float64(col)*areaW),
+				clipH: math.Min(areaH, contentH-float64(row)*areaH),
+			})
+		}
+	}
+	return writePDF(w, s, pageW, pageH, margin, k, tiles)
+}
@@ -0,0 +1,228 @@
+package render
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font/sfnt"
+
+	"miro-lite-standalone/backend/internal/board"
+)
+
+// renderPDF met b en scène et l'écrit en PDF, en vérifiant la table xref.
+func renderPDF(t *testing.T, b *board.Model, opts ...Option) []byte {
+	t.Helper()
+	s, err := Layout(b, opts...)
+	if err != nil {
+		t.Fatalf("Layout: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := PDF(&buf, s); err != nil {
+		t.Fatalf("PDF: %v", err)
+	}
+	checkXref(t, buf.Bytes())
+	return buf.Bytes()
+}
+
+var startxref = regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`)
+
+// checkXref vérifie que chaque entrée de la table xref pointe sur son objet.
+func checkXref(t *testing.T, data []byte) {
+	t.Helper()
+	if !bytes.HasPrefix(data, []byte("%PDF-1.7\n")) {
+		t.Fatalf("header = %q", data[:min(len(data), 16)])
+	}
+	m := startxref.FindSubmatch(data)
+	if m == nil {
+		t.Fatal("no startxref")
+	}
+	off, _ := strconv.Atoi(string(m[1]))
+	lines := strings.Split(string(data[off:]), "\n")
+	var count int
+	if _, err := fmt.Sscanf(lines[1], "0 %d", &count); err != nil || lines[0] != "xref" {
+		t.Fatalf("xref header = %q", lines[:2])
+	}
+	for id := 1; id < count; id++ {
+		pos, err := strconv.Atoi(lines[2+id][:10])
+		if err != nil || !bytes.HasPrefix(data[pos:], fmt.Appendf(nil, "%d 0 obj\n", id)) {
+			t.Fatalf("xref entry %d = %q does not point to its object", id, lines[2+id])
+		}
+	}
+}
+
+var mediaBox = regexp.MustCompile(`/Type /Page /Parent \d+ 0 R /MediaBox \[0 0 ([\d.]+) ([\d.]+)\]`)
+
+func TestPDFPages(t *testing.T) {
+	b := &board.Model{ID: "b1", Title: "Plan", Widgets: []board.Widget{
+		{ID: "w1", Type: "text", Text: "hello", Width: 760, Height: 560},
+	}}
+	// Viewport : 800×600 unités, soit 600×450 points à l'échelle 1.
+	tests := []struct {
+		name  string
+		opts  []Option
+		pages int
+		size  string
+	}{
+		{"auto orientation", nil, 1, "841.89 595.28"},
+		{"portrait", []Option{WithOrientation(OrientationPortrait)}, 2, "595.28 841.89"},
+		{"landscape", []Option{WithOrientation(OrientationLandscape)}, 1, "841.89 595.28"},
+		{"scale", []Option{WithScale(2)}, 4, "841.89 595.28"},
+		{"fit", []Option{WithScale(8), WithFitToPage(true)}, 1, "841.89 595.28"},
+		{"paper", []Option{WithPaper(papers[3])}, 1, "792 612"},
+		{"no margin", []Option{WithPaper(papers[3]), WithMargin(0), WithOrientation(OrientationPortrait)}, 1, "612 792"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := renderPDF(t, b, tt.opts...)
+			boxes := mediaBox.FindAllSubmatch(data, -1)
+			if len(boxes) != tt.pages {
+				t.Fatalf("pages = %d, want %d", len(boxes), tt.pages)
+			}
+			if !bytes.Contains(data, fmt.Appendf(nil, "/Count %d >>", tt.pages)) {
+				t.Fatalf("page tree does not count %d pages", tt.pages)
+			}
+			for _, box := range boxes {
+				if got := string(box[1]) + " " + string(box[2]); got != tt.size {
+					t.Fatalf("MediaBox = %s, want %s", got, tt.size)
+				}
+			}
+			if !bytes.Contains(data, []byte("/Title "+pdfText("Plan"))) {
+				t.Fatal("no document title")
+			}
+		})
+	}
+}
+
+func TestPDFPageLimit(t *testing.T) {
+	s, err := Layout(&board.Model{ID: "b1"}, WithViewport(Rect{W: 10_000, H: 10_000}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var validation *board.ValidationError
+	if err := PDF(&bytes.Buffer{}, s); !errors.As(err, &validation) || validation.Field != "scale" {
+		t.Fatalf("PDF err = %v, want a validation error on scale", err)
+	}
+	s.Page.Fit = true
+	if err := PDF(&bytes.Buffer{}, s); err != nil {
+		t.Fatalf("PDF fit to page: %v", err)
+	}
+}
+
+func encodeImage(t *testing.T, mediaType string, img image.Image) string {
+	t.Helper()
+	var buf bytes.Buffer
+	var err error
+	if mediaType == "image/jpeg" {
+		err = jpeg.Encode(&buf, img, nil)
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return board.EncodeDataURL(mediaType, buf.Bytes())
+}
+
+func TestPDFImages(t *testing.T) {
+	opaque := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for i := range opaque.Pix {
+		opaque.Pix[i] = 0xff
+	}
+	gray := image.NewGray(image.Rect(0, 0, 4, 2))
+	tests := []struct {
+		name    string
+		src     string
+		want    []string
+		notWant []string
+	}{
+		{"png", encodeImage(t, "image/png", opaque), []string{"/Width 4 /Height 2", "/ColorSpace /DeviceRGB /Filter /FlateDecode"}, []string{"/SMask", "/DCTDecode"}},
+		{"transparent png", encodeImage(t, "image/png", image.NewNRGBA(image.Rect(0, 0, 4, 2))), []string{"/SMask", "/ColorSpace /DeviceGray"}, []string{"/DCTDecode"}},
+		{"jpeg", encodeImage(t, "image/jpeg", opaque), []string{"/ColorSpace /DeviceRGB /Filter /DCTDecode"}, []string{"/SMask"}},
+		{"gray jpeg", encodeImage(t, "image/jpeg", gray), []string{"/ColorSpace /DeviceGray /Filter /DCTDecode"}, nil},
+		{"external", "https://example.com/a.png", []string{"/F0"}, []string{"/Subtype /Image"}},
+		{"broken", "data:image/png;base64,AAAA", []string{"/F0"}, []string{"/Subtype /Image"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := renderPDF(t, &board.Model{ID: "b1", Widgets: []board.Widget{
+				{ID: "w1", Type: "image", Config: map[string]interface{}{"src": tt.src, "alt": "Logo"}},
+			}})
+			for _, s := range tt.want {
+				if !bytes.Contains(data, []byte(s)) {
+					t.Fatalf("PDF lacks %q", s)
+				}
+			}
+			for _, s := range tt.notWant {
+				if bytes.Contains(data, []byte(s)) {
+					t.Fatalf("PDF contains %q", s)
+				}
+			}
+		})
+	}
+}
+
+var tjString = regexp.MustCompile(`<([0-9A-F]*)>`)
+
+func TestPDFFontEncode(t *testing.T) {
+	tests := []struct {
+		text string
+		bold bool
+	}{
+		{"", false},
+		{"hello", false},
+		{"Héllo wörld", false},
+		{"AVATAR", true},
+		{"€ → ✓", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			f := newPDFFont(tt.bold)
+			tj := f.encode(tt.text)
+			// Les glyphes, relus à travers la table ToUnicode, redonnent le
+			// texte ; les nombres entre les chaînes sont des approches.
+			var got []rune
+			for _, m := range tjString.FindAllStringSubmatch(tj, -1) {
+				for i := 0; i+4 <= len(m[1]); i += 4 {
+					gid, err := strconv.ParseUint(m[1][i:i+4], 16, 16)
+					if err != nil {
+						t.Fatalf("TJ %q: %v", tj, err)
+					}
+					got = append(got, f.used[sfnt.GlyphIndex(gid)])
+				}
+			}
+			if string(got) != tt.text {
+				t.Fatalf("decoded %q, want %q (TJ %s)", string(got), tt.text, tj)
+			}
+			d := &pdfDoc{}
+			f.write(d)
+			if !bytes.Contains(d.buf.Bytes(), []byte("/BaseFont /"+f.name)) {
+				t.Fatalf("font %s not written", f.name)
+			}
+		})
+	}
+}
+
+func TestPDFColors(t *testing.T) {
+	tests := []struct {
+		c    color.RGBA
+		op   string
+		want string
+	}{
+		{color.RGBA{0, 0, 0, 0xff}, "rg", "0 0 0 rg"},
+		{color.RGBA{0xff, 0x80, 0x00, 0xff}, "RG", "1 0.502 0 RG"},
+	}
+	for _, tt := range tests {
+		if got := rgb(tt.c, tt.op); got != tt.want {
+			t.Fatalf("rgb(%v) = %q, want %q", tt.c, got, tt.want)
+		}
+	}
+}
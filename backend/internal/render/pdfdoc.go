@@ -0,0 +1,361 @@
+package render
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// pdfDoc accumule les objets d'un PDF ; offsets[i] est la position de
+// l'objet i+1.
+type pdfDoc struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (d *pdfDoc) alloc() int {
+	d.offsets = append(d.offsets, 0)
+	return len(d.offsets)
+}
+
+func (d *pdfDoc) object(id int, body string) {
+	d.offsets[id-1] = d.buf.Len()
+	fmt.Fprintf(&d.buf, "%d 0 obj\n%s\nendobj\n", id, body)
+}
+
+// stream écrit data compressé (FlateDecode). dict est le contenu du
+// dictionnaire, sans Length ni Filter.
+func (d *pdfDoc) stream(id int, dict string, data []byte) {
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	_, _ = zw.Write(data)
+	_ = zw.Close()
+	d.rawStream(id, dict+" /Filter /FlateDecode", z.Bytes())
+}
+
+func (d *pdfDoc) rawStream(id int, dict string, data []byte) {
+	d.offsets[id-1] = d.buf.Len()
+	fmt.Fprintf(&d.buf, "%d 0 obj\n<< %s /Length %d >>\nstream\n", id, dict, len(data))
+	d.buf.Write(data)
+	d.buf.WriteString("\nendstream\nendobj\n")
+}
+
+func (d *pdfDoc) finish(w io.Writer, root, info int) error {
+	xref := d.buf.Len()
+	fmt.Fprintf(&d.buf, "xref\n0 %d\n0000000000 65535 f \n", len(d.offsets)+1)
+	for _, off := range d.offsets {
+		fmt.Fprintf(&d.buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&d.buf, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(d.offsets)+1, root, info, xref)
+	_, err := d.buf.WriteTo(w)
+	return err
+}
+
+// writePDF écrit le document : le board dans un XObject en coordonnées du
+// board, puis une page par tuile. k est le nombre de points par unité.
+func writePDF(w io.Writer, s *Scene, pageW, pageH, margin, k float64, tiles []pdfTile) error {
+	d := &pdfDoc{}
+	d.buf.WriteString("%PDF-1.7\n%\xe2\xe3\xcf\xd3\n")
+	catalog, pages, boardForm, info := d.alloc(), d.alloc(), d.alloc(), d.alloc()
+
+	c := newPDFContent(d)
+	c.draw(s)
+	var resources strings.Builder
+	resources.WriteString("<< /Font <<")
+	for _, f := range c.fonts {
+		if f != nil {
+			fmt.Fprintf(&resources, " /%s %d 0 R", f.resource, f.write(d))
+		}
+	}
+	resources.WriteString(" >> /XObject <<")
+	for i, id := range c.images {
+		fmt.Fprintf(&resources, " /Im%d %d 0 R", i, id)
+	}
+	resources.WriteString(" >> >>")
+	vp := s.Viewport
+	d.stream(boardForm, fmt.Sprintf("/Type /XObject /Subtype /Form /BBox [%s %s %s %s] /Resources %s",
+		num(vp.X), num(vp.Y), num(vp.X+vp.W), num(vp.Y+vp.H), resources.String()), c.buf.Bytes())
+
+	kids := make([]string, len(tiles))
+	for i, t := range tiles {
+		page, content := d.alloc(), d.alloc()
+		kids[i] = fmt.Sprintf("%d 0 R", page)
+		ops := fmt.Sprintf("q %s %s %s %s re W n %s 0 0 %s %s %s cm /Board Do Q",
+			num(margin), num(pageH-margin-t.clipH), num(t.clipW), num(t.clipH),
+			num4(k), num4(-k), num(t.tx), num(t.ty))
+		d.stream(content, "", []byte(ops))
+		d.object(page, fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Resources << /XObject << /Board %d 0 R >> >> /Contents %d 0 R >>",
+			pages, num(pageW), num(pageH), boardForm, content))
+	}
+	d.object(pages, fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(tiles)))
+	d.object(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pages))
+	title := s.Title
+	if title == "" {
+		title = s.BoardID
+	}
+	d.object(info, fmt.Sprintf("<< /Title %s /Producer (miro-lite) >>", pdfText(title)))
+	return d.finish(w, catalog, info)
+}
+
+// num4 garde plus de décimales, pour les facteurs d'échelle.
+func num4(f float64) string {
+	return strconv.FormatFloat(math.Round(f*10000)/10000, 'f', -1, 64)
+}
+
+// pdfText encode une chaîne de texte PDF en UTF-16BE.
+func pdfText(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", u)
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// pdfContent écrit le flux de dessin du board. L'axe y descend, comme dans
+// le board : les textes et les images sont retournés en conséquence.
+type pdfContent struct {
+	doc    *pdfDoc
+	buf    bytes.Buffer
+	fonts  [2]*pdfFont
+	faces  fonts
+	images []int
+}
+
+func newPDFContent(d *pdfDoc) *pdfContent {
+	return &pdfContent{doc: d, faces: make(fonts)}
+}
+
+func (c *pdfContent) draw(s *Scene) {
+	vp := s.Viewport
+	fmt.Fprintf(&c.buf, "%s %s %s %s %s re f\n", rgb(s.Background, "rg"), num(vp.X), num(vp.Y), num(vp.W), num(vp.H))
+	for _, item := range s.Items {
+		switch it := item.(type) {
+		case *Box:
+			c.box(it)
+		case *Label:
+			c.label(it)
+		case *Picture:
+			c.picture(it)
+		}
+	}
+}
+
+func rgb(col color.RGBA, op string) string {
+	return fmt.Sprintf("%s %s %s %s", num4(float64(col.R)/255), num4(float64(col.G)/255), num4(float64(col.B)/255), op)
+}
+
+func (c *pdfContent) box(b *Box) {
+	r := b.Rect
+	if b.Fill.A != 0 {
+		fmt.Fprintf(&c.buf, "%s %s %s %s %s re f\n", rgb(b.Fill, "rg"), num(r.X), num(r.Y), num(r.W), num(r.H))
+	}
+	if b.StrokeWidth <= 0 {
+		return
+	}
+	sw := b.StrokeWidth
+	dash := "[] 0 d"
+	if b.Dashed {
+		dash = fmt.Sprintf("[%s %s] 0 d", num(dashOn*sw), num(dashOff*sw))
+	}
+	// Trait intérieur, comme pour SVG.
+	r = r.inset(sw / 2)
+	fmt.Fprintf(&c.buf, "%s %s w %s %s %s %s %s re S\n", rgb(b.Stroke, "RG"), num(sw), dash, num(r.X), num(r.Y), num(r.W), num(r.H))
+}
+
+func (c *pdfContent) label(l *Label) {
+	if len(l.Lines) == 0 {
+		return
+	}
+	f := c.font(l.Bold)
+	r := l.Clip
+	fmt.Fprintf(&c.buf, "q %s %s %s %s re W n BT %s /%s %s Tf\n", num(r.X), num(r.Y), num(r.W), num(r.H), rgb(l.Color, "rg"), f.resource, num(l.Size))
+	for _, line := range l.Lines {
+		fmt.Fprintf(&c.buf, "1 0 0 -1 %s %s Tm [%s] TJ\n", num(line.X), num(line.Y), f.encode(line.Text))
+	}
+	c.buf.WriteString("ET Q\n")
+}
+
+func (c *pdfContent) picture(p *Picture) {
+	img, ok := decodePicture(p)
+	if !ok {
+		c.label(c.faces.placeholder(p.Rect, p.Alt))
+		return
+	}
+	id := c.doc.alloc()
+	writePDFImage(c.doc, id, p, img)
+	c.images = append(c.images, id)
+	r := fitRect(p.Rect, img.Bounds())
+	// L'image occupe le carré unité, première ligne en haut : elle est
+	// retournée avec l'axe y.
+	fmt.Fprintf(&c.buf, "q %s 0 0 %s %s %s cm /Im%d Do Q\n", num4(r.W), num4(-r.H), num(r.X), num(r.Y+r.H), len(c.images)-1)
+}
+
+// writePDFImage embarque img. Un JPEG RVB ou en niveaux de gris est recopié
+// tel quel ; les autres images sont recompressées, avec un masque si elles
+// ont de la transparence.
+func writePDFImage(d *pdfDoc, id int, p *Picture, img image.Image) {
+	b := img.Bounds()
+	dims := fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /BitsPerComponent 8", b.Dx(), b.Dy())
+	if p.MediaType == "image/jpeg" {
+		switch img.ColorModel() {
+		case color.YCbCrModel:
+			d.rawStream(id, dims+" /ColorSpace /DeviceRGB /Filter /DCTDecode", p.Data)
+			return
+		case color.GrayModel:
+			d.rawStream(id, dims+" /ColorSpace /DeviceGray /Filter /DCTDecode", p.Data)
+			return
+		}
+	}
+	pixels := make([]byte, 0, 3*b.Dx()*b.Dy())
+	alpha := make([]byte, 0, b.Dx()*b.Dy())
+	opaque := true
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			pixels = append(pixels, c.R, c.G, c.B)
+			alpha = append(alpha, c.A)
+			opaque = opaque && c.A == 0xff
+		}
+	}
+	if !opaque {
+		mask := d.alloc()
+		d.stream(mask, fmt.Sprintf("/Type /XObject /Subtype /Image /Width %d /Height %d /BitsPerComponent 8 /ColorSpace /DeviceGray", b.Dx(), b.Dy()), alpha)
+		dims += fmt.Sprintf(" /SMask %d 0 R", mask)
+	}
+	d.stream(id, dims+" /ColorSpace /DeviceRGB", pixels)
+}
+
+func (c *pdfContent) font(bold bool) *pdfFont {
+	i := 0
+	if bold {
+		i = 1
+	}
+	if c.fonts[i] == nil {
+		c.fonts[i] = newPDFFont(bold)
+	}
+	return c.fonts[i]
+}
+
+// pdfFont est une police Go embarquée en entier (Type0, Identity-H) : le
+// texte est écrit en index de glyphes, avec les mêmes chasses et approches
+// que pour la mise en page.
+type pdfFont struct {
+	name     string
+	resource string
+	sf       *sfnt.Font
+	ttf      []byte
+	upem     fixed.Int26_6
+	buf      sfnt.Buffer
+	used     map[sfnt.GlyphIndex]rune
+}
+
+func newPDFFont(bold bool) *pdfFont {
+	f := &pdfFont{name: "GoRegular", resource: "F0", sf: regularFont, ttf: goregular.TTF, used: make(map[sfnt.GlyphIndex]rune)}
+	if bold {
+		f.name, f.resource, f.sf, f.ttf = "GoBold", "F1", boldFont, gobold.TTF
+	}
+	f.upem = fixed.I(int(f.sf.UnitsPerEm()))
+	return f
+}
+
+// units convertit une mesure à la taille d'un em en millièmes d'em.
+func (f *pdfFont) units(v fixed.Int26_6) int {
+	return int(math.Round(float64(v) * 1000 / float64(f.upem)))
+}
+
+// encode renvoie le contenu d'un tableau TJ pour text.
+func (f *pdfFont) encode(text string) string {
+	var b strings.Builder
+	prev, hasPrev := sfnt.GlyphIndex(0), false
+	b.WriteString("<")
+	for _, r := range text {
+		gid, err := f.sf.GlyphIndex(&f.buf, r)
+		if err != nil {
+			gid = 0
+		}
+		if hasPrev {
+			if kern, err := f.sf.Kern(&f.buf, prev, gid, f.upem, font.HintingNone); err == nil && kern != 0 {
+				fmt.Fprintf(&b, "> %d <", -f.units(kern))
+			}
+		}
+		fmt.Fprintf(&b, "%04X", uint16(gid))
+		if _, ok := f.used[gid]; !ok {
+			f.used[gid] = r
+		}
+		prev, hasPrev = gid, true
+	}
+	b.WriteString(">")
+	return b.String()
+}
+
+// write écrit la police et renvoie l'identifiant de son dictionnaire.
+func (f *pdfFont) write(d *pdfDoc) int {
+	font0, cidFont, descriptor, file, toUnicode := d.alloc(), d.alloc(), d.alloc(), d.alloc(), d.alloc()
+	d.stream(file, fmt.Sprintf("/Length1 %d", len(f.ttf)), f.ttf)
+
+	m, _ := f.sf.Metrics(&f.buf, f.upem, font.HintingNone)
+	bounds, _ := f.sf.Bounds(&f.buf, f.upem, font.HintingNone)
+	d.object(descriptor, fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 32 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 %d 0 R >>",
+		f.name, f.units(bounds.Min.X), -f.units(bounds.Max.Y), f.units(bounds.Max.X), -f.units(bounds.Min.Y),
+		f.units(m.Ascent), -f.units(m.Descent), f.units(m.CapHeight), file))
+
+	gids := make([]sfnt.GlyphIndex, 0, len(f.used))
+	for gid := range f.used {
+		gids = append(gids, gid)
+	}
+	slices.Sort(gids)
+	var widths strings.Builder
+	for _, gid := range gids {
+		adv, err := f.sf.GlyphAdvance(&f.buf, gid, f.upem, font.HintingNone)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(&widths, "%d [%d] ", gid, f.units(adv))
+	}
+	d.object(cidFont, fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> /FontDescriptor %d 0 R /DW 0 /W [%s] /CIDToGIDMap /Identity >>",
+		f.name, descriptor, strings.TrimSpace(widths.String())))
+	d.stream(toUnicode, "", f.cmap(gids))
+	d.object(font0, fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [%d 0 R] /ToUnicode %d 0 R >>",
+		f.name, cidFont, toUnicode))
+	return font0
+}
+
+// cmap associe chaque glyphe utilisé à son caractère, pour la recherche et
+// le copier-coller dans le PDF.
+func (f *pdfFont) cmap(gids []sfnt.GlyphIndex) []byte {
+	var b bytes.Buffer
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n")
+	b.WriteString("/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n")
+	b.WriteString("/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n")
+	b.WriteString("1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	for start := 0; start < len(gids); start += 100 {
+		chunk := gids[start:min(start+100, len(gids))]
+		fmt.Fprintf(&b, "%d beginbfchar\n", len(chunk))
+		for _, gid := range chunk {
+			fmt.Fprintf(&b, "<%04X> <", uint16(gid))
+			for _, u := range utf16.Encode([]rune{f.used[gid]}) {
+				fmt.Fprintf(&b, "%04X", u)
+			}
+			b.WriteString(">\n")
+		}
+		b.WriteString("endbfchar\n")
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return b.Bytes()
+}
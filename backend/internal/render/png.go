@@ -0,0 +1,180 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"math"
+
+	_ "golang.org/x/image/bmp"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/image/vector"
+	_ "golang.org/x/image/webp"
+
+	"miro-lite-standalone/backend/internal/board"
+)
+
+const (
+	// MaxPixels borne la taille d'un PNG (viewport × échelle²) : l'image est
+	// entièrement en mémoire, à 4 octets par pixel.
+	MaxPixels = 40_000_000
+	// maxImagePixels borne une image embarquée décodée ; au-delà, elle est
+	// remplacée par son texte alternatif.
+	maxImagePixels = 25_000_000
+)
+
+// PNG rastérise s. Les images externes ne sont pas téléchargées : leur texte
+// alternatif les remplace.
+func PNG(w io.Writer, s *Scene) error {
+	width, height := pixelSize(s)
+	if width*height > MaxPixels {
+		return &board.ValidationError{Field: "scale", Message: fmt.Sprintf("%dx%d pixels exceeds the %d pixel limit: reduce scale or viewport", width, height, MaxPixels)}
+	}
+	c := &raster{
+		img:   image.NewRGBA(image.Rect(0, 0, width, height)),
+		scene: s,
+		faces: make(fonts),
+	}
+	draw.Draw(c.img, c.img.Bounds(), image.NewUniform(s.Background), image.Point{}, draw.Src)
+	for _, item := range s.Items {
+		switch it := item.(type) {
+		case *Box:
+			c.box(it)
+		case *Label:
+			c.label(it)
+		case *Picture:
+			c.picture(it)
+		}
+	}
+	return png.Encode(w, c.img)
+}
+
+func pixelSize(s *Scene) (int, int) {
+	return max(1, int(math.Ceil(s.Viewport.W*s.Scale))), max(1, int(math.Ceil(s.Viewport.H*s.Scale)))
+}
+
+type raster struct {
+	img   *image.RGBA
+	scene *Scene
+	faces fonts
+	ras   vector.Rasterizer
+}
+
+// px passe d'un rectangle du board aux pixels de l'image.
+func (c *raster) px(r Rect) Rect {
+	vp, k := c.scene.Viewport, c.scene.Scale
+	return Rect{X: (r.X - vp.X) * k, Y: (r.Y - vp.Y) * k, W: r.W * k, H: r.H * k}
+}
+
+// fill remplit r (en pixels), anticrénelé aux bords fractionnaires.
+func (c *raster) fill(r Rect, col color.RGBA) {
+	b := c.img.Bounds()
+	x0, y0 := math.Max(r.X, 0), math.Max(r.Y, 0)
+	x1, y1 := math.Min(r.X+r.W, float64(b.Dx())), math.Min(r.Y+r.H, float64(b.Dy()))
+	if x1 <= x0 || y1 <= y0 || col.A == 0 {
+		return
+	}
+	dst := image.Rect(int(math.Floor(x0)), int(math.Floor(y0)), int(math.Ceil(x1)), int(math.Ceil(y1)))
+	ox, oy := float32(dst.Min.X), float32(dst.Min.Y)
+	c.ras.Reset(dst.Dx(), dst.Dy())
+	c.ras.MoveTo(float32(x0)-ox, float32(y0)-oy)
+	c.ras.LineTo(float32(x1)-ox, float32(y0)-oy)
+	c.ras.LineTo(float32(x1)-ox, float32(y1)-oy)
+	c.ras.LineTo(float32(x0)-ox, float32(y1)-oy)
+	c.ras.ClosePath()
+	c.ras.DrawOp = draw.Over
+	c.ras.Draw(c.img, dst, image.NewUniform(col), image.Point{})
+}
+
+func (c *raster) box(b *Box) {
+	r := c.px(b.Rect)
+	c.fill(r, b.Fill)
+	if b.StrokeWidth <= 0 {
+		return
+	}
+	// Bordure intérieure, un côté après l'autre.
+	sw := b.StrokeWidth * c.scene.Scale
+	edges := []Rect{
+		{X: r.X, Y: r.Y, W: r.W, H: sw},
+		{X: r.X, Y: r.Y + r.H - sw, W: r.W, H: sw},
+		{X: r.X, Y: r.Y + sw, W: sw, H: r.H - 2*sw},
+		{X: r.X + r.W - sw, Y: r.Y + sw, W: sw, H: r.H - 2*sw},
+	}
+	for _, e := range edges {
+		if !b.Dashed {
+			c.fill(e, b.Stroke)
+			continue
+		}
+		on, period := dashOn*sw, (dashOn+dashOff)*sw
+		if e.W >= e.H {
+			for x := e.X; x < e.X+e.W; x += period {
+				c.fill(Rect{X: x, Y: e.Y, W: math.Min(on, e.X+e.W-x), H: e.H}, b.Stroke)
+			}
+		} else {
+			for y := e.Y; y < e.Y+e.H; y += period {
+				c.fill(Rect{X: e.X, Y: y, W: e.W, H: math.Min(on, e.Y+e.H-y)}, b.Stroke)
+			}
+		}
+	}
+}
+
+func (c *raster) label(l *Label) {
+	clip := c.px(l.Clip)
+	bounds := image.Rect(int(math.Floor(clip.X)), int(math.Floor(clip.Y)), int(math.Ceil(clip.X+clip.W)), int(math.Ceil(clip.Y+clip.H)))
+	dst, ok := c.img.SubImage(bounds).(*image.RGBA)
+	if !ok || dst.Bounds().Empty() {
+		return
+	}
+	d := &font.Drawer{Dst: dst, Src: image.NewUniform(l.Color), Face: c.faces.face(l.Size*c.scene.Scale, l.Bold)}
+	vp, k := c.scene.Viewport, c.scene.Scale
+	for _, line := range l.Lines {
+		d.Dot = fixed.Point26_6{X: toFixed((line.X - vp.X) * k), Y: toFixed((line.Y - vp.Y) * k)}
+		d.DrawString(line.Text)
+	}
+}
+
+func toFixed(f float64) fixed.Int26_6 {
+	return fixed.Int26_6(math.Round(f * 64))
+}
+
+func (c *raster) picture(p *Picture) {
+	src, ok := decodePicture(p)
+	if !ok {
+		c.label(c.faces.placeholder(p.Rect, p.Alt))
+		return
+	}
+	dst := fitRect(c.px(p.Rect), src.Bounds())
+	draw.BiLinear.Scale(c.img, image.Rect(int(math.Round(dst.X)), int(math.Round(dst.Y)), int(math.Round(dst.X+dst.W)), int(math.Round(dst.Y+dst.H))), src, src.Bounds(), draw.Over, nil)
+}
+
+// decodePicture décode une image embarquée (PNG, JPEG, GIF, WebP ou BMP).
+func decodePicture(p *Picture) (image.Image, bool) {
+	if p.Data == nil {
+		return nil, false
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(p.Data))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxImagePixels {
+		return nil, false
+	}
+	img, _, err := image.Decode(bytes.NewReader(p.Data))
+	if err != nil {
+		return nil, false
+	}
+	return img, true
+}
+
+// fitRect centre une image de taille size dans r, sans la déformer
+// (object-fit: contain).
+func fitRect(r Rect, size image.Rectangle) Rect {
+	iw, ih := float64(size.Dx()), float64(size.Dy())
+	k := math.Min(r.W/iw, r.H/ih)
+	w, h := iw*k, ih*k
+	return Rect{X: r.X + (r.W-w)/2, Y: r.Y + (r.H-h)/2, W: w, H: h}
+}
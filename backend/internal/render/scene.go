@@ -0,0 +1,446 @@
+// Package render dessine un board.Model. Layout traduit les widgets en une
+// liste de formes simples (Scene), en coordonnées du board ; SVG, PNG et PDF
+// l'écrivent ensuite, sans navigateur. Le rendu suit celui du canvas du
+// frontend : mêmes tailles, mêmes couleurs, même police pour tous les
+// formats.
+package render
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"miro-lite-standalone/backend/internal/board"
+)
+
+const (
+	// MaxScale borne le zoom d'un export.
+	MaxScale = 8
+	// maxViewportSide borne chaque côté du viewport, en unités du board.
+	maxViewportSide = 100_000
+
+	viewportPadding = 20
+	widgetPadding   = 10
+	fontSize        = 14
+	lineHeight      = 20
+	counterSize     = 24
+	tableSize       = 13
+	tableRowHeight  = 22
+
+	// Pointillés des bordures Dashed, en épaisseurs de trait.
+	dashOn  = 6
+	dashOff = 4
+)
+
+// Taille des widgets enregistrés sans dimensions.
+const (
+	defaultWidth  = 240
+	defaultHeight = 160
+)
+
+var (
+	colorSurface = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	colorSoft    = color.RGBA{0xf8, 0xfa, 0xff, 0xff}
+	colorBorder  = color.RGBA{0xdb, 0xe4, 0xf0, 0xff}
+	colorText    = color.RGBA{0x1f, 0x2a, 0x44, 0xff}
+	colorMuted   = color.RGBA{0x60, 0x71, 0x8f, 0xff}
+	colorSticky  = color.RGBA{0xfe, 0xf0, 0x8a, 0xff}
+)
+
+// stickyColors traduit config.color des widgets text ; une couleur #rrggbb
+// est aussi acceptée.
+var stickyColors = map[string]color.RGBA{
+	"yellow": colorSticky,
+	"green":  {0xbb, 0xf7, 0xd0, 0xff},
+	"blue":   {0xbf, 0xdb, 0xfe, 0xff},
+	"pink":   {0xfb, 0xcf, 0xe8, 0xff},
+	"orange": {0xfe, 0xd7, 0xaa, 0xff},
+	"purple": {0xdd, 0xd6, 0xfe, 0xff},
+	"gray":   {0xe5, 0xe7, 0xeb, 0xff},
+}
+
+// Rect est un rectangle en unités du board.
+type Rect struct {
+	X, Y, W, H float64
+}
+
+func (r Rect) inset(d float64) Rect {
+	return Rect{X: r.X + d, Y: r.Y + d, W: math.Max(0, r.W-2*d), H: math.Max(0, r.H-2*d)}
+}
+
+func (r Rect) union(o Rect) Rect {
+	x0, y0 := math.Min(r.X, o.X), math.Min(r.Y, o.Y)
+	x1, y1 := math.Max(r.X+r.W, o.X+o.W), math.Max(r.Y+r.H, o.Y+o.H)
+	return Rect{X: x0, Y: y0, W: x1 - x0, H: y1 - y0}
+}
+
+func (r Rect) overlaps(o Rect) bool {
+	return r.X < o.X+o.W && o.X < r.X+r.W && r.Y < o.Y+o.H && o.Y < r.Y+r.H
+}
+
+// ParseRect lit "x,y,w,h".
+func ParseRect(raw string) (Rect, error) {
+	parts := strings.Split(raw, ",")
+	if len(parts) != 4 {
+		return Rect{}, fmt.Errorf("expected x,y,width,height")
+	}
+	var v [4]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return Rect{}, fmt.Errorf("%q is not a number", p)
+		}
+		v[i] = f
+	}
+	return Rect{X: v[0], Y: v[1], W: v[2], H: v[3]}, nil
+}
+
+// Scene est un board prêt à dessiner. Les Items sont dans l'ordre de dessin.
+type Scene struct {
+	BoardID    string
+	Title      string
+	Viewport   Rect
+	Scale      float64
+	Background color.RGBA
+	Items      []Item
+	// Page sert à la mise en pages du PDF.
+	Page PageSetup
+}
+
+// Item est l'une des formes Box, Label ou Picture.
+type Item interface {
+	bounds() Rect
+}
+
+// Box est un rectangle plein, bordé si StrokeWidth est positif.
+type Box struct {
+	Rect        Rect
+	Fill        color.RGBA
+	Stroke      color.RGBA
+	StrokeWidth float64
+	Dashed      bool
+}
+
+// Label est un texte déjà découpé en lignes, coupé à Clip.
+type Label struct {
+	Clip  Rect
+	Lines []Line
+	Size  float64
+	Bold  bool
+	Color color.RGBA
+}
+
+// Line est une ligne de texte posée sur sa ligne de base.
+type Line struct {
+	Text string
+	X, Y float64
+}
+
+// Picture est l'image d'un widget, à inscrire dans Rect sans la déformer.
+// Data contient une image embarquée, Href une image externe (http ou
+// https) que les exports ne vont jamais chercher eux-mêmes.
+type Picture struct {
+	Rect      Rect
+	MediaType string
+	Data      []byte
+	Href      string
+	Alt       string
+}
+
+func (b *Box) bounds() Rect     { return b.Rect }
+func (l *Label) bounds() Rect   { return l.Clip }
+func (p *Picture) bounds() Rect { return p.Rect }
+
+// Option règle Layout.
+type Option func(*options)
+
+type options struct {
+	viewport *Rect
+	scale    float64
+	page     PageSetup
+}
+
+// WithViewport limite le rendu à la zone r du board. Par défaut, la zone
+// couvre tous les widgets.
+func WithViewport(r Rect) Option {
+	return func(o *options) { o.viewport = &r }
+}
+
+// WithScale fixe le nombre de pixels par unité du board (1 par défaut). En
+// PDF, une unité vaut 0,75 point à l'échelle 1.
+func WithScale(scale float64) Option {
+	return func(o *options) { o.scale = scale }
+}
+
+// Layout met b en scène. Les erreurs d'options sont des
+// *board.ValidationError.
+func Layout(b *board.Model, opts ...Option) (*Scene, error) {
+	o := options{scale: 1, page: defaultPage}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if math.IsNaN(o.scale) || o.scale <= 0 || o.scale > MaxScale {
+		return nil, &board.ValidationError{Field: "scale", Message: fmt.Sprintf("must be greater than 0 and at most %d", MaxScale)}
+	}
+	if err := o.page.validate(); err != nil {
+		return nil, err
+	}
+	viewport := contentBounds(b)
+	if o.viewport != nil {
+		viewport = *o.viewport
+		if viewport.W <= 0 || viewport.H <= 0 || viewport.W > maxViewportSide || viewport.H > maxViewportSide {
+			return nil, &board.ValidationError{Field: "viewport", Message: fmt.Sprintf("width and height must be greater than 0 and at most %d", maxViewportSide)}
+		}
+	}
+	s := &Scene{BoardID: b.ID, Title: b.Title, Viewport: viewport, Scale: o.scale, Background: colorSurface, Page: o.page}
+	f := make(fonts)
+	for _, w := range b.Widgets {
+		frame := widgetFrame(w)
+		if !frame.overlaps(viewport) {
+			continue
+		}
+		s.Items = append(s.Items, f.layoutWidget(w, frame)...)
+	}
+	return s, nil
+}
+
+// contentBounds entoure les widgets d'une marge, ou vaut 800×600 pour un
+// board vide.
+func contentBounds(b *board.Model) Rect {
+	if len(b.Widgets) == 0 {
+		return Rect{W: 800, H: 600}
+	}
+	r := widgetFrame(b.Widgets[0])
+	for _, w := range b.Widgets[1:] {
+		r = r.union(widgetFrame(w))
+	}
+	r = r.inset(-viewportPadding)
+	r.X, r.Y = math.Floor(r.X), math.Floor(r.Y)
+	r.W, r.H = math.Ceil(r.W), math.Ceil(r.H)
+	return r
+}
+
+func widgetFrame(w board.Widget) Rect {
+	r := Rect{X: w.X, Y: w.Y, W: w.Width, H: w.Height}
+	if !(r.W > 0) {
+		r.W = defaultWidth
+	}
+	if !(r.H > 0) {
+		r.H = defaultHeight
+	}
+	return r
+}
+
+func (f fonts) layoutWidget(w board.Widget, frame Rect) []Item {
+	content := frame.inset(widgetPadding)
+	card := &Box{Rect: frame, Fill: colorSurface, Stroke: colorBorder, StrokeWidth: 1}
+	switch w.Type {
+	case "text":
+		card = &Box{Rect: frame, Fill: stickyColor(w.Config["color"])}
+		return []Item{card, f.paragraph(content, widgetText(w), fontSize, false, colorText)}
+	case "textarea":
+		return []Item{card, f.paragraph(content, widgetText(w), fontSize, false, colorText)}
+	case "counter":
+		label, _ := w.Config["label"].(string)
+		value := content
+		value.Y += lineHeight + 8
+		value.H = math.Max(0, value.H-lineHeight-8)
+		return []Item{card,
+			f.paragraph(content, label, fontSize, false, colorText),
+			f.paragraph(value, formatValue(w.Config["value"]), counterSize, true, colorText),
+		}
+	case "chart":
+		chartType, _ := w.Config["chartType"].(string)
+		if chartType == "" {
+			chartType = "pie"
+		}
+		return []Item{card, f.paragraph(content, "Chart: "+chartType, fontSize, false, colorText)}
+	case "table":
+		return append([]Item{card}, f.layoutTable(content, w.Config["rows"])...)
+	case "image":
+		return append([]Item{card}, f.layoutImage(content, w)...)
+	default:
+		card.Dashed = true
+		return []Item{card, f.paragraph(content, w.Type+" widget", fontSize, false, colorMuted)}
+	}
+}
+
+func widgetText(w board.Widget) string {
+	if text, ok := w.Config["text"].(string); ok {
+		return text
+	}
+	return w.Text
+}
+
+func stickyColor(v interface{}) color.RGBA {
+	name, _ := v.(string)
+	name = strings.ToLower(strings.TrimSpace(name))
+	if c, ok := stickyColors[name]; ok {
+		return c
+	}
+	if c, ok := parseHex(name); ok {
+		return c
+	}
+	return colorSticky
+}
+
+func parseHex(s string) (color.RGBA, bool) {
+	if len(s) != 7 || s[0] != '#' {
+		return color.RGBA{}, false
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return color.RGBA{}, false
+	}
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}, true
+}
+
+// formatValue écrit une valeur de config comme le ferait le frontend.
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// paragraph découpe text pour tenir dans r.
+func (f fonts) paragraph(r Rect, text string, size float64, bold bool, c color.RGBA) *Label {
+	lh := size * lineHeight / fontSize
+	maxLines := int(math.Floor(r.H / lh))
+	l := &Label{Clip: r, Size: size, Bold: bold, Color: c}
+	for i, line := range f.wrap(text, size, bold, r.W, maxLines) {
+		l.Lines = append(l.Lines, Line{Text: line, X: r.X, Y: r.Y + float64(i)*lh + f.ascent(size, bold) + (lh-size)/2})
+	}
+	return l
+}
+
+// layoutTable dessine config.rows : des lignes tableaux (cellules), objets
+// (une colonne par clé, avec un en-tête) ou valeurs simples.
+func (f fonts) layoutTable(r Rect, raw interface{}) []Item {
+	rows, _ := raw.([]interface{})
+	if len(rows) == 0 {
+		return []Item{f.paragraph(r, "Table widget", fontSize, false, colorText)}
+	}
+	var header []string
+	var cells [][]string
+	if keys := objectKeys(rows); keys != nil {
+		header = keys
+		for _, row := range rows {
+			obj, _ := row.(map[string]interface{})
+			line := make([]string, len(keys))
+			for i, k := range keys {
+				line[i] = formatValue(obj[k])
+			}
+			cells = append(cells, line)
+		}
+	} else {
+		for _, row := range rows {
+			if arr, ok := row.([]interface{}); ok {
+				line := make([]string, len(arr))
+				for i, v := range arr {
+					line[i] = formatValue(v)
+				}
+				cells = append(cells, line)
+			} else {
+				cells = append(cells, []string{formatValue(row)})
+			}
+		}
+	}
+	cols := len(header)
+	for _, line := range cells {
+		cols = max(cols, len(line))
+	}
+	if cols == 0 {
+		return []Item{f.paragraph(r, "Table widget", fontSize, false, colorText)}
+	}
+	colWidth := r.W / float64(cols)
+	var items []Item
+	y := r.Y
+	addRow := func(line []string, bold bool) bool {
+		if y+tableRowHeight > r.Y+r.H {
+			return false
+		}
+		if bold {
+			items = append(items, &Box{Rect: Rect{X: r.X, Y: y, W: r.W, H: tableRowHeight}, Fill: colorSoft})
+		}
+		for i := 0; i < cols; i++ {
+			cell := Rect{X: r.X + float64(i)*colWidth, Y: y, W: colWidth, H: tableRowHeight}
+			items = append(items, &Box{Rect: cell, Stroke: colorBorder, StrokeWidth: 1})
+			if i < len(line) && line[i] != "" {
+				// Une seule ligne par cellule, centrée verticalement.
+				lh := float64(tableSize) * lineHeight / fontSize
+				text := Rect{X: cell.X + 6, Y: cell.Y + (tableRowHeight-lh)/2, W: math.Max(0, cell.W-12), H: lh}
+				items = append(items, f.paragraph(text, line[i], tableSize, bold, colorText))
+			}
+		}
+		y += tableRowHeight
+		return true
+	}
+	if header != nil && !addRow(header, true) {
+		return items
+	}
+	for _, line := range cells {
+		if !addRow(line, false) {
+			break
+		}
+	}
+	return items
+}
+
+// objectKeys renvoie les clés, triées, de lignes qui sont toutes des objets.
+func objectKeys(rows []interface{}) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, row := range rows {
+		obj, ok := row.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		for k := range obj {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// layoutImage place config.src. Une source qui n'est ni une data URL ni un
+// lien http(s) est remplacée par son texte alternatif.
+func (f fonts) layoutImage(r Rect, w board.Widget) []Item {
+	src, _ := w.Config["src"].(string)
+	alt, _ := w.Config["alt"].(string)
+	if src == "" {
+		return []Item{f.paragraph(r, "No image yet", fontSize, false, colorText)}
+	}
+	if strings.HasPrefix(src, "data:") {
+		mediaType, data, err := board.DecodeDataURL(src)
+		if err == nil && strings.HasPrefix(mediaType, "image/") {
+			return []Item{&Picture{Rect: r, MediaType: mediaType, Data: data, Alt: alt}}
+		}
+	} else if lower := strings.ToLower(src); strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://") {
+		return []Item{&Picture{Rect: r, Href: src, Alt: alt}}
+	}
+	return []Item{f.placeholder(r, alt)}
+}
+
+// placeholder remplace une image qui ne peut pas être dessinée.
+func (f fonts) placeholder(r Rect, alt string) *Label {
+	if alt == "" {
+		alt = "Image"
+	}
+	return f.paragraph(r, alt, fontSize, false, colorMuted)
+}
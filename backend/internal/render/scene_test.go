@@ -0,0 +1,163 @@
+package render
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"miro-lite-standalone/backend/internal/board"
+)
+
+// labels renvoie le texte des Labels de items, lignes jointes par "|".
+func labels(items []Item) []string {
+	var out []string
+	for _, item := range items {
+		if l, ok := item.(*Label); ok {
+			var lines []string
+			for _, line := range l.Lines {
+				lines = append(lines, line.Text)
+			}
+			out = append(out, strings.Join(lines, "|"))
+		}
+	}
+	return out
+}
+
+func TestLayoutWidgets(t *testing.T) {
+	tests := []struct {
+		name   string
+		widget board.Widget
+		fill   string
+		dashed bool
+		want   []string
+	}{
+		{"sticky", board.Widget{Type: "text", Text: "hello", Config: map[string]interface{}{"color": "blue"}}, "#bfdbfe", false, []string{"hello"}},
+		{"sticky hex color", board.Widget{Type: "text", Config: map[string]interface{}{"text": "config wins", "color": "#102030"}}, "#102030", false, []string{"config wins"}},
+		{"sticky unknown color", board.Widget{Type: "text", Config: map[string]interface{}{"color": "teal"}}, "#fef08a", false, []string{""}},
+		{"textarea", board.Widget{Type: "textarea", Text: "a\nb"}, "#ffffff", false, []string{"a|b"}},
+		{"counter", board.Widget{Type: "counter", Config: map[string]interface{}{"label": "Votes", "value": 3.5}}, "#ffffff", false, []string{"Votes", "3.5"}},
+		{"chart", board.Widget{Type: "chart"}, "#ffffff", false, []string{"Chart: pie"}},
+		{"empty table", board.Widget{Type: "table"}, "#ffffff", false, []string{"Table widget"}},
+		{"array table", board.Widget{Type: "table", Config: map[string]interface{}{"rows": []interface{}{
+			[]interface{}{"a", 1.0}, []interface{}{true},
+		}}}, "#ffffff", false, []string{"a", "1", "true"}},
+		{"object table", board.Widget{Type: "table", Config: map[string]interface{}{"rows": []interface{}{
+			map[string]interface{}{"b": "x", "a": "y"},
+		}}}, "#ffffff", false, []string{"a", "b", "y", "x"}},
+		{"image without source", board.Widget{Type: "image"}, "#ffffff", false, []string{"No image yet"}},
+		{"image with bad source", board.Widget{Type: "image", Config: map[string]interface{}{"src": "ftp://x", "alt": "Logo"}}, "#ffffff", false, []string{"Logo"}},
+		{"unknown type", board.Widget{Type: "kanban"}, "#ffffff", true, []string{"kanban widget"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Layout(&board.Model{ID: "b1", Widgets: []board.Widget{tt.widget}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			card, ok := s.Items[0].(*Box)
+			if !ok {
+				t.Fatalf("first item = %T, want *Box", s.Items[0])
+			}
+			if got := hex(card.Fill); got != tt.fill || card.Dashed != tt.dashed {
+				t.Fatalf("card fill, dashed = %s, %v, want %s, %v", got, card.Dashed, tt.fill, tt.dashed)
+			}
+			if card.Rect != (Rect{W: defaultWidth, H: defaultHeight}) {
+				t.Fatalf("card = %+v, want the default size", card.Rect)
+			}
+			if got := labels(s.Items); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("labels = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLayoutViewport(t *testing.T) {
+	b := &board.Model{ID: "b1", Widgets: []board.Widget{
+		{ID: "w1", Type: "text", Text: "left", X: 0, Y: 0, Width: 100, Height: 50},
+		{ID: "w2", Type: "text", Text: "right", X: 500, Y: 300, Width: 100, Height: 50},
+	}}
+	tests := []struct {
+		name     string
+		opts     []Option
+		viewport Rect
+		want     []string
+	}{
+		{"all widgets", nil, Rect{X: -20, Y: -20, W: 640, H: 390}, []string{"left", "right"}},
+		{"viewport", []Option{WithViewport(Rect{X: 450, Y: 250, W: 200, H: 200})}, Rect{X: 450, Y: 250, W: 200, H: 200}, []string{"right"}},
+		{"empty viewport", []Option{WithViewport(Rect{X: 1000, Y: 1000, W: 10, H: 10})}, Rect{X: 1000, Y: 1000, W: 10, H: 10}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Layout(b, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Viewport != tt.viewport {
+				t.Fatalf("viewport = %+v, want %+v", s.Viewport, tt.viewport)
+			}
+			if got := labels(s.Items); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("labels = %q, want %q", got, tt.want)
+			}
+		})
+	}
+	s, err := Layout(&board.Model{ID: "empty"})
+	if err != nil || s.Viewport != (Rect{W: 800, H: 600}) {
+		t.Fatalf("empty board viewport = %+v, %v, want 800×600", s.Viewport, err)
+	}
+}
+
+func TestLayoutOptions(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  []Option
+		field string
+	}{
+		{"defaults", nil, ""},
+		{"max scale", []Option{WithScale(MaxScale)}, ""},
+		{"zero scale", []Option{WithScale(0)}, "scale"},
+		{"large scale", []Option{WithScale(MaxScale + 1)}, "scale"},
+		{"NaN scale", []Option{WithScale(math.NaN())}, "scale"},
+		{"empty viewport", []Option{WithViewport(Rect{W: 0, H: 10})}, "viewport"},
+		{"huge viewport", []Option{WithViewport(Rect{W: maxViewportSide + 1, H: 10})}, "viewport"},
+		{"negative margin", []Option{WithMargin(-1)}, "margin"},
+		{"margin wider than the paper", []Option{WithPaper(papers[2]), WithMargin(100)}, "margin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Layout(&board.Model{ID: "b1"}, tt.opts...)
+			var validation *board.ValidationError
+			switch {
+			case tt.field == "" && err != nil:
+				t.Fatalf("Layout: %v", err)
+			case tt.field != "" && (!errors.As(err, &validation) || validation.Field != tt.field):
+				t.Fatalf("Layout err = %v, want a validation error on %s", err, tt.field)
+			}
+		})
+	}
+}
+
+func TestParseRect(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    Rect
+		wantErr bool
+	}{
+		{"0,0,100,50", Rect{W: 100, H: 50}, false},
+		{" -10.5, 20 ,1e3,4 ", Rect{X: -10.5, Y: 20, W: 1000, H: 4}, false},
+		{"1,2,3", Rect{}, true},
+		{"1,2,3,4,5", Rect{}, true},
+		{"a,2,3,4", Rect{}, true},
+		{"NaN,2,3,4", Rect{}, true},
+		{"1,2,Inf,4", Rect{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			got, err := ParseRect(tt.raw)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Fatalf("ParseRect = %+v, %v, want %+v (error %v)", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
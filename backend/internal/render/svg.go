@@ -0,0 +1,108 @@
+package render
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"miro-lite-standalone/backend/internal/board"
+)
+
+// svgFonts : la police Go d'abord, avec laquelle les lignes ont été
+// découpées ; un texte plus large dans une autre police reste coupé au
+// widget.
+const svgFonts = "Go, 'Helvetica Neue', Arial, sans-serif"
+
+// SVG écrit s en SVG. Le viewBox garde les coordonnées du board, width et
+// height portent l'échelle. Les images embarquées sont recopiées en data URL.
+func SVG(w io.Writer, s *Scene) error {
+	out := bufio.NewWriter(w)
+	vp := s.Viewport
+	fmt.Fprintf(out, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(out, `<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="%s %s %s %s">`+"\n",
+		num(vp.W*s.Scale), num(vp.H*s.Scale), num(vp.X), num(vp.Y), num(vp.W), num(vp.H))
+	if s.Title != "" {
+		fmt.Fprintf(out, "<title>%s</title>\n", escape(s.Title))
+	}
+	fmt.Fprintf(out, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s"/>`+"\n",
+		num(vp.X), num(vp.Y), num(vp.W), num(vp.H), hex(s.Background))
+	clips := 0
+	for _, item := range s.Items {
+		switch it := item.(type) {
+		case *Box:
+			svgBox(out, it)
+		case *Label:
+			if len(it.Lines) == 0 {
+				continue
+			}
+			clips++
+			c := it.Clip
+			fmt.Fprintf(out, `<clipPath id="c%d"><rect x="%s" y="%s" width="%s" height="%s"/></clipPath>`+"\n",
+				clips, num(c.X), num(c.Y), num(c.W), num(c.H))
+			weight := "normal"
+			if it.Bold {
+				weight = "bold"
+			}
+			fmt.Fprintf(out, `<text clip-path="url(#c%d)" font-family="%s" font-size="%s" font-weight="%s" fill="%s" xml:space="preserve">`,
+				clips, svgFonts, num(it.Size), weight, hex(it.Color))
+			for _, line := range it.Lines {
+				fmt.Fprintf(out, `<tspan x="%s" y="%s">%s</tspan>`, num(line.X), num(line.Y), escape(line.Text))
+			}
+			fmt.Fprint(out, "</text>\n")
+		case *Picture:
+			href := it.Href
+			if it.Data != nil {
+				href = board.EncodeDataURL(it.MediaType, it.Data)
+			}
+			r := it.Rect
+			fmt.Fprintf(out, `<image x="%s" y="%s" width="%s" height="%s" preserveAspectRatio="xMidYMid meet" href="%s">`,
+				num(r.X), num(r.Y), num(r.W), num(r.H), escape(href))
+			if it.Alt != "" {
+				fmt.Fprintf(out, "<title>%s</title>", escape(it.Alt))
+			}
+			fmt.Fprint(out, "</image>\n")
+		}
+	}
+	fmt.Fprint(out, "</svg>\n")
+	return out.Flush()
+}
+
+func svgBox(out io.Writer, b *Box) {
+	r := b.Rect
+	if b.StrokeWidth > 0 {
+		// Le trait reste à l'intérieur du rectangle, comme une bordure CSS.
+		r = r.inset(b.StrokeWidth / 2)
+	}
+	fill := "none"
+	if b.Fill.A != 0 {
+		fill = hex(b.Fill)
+	}
+	fmt.Fprintf(out, `<rect x="%s" y="%s" width="%s" height="%s" fill="%s"`, num(r.X), num(r.Y), num(r.W), num(r.H), fill)
+	if b.StrokeWidth > 0 {
+		fmt.Fprintf(out, ` stroke="%s" stroke-width="%s"`, hex(b.Stroke), num(b.StrokeWidth))
+		if b.Dashed {
+			fmt.Fprintf(out, ` stroke-dasharray="%s %s"`, num(dashOn*b.StrokeWidth), num(dashOff*b.StrokeWidth))
+		}
+	}
+	fmt.Fprint(out, "/>\n")
+}
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// num arrondit au centième, assez fin pour n'importe quelle échelle d'export.
+func num(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
+
+func escape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
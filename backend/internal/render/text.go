@@ -0,0 +1,172 @@
+package render
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// ellipsis termine une ligne tronquée.
+const ellipsis = "…"
+
+// Les polices Go sont embarquées : le découpage des lignes ne dépend pas des
+// polices installées sur le serveur.
+var (
+	regularFont = mustParse(goregular.TTF)
+	boldFont    = mustParse(gobold.TTF)
+)
+
+func mustParse(ttf []byte) *opentype.Font {
+	f, err := opentype.Parse(ttf)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+type faceKey struct {
+	size float64
+	bold bool
+}
+
+// fonts garde les polices d'un rendu, créées à la demande aux quelques
+// tailles de scene.go. Une font.Face n'est pas sûre en concurrence : chaque
+// rendu a les siennes.
+type fonts map[faceKey]*fontFace
+
+type fontFace struct {
+	font.Face
+	// minAdvance : chasse du plus étroit des caractères ASCII imprimables.
+	minAdvance float64
+}
+
+func (f fonts) face(size float64, bold bool) *fontFace {
+	key := faceKey{size, bold}
+	if face, ok := f[key]; ok {
+		return face
+	}
+	face := &fontFace{Face: newFace(size, bold), minAdvance: math.Inf(1)}
+	for r := rune(' '); r <= '~'; r++ {
+		if adv, ok := face.GlyphAdvance(r); ok && adv > 0 {
+			face.minAdvance = math.Min(face.minAdvance, fixedFloat(adv))
+		}
+	}
+	f[key] = face
+	return face
+}
+
+// newFace crée une police à la taille size, en pixels.
+func newFace(size float64, bold bool) font.Face {
+	src := regularFont
+	if bold {
+		src = boldFont
+	}
+	f, err := opentype.NewFace(src, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingNone})
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func (f fonts) measure(text string, size float64, bold bool) float64 {
+	return fixedFloat(font.MeasureString(f.face(size, bold), text))
+}
+
+func (f fonts) ascent(size float64, bold bool) float64 {
+	return fixedFloat(f.face(size, bold).Metrics().Ascent)
+}
+
+func fixedFloat(v fixed.Int26_6) float64 {
+	return float64(v) / 64
+}
+
+// wrap découpe text en lignes de largeur width au plus, en coupant aux
+// espaces (ou dans un mot trop long). Au-delà de maxLines, la dernière ligne
+// est tronquée par une ellipse. Le coût ne dépend pas de la longueur de
+// text au-delà de ce qui est affiché : le texte est lu au fil de l'eau, une
+// ligne ne compte jamais plus de window runes et rien n'est mesuré au-delà.
+func (f fonts) wrap(text string, size float64, bold bool, width float64, maxLines int) []string {
+	if maxLines <= 0 || width <= 0 {
+		return nil
+	}
+	window := int(width/f.face(size, bold).minAdvance) + 1
+	fits := func(s string) bool {
+		if _, cut := runePrefix(s, window); cut {
+			return false
+		}
+		return f.measure(s, size, bold) <= width
+	}
+	var lines []string
+	// Les itérateurs ne découpent que ce qui est lu ; "\r" est un espace.
+	for para := range strings.SplitSeq(text, "\n") {
+		line := ""
+		for word := range strings.FieldsFuncSeq(para, unicode.IsSpace) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if fits(candidate) {
+				line = candidate
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+			// Un mot plus large que la ligne est coupé là où il déborde.
+			for !fits(word) && len(lines) <= maxLines {
+				head := fitPrefix(word, "", window, fits)
+				lines = append(lines, head)
+				word = word[len(head):]
+			}
+			line = word
+			if len(lines) > maxLines {
+				break
+			}
+		}
+		lines = append(lines, line)
+		if len(lines) > maxLines {
+			break
+		}
+	}
+	if len(lines) > maxLines {
+		lines = lines[:maxLines]
+		last := lines[maxLines-1]
+		lines[maxLines-1] = fitPrefix(last, ellipsis, window, fits) + ellipsis
+	}
+	return lines
+}
+
+// fitPrefix renvoie le plus long préfixe de s (au moins une rune, sauf si
+// suffix ne tient pas seul) tel que préfixe+suffix tienne. Seules les window
+// premières runes sont essayées.
+func fitPrefix(s, suffix string, window int, fits func(string) bool) string {
+	head, _ := runePrefix(s, window)
+	runes := []rune(head)
+	// La largeur croît avec la longueur du préfixe.
+	n := sort.Search(len(runes)+1, func(i int) bool {
+		return !fits(string(runes[:i]) + suffix)
+	}) - 1
+	n = max(n, 0)
+	if n == 0 && suffix == "" && len(runes) > 0 {
+		n = 1
+	}
+	return string(runes[:n])
+}
+
+// runePrefix renvoie les n premières runes de s et indique si s en a plus.
+func runePrefix(s string, n int) (string, bool) {
+	for i := range s {
+		if n == 0 {
+			return s[:i], true
+		}
+		n--
+	}
+	return s, false
+}
@@ -0,0 +1,108 @@
+package render
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"miro-lite-standalone/backend/internal/board"
+)
+
+func TestWrap(t *testing.T) {
+	f := make(fonts)
+	// Largeur de dix "m", soit environ dix-huit lettres plus étroites.
+	width := f.measure("mmmmmmmmmm", fontSize, false)
+	tests := []struct {
+		name     string
+		text     string
+		maxLines int
+		want     []string
+	}{
+		{"empty", "", 3, []string{""}},
+		{"fits", "hello world", 3, []string{"hello world"}},
+		{"collapses spaces", "  hello \t world  ", 3, []string{"hello world"}},
+		{"wraps at spaces", "hello world again and again", 5, []string{"hello world again", "and again"}},
+		{"keeps paragraphs", "a\r\n\nb", 5, []string{"a", "", "b"}},
+		{"breaks a long word", strings.Repeat("m", 25), 5, []string{"mmmmmmmmmm", "mmmmmmmmmm", "mmmmm"}},
+		{"truncates", "one two three four five six seven eight nine ten", 2, []string{"one two three four", "five six seven…"}},
+		{"truncates a long word", strings.Repeat("m", 1_000), 2, []string{"mmmmmmmmmm", "mmmmmmmm…"}},
+		{"truncates paragraphs", "a\nb\nc", 2, []string{"a", "b…"}},
+		{"no room", "hello", 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := f.wrap(tt.text, fontSize, false, width, tt.maxLines)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("wrap = %q, want %q", got, tt.want)
+			}
+			for _, line := range got {
+				if w := f.measure(line, fontSize, false); w > width {
+					t.Fatalf("line %q is %v wide, want at most %v", line, w, width)
+				}
+			}
+		})
+	}
+}
+
+// Le travail de wrap ne dépend que de ce qui est affiché : un texte énorme
+// ne coûte pas plus que son début.
+func TestWrapBounded(t *testing.T) {
+	f := make(fonts)
+	width := f.measure("mmmmmmmmmm", fontSize, false)
+	tests := []struct {
+		name string
+		unit string
+	}{
+		{"one word", "m"},
+		{"many words", "ab "},
+		{"many paragraphs", "ab\n"},
+		{"wide runes", "漢"},
+		{"zero width runes", "\u200b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			small := strings.Repeat(tt.unit, 100)
+			huge := strings.Repeat(tt.unit, 1_000_000)
+			want := f.wrap(small, fontSize, false, width, 3)
+			if got := f.wrap(huge, fontSize, false, width, 3); !reflect.DeepEqual(got, want) {
+				t.Fatalf("wrap(huge) = %q, want %q", got, want)
+			}
+			smallAllocs := testing.AllocsPerRun(5, func() { f.wrap(small, fontSize, false, width, 3) })
+			hugeAllocs := testing.AllocsPerRun(5, func() { f.wrap(huge, fontSize, false, width, 3) })
+			if hugeAllocs > smallAllocs {
+				t.Fatalf("allocs = %v for a huge text, want at most %v", hugeAllocs, smallAllocs)
+			}
+		})
+	}
+}
+
+// Chaque rendu a ses polices : des exports simultanés ne partagent rien.
+func TestLayoutConcurrent(t *testing.T) {
+	b := &board.Model{ID: "b1", Widgets: []board.Widget{
+		{ID: "w1", Type: "text", Width: 200, Height: 100, Text: strings.Repeat("lorem ipsum ", 50)},
+		{ID: "w2", Type: "counter", X: 300, Config: map[string]interface{}{"label": "Votes", "value": 3.0}},
+	}}
+	want, err := Layout(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			s, err := Layout(b)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if !reflect.DeepEqual(s, want) {
+				t.Error("concurrent layout differs")
+			}
+			if err := PNG(io.Discard, s); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+	wg.Wait()
+}
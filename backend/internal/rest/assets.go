@@ -0,0 +1,114 @@
+package rest
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"miro-lite-standalone/backend/internal/board"
+)
+
+// maxUploadMemory est la part du formulaire multipart gardée en mémoire ; le
+// reste passe par des fichiers temporaires.
+const maxUploadMemory = 32 << 20
+
+func (h *Handler) listAssets(w http.ResponseWriter, r *http.Request) {
+	assets, err := h.svc.Assets(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	for i := range assets {
+		if !assets[i].External {
+			assets[i].URL = "/api/boards/" + r.PathValue("id") + "/assets/" + assets[i].WidgetID
+		}
+	}
+	h.writeJSON(w, r, http.StatusOK, assets)
+}
+
+func (h *Handler) getAsset(w http.ResponseWriter, r *http.Request) {
+	asset, data, err := h.svc.Asset(r.Context(), r.PathValue("id"), r.PathValue("widgetId"))
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	if asset.External {
+		http.Redirect(w, r, asset.URL, http.StatusFound)
+		return
+	}
+	w.Header().Set("Content-Type", asset.MediaType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	_, _ = w.Write(data)
+}
+
+// uploadAsset crée un widget image à partir d'un fichier multipart (champ
+// "file"), embarqué en data URL comme le fait le frontend.
+func (h *Handler) uploadAsset(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if err := r.ParseMultipartForm(maxUploadMemory); err != nil {
+		if writeTooLarge(w, err) {
+			return
+		}
+		writeError(w, http.StatusBadRequest, board.CodeValidation, "invalid multipart form: "+err.Error(), nil)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, board.CodeValidation, "missing file field", map[string]interface{}{"field": "file"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, board.CodeValidation, "unreadable file", map[string]interface{}{"field": "file"})
+		return
+	}
+	mediaType := header.Header.Get("Content-Type")
+	if mediaType == "" || mediaType == "application/octet-stream" {
+		mediaType = http.DetectContentType(data)
+	}
+	if !strings.HasPrefix(mediaType, "image/") {
+		writeError(w, http.StatusBadRequest, board.CodeValidation, "only images can be uploaded", map[string]interface{}{"field": "file"})
+		return
+	}
+
+	widget := board.Widget{
+		ID:     board.NewWidgetID(),
+		Type:   "image",
+		X:      formFloat(r, "x", 0),
+		Y:      formFloat(r, "y", 0),
+		Width:  formFloat(r, "width", 320),
+		Height: formFloat(r, "height", 240),
+		Config: map[string]interface{}{
+			"src": board.EncodeDataURL(mediaType, data),
+			"alt": header.Filename,
+		},
+	}
+	ifVersion, fromIfMatch := ifMatchVersion(r, id)
+	b, created, err := h.svc.AddWidget(r.Context(), id, ifVersion, widget)
+	if err != nil {
+		h.writeWriteError(w, r, err, fromIfMatch)
+		return
+	}
+	setBoardETag(w, b)
+	w.Header().Set("Location", "/api/boards/"+b.ID+"/assets/"+created.ID)
+	h.writeJSON(w, r, http.StatusCreated, board.Asset{
+		WidgetID:  created.ID,
+		MediaType: mediaType,
+		Size:      len(data),
+		URL:       "/api/boards/" + b.ID + "/assets/" + created.ID,
+	})
+}
+
+func formFloat(r *http.Request, key string, fallback float64) float64 {
+	raw := r.FormValue(key)
+	if raw == "" {
+		return fallback
+	}
+	v, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return fallback
+	}
+	return v
+}
@@ -0,0 +1,141 @@
+package rest
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"net/http"
+
+	"miro-lite-standalone/backend/internal/board"
+)
+
+type boardSummary struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Version     int    `json:"version"`
+	WidgetCount int    `json:"widgetCount"`
+}
+
+type createBoardRequest struct {
+	Title string `json:"title"`
+}
+
+func (h *Handler) listBoards(w http.ResponseWriter, r *http.Request) {
+	boards := h.svc.ListBoards()
+	if checkNotModified(w, r, boardListETag(boards)) {
+		return
+	}
+	// Les widgets (et leurs images) ne sont pas renvoyés dans la liste.
+	summaries := make([]boardSummary, 0, len(boards))
+	for _, b := range boards {
+		summaries = append(summaries, boardSummary{
+			ID:          b.ID,
+			Title:       b.Title,
+			Version:     b.Version,
+			WidgetCount: len(b.Widgets),
+		})
+	}
+	h.writeJSON(w, r, http.StatusOK, summaries)
+}
+
+// boardListETag est faible : il change dès qu'un board est créé, supprimé ou
+// modifié, sans garantir l'identité octet par octet de la réponse.
+func boardListETag(boards []*board.Model) string {
+	hash := sha1.New()
+	for _, b := range boards {
+		fmt.Fprintf(hash, "%s.%d\n", b.ID, b.Version)
+	}
+	return fmt.Sprintf(`W/"boards-%x"`, hash.Sum(nil)[:8])
+}
+
+func (h *Handler) createBoard(w http.ResponseWriter, r *http.Request) {
+	var req createBoardRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	b, err := h.svc.CreateBoard(r.Context(), board.NewBoardID(), req.Title)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	setBoardETag(w, b)
+	w.Header().Set("Location", "/api/boards/"+b.ID)
+	h.writeJSON(w, r, http.StatusCreated, b)
+}
+
+func (h *Handler) getBoard(w http.ResponseWriter, r *http.Request) {
+	b, err := h.svc.ResolveBoard(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	if checkNotModified(w, r, boardETag(b.ID, b.Version)) {
+		return
+	}
+	h.writeBoardJSON(w, r, http.StatusOK, b)
+}
+
+// saveBoard remplace les widgets du board. La version attendue vient de
+// If-Match si présent, sinon du champ version du corps, qui est alors
+// obligatoire : seul If-Match: * écrit sans contrôle de version.
+func (h *Handler) saveBoard(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var req board.SaveRequest
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+	var b *board.Model
+	var err error
+	ifVersion, fromIfMatch := ifMatchVersion(r, id)
+	if fromIfMatch {
+		b, err = h.svc.Replace(r.Context(), id, ifVersion, req)
+	} else {
+		b, err = h.svc.Save(r.Context(), id, req)
+	}
+	if err != nil {
+		h.writeWriteError(w, r, err, fromIfMatch)
+		return
+	}
+	setBoardETag(w, b)
+	h.writeBoardJSON(w, r, http.StatusOK, b)
+}
+
+func (h *Handler) deleteBoard(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	ifVersion, fromIfMatch := ifMatchVersion(r, id)
+	if err := h.svc.DeleteBoard(r.Context(), id, ifVersion); err != nil {
+		h.writeWriteError(w, r, err, fromIfMatch)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) boardHistory(w http.ResponseWriter, r *http.Request) {
+	revisions, err := h.svc.History(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, revisions)
+}
+
+// undo et redo portent sur les écritures de l'appelant (voir
+// middleware.Identity et board.Service.Undo).
+func (h *Handler) undo(w http.ResponseWriter, r *http.Request) {
+	b, err := h.svc.Undo(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	setBoardETag(w, b)
+	h.writeBoardJSON(w, r, http.StatusOK, b)
+}
+
+func (h *Handler) redo(w http.ResponseWriter, r *http.Request) {
+	b, err := h.svc.Redo(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	setBoardETag(w, b)
+	h.writeBoardJSON(w, r, http.StatusOK, b)
+}
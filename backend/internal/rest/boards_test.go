@@ -0,0 +1,128 @@
+package rest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"miro-lite-standalone/backend/internal/board"
+)
+
+func newTestHandler(t *testing.T, opts ...board.Option) (*Handler, *board.Service) {
+	t.Helper()
+	svc, err := board.NewService("", opts...)
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	return NewHandler(svc, nil, nil), svc
+}
+
+func TestSaveBoardVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		ifMatch string
+		body    string
+		want    int
+	}{
+		{"body version", "", `{"version":2,"widgets":[]}`, http.StatusOK},
+		{"missing version", "", `{"widgets":[]}`, http.StatusConflict},
+		{"zero version", "", `{"version":0,"widgets":[]}`, http.StatusConflict},
+		{"stale version", "", `{"version":1,"widgets":[]}`, http.StatusConflict},
+		{"negative version", "", `{"version":-1,"widgets":[]}`, http.StatusBadRequest},
+		{"if-match current", `"b1.2"`, `{"widgets":[]}`, http.StatusOK},
+		{"if-match stale", `"b1.1"`, `{"version":2,"widgets":[]}`, http.StatusPreconditionFailed},
+		{"if-match other board", `"b2.2"`, `{"version":2,"widgets":[]}`, http.StatusPreconditionFailed},
+		{"if-match star", "*", `{"widgets":[]}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, svc := newTestHandler(t)
+			if _, _, err := svc.AddWidget(context.Background(), "b1", board.AnyVersion, board.Widget{ID: "w1", Type: "text"}); err != nil {
+				t.Fatalf("AddWidget: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodPut, "/api/boards/b1", strings.NewReader(tt.body))
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			b, _ := svc.GetBoard("b1")
+			if saved := len(b.Widgets) == 0; saved != (tt.want == http.StatusOK) {
+				t.Fatalf("board after PUT = %+v", b)
+			}
+		})
+	}
+}
+
+func TestMissingBoard(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy board.MissingBoardPolicy
+		method string
+		path   string
+		want   int
+	}{
+		{"strict get", board.MissingBoardStrict, http.MethodGet, "/api/boards/nope", http.StatusNotFound},
+		{"strict widgets", board.MissingBoardStrict, http.MethodGet, "/api/boards/nope/widgets", http.StatusNotFound},
+		{"strict delete", board.MissingBoardStrict, http.MethodDelete, "/api/boards/nope", http.StatusNotFound},
+		{"auto-create get", board.MissingBoardAutoCreate, http.MethodGet, "/api/boards/nope", http.StatusOK},
+		{"auto-create delete", board.MissingBoardAutoCreate, http.MethodDelete, "/api/boards/nope", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, _ := newTestHandler(t, board.WithMissingBoardPolicy(tt.policy))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.want != http.StatusNotFound {
+				return
+			}
+			var body errorBody
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error.Code != board.CodeNotFound {
+				t.Fatalf("body = %s", rec.Body)
+			}
+		})
+	}
+}
+
+func TestSaveBoardMaxWidgets(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"at the limit", `{"version":2,"widgets":[{"id":"a","type":"text"},{"id":"b","type":"text"}]}`, http.StatusOK},
+		{"over the limit", `{"version":2,"widgets":[{"id":"a","type":"text"},{"id":"b","type":"text"},{"id":"c","type":"text"}]}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, svc := newTestHandler(t, board.WithMaxWidgets(2))
+			if _, _, err := svc.AddWidget(context.Background(), "b1", board.AnyVersion, board.Widget{ID: "w1", Type: "text"}); err != nil {
+				t.Fatalf("AddWidget: %v", err)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/api/boards/b1", strings.NewReader(tt.body)))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.want == http.StatusOK {
+				return
+			}
+			var body errorBody
+			if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || body.Error.Code != board.CodeValidation {
+				t.Fatalf("body = %s", rec.Body)
+			}
+			if b, _ := svc.GetBoard("b1"); b.Version != 2 || len(b.Widgets) != 1 || b.Widgets[0].ID != "w1" {
+				t.Fatalf("board after a refused PUT = %+v", b)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package rest
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"miro-lite-standalone/backend/internal/board"
+	"miro-lite-standalone/backend/internal/render"
+)
+
+func (h *Handler) exportSVG(w http.ResponseWriter, r *http.Request) {
+	h.export(w, r, render.FormatSVG)
+}
+
+func (h *Handler) exportPNG(w http.ResponseWriter, r *http.Request) {
+	h.export(w, r, render.FormatPNG)
+}
+
+func (h *Handler) exportPDF(w http.ResponseWriter, r *http.Request) {
+	h.export(w, r, render.FormatPDF)
+}
+
+// export dessine le board. Les paramètres viewport=x,y,width,height (en
+// unités du board, tous les widgets par défaut) et scale (1 par défaut)
+// choisissent la zone et la résolution ; paper, orientation, margin (en mm)
+// et fit règlent les pages du PDF. Le rendu est fait en mémoire avant la
+// réponse, pour qu'une erreur reste une erreur JSON.
+func (h *Handler) export(w http.ResponseWriter, r *http.Request, format render.Format) {
+	opts, err := exportOptions(r)
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	b, err := h.svc.ResolveBoard(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	if checkNotModified(w, r, boardETag(b.ID, b.Version)) {
+		return
+	}
+	scene, err := render.Layout(b, opts...)
+	if err != nil {
+		w.Header().Del("ETag")
+		h.writeServiceError(w, r, err)
+		return
+	}
+	var buf bytes.Buffer
+	if err := render.Write(&buf, scene, format); err != nil {
+		w.Header().Del("ETag")
+		h.writeServiceError(w, r, err)
+		return
+	}
+	w.Header().Set("Content-Type", format.MediaType())
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": b.ID + "." + string(format)}))
+	_, _ = buf.WriteTo(w)
+}
+
+func exportOptions(r *http.Request) ([]render.Option, error) {
+	var opts []render.Option
+	q := r.URL.Query()
+	if raw := q.Get("viewport"); raw != "" {
+		viewport, err := render.ParseRect(raw)
+		if err != nil {
+			return nil, &board.ValidationError{Field: "viewport", Message: err.Error()}
+		}
+		opts = append(opts, render.WithViewport(viewport))
+	}
+	if raw := q.Get("scale"); raw != "" {
+		scale, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, &board.ValidationError{Field: "scale", Message: "must be a number"}
+		}
+		opts = append(opts, render.WithScale(scale))
+	}
+	if raw := q.Get("paper"); raw != "" {
+		paper, err := render.ParsePaper(raw)
+		if err != nil {
+			return nil, &board.ValidationError{Field: "paper", Message: err.Error()}
+		}
+		opts = append(opts, render.WithPaper(paper))
+	}
+	if raw := q.Get("orientation"); raw != "" {
+		orientation, err := render.ParseOrientation(raw)
+		if err != nil {
+			return nil, &board.ValidationError{Field: "orientation", Message: err.Error()}
+		}
+		opts = append(opts, render.WithOrientation(orientation))
+	}
+	if raw := q.Get("margin"); raw != "" {
+		margin, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, &board.ValidationError{Field: "margin", Message: "must be a number"}
+		}
+		opts = append(opts, render.WithMargin(margin))
+	}
+	if raw := q.Get("fit"); raw != "" {
+		fit, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, &board.ValidationError{Field: "fit", Message: "must be true or false"}
+		}
+		opts = append(opts, render.WithFitToPage(fit))
+	}
+	return opts, nil
+}
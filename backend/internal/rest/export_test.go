@@ -0,0 +1,77 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"miro-lite-standalone/backend/internal/board"
+)
+
+func TestExport(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  string
+		want  int
+		field string
+		magic string
+	}{
+		{"svg", "/api/boards/b1/export.svg", http.StatusOK, "", "<?xml"},
+		{"png", "/api/boards/b1/export.png?scale=0.5", http.StatusOK, "", "\x89PNG"},
+		{"pdf", "/api/boards/b1/export.pdf?paper=letter&orientation=portrait&margin=5", http.StatusOK, "", "%PDF-"},
+		{"pdf fit", "/api/boards/b1/export.pdf?viewport=0,0,20000,20000&fit=true", http.StatusOK, "", "%PDF-"},
+		{"viewport", "/api/boards/b1/export.svg?viewport=0,0,100", http.StatusBadRequest, "viewport", ""},
+		{"scale", "/api/boards/b1/export.png?scale=big", http.StatusBadRequest, "scale", ""},
+		{"scale out of range", "/api/boards/b1/export.png?scale=9", http.StatusBadRequest, "scale", ""},
+		{"paper", "/api/boards/b1/export.pdf?paper=B5", http.StatusBadRequest, "paper", ""},
+		{"orientation", "/api/boards/b1/export.pdf?orientation=up", http.StatusBadRequest, "orientation", ""},
+		{"margin", "/api/boards/b1/export.pdf?margin=500", http.StatusBadRequest, "margin", ""},
+		{"fit", "/api/boards/b1/export.pdf?fit=maybe", http.StatusBadRequest, "fit", ""},
+		{"too many pages", "/api/boards/b1/export.pdf?viewport=0,0,20000,20000", http.StatusBadRequest, "scale", ""},
+		{"missing board", "/api/boards/nope/export.svg", http.StatusNotFound, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, svc := newTestHandler(t, board.WithMissingBoardPolicy(board.MissingBoardStrict))
+			if _, err := svc.CreateBoard(context.Background(), "b1", "Plan"); err != nil {
+				t.Fatalf("CreateBoard: %v", err)
+			}
+			if _, _, err := svc.AddWidget(context.Background(), "b1", board.AnyVersion, board.Widget{ID: "w1", Type: "text", Text: "hello"}); err != nil {
+				t.Fatalf("AddWidget: %v", err)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if tt.want != http.StatusOK {
+				var body errorBody
+				if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || (tt.field != "" && body.Error.Details["field"] != tt.field) {
+					t.Fatalf("body = %s, want an error on %q", rec.Body, tt.field)
+				}
+				if rec.Header().Get("ETag") != "" {
+					t.Fatalf("ETag = %q on an error", rec.Header().Get("ETag"))
+				}
+				return
+			}
+			if !bytes.HasPrefix(rec.Body.Bytes(), []byte(tt.magic)) {
+				t.Fatalf("body starts with %q, want %q", rec.Body.Bytes()[:min(rec.Body.Len(), 8)], tt.magic)
+			}
+			if got := rec.Header().Get("Content-Disposition"); got == "" {
+				t.Fatal("no Content-Disposition")
+			}
+
+			// La même exportation, tant que le board ne change pas, est un 304.
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+			again := httptest.NewRecorder()
+			h.ServeHTTP(again, req)
+			if again.Code != http.StatusNotModified {
+				t.Fatalf("conditional status = %d, want %d", again.Code, http.StatusNotModified)
+			}
+		})
+	}
+}
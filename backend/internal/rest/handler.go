@@ -0,0 +1,66 @@
+// Package rest expose board.Service en REST/JSON, en parité avec le schéma
+// GraphQL. Les deux transports appellent les mêmes méthodes du service.
+package rest
+
+import (
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"miro-lite-standalone/backend/internal/board"
+	"miro-lite-standalone/backend/internal/metrics"
+	"miro-lite-standalone/backend/internal/tracing"
+)
+
+type Handler struct {
+	svc     *board.Service
+	mux     *http.ServeMux
+	metrics *metrics.Metrics
+	logger  *slog.Logger
+}
+
+// NewHandler monte les routes REST. m et logger peuvent être nil.
+func NewHandler(svc *board.Service, m *metrics.Metrics, logger *slog.Logger) *Handler {
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
+	h := &Handler{svc: svc, mux: http.NewServeMux(), metrics: m, logger: logger}
+
+	h.handle("GET /api/openapi.json", serveOpenAPI)
+
+	h.handle("GET /api/boards", h.listBoards)
+	h.handle("POST /api/boards", h.createBoard)
+	h.handle("GET /api/boards/{id}", h.getBoard)
+	h.handle("PUT /api/boards/{id}", h.saveBoard)
+	h.handle("DELETE /api/boards/{id}", h.deleteBoard)
+	h.handle("GET /api/boards/{id}/history", h.boardHistory)
+	h.handle("POST /api/boards/{id}/undo", h.undo)
+	h.handle("POST /api/boards/{id}/redo", h.redo)
+	h.handle("GET /api/boards/{id}/export.svg", h.exportSVG)
+	h.handle("GET /api/boards/{id}/export.png", h.exportPNG)
+	h.handle("GET /api/boards/{id}/export.pdf", h.exportPDF)
+
+	h.handle("GET /api/boards/{id}/widgets", h.listWidgets)
+	h.handle("POST /api/boards/{id}/widgets", h.addWidget)
+	h.handle("GET /api/boards/{id}/widgets/{widgetId}", h.getWidget)
+	h.handle("PUT /api/boards/{id}/widgets/{widgetId}", h.updateWidget)
+	h.handle("DELETE /api/boards/{id}/widgets/{widgetId}", h.deleteWidget)
+
+	h.handle("GET /api/boards/{id}/assets", h.listAssets)
+	h.handle("POST /api/boards/{id}/assets", h.uploadAsset)
+	h.handle("GET /api/boards/{id}/assets/{widgetId}", h.getAsset)
+
+	h.mux.HandleFunc("/api/", func(w http.ResponseWriter, _ *http.Request) {
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "no such endpoint", nil)
+	})
+	return h
+}
+
+func (h *Handler) handle(pattern string, fn http.HandlerFunc) {
+	_, route, _ := strings.Cut(pattern, " ")
+	h.mux.Handle(pattern, tracing.Route(route, h.metrics.InstrumentHandler(route, fn)))
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
@@ -0,0 +1,240 @@
+package rest
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"miro-lite-standalone/backend/internal/board"
+	"miro-lite-standalone/backend/internal/middleware"
+)
+
+const codePreconditionFailed = "PRECONDITION_FAILED"
+
+type errorBody struct {
+	Error errorPayload `json:"error"`
+}
+
+type errorPayload struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+func (h *Handler) writeJSON(w http.ResponseWriter, r *http.Request, status int, v interface{}) {
+	if err := encodeJSON(w, status, v); err != nil {
+		h.logWriteFailure(r, err)
+	}
+}
+
+func encodeJSON(w http.ResponseWriter, status int, v interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return json.NewEncoder(w).Encode(v)
+}
+
+// logWriteFailure trace une réponse interrompue, le plus souvent parce que
+// le client s'est déconnecté : le statut est déjà parti, on ne peut plus rien
+// lui signaler.
+func (h *Handler) logWriteFailure(r *http.Request, err error) {
+	h.logger.WarnContext(r.Context(), "rest: response write failed", "method", r.Method, "path", r.URL.Path, "err", err)
+}
+
+// writeBoardJSON encode le board widget par widget au lieu de construire tout
+// le document en mémoire : les boards avec images embarquées pèsent plusieurs
+// Mo et chaque widget est envoyé (et compressé) dès qu'il est prêt.
+func (h *Handler) writeBoardJSON(w http.ResponseWriter, r *http.Request, status int, b *board.Model) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := streamBoardJSON(w, b); err != nil {
+		h.logWriteFailure(r, err)
+	}
+}
+
+func streamBoardJSON(w http.ResponseWriter, b *board.Model) error {
+	bw := bufio.NewWriterSize(w, 32<<10)
+	enc := json.NewEncoder(bw)
+	header := struct {
+		ID      string `json:"id"`
+		Title   string `json:"title"`
+		Version int    `json:"version"`
+	}{b.ID, b.Title, b.Version}
+	head, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	// On rouvre l'objet pour y ajouter le tableau de widgets. Les erreurs
+	// d'écriture restent dans bw et ressortent au Flush.
+	_, _ = bw.Write(head[:len(head)-1])
+	_, _ = bw.WriteString(`,"widgets":[`)
+	for i := range b.Widgets {
+		if i > 0 {
+			_ = bw.WriteByte(',')
+		}
+		if err := enc.Encode(b.Widgets[i]); err != nil {
+			return fmt.Errorf("widget %s: %w", b.Widgets[i].ID, err)
+		}
+	}
+	_, _ = bw.WriteString("]}\n")
+	return bw.Flush()
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string, details map[string]interface{}) {
+	_ = encodeJSON(w, status, errorBody{Error: errorPayload{Code: code, Message: message, Details: details}})
+}
+
+// writeServiceError mappe les erreurs du domaine sur les mêmes codes que
+// l'ErrorPresenter GraphQL.
+func (h *Handler) writeServiceError(w http.ResponseWriter, r *http.Request, err error) {
+	code := board.ErrorCode(err)
+	details := map[string]interface{}{}
+
+	var notFound *board.NotFoundError
+	var conflict *board.VersionConflictError
+	var validation *board.ValidationError
+	switch {
+	case errors.As(err, &conflict):
+		details["boardId"] = conflict.BoardID
+		details["currentVersion"] = conflict.CurrentVersion
+		details["providedVersion"] = conflict.ProvidedVersion
+		w.Header().Set("ETag", boardETag(conflict.BoardID, conflict.CurrentVersion))
+	case errors.As(err, &notFound):
+		details["boardId"] = notFound.BoardID
+		if notFound.WidgetID != "" {
+			details["widgetId"] = notFound.WidgetID
+		}
+	case errors.As(err, &validation):
+		details["field"] = validation.Field
+	}
+
+	switch code {
+	case board.CodeNotFound:
+		writeError(w, http.StatusNotFound, code, err.Error(), details)
+	case board.CodeVersionConflict:
+		writeError(w, http.StatusConflict, code, err.Error(), details)
+	case board.CodeValidation:
+		writeError(w, http.StatusBadRequest, code, err.Error(), details)
+	case board.CodeUnavailable:
+		writeError(w, http.StatusServiceUnavailable, code, err.Error(), nil)
+	default:
+		h.logger.ErrorContext(r.Context(), "rest: internal error", "method", r.Method, "path", r.URL.Path, "err", err)
+		writeError(w, http.StatusInternalServerError, code, "internal error", nil)
+	}
+}
+
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		if writeTooLarge(w, err) {
+			return false
+		}
+		writeError(w, http.StatusBadRequest, board.CodeValidation, "invalid json: "+err.Error(), nil)
+		return false
+	}
+	return true
+}
+
+// writeTooLarge répond 413 si err vient de la limite posée par
+// middleware.MaxBody.
+func writeTooLarge(w http.ResponseWriter, err error) bool {
+	var tooLarge *http.MaxBytesError
+	if !errors.As(err, &tooLarge) {
+		return false
+	}
+	writeError(w, http.StatusRequestEntityTooLarge, middleware.CodePayloadTooLarge,
+		fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit), nil)
+	return true
+}
+
+// ─── ETag / If-Match ──────────────────────────────────────────────────────────
+
+// boardETag dérive un ETag fort de l'id et de la version : toute écriture
+// incrémente Model.Version, donc la paire identifie exactement le contenu.
+func boardETag(id string, version int) string {
+	return fmt.Sprintf(`"%s.%d"`, url.PathEscape(id), version)
+}
+
+func setBoardETag(w http.ResponseWriter, b *board.Model) {
+	w.Header().Set("ETag", boardETag(b.ID, b.Version))
+}
+
+// checkNotModified pose ETag et Cache-Control puis, si If-None-Match
+// correspond, répond 304 sans encoder le corps. Renvoie true dans ce cas.
+func checkNotModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+	// Les clients peuvent garder la réponse mais doivent revalider.
+	w.Header().Set("Cache-Control", "no-cache")
+	if !etagListMatches(r.Header.Get("If-None-Match"), etag) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// etagListMatches applique la comparaison faible de RFC 9110 §13.1.2 :
+// le préfixe W/ est ignoré des deux côtés, comme le codage ajouté par
+// middleware.Compress.
+func etagListMatches(header, etag string) bool {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return false
+	}
+	if header == "*" {
+		return true
+	}
+	want := strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(header, ",") {
+		if middleware.TrimETagEncoding(strings.TrimPrefix(strings.TrimSpace(tag), "W/")) == want {
+			return true
+		}
+	}
+	return false
+}
+
+// ifMatchVersion traduit l'en-tête If-Match en version attendue. ok=false si
+// l'en-tête est absent ; seul If-Match: * donne board.AnyVersion. Un ETag qui
+// ne désigne pas ce board donne 0, qui ne correspondra jamais à une version
+// réelle.
+func ifMatchVersion(r *http.Request, id string) (version int, ok bool) {
+	raw := strings.TrimSpace(r.Header.Get("If-Match"))
+	if raw == "" {
+		return board.AnyVersion, false
+	}
+	if raw == "*" {
+		return board.AnyVersion, true
+	}
+	prefix := `"` + url.PathEscape(id) + "."
+	for _, tag := range strings.Split(raw, ",") {
+		// If-Match impose la comparaison forte : un ETag faible ne correspond
+		// jamais. Le codage ajouté par middleware.Compress ne change pas la
+		// version désignée.
+		tag = middleware.TrimETagEncoding(strings.TrimSpace(tag))
+		if !strings.HasPrefix(tag, prefix) || !strings.HasSuffix(tag, `"`) {
+			continue
+		}
+		v, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(tag, prefix), `"`))
+		if err == nil && v > 0 {
+			return v, true
+		}
+	}
+	return 0, true
+}
+
+// writeWriteError renvoie 412 plutôt que 409 quand le conflit vient d'un
+// If-Match : c'est la précondition HTTP qui a échoué, pas le corps.
+func (h *Handler) writeWriteError(w http.ResponseWriter, r *http.Request, err error, fromIfMatch bool) {
+	var conflict *board.VersionConflictError
+	if fromIfMatch && errors.As(err, &conflict) {
+		w.Header().Set("ETag", boardETag(conflict.BoardID, conflict.CurrentVersion))
+		writeError(w, http.StatusPreconditionFailed, codePreconditionFailed, "If-Match does not match current board version", map[string]interface{}{
+			"boardId":        conflict.BoardID,
+			"currentVersion": conflict.CurrentVersion,
+		})
+		return
+	}
+	h.writeServiceError(w, r, err)
+}
@@ -0,0 +1,160 @@
+package rest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"miro-lite-standalone/backend/internal/board"
+)
+
+func TestEtagListMatches(t *testing.T) {
+	tests := []struct {
+		header string
+		etag   string
+		want   bool
+	}{
+		{"", `"b1.2"`, false},
+		{"*", `"b1.2"`, true},
+		{`"b1.2"`, `"b1.2"`, true},
+		{`W/"b1.2"`, `"b1.2"`, true},
+		{`"b1.2"`, `W/"b1.2"`, true},
+		{`"b1.1", "b1.2"`, `"b1.2"`, true},
+		{`"b1.1"`, `"b1.2"`, false},
+		{`"b1.20"`, `"b1.2"`, false},
+		{`"b1.2-gzip"`, `"b1.2"`, true},
+		{`W/"b1.2-br"`, `"b1.2"`, true},
+		{`"b1.2-zstd"`, `"b1.2"`, false},
+	}
+	for _, tt := range tests {
+		if got := etagListMatches(tt.header, tt.etag); got != tt.want {
+			t.Errorf("etagListMatches(%q, %q) = %v, want %v", tt.header, tt.etag, got, tt.want)
+		}
+	}
+}
+
+func TestIfMatchVersion(t *testing.T) {
+	tests := []struct {
+		header string
+		want   int
+		wantOK bool
+	}{
+		{"", board.AnyVersion, false},
+		{"*", board.AnyVersion, true},
+		{`"b%201.3"`, 3, true},
+		{`"b2.3", "b%201.4"`, 4, true},
+		{`W/"b%201.3"`, 0, true},
+		{`"b%201.3-gzip"`, 3, true},
+		{`"b%201.3-br"`, 3, true},
+		{`W/"b%201.3-gzip"`, 0, true},
+		{`"b2.3"`, 0, true},
+		{`"b%201.x"`, 0, true},
+		{`"b%201.0"`, 0, true},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPut, "/", nil)
+		if tt.header != "" {
+			r.Header.Set("If-Match", tt.header)
+		}
+		got, ok := ifMatchVersion(r, "b 1")
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("ifMatchVersion(%q) = %d, %v, want %d, %v", tt.header, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestConditionalGet(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		ifNoneMatch string
+		want        int
+	}{
+		{"board without header", "/api/boards/b1", "", http.StatusOK},
+		{"board current", "/api/boards/b1", `"b1.2"`, http.StatusNotModified},
+		{"board weak", "/api/boards/b1", `W/"b1.2"`, http.StatusNotModified},
+		{"board stale", "/api/boards/b1", `"b1.1"`, http.StatusOK},
+		{"widget current", "/api/boards/b1/widgets/w1", `"b1.2"`, http.StatusNotModified},
+		{"widgets any", "/api/boards/b1/widgets", "*", http.StatusNotModified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, svc := newTestHandler(t)
+			if _, _, err := svc.AddWidget(context.Background(), "b1", board.AnyVersion, board.Widget{ID: "w1", Type: "text"}); err != nil {
+				t.Fatalf("AddWidget: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.ifNoneMatch != "" {
+				req.Header.Set("If-None-Match", tt.ifNoneMatch)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if got := rec.Header().Get("ETag"); got != `"b1.2"` {
+				t.Fatalf("ETag = %s", got)
+			}
+			if tt.want == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Fatalf("304 with a body: %s", rec.Body)
+			}
+		})
+	}
+}
+
+func TestBoardListETag(t *testing.T) {
+	h, svc := newTestHandler(t)
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/boards", nil)
+		req.Header.Set("If-None-Match", ifNoneMatch)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+	etag := get("").Header().Get("ETag")
+	if rec := get(etag); rec.Code != http.StatusNotModified {
+		t.Fatalf("unchanged list: status %d", rec.Code)
+	}
+	if _, err := svc.CreateBoard(context.Background(), "b1", "t"); err != nil {
+		t.Fatalf("CreateBoard: %v", err)
+	}
+	if rec := get(etag); rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Fatalf("list after create: status %d, ETag %s", rec.Code, rec.Header().Get("ETag"))
+	}
+}
+
+func TestStreamBoardJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		b    board.Model
+	}{
+		{"empty", board.Model{ID: "b1", Version: 1, Widgets: []board.Widget{}}},
+		{"escaped title", board.Model{ID: "b<1>", Title: "a \"quoted\" & <b>", Version: 2, Widgets: []board.Widget{}}},
+		{"widgets", board.Model{ID: "b1", Version: 3, Widgets: []board.Widget{
+			{ID: "w1", Type: "text", X: 1.5, Config: map[string]interface{}{"text": "héllo"}},
+			{ID: "w2", Type: "image", Width: 10, Height: 20, Config: map[string]interface{}{"src": "data:image/png;base64,AAAA"}},
+		}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			if err := streamBoardJSON(rec, &tt.b); err != nil {
+				t.Fatalf("streamBoardJSON: %v", err)
+			}
+			if !json.Valid(rec.Body.Bytes()) {
+				t.Fatalf("invalid JSON: %s", rec.Body)
+			}
+			var got, want interface{}
+			wantJSON, _ := json.Marshal(tt.b)
+			_ = json.Unmarshal(rec.Body.Bytes(), &got)
+			_ = json.Unmarshal(wantJSON, &want)
+			gotJSON, _ := json.Marshal(got)
+			if normalized, _ := json.Marshal(want); !bytes.Equal(gotJSON, normalized) {
+				t.Fatalf("streamed %s, want %s", gotJSON, normalized)
+			}
+		})
+	}
+}
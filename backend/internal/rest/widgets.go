@@ -0,0 +1,80 @@
+package rest
+
+import (
+	"net/http"
+
+	"miro-lite-standalone/backend/internal/board"
+)
+
+func (h *Handler) listWidgets(w http.ResponseWriter, r *http.Request) {
+	b, err := h.svc.ResolveBoard(r.Context(), r.PathValue("id"))
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	if checkNotModified(w, r, boardETag(b.ID, b.Version)) {
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, b.Widgets)
+}
+
+func (h *Handler) getWidget(w http.ResponseWriter, r *http.Request) {
+	b, widget, err := h.svc.GetWidget(r.Context(), r.PathValue("id"), r.PathValue("widgetId"))
+	if err != nil {
+		h.writeServiceError(w, r, err)
+		return
+	}
+	if checkNotModified(w, r, boardETag(b.ID, b.Version)) {
+		return
+	}
+	h.writeJSON(w, r, http.StatusOK, widget)
+}
+
+func (h *Handler) addWidget(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var widget board.Widget
+	if !decodeJSON(w, r, &widget) {
+		return
+	}
+	if widget.ID == "" {
+		widget.ID = board.NewWidgetID()
+	}
+	ifVersion, fromIfMatch := ifMatchVersion(r, id)
+	b, created, err := h.svc.AddWidget(r.Context(), id, ifVersion, widget)
+	if err != nil {
+		h.writeWriteError(w, r, err, fromIfMatch)
+		return
+	}
+	setBoardETag(w, b)
+	w.Header().Set("Location", "/api/boards/"+b.ID+"/widgets/"+created.ID)
+	h.writeJSON(w, r, http.StatusCreated, created)
+}
+
+func (h *Handler) updateWidget(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	var widget board.Widget
+	if !decodeJSON(w, r, &widget) {
+		return
+	}
+	widget.ID = r.PathValue("widgetId")
+	ifVersion, fromIfMatch := ifMatchVersion(r, id)
+	b, updated, err := h.svc.UpdateWidget(r.Context(), id, ifVersion, widget)
+	if err != nil {
+		h.writeWriteError(w, r, err, fromIfMatch)
+		return
+	}
+	setBoardETag(w, b)
+	h.writeJSON(w, r, http.StatusOK, updated)
+}
+
+func (h *Handler) deleteWidget(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	ifVersion, fromIfMatch := ifMatchVersion(r, id)
+	b, err := h.svc.DeleteWidget(r.Context(), id, r.PathValue("widgetId"), ifVersion)
+	if err != nil {
+		h.writeWriteError(w, r, err, fromIfMatch)
+		return
+	}
+	setBoardETag(w, b)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,55 @@
+package rest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"miro-lite-standalone/backend/internal/board"
+)
+
+func TestWidgetEndpoints(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   string
+		path     string
+		ifMatch  string
+		body     string
+		want     int
+		wantETag string
+	}{
+		{"list", http.MethodGet, "/api/boards/b1/widgets", "", "", http.StatusOK, `"b1.2"`},
+		{"get", http.MethodGet, "/api/boards/b1/widgets/w1", "", "", http.StatusOK, `"b1.2"`},
+		{"get missing", http.MethodGet, "/api/boards/b1/widgets/w9", "", "", http.StatusNotFound, ""},
+		{"add", http.MethodPost, "/api/boards/b1/widgets", "", `{"id":"w2","type":"text"}`, http.StatusCreated, `"b1.3"`},
+		{"add duplicate", http.MethodPost, "/api/boards/b1/widgets", "", `{"id":"w1","type":"text"}`, http.StatusBadRequest, ""},
+		{"add without type", http.MethodPost, "/api/boards/b1/widgets", "", `{"id":"w2"}`, http.StatusBadRequest, ""},
+		{"add if-match stale", http.MethodPost, "/api/boards/b1/widgets", `"b1.1"`, `{"id":"w2","type":"text"}`, http.StatusPreconditionFailed, `"b1.2"`},
+		{"update", http.MethodPut, "/api/boards/b1/widgets/w1", `"b1.2"`, `{"type":"text","x":5}`, http.StatusOK, `"b1.3"`},
+		{"update missing", http.MethodPut, "/api/boards/b1/widgets/w9", "", `{"type":"text"}`, http.StatusNotFound, ""},
+		{"delete", http.MethodDelete, "/api/boards/b1/widgets/w1", "*", "", http.StatusNoContent, `"b1.3"`},
+		{"delete missing", http.MethodDelete, "/api/boards/b1/widgets/w9", "", "", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, svc := newTestHandler(t)
+			if _, _, err := svc.AddWidget(context.Background(), "b1", board.AnyVersion, board.Widget{ID: "w1", Type: "text"}); err != nil {
+				t.Fatalf("AddWidget: %v", err)
+			}
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+			if got := rec.Header().Get("ETag"); tt.wantETag != "" && got != tt.wantETag {
+				t.Fatalf("ETag = %s, want %s", got, tt.wantETag)
+			}
+		})
+	}
+}
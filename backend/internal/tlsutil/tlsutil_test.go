@@ -0,0 +1,118 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSelfSigned(t *testing.T) {
+	tests := []struct {
+		host  string
+		valid bool
+	}{
+		{"localhost", true},
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"board.test", true},
+		{"10.0.0.7", true},
+		{"other.test", false},
+	}
+	cert, err := SelfSigned("board.test", "10.0.0.7", "")
+	if err != nil {
+		t.Fatalf("SelfSigned: %v", err)
+	}
+	for _, tt := range tests {
+		if err := cert.Leaf.VerifyHostname(tt.host); (err == nil) != tt.valid {
+			t.Errorf("VerifyHostname(%q) = %v, want valid %v", tt.host, err, tt.valid)
+		}
+	}
+	if len(Fingerprint(cert)) != 64 || Fingerprint(nil) != "" {
+		t.Fatalf("Fingerprint = %q", Fingerprint(cert))
+	}
+}
+
+func writePair(t *testing.T, certFile, keyFile string, mod time.Time) *tls.Certificate {
+	t.Helper()
+	cert, err := SelfSigned()
+	if err != nil {
+		t.Fatalf("SelfSigned: %v", err)
+	}
+	key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for file, block := range map[string]*pem.Block{
+		certFile: {Type: "CERTIFICATE", Bytes: cert.Certificate[0]},
+		keyFile:  {Type: "PRIVATE KEY", Bytes: key},
+	} {
+		if err := os.WriteFile(file, pem.EncodeToMemory(block), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cert
+}
+
+func TestReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	start := time.Now().Add(-time.Hour)
+	first := writePair(t, certFile, keyFile, start)
+
+	var reloads []error
+	r, err := NewReloader(certFile, keyFile, func(err error) { reloads = append(reloads, err) })
+	if err != nil {
+		t.Fatalf("NewReloader: %v", err)
+	}
+	serve := func() string {
+		t.Helper()
+		r.mu.Lock()
+		r.lastChecked = time.Time{}
+		r.mu.Unlock()
+		cert, err := r.GetCertificate(nil)
+		if err != nil {
+			t.Fatalf("GetCertificate: %v", err)
+		}
+		return Fingerprint(cert)
+	}
+
+	steps := []struct {
+		name    string
+		change  func() *tls.Certificate
+		reloads int
+	}{
+		{"unchanged", func() *tls.Certificate { return nil }, 0},
+		{"rotated", func() *tls.Certificate { return writePair(t, certFile, keyFile, start.Add(time.Minute)) }, 1},
+		{"invalid key keeps the previous pair", func() *tls.Certificate {
+			if err := os.WriteFile(keyFile, []byte("garbage"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			return nil
+		}, 2},
+	}
+	current := Fingerprint(first)
+	for _, step := range steps {
+		if cert := step.change(); cert != nil {
+			current = Fingerprint(cert)
+		}
+		if got := serve(); got != current {
+			t.Fatalf("%s: served %s, want %s", step.name, got, current)
+		}
+		if len(reloads) != step.reloads {
+			t.Fatalf("%s: %d reload callbacks, want %d", step.name, len(reloads), step.reloads)
+		}
+	}
+	if reloads[0] != nil || reloads[1] == nil {
+		t.Fatalf("reload errors = %v", reloads)
+	}
+	if _, err := NewReloader(filepath.Join(dir, "missing.crt"), keyFile, nil); err == nil {
+		t.Fatal("NewReloader accepted a missing certificate")
+	}
+}
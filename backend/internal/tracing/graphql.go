@@ -0,0 +1,89 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/99designs/gqlgen/graphql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const graphqlTracerName = "miro-lite-standalone/backend/internal/graph"
+
+// GraphQLExtension renvoie l'extension gqlgen qui ouvre un span par
+// opération et un span par champ résolu par un resolver (les champs
+// simplement lus sur une struct ne sont pas tracés). À ajouter avec
+// handler.Server.Use.
+func GraphQLExtension() graphql.HandlerExtension {
+	return graphqlTracer{}
+}
+
+type graphqlTracer struct{}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationInterceptor
+	graphql.FieldInterceptor
+} = graphqlTracer{}
+
+func (graphqlTracer) ExtensionName() string { return "OpenTelemetry" }
+
+func (graphqlTracer) Validate(graphql.ExecutableSchema) error { return nil }
+
+func (graphqlTracer) InterceptOperation(ctx context.Context, next graphql.OperationHandler) graphql.ResponseHandler {
+	oc := graphql.GetOperationContext(ctx)
+	opType, name := "unknown", oc.OperationName
+	if oc.Operation != nil {
+		opType = string(oc.Operation.Operation)
+		if name == "" {
+			name = oc.Operation.Name
+		}
+	}
+	spanName := opType
+	if name != "" {
+		spanName = opType + " " + name
+	}
+	ctx, span := otel.Tracer(graphqlTracerName).Start(ctx, spanName,
+		trace.WithAttributes(
+			attribute.String("graphql.operation.type", opType),
+			attribute.String("graphql.operation.name", name),
+		))
+	handler := next(ctx)
+
+	// Une subscription produit une réponse par événement : le span couvre
+	// la mise en place, les resolvers de chaque événement y restent rattachés.
+	if opType == "subscription" {
+		span.End()
+		return handler
+	}
+	return func(ctx context.Context) *graphql.Response {
+		resp := handler(ctx)
+		if resp != nil && len(resp.Errors) > 0 {
+			span.SetStatus(codes.Error, resp.Errors[0].Message)
+			span.SetAttributes(attribute.Int("graphql.errors", len(resp.Errors)))
+		}
+		span.End()
+		return resp
+	}
+}
+
+func (graphqlTracer) InterceptField(ctx context.Context, next graphql.Resolver) (any, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || !fc.IsResolver {
+		return next(ctx)
+	}
+	ctx, span := otel.Tracer(graphqlTracerName).Start(ctx, fmt.Sprintf("%s.%s", fc.Object, fc.Field.Name),
+		trace.WithAttributes(
+			attribute.String("graphql.field.path", fc.Path().String()),
+		))
+	defer span.End()
+	res, err := next(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return res, err
+}
@@ -0,0 +1,50 @@
+package tracing
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/99designs/gqlgen/client"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"go.opentelemetry.io/otel/codes"
+
+	"miro-lite-standalone/backend/internal/board"
+	"miro-lite-standalone/backend/internal/graph"
+)
+
+func TestGraphQLExtension(t *testing.T) {
+	svc, err := board.NewService("", board.WithMissingBoardPolicy(board.MissingBoardStrict))
+	if err != nil {
+		t.Fatalf("NewService: %v", err)
+	}
+	srv := handler.New(graph.NewExecutableSchema(graph.NewConfig(graph.NewResolver(svc, nil))))
+	srv.AddTransport(transport.POST{})
+	srv.Use(GraphQLExtension())
+	c := client.New(srv)
+
+	tests := []struct {
+		name      string
+		query     string
+		wantSpans []string
+		wantError bool
+	}{
+		{"named query", `query ListBoards { boards { id title } }`, []string{"Query.boards", "query ListBoards"}, false},
+		{"anonymous query", `{ boards { id } }`, []string{"Query.boards", "query"}, false},
+		{"resolver error", `query GetBoard { board(id: "nope") { id } }`, []string{"board.ResolveBoard", "Query.board", "query GetBoard"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := recordSpans(t)
+			var resp map[string]any
+			_ = c.Post(tt.query, &resp)
+			if got := spanNames(rec); !slices.Equal(got, tt.wantSpans) {
+				t.Fatalf("spans = %q, want %q", got, tt.wantSpans)
+			}
+			op := rec.Ended()[len(rec.Ended())-1]
+			if gotError := op.Status().Code == codes.Error; gotError != tt.wantError {
+				t.Errorf("operation status = %v, want error %v", op.Status(), tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type routeKey struct{}
+
+// route est rempli par Route pendant le traitement, pour que le nom du span
+// HTTP porte le motif de la route plutôt que le chemin.
+type route struct {
+	pattern string
+}
+
+// Middleware ouvre un span serveur par requête HTTP, en reprenant le
+// contexte de trace entrant (traceparent). skip liste les chemins exclus
+// (sondes, /metrics).
+func Middleware(skip ...string) func(http.Handler) http.Handler {
+	skipped := make(map[string]bool, len(skip))
+	for _, path := range skip {
+		skipped[path] = true
+	}
+	return func(next http.Handler) http.Handler {
+		traced := otelhttp.NewHandler(next, "http.server",
+			otelhttp.WithFilter(func(r *http.Request) bool { return !skipped[r.URL.Path] }),
+			otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+				if rt, ok := r.Context().Value(routeKey{}).(*route); ok && rt.pattern != "" {
+					return r.Method + " " + rt.pattern
+				}
+				return "HTTP " + r.Method
+			}),
+		)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			traced.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), routeKey{}, &route{})))
+		})
+	}
+}
+
+// Route nomme le span HTTP courant d'après pattern (motif ServeMux, sans la
+// méthode) et pose l'attribut http.route.
+func Route(pattern string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rt, ok := r.Context().Value(routeKey{}).(*route); ok {
+			rt.pattern = pattern
+		}
+		span := trace.SpanFromContext(r.Context())
+		span.SetName(r.Method + " " + pattern)
+		span.SetAttributes(attribute.String("http.route", pattern))
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Transport instrumente un http.RoundTripper sortant : un span client par
+// appel et injection de traceparent dans les en-têtes. base nil vaut
+// http.DefaultTransport.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(base)
+}
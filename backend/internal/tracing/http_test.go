@@ -0,0 +1,61 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestMiddleware(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) })
+	mux := http.NewServeMux()
+	mux.Handle("GET /boards/{id}", Route("/boards/{id}", ok))
+	mux.Handle("/metrics", ok)
+	handler := Middleware("/metrics")(mux)
+
+	tests := []struct {
+		name      string
+		method    string
+		path      string
+		wantSpans []string
+		wantRoute string
+	}{
+		{"routed", http.MethodGet, "/boards/b1", []string{"GET /boards/{id}"}, "/boards/{id}"},
+		{"unrouted", http.MethodPost, "/nope", []string{"HTTP POST"}, ""},
+		{"skipped", http.MethodGet, "/metrics", nil, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := recordSpans(t)
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+			if got := spanNames(rec); !slices.Equal(got, tt.wantSpans) {
+				t.Fatalf("spans = %q, want %q", got, tt.wantSpans)
+			}
+			if tt.wantRoute == "" {
+				return
+			}
+			attrs := rec.Ended()[0].Attributes()
+			if !slices.Contains(attrs, attribute.String("http.route", tt.wantRoute)) {
+				t.Errorf("attributes = %v, want http.route=%s", attrs, tt.wantRoute)
+			}
+		})
+	}
+}
+
+func TestTransport(t *testing.T) {
+	rec := recordSpans(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	resp, err := (&http.Client{Transport: Transport(nil)}).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+	if got := spanNames(rec); len(got) != 1 {
+		t.Fatalf("spans = %q, want one client span", got)
+	}
+}
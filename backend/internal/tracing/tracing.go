@@ -0,0 +1,80 @@
+// Package tracing configure OpenTelemetry : export des spans (OTLP/HTTP ou
+// stdout), propagation W3C, et instrumentation du serveur HTTP et de gqlgen.
+// Sans exporter configuré, le TracerProvider global reste le no-op d'OTel et
+// les spans ne coûtent presque rien.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+type Options struct {
+	// Exporter vaut none, stdout ou otlp.
+	Exporter string
+	// Endpoint est l'URL du collecteur OTLP/HTTP (http://localhost:4318).
+	// Vide : les variables OTEL_EXPORTER_OTLP_* standard s'appliquent.
+	Endpoint string
+	// SampleRatio est la part des traces racines conservées, entre 0 et 1.
+	SampleRatio float64
+	ServiceName string
+	Version     string
+}
+
+// Setup installe le TracerProvider et le propagateur globaux. La fonction
+// renvoyée vide les spans en attente ; à appeler à l'arrêt.
+func Setup(ctx context.Context, opts Options) (shutdown func(context.Context) error, err error) {
+	// La propagation sert aussi sans exporter : un proxy en amont peut
+	// tracer, on relaie son contexte au client remote.
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	switch strings.ToLower(opts.Exporter) {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+	case ExporterOTLP:
+		var clientOpts []otlptracehttp.Option
+		if opts.Endpoint != "" {
+			clientOpts = append(clientOpts, otlptracehttp.WithEndpointURL(opts.Endpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, clientOpts...)
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q (want none, stdout or otlp)", opts.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("trace exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(opts.ServiceName),
+		semconv.ServiceVersion(opts.Version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("trace resource: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
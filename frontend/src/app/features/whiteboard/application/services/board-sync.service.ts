@@ -24,7 +24,7 @@ export class BoardSyncService {
       ),
       mapTo(void 0),
       catchError((e) =>
-        e?.status === 409
+        e?.code === "VERSION_CONFLICT" || e?.status === 409
           ? this.retrySaveWithLatestServerVersion(
               repo,
               localBoard.id,
@@ -1,7 +1,10 @@
+export const VERSION_CONFLICT_CODE = "VERSION_CONFLICT";
+
 export class VersionConflictError extends Error {
   readonly status = 409;
+  readonly code = VERSION_CONFLICT_CODE;
 
-  constructor(message: string) {
+  constructor(message: string, readonly currentVersion?: number) {
     super(message);
     this.name = "VersionConflictError";
   }
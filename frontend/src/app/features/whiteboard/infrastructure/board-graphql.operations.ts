@@ -28,8 +28,8 @@ export const SAVE_BOARD = gql`
 `;
 
 export const BOARD_UPDATED_SUBSCRIPTION = gql`
-  subscription BoardUpdated($boardId: ID!) {
-    boardUpdated(boardId: $boardId) {
+  subscription BoardUpdated($boardId: ID!, $sinceVersion: Int) {
+    boardUpdated(boardId: $boardId, sinceVersion: $sinceVersion) {
       id
       version
       widgets {
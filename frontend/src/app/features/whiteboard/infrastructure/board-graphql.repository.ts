@@ -9,7 +9,10 @@ import {
   widgetToInput,
 } from "./board-graphql.mapper";
 import { GET_BOARD, SAVE_BOARD } from "./board-graphql.operations";
-import { VersionConflictError } from "./board-graphql.errors";
+import {
+  VERSION_CONFLICT_CODE,
+  VersionConflictError,
+} from "./board-graphql.errors";
 import {
   createBoardSubscriptionStream,
   toWebSocketUrl,
@@ -64,7 +67,11 @@ export class BoardGraphqlRepository implements BoardRepositoryPort {
           const msg = extractGraphqlMessage(err) ?? "";
           if (isVersionConflictError(err, msg)) {
             return throwError(
-              () => new VersionConflictError(msg || "Version conflict")
+              () =>
+                new VersionConflictError(
+                  msg || "Version conflict",
+                  extractGraphqlExtensions(err)?.currentVersion
+                )
             );
           }
           return throwError(() => err);
@@ -87,7 +94,17 @@ function extractGraphqlMessage(err: unknown): string | undefined {
   );
 }
 
+function extractGraphqlExtensions(err: unknown): Record<string, any> | undefined {
+  const asAny = err as any;
+  return (
+    asAny?.graphQLErrors?.[0]?.extensions ||
+    asAny?.errors?.[0]?.extensions ||
+    asAny?.networkError?.result?.errors?.[0]?.extensions
+  );
+}
+
 function isVersionConflictError(err: unknown, message: string): boolean {
+  if (extractGraphqlExtensions(err)?.code === VERSION_CONFLICT_CODE) return true;
   if (message.toLowerCase().includes("version conflict")) return true;
   const asAny = err as any;
   const networkStatus = asAny?.networkError?.statusCode;
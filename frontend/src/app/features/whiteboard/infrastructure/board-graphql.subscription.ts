@@ -26,6 +26,9 @@ export function createBoardSubscriptionStream(
     let reconnectAttempt = 0;
     let activeOperationId: string | null = null;
     let waitingForOnline = false;
+    // Last version received: a resubscription resumes from it, so updates
+    // missed while disconnected (or after RESYNC_REQUIRED) are replayed.
+    let lastVersion: number | null = null;
     const baseReconnectDelayMs = 250;
     const maxReconnectDelayMs = 5000;
 
@@ -122,7 +125,10 @@ export function createBoardSubscriptionStream(
               type: "subscribe",
               payload: {
                 query: print(BOARD_UPDATED_SUBSCRIPTION),
-                variables: { boardId },
+                variables:
+                  lastVersion === null
+                    ? { boardId }
+                    : { boardId, sinceVersion: lastVersion },
               },
             })
           );
@@ -137,9 +143,11 @@ export function createBoardSubscriptionStream(
         if (payload.type === "next" && payload.id === operationId) {
           const board = payload.payload?.data?.boardUpdated;
           if (!board) return;
+          const version = board.version ?? 1;
+          lastVersion = version;
           observer.next({
             id: board.id ?? boardId,
-            version: board.version ?? 1,
+            version,
             widgets: board.widgets?.map(payloadToWidget) ?? [],
           });
           return;